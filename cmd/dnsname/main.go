@@ -0,0 +1,16 @@
+//go:build linux
+
+// Command dnsname is a CNI plugin that publishes container hostnames and
+// aliases into NSM's embedded DNS resolver, so podman/CNI networks resolve
+// container names without dnsmasq.
+package main
+
+import (
+	"github.com/containernetworking/cni/pkg/skel"
+
+	"github.com/kashifsb/nsm/internal/dns"
+)
+
+func main() {
+	skel.PluginMain(dns.CmdAdd, dns.CmdCheck, dns.CmdDel, dns.CNIVersions, "dnsname", "dnsname is a CNI plugin that publishes container names into NSM's embedded resolver")
+}
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kashifsb/nsm/internal/config"
+	"github.com/kashifsb/nsm/pkg/utils"
+)
+
+// newConfigCmd returns the `nsm config` command group: `set` persists
+// overrides to ~/.nsm/config.yaml via config.Save, `get` prints the
+// resolved configuration config.Load would hand to the rest of NSM.
+func newConfigCmd() *cobra.Command {
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect or persist NSM's saved configuration",
+	}
+
+	configCmd.AddCommand(&cobra.Command{
+		Use:   "set key=value [key=value...]",
+		Short: "Set one or more config values and persist them to ~/.nsm/config.yaml",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runConfigSet(cmd, args)
+		},
+	})
+
+	configCmd.AddCommand(&cobra.Command{
+		Use:   "get [key]",
+		Short: "Print the resolved configuration, or a single key's value",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runConfigGet(cmd, args)
+		},
+	})
+
+	return configCmd
+}
+
+func runConfigSet(cmd *cobra.Command, pairs []string) error {
+	cfg, err := config.Load(cmd)
+	if err != nil {
+		return fmt.Errorf("load current configuration: %w", err)
+	}
+
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return fmt.Errorf("invalid key=value pair %q, expected key=value", pair)
+		}
+		if err := setConfigField(cfg, key, value); err != nil {
+			return err
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	if err := config.Save(cfg); err != nil {
+		return fmt.Errorf("save configuration: %w", err)
+	}
+
+	fmt.Println("Saved to ~/.nsm/config.yaml")
+	return nil
+}
+
+func runConfigGet(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(cmd)
+	if err != nil {
+		return fmt.Errorf("load current configuration: %w", err)
+	}
+
+	if len(args) == 0 {
+		out, err := utils.PrettyJSON(cfg)
+		if err != nil {
+			return fmt.Errorf("render configuration: %w", err)
+		}
+		fmt.Println(out)
+		return nil
+	}
+
+	value, err := getConfigField(cfg, args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Println(value)
+	return nil
+}
+
+// setConfigField applies value to cfg's field named by key (the same
+// snake-case names used by NSM_* env vars and .nsm.yaml, e.g. "http-port"
+// or "enable-dns").
+func setConfigField(cfg *config.Config, key, value string) error {
+	switch key {
+	case "project-type":
+		cfg.ProjectType = config.ProjectType(value)
+	case "domain":
+		cfg.Domain = value
+	case "command":
+		cfg.Command = value
+	case "static":
+		cfg.StaticDir = value
+	case "static-spa":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("static-spa: %w", err)
+		}
+		cfg.StaticSPA = b
+	case "static-dir-listing":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("static-dir-listing: %w", err)
+		}
+		cfg.StaticDirListing = b
+	case "static-404":
+		cfg.Static404 = value
+	case "http-port":
+		port, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("http-port: %w", err)
+		}
+		cfg.HTTPPort = port
+	case "https-port":
+		port, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("https-port: %w", err)
+		}
+		cfg.HTTPSPort = port
+	case "use-port-443":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("use-port-443: %w", err)
+		}
+		cfg.UsePort443 = b
+	case "enable-https":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("enable-https: %w", err)
+		}
+		cfg.EnableHTTPS = b
+	case "enable-dns":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("enable-dns: %w", err)
+		}
+		cfg.EnableDNS = b
+	case "enable-proxy":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("enable-proxy: %w", err)
+		}
+		cfg.EnableProxy = b
+	case "auto-open":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("auto-open: %w", err)
+		}
+		cfg.AutoOpen = b
+	case "cert-issuer":
+		cfg.CertIssuer = value
+	case "acme-directory-url":
+		cfg.ACMEDirectoryURL = value
+	case "acme-email":
+		cfg.ACMEEmail = value
+	case "acme-challenge":
+		cfg.ACMEChallenge = value
+	case "dns-provider":
+		cfg.DNSProvider = value
+	case "upstream-dns":
+		if value == "" {
+			cfg.UpstreamDNS = nil
+		} else {
+			cfg.UpstreamDNS = strings.Split(value, ",")
+		}
+	case "bootstrap-dns":
+		cfg.BootstrapDNS = value
+	case "log-format":
+		cfg.LogFormat = value
+	case "raw-output":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("raw-output: %w", err)
+		}
+		cfg.RawOutput = b
+	case "metrics-addr":
+		cfg.MetricsAddr = value
+	case "access-log":
+		cfg.AccessLogPath = value
+	case "access-log-format":
+		cfg.AccessLogFormat = value
+	case "access-log-max-size-mb":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("access-log-max-size-mb: %w", err)
+		}
+		cfg.AccessLogMaxSizeMB = n
+	case "access-log-max-backups":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("access-log-max-backups: %w", err)
+		}
+		cfg.AccessLogMaxBackups = n
+	case "access-log-max-age-days":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("access-log-max-age-days: %w", err)
+		}
+		cfg.AccessLogMaxAgeDays = n
+	case "diag-addr":
+		cfg.DiagAddr = value
+	case "ctl-addr":
+		cfg.CtlAddr = value
+	case "teardown-timeout":
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("teardown-timeout: %w", err)
+		}
+		cfg.TeardownTimeout = d
+	case "extra-upstreams":
+		if value == "" {
+			cfg.ExtraUpstreams = nil
+		} else {
+			cfg.ExtraUpstreams = strings.Split(value, ",")
+		}
+	case "upstream-policy":
+		cfg.UpstreamPolicy = value
+	case "http-proxy":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("http-proxy: %w", err)
+		}
+		cfg.HTTPProxy = b
+	case "http-proxy-allow":
+		if value == "" {
+			cfg.HTTPProxyAllowHosts = nil
+		} else {
+			cfg.HTTPProxyAllowHosts = strings.Split(value, ",")
+		}
+	case "http-proxy-deny":
+		if value == "" {
+			cfg.HTTPProxyDenyHosts = nil
+		} else {
+			cfg.HTTPProxyDenyHosts = strings.Split(value, ",")
+		}
+	case "proxy-middlewares":
+		if value == "" {
+			cfg.ProxyMiddlewares = nil
+		} else {
+			cfg.ProxyMiddlewares = strings.Split(value, ",")
+		}
+	case "proxy-inject-script":
+		cfg.ProxyInjectScript = value
+	case "proxy-request-headers":
+		if value == "" {
+			cfg.ProxyRequestHeaders = nil
+		} else {
+			cfg.ProxyRequestHeaders = strings.Split(value, ",")
+		}
+	case "proxy-response-headers":
+		if value == "" {
+			cfg.ProxyResponseHeaders = nil
+		} else {
+			cfg.ProxyResponseHeaders = strings.Split(value, ",")
+		}
+	case "proxy-basic-auth-users":
+		if value == "" {
+			cfg.ProxyBasicAuthUsers = nil
+		} else {
+			cfg.ProxyBasicAuthUsers = strings.Split(value, ",")
+		}
+	case "tunnel-provider":
+		cfg.Tunnel.Provider = value
+	default:
+		return fmt.Errorf("unknown config key %q", key)
+	}
+	return nil
+}
+
+func getConfigField(cfg *config.Config, key string) (string, error) {
+	switch key {
+	case "project-type":
+		return string(cfg.ProjectType), nil
+	case "domain":
+		return cfg.Domain, nil
+	case "command":
+		return cfg.Command, nil
+	case "static":
+		return cfg.StaticDir, nil
+	case "static-spa":
+		return strconv.FormatBool(cfg.StaticSPA), nil
+	case "static-dir-listing":
+		return strconv.FormatBool(cfg.StaticDirListing), nil
+	case "static-404":
+		return cfg.Static404, nil
+	case "http-port":
+		return strconv.Itoa(cfg.HTTPPort), nil
+	case "https-port":
+		return strconv.Itoa(cfg.HTTPSPort), nil
+	case "use-port-443":
+		return strconv.FormatBool(cfg.UsePort443), nil
+	case "enable-https":
+		return strconv.FormatBool(cfg.EnableHTTPS), nil
+	case "enable-dns":
+		return strconv.FormatBool(cfg.EnableDNS), nil
+	case "enable-proxy":
+		return strconv.FormatBool(cfg.EnableProxy), nil
+	case "auto-open":
+		return strconv.FormatBool(cfg.AutoOpen), nil
+	case "cert-issuer":
+		return cfg.CertIssuer, nil
+	case "acme-directory-url":
+		return cfg.ACMEDirectoryURL, nil
+	case "acme-email":
+		return cfg.ACMEEmail, nil
+	case "acme-challenge":
+		return cfg.ACMEChallenge, nil
+	case "dns-provider":
+		return cfg.DNSProvider, nil
+	case "upstream-dns":
+		return strings.Join(cfg.UpstreamDNS, ","), nil
+	case "bootstrap-dns":
+		return cfg.BootstrapDNS, nil
+	case "log-format":
+		return cfg.LogFormat, nil
+	case "raw-output":
+		return strconv.FormatBool(cfg.RawOutput), nil
+	case "metrics-addr":
+		return cfg.MetricsAddr, nil
+	case "access-log":
+		return cfg.AccessLogPath, nil
+	case "access-log-format":
+		return cfg.AccessLogFormat, nil
+	case "access-log-max-size-mb":
+		return strconv.Itoa(cfg.AccessLogMaxSizeMB), nil
+	case "access-log-max-backups":
+		return strconv.Itoa(cfg.AccessLogMaxBackups), nil
+	case "access-log-max-age-days":
+		return strconv.Itoa(cfg.AccessLogMaxAgeDays), nil
+	case "diag-addr":
+		return cfg.DiagAddr, nil
+	case "ctl-addr":
+		return cfg.CtlAddr, nil
+	case "teardown-timeout":
+		return cfg.TeardownTimeout.String(), nil
+	case "extra-upstreams":
+		return strings.Join(cfg.ExtraUpstreams, ","), nil
+	case "upstream-policy":
+		return cfg.UpstreamPolicy, nil
+	case "http-proxy":
+		return strconv.FormatBool(cfg.HTTPProxy), nil
+	case "http-proxy-allow":
+		return strings.Join(cfg.HTTPProxyAllowHosts, ","), nil
+	case "http-proxy-deny":
+		return strings.Join(cfg.HTTPProxyDenyHosts, ","), nil
+	case "proxy-middlewares":
+		return strings.Join(cfg.ProxyMiddlewares, ","), nil
+	case "proxy-inject-script":
+		return cfg.ProxyInjectScript, nil
+	case "proxy-request-headers":
+		return strings.Join(cfg.ProxyRequestHeaders, ","), nil
+	case "proxy-response-headers":
+		return strings.Join(cfg.ProxyResponseHeaders, ","), nil
+	case "proxy-basic-auth-users":
+		return strings.Join(cfg.ProxyBasicAuthUsers, ","), nil
+	case "tunnel-provider":
+		return cfg.Tunnel.Provider, nil
+	default:
+		return "", fmt.Errorf("unknown config key %q", key)
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kashifsb/nsm/internal/app"
+	"github.com/kashifsb/nsm/internal/config"
+)
+
+// newCtlCmd returns `nsm ctl`, a companion command group driving an NSM
+// already running in this project directory over its IPC socket
+// (DataDir/nsm.sock) - unlike `nsm reload`/`nsm site`, which speak the
+// older control socket's minimal text protocol, this one gets the typed
+// JSON status API and the newer restart-dev/rotate-cert/stop commands.
+func newCtlCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ctl",
+		Short: "Drive a running NSM over its IPC socket without attaching to its TTY",
+	}
+
+	cmd.AddCommand(newCtlStatusCmd())
+	cmd.AddCommand(newCtlReloadCmd())
+	cmd.AddCommand(newCtlRestartDevCmd())
+	cmd.AddCommand(newCtlRotateCertCmd())
+	cmd.AddCommand(newCtlStopCmd())
+	return cmd
+}
+
+// ctlDataDir resolves the DataDir of the NSM running in the current
+// working directory, the same derivation finalize uses, without loading
+// a full Config.
+func ctlDataDir() (string, error) {
+	projectDir, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("get working directory: %w", err)
+	}
+	return config.DataDirForProjectDir(projectDir)
+}
+
+func newCtlStatusCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Print the running NSM's current Status as JSON",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dataDir, err := ctlDataDir()
+			if err != nil {
+				return err
+			}
+
+			status, err := app.SendIPCStatus(dataDir)
+			if err != nil {
+				return fmt.Errorf("status failed: %w", err)
+			}
+
+			out, err := json.MarshalIndent(status, "", "  ")
+			if err != nil {
+				return fmt.Errorf("encode status: %w", err)
+			}
+			fmt.Println(string(out))
+			return nil
+		},
+	}
+}
+
+func newCtlReloadCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "reload",
+		Short: "Ask a running NSM to reload its configuration",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dataDir, err := ctlDataDir()
+			if err != nil {
+				return err
+			}
+			if err := app.SendIPCReload(dataDir); err != nil {
+				return fmt.Errorf("reload failed: %w", err)
+			}
+			fmt.Println("Reload requested")
+			return nil
+		},
+	}
+}
+
+func newCtlRestartDevCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "restart-dev",
+		Short: "Stop and restart a running NSM's dev server step",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dataDir, err := ctlDataDir()
+			if err != nil {
+				return err
+			}
+			if err := app.SendIPCRestartDev(dataDir); err != nil {
+				return fmt.Errorf("restart-dev failed: %w", err)
+			}
+			fmt.Println("Dev server restarted")
+			return nil
+		},
+	}
+}
+
+func newCtlRotateCertCmd() *cobra.Command {
+	var domain string
+
+	cmd := &cobra.Command{
+		Use:   "rotate-cert",
+		Short: "Force a running NSM to reissue and reload its TLS certificate",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dataDir, err := ctlDataDir()
+			if err != nil {
+				return err
+			}
+			if err := app.SendIPCRotateCert(dataDir, domain); err != nil {
+				return fmt.Errorf("rotate-cert failed: %w", err)
+			}
+			fmt.Println("Certificate rotated")
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&domain, "domain", "", "Domain to reissue a certificate for (default: the project's configured domain)")
+	return cmd
+}
+
+func newCtlStopCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "stop",
+		Short: "Ask a running NSM to shut down gracefully",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dataDir, err := ctlDataDir()
+			if err != nil {
+				return err
+			}
+			if err := app.SendIPCStop(dataDir); err != nil {
+				return fmt.Errorf("stop failed: %w", err)
+			}
+			fmt.Println("Stop requested")
+			return nil
+		},
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/signal"
@@ -9,17 +10,29 @@ import (
 	"syscall"
 
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 
 	"github.com/kashifsb/nsm/internal/app"
 	"github.com/kashifsb/nsm/internal/config"
+	"github.com/kashifsb/nsm/internal/setup"
 	"github.com/kashifsb/nsm/pkg/logger"
+	"github.com/kashifsb/nsm/pkg/versioninfo"
 )
 
 var (
-	version = "1.0.1"
-	commit  = "dev"
+	version   = "1.0.1"
+	commit    = "dev"
+	buildDate = "unknown"
 )
 
+const versionBanner = `
+ _   _ ____  __  __
+| \ | / ___||  \/  |
+|  \| \___ \| |\/| |
+| |\  |___) | |  | |
+|_| \_|____/|_|  |_|
+`
+
 func main() {
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
@@ -38,13 +51,63 @@ func main() {
 	rootCmd.Flags().StringP("project-type", "t", "", "Project type (vite, react, go, rust, python, java, dotnet)")
 	rootCmd.Flags().StringP("domain", "d", "", "Custom domain (e.g., api.dev)")
 	rootCmd.Flags().StringP("command", "c", "", "Development command to run")
+	rootCmd.Flags().String("static", "", "Serve this directory directly instead of launching a dev server (implies project type \"static\")")
+	rootCmd.Flags().Bool("static-spa", false, "Rewrite unknown paths to index.html for client-side routers (static mode only)")
+	rootCmd.Flags().Bool("static-dir-listing", false, "Serve directory index pages for directories with no index.html (static mode only)")
+	rootCmd.Flags().String("static-404", "", "File within --static to serve (with a 404 status) when a path isn't found")
 	rootCmd.Flags().IntP("http-port", "p", 0, "HTTP port (0 = auto)")
 	rootCmd.Flags().IntP("https-port", "s", 0, "HTTPS port (0 = auto, prefers 443)")
+	rootCmd.Flags().BoolP("enable-dns", "D", true, "Run the embedded DNS server (same as NSM_ENABLE_DNS=0 to disable)")
+	rootCmd.Flags().Bool("enable-https", true, "Serve over HTTPS (same as NSM_ENABLE_HTTPS=0 to disable)")
+	rootCmd.Flags().Bool("enable-proxy", true, "Run the reverse proxy (same as NSM_ENABLE_PROXY=0 to disable)")
 	rootCmd.Flags().Bool("headless", false, "Run without interactive UI")
 	rootCmd.Flags().BoolP("no-443", "n", false, "Don't use port 443")
+	rootCmd.Flags().Bool("no-auto-open", false, "Don't automatically open the browser once ready (same as NSM_AUTO_OPEN=0)")
+	rootCmd.Flags().String("cert-issuer", "", "Certificate issuer: mkcert, local (NSM's built-in CA, used automatically if mkcert is missing), or acme (publicly trusted)")
+	rootCmd.Flags().String("acme-directory-url", "", "ACME directory URL (default: Let's Encrypt production)")
+	rootCmd.Flags().String("acme-email", "", "Contact email registered with the ACME account")
+	rootCmd.Flags().String("acme-challenge", "", "ACME challenge type: http-01, tls-alpn-01, or dns-01")
+	rootCmd.Flags().String("dns-provider", "", "DNS-01 provider: cloudflare, route53, manual, or exec")
+	rootCmd.Flags().String("log-format", "", "Output format for service logs: text (default) or json (canonicalized events for piping into jq)")
+	rootCmd.Flags().Bool("raw", false, "Pass service output straight to the terminal, ANSI codes and all, instead of the TUI's formatted log view")
+	rootCmd.Flags().String("metrics-addr", "", "Address for the Prometheus /metrics, /healthz, and /readyz sidecar server (e.g. :9090); empty disables it")
+	rootCmd.Flags().String("access-log", "", "Path to write an access log (default ~/.nsm/logs/access.log)")
+	rootCmd.Flags().String("access-log-format", "", "Access log line format: common, combined (default), or json")
+	rootCmd.Flags().Int("access-log-max-size-mb", 0, "Rotate the access log once it exceeds this size in MB (default 100)")
+	rootCmd.Flags().Int("access-log-max-backups", 0, "Number of rotated access log files to keep (default: keep all)")
+	rootCmd.Flags().Int("access-log-max-age-days", 0, "Days to keep rotated access log files before deleting them (default: keep forever)")
+	rootCmd.Flags().String("diag-addr", "", "Address for the headless-mode diagnostic server (/healthz, /readyz, /state, /logs, /debug/pprof/*, e.g. :9091); empty disables it")
+	rootCmd.Flags().String("ctl-addr", "", "Address for the loopback HTTP variant of the IPC control socket, token-authenticated via DataDir/config/token (e.g. :9092); empty runs only the Unix domain socket at DataDir/nsm.sock")
+	rootCmd.Flags().Duration("teardown-timeout", 0, "Hard deadline for graceful shutdown's dev/proxy/dns/certs/ports teardown before NSM quits anyway (default 15s)")
+	rootCmd.Flags().StringArray("upstream", nil, "Additional backend target (host:port) to front alongside the dev server; repeatable for micro-frontend setups")
+	rootCmd.Flags().String("upstream-policy", "", "Selection policy when multiple upstream targets are configured: round-robin (default), random, least-conn, ip-hash, or first-healthy")
+	rootCmd.Flags().Bool("http-proxy", false, "Run as an HTTP CONNECT / forward proxy instead of fronting the dev server, for tunneling a client's HTTP_PROXY traffic through NSM")
+	rootCmd.Flags().StringArray("http-proxy-allow", nil, "Host (or suffix, matching subdomains) to allow when --http-proxy is set; repeatable; empty allows anything not denied")
+	rootCmd.Flags().StringArray("http-proxy-deny", nil, "Host (or suffix, matching subdomains) to deny when --http-proxy is set; repeatable; takes precedence over --http-proxy-allow")
+	rootCmd.Flags().StringArray("proxy-middleware", nil, "Name a proxy middleware to enable, in order (recovery, logging, cors, compress, html-inject, headers, basic-auth); repeatable; defaults to recovery, logging, cors")
+	rootCmd.Flags().String("proxy-inject-script", "", "HTML/script to inject before </body> in every text/html response; requires \"html-inject\" in --proxy-middleware")
+	rootCmd.Flags().StringArray("proxy-request-header", nil, "\"Name: Value\" header to add to every proxied request; repeatable; requires \"headers\" in --proxy-middleware")
+	rootCmd.Flags().StringArray("proxy-response-header", nil, "\"Name: Value\" header to add to every proxied response; repeatable; requires \"headers\" in --proxy-middleware")
+	rootCmd.Flags().StringArray("proxy-basic-auth", nil, "\"user:password\" allowed to access the proxy; repeatable; requires \"basic-auth\" in --proxy-middleware")
 	rootCmd.Flags().BoolP("debug", "v", false, "Enable debug logging")
 	rootCmd.Flags().BoolP("auto-yes", "y", false, "Auto-confirm prompts")
 
+	versionCmd := &cobra.Command{
+		Use:   "version",
+		Short: "Print version, build, and environment info",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			asJSON, _ := cmd.Flags().GetBool("json")
+			return runVersion(asJSON)
+		},
+	}
+	versionCmd.Flags().Bool("json", false, "Output as JSON")
+	rootCmd.AddCommand(versionCmd)
+	rootCmd.AddCommand(newConfigCmd())
+	rootCmd.AddCommand(newReloadCmd())
+	rootCmd.AddCommand(newServiceCmd())
+	rootCmd.AddCommand(newSiteCmd())
+	rootCmd.AddCommand(newCtlCmd())
+
 	if err := rootCmd.Execute(); err != nil {
 		// Provide better error context
 		if err.Error() == "shutdown completed with some errors" {
@@ -70,34 +133,46 @@ func runNSM(ctx context.Context, cmd *cobra.Command) error {
 	// Initialize logger
 	debug, _ := cmd.Flags().GetBool("debug")
 	logger.Init(debug)
+	logger.LogStructuredConfig(logger.CurrentConfig())
 
-	// Parse configuration
-	cfg, err := config.ParseFromFlags(cmd)
+	// Parse configuration: built-in defaults, then ~/.nsm/config.yaml and
+	// .nsm.yaml, then NSM_* env vars, then cmd's flags.
+	cfg, err := config.Load(cmd)
 	if err != nil {
-		return fmt.Errorf("parse configuration: %w", err)
+		return fmt.Errorf("load configuration: %w", err)
 	}
 
-	// Check if headless mode
+	// Check if headless mode. --raw implies headless too: raw passthrough
+	// writes service output straight to stdout, which would otherwise be
+	// fought over by the TUI's alt-screen buffer. Absent an explicit
+	// --headless, fall back to auto-detecting a non-interactive stdout (CI,
+	// Docker, systemd, nohup) so the TUI never gets launched somewhere it
+	// can't render.
 	headless, _ := cmd.Flags().GetBool("headless")
-	if headless {
-		return runHeadless(ctx, cfg)
+	if !cmd.Flags().Changed("headless") {
+		headless = !term.IsTerminal(int(os.Stdout.Fd()))
+	}
+	if headless || cfg.RawOutput {
+		return runHeadless(ctx, cfg, cmd)
 	}
 
 	// Run interactive UI
-	return runInteractive(ctx, cfg)
+	return runInteractive(ctx, cfg, cmd)
 }
 
-func runInteractive(ctx context.Context, cfg *config.Config) error {
+func runInteractive(ctx context.Context, cfg *config.Config, cmd *cobra.Command) error {
 	// Create and run the app with UI
 	appInstance, err := app.NewApp(cfg)
 	if err != nil {
 		return fmt.Errorf("create app: %w", err)
 	}
 
+	watchReloadSignal(ctx, appInstance, cmd)
+
 	return appInstance.Run(ctx)
 }
 
-func runHeadless(ctx context.Context, cfg *config.Config) error {
+func runHeadless(ctx context.Context, cfg *config.Config, cmd *cobra.Command) error {
 	// Direct execution without UI
 	logger.Info("Running in headless mode")
 
@@ -107,6 +182,66 @@ func runHeadless(ctx context.Context, cfg *config.Config) error {
 		return fmt.Errorf("create app: %w", err)
 	}
 
+	watchReloadSignal(ctx, appInstance, cmd)
+
 	// Run the app in headless mode
 	return appInstance.RunHeadless(ctx)
 }
+
+// watchReloadSignal re-parses flags and applies them to appInstance every
+// time the process receives SIGHUP, so DNS upstreams, domain, and log
+// level can change without restarting NSM. It also installs the same
+// re-parse-and-apply function as appInstance's reload handler, which its
+// own config file watcher and control socket ("nsm reload") call for the
+// same effect without a signal.
+func watchReloadSignal(ctx context.Context, appInstance *app.App, cmd *cobra.Command) {
+	reload := func() error {
+		cfg, err := config.Load(cmd)
+		if err != nil {
+			return fmt.Errorf("load configuration: %w", err)
+		}
+		return appInstance.Reload(cfg)
+	}
+	appInstance.SetReloadHandler(reload)
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	go func() {
+		defer signal.Stop(hup)
+
+		for {
+			select {
+			case <-hup:
+				logger.Info("Received SIGHUP, reloading configuration")
+				if err := reload(); err != nil {
+					logger.Error("Reload failed", "error", err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func runVersion(asJSON bool) error {
+	info := versioninfo.Detect(version, commit, buildDate)
+
+	if status, err := setup.GetSystemStatus(); err == nil {
+		for _, tld := range status.TLDs {
+			info.TLDs = append(info.TLDs, tld.Name)
+		}
+	}
+
+	if asJSON {
+		data, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			return fmt.Errorf("marshal version info: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	fmt.Println(versioninfo.Render(versionBanner, info))
+	return nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kashifsb/nsm/internal/app"
+)
+
+// newReloadCmd returns `nsm reload`: a companion command that asks an NSM
+// already running in this project directory (over its control socket) to
+// re-read its configuration, the same trigger a .nsm.yaml edit or SIGHUP
+// already cause.
+func newReloadCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "reload",
+		Short: "Ask a running NSM in this directory to reload its configuration",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			projectDir, err := os.Getwd()
+			if err != nil {
+				return fmt.Errorf("get working directory: %w", err)
+			}
+
+			if err := app.SendReload(projectDir); err != nil {
+				return fmt.Errorf("reload failed: %w", err)
+			}
+
+			fmt.Println("Reload requested")
+			return nil
+		},
+	}
+}
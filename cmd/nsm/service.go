@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kashifsb/nsm/pkg/service"
+)
+
+// serviceName/serviceLabel identify the background service `nsm service`
+// installs: this project's `nsm` invocation, kept running without a
+// terminal attached.
+const serviceLabel = "com.nsm.nsm"
+
+func nsmService(args []string) (service.Service, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return service.Service{}, fmt.Errorf("resolve nsm executable path: %w", err)
+	}
+
+	homeDir, _ := os.UserHomeDir()
+	return service.Service{
+		Name:     "nsm",
+		Label:    serviceLabel,
+		ExecPath: exe,
+		Args:     append([]string{"--headless"}, args...),
+		LogDir:   filepath.Join(homeDir, ".nsm", "logs"),
+	}, nil
+}
+
+// checkInstallLocation warns, but doesn't block, when the running
+// executable isn't somewhere a later `go install`/Homebrew upgrade or
+// project move won't silently break the installed service - the same
+// pre-check AdGuard Home runs before letting a user install itself as a
+// LaunchDaemon.
+func checkInstallLocation(exe string) {
+	homeDir, _ := os.UserHomeDir()
+	safeDirs := []string{"/Applications", filepath.Join(homeDir, "bin"), "/usr/local/bin", "/opt/homebrew/bin"}
+
+	for _, dir := range safeDirs {
+		if strings.HasPrefix(exe, dir) {
+			return
+		}
+	}
+
+	fmt.Fprintf(os.Stderr,
+		"Warning: %s isn't under /Applications or ~/bin - if this binary is moved or deleted, the installed service will fail to start.\n", exe)
+}
+
+// newServiceCmd returns `nsm service`, installing/managing this project's
+// `nsm` invocation as a background launchd/systemd service so it keeps
+// running without a terminal attached.
+func newServiceCmd() *cobra.Command {
+	serviceCmd := &cobra.Command{
+		Use:   "service",
+		Short: "Install and manage NSM as a background service",
+		Long: "Install, start, stop, or check the status of this project's `nsm` invocation\n" +
+			"as a background launchd (macOS) or systemd (Linux) service.",
+	}
+
+	installCmd := &cobra.Command{
+		Use:   "install",
+		Short: "Install nsm as a background service",
+		Long: "Install nsm as a background service, re-running it with the flags given after\n" +
+			"\"--\" (e.g. `nsm service install -- --domain myapp.dev --command \"npm run dev\"`).",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			system, _ := cmd.Flags().GetBool("system")
+
+			svc, err := nsmService(args)
+			if err != nil {
+				return err
+			}
+			svc.System = system
+
+			checkInstallLocation(svc.ExecPath)
+
+			if err := installService(svc); err != nil {
+				return err
+			}
+			if err := startService(svc); err != nil {
+				return err
+			}
+			return verifyService(svc)
+		},
+	}
+	installCmd.Flags().Bool("system", false, "Install system-wide (requires root) instead of for the current user")
+
+	uninstallCmd := &cobra.Command{
+		Use:   "uninstall",
+		Short: "Remove the installed nsm service",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			system, _ := cmd.Flags().GetBool("system")
+			svc, err := nsmService(nil)
+			if err != nil {
+				return err
+			}
+			svc.System = system
+			return svc.Uninstall()
+		},
+	}
+	uninstallCmd.Flags().Bool("system", false, "Uninstall the system-wide service instead of the per-user one")
+
+	startCmd := &cobra.Command{
+		Use:   "start",
+		Short: "Start the installed nsm service",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			svc, err := nsmService(nil)
+			if err != nil {
+				return err
+			}
+			return startService(svc)
+		},
+	}
+
+	stopCmd := &cobra.Command{
+		Use:   "stop",
+		Short: "Stop the installed nsm service",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			svc, err := nsmService(nil)
+			if err != nil {
+				return err
+			}
+			return svc.Stop()
+		},
+	}
+
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show whether the nsm service is installed and running",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			svc, err := nsmService(nil)
+			if err != nil {
+				return err
+			}
+			return verifyService(svc)
+		},
+	}
+
+	serviceCmd.AddCommand(installCmd, uninstallCmd, startCmd, stopCmd, statusCmd)
+	return serviceCmd
+}
+
+func installService(svc service.Service) error {
+	fmt.Printf("Installing %s as a background service...\n", svc.Name)
+	return svc.Install()
+}
+
+func startService(svc service.Service) error {
+	return svc.Start()
+}
+
+// verifyService prints the service's current status and reports running
+// as an error only when it's installed but not running; an uninstalled
+// service is reported, not failed, so `nsm service status` works before
+// the first install.
+func verifyService(svc service.Service) error {
+	status, err := svc.Status()
+	if err != nil {
+		return fmt.Errorf("check service status: %w", err)
+	}
+
+	if !status.Installed {
+		fmt.Println("nsm service is not installed")
+		return nil
+	}
+
+	if status.Running {
+		fmt.Println("nsm service is installed and running")
+		return nil
+	}
+
+	fmt.Println("nsm service is installed but not running")
+	return fmt.Errorf("nsm service is not running")
+}
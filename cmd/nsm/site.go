@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kashifsb/nsm/internal/app"
+)
+
+// newSiteCmd returns `nsm site`, a companion command group for adding and
+// removing Sites on an NSM already running in this project directory over
+// its control socket - the runtime counterpart to editing Sites in
+// .nsm.yaml and running `nsm reload`.
+func newSiteCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "site",
+		Short: "Manage sites running alongside this project on a running NSM",
+	}
+
+	cmd.AddCommand(newSiteAddCmd())
+	cmd.AddCommand(newSiteRemoveCmd())
+	return cmd
+}
+
+func newSiteAddCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "add <domain>",
+		Short: "Start a site already declared under sites in the project configuration",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			projectDir, err := os.Getwd()
+			if err != nil {
+				return fmt.Errorf("get working directory: %w", err)
+			}
+
+			if err := app.SendSiteAdd(projectDir, args[0]); err != nil {
+				return fmt.Errorf("add site failed: %w", err)
+			}
+
+			fmt.Printf("Site %s added\n", args[0])
+			return nil
+		},
+	}
+}
+
+func newSiteRemoveCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "remove <domain>",
+		Short: "Stop a running site and remove it from the shared proxy",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			projectDir, err := os.Getwd()
+			if err != nil {
+				return fmt.Errorf("get working directory: %w", err)
+			}
+
+			if err := app.SendSiteRemove(projectDir, args[0]); err != nil {
+				return fmt.Errorf("remove site failed: %w", err)
+			}
+
+			fmt.Printf("Site %s removed\n", args[0])
+			return nil
+		},
+	}
+}
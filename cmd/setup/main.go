@@ -2,24 +2,43 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/signal"
 	"runtime"
+	"strings"
 	"syscall"
 
 	"github.com/spf13/cobra"
 
 	"github.com/kashifsb/nsm/internal/setup"
+	nsmdns "github.com/kashifsb/nsm/pkg/dns"
 	"github.com/kashifsb/nsm/pkg/logger"
+	"github.com/kashifsb/nsm/pkg/versioninfo"
 )
 
 var (
-	version = "3.0.0"
-	commit  = "dev"
+	version   = "3.0.0"
+	commit    = "dev"
+	buildDate = "unknown"
 )
 
+const versionBanner = `
+ _   _ ____  __  __       ____       _
+| \ | / ___||  \/  |     / ___|  ___| |_ _   _ _ __
+|  \| \___ \| |\/| |_____\___ \ / _ \ __| | | | '_ \
+| |\  |___) | |  | |_____|___) |  __/ |_| |_| | |_) |
+|_| \_|____/|_|  |_|    |____/ \___|\__|\__,_| .__/
+                                              |_|
+`
+
 func main() {
+	// A disconnected CLI pipe (e.g. `nsm-setup install | head`) delivers
+	// SIGPIPE; the default action would kill the wizard mid-run, so ignore
+	// it and let writes past a closed pipe fail with an error instead.
+	signal.Ignore(syscall.SIGPIPE)
+
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
@@ -92,14 +111,132 @@ func main() {
 		},
 	}
 
+	// dns-daemon runs the embedded resolver in the foreground; it's what
+	// the launchd agent / systemd user unit installed by `setup install`
+	// actually execs, not something a user runs directly.
+	dnsDaemonCmd := &cobra.Command{
+		Use:    "dns-daemon",
+		Short:  "Run the embedded background DNS resolver in the foreground",
+		Hidden: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tlds, _ := cmd.Flags().GetStringSlice("tlds")
+			return runDNSDaemon(ctx, tlds)
+		},
+	}
+	dnsDaemonCmd.Flags().StringSlice("tlds", []string{"dev", "test", "local"}, "TLDs to answer authoritatively for")
+
+	// dns reports on the background DNS daemon started by `setup install`.
+	dnsCmd := &cobra.Command{
+		Use:   "dns",
+		Short: "Inspect the embedded background DNS daemon",
+	}
+	dnsStatusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show the DNS daemon's query rate, cache hit rate, and upstreams",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			asJSON, _ := cmd.Flags().GetBool("json")
+			return runDNSStatus(asJSON)
+		},
+	}
+	dnsStatusCmd.Flags().Bool("json", false, "Output status as JSON")
+	dnsCmd.AddCommand(dnsStatusCmd)
+
+	// config manages the declarative ~/.nsm/nsm.conf alternative to flags.
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Manage NSM's declarative nsm.conf",
+	}
+	configReloadCmd := &cobra.Command{
+		Use:   "reload",
+		Short: "Apply changes made to nsm.conf since the last install/reload",
+		Long:  "Diffs ~/.nsm/nsm.conf against the last applied configuration and configures or\nunconfigures only the TLDs that changed, without rerunning the full install flow.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runConfigReload(ctx)
+		},
+	}
+	configCmd.AddCommand(configReloadCmd)
+
+	// acme-renew keeps cert-mode acme/both domains renewed in the
+	// background; it's what the launchd agent / systemd user unit
+	// installed by `setup install` execs when a renewal is due.
+	acmeRenewCmd := &cobra.Command{
+		Use:    "acme-renew",
+		Short:  "Run the ACME certificate renewal daemon in the foreground",
+		Hidden: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dataDir, _ := cmd.Flags().GetString("data-dir")
+			domains, _ := cmd.Flags().GetStringSlice("domains")
+			email, _ := cmd.Flags().GetString("email")
+			dnsProvider, _ := cmd.Flags().GetString("dns-provider")
+			challenge, _ := cmd.Flags().GetString("challenge")
+			return runACMERenew(ctx, dataDir, domains, email, dnsProvider, challenge)
+		},
+	}
+	acmeRenewCmd.Flags().String("data-dir", "", "NSM data directory containing the certs/acme state")
+	acmeRenewCmd.Flags().StringSlice("domains", nil, "Domains to keep renewed")
+	acmeRenewCmd.Flags().String("email", "", "ACME account contact email")
+	acmeRenewCmd.Flags().String("dns-provider", "", "DNS-01 provider (cloudflare, route53, digitalocean, gandi, manual, exec)")
+	acmeRenewCmd.Flags().String("challenge", "http-01", "ACME challenge type (http-01 or dns-01)")
+
 	// Example command
 	exampleCmd := &cobra.Command{
 		Use:   "example [framework]",
 		Short: "Create example project",
-		Long:  "Create a new example project for the specified framework",
+		Long:  "Create a new example project for the specified framework. Omit the framework to pick one interactively.",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			framework := ""
+			if len(args) == 1 {
+				framework = args[0]
+			}
+			headless, _ := cmd.Flags().GetBool("headless")
+			autoYes, _ := cmd.Flags().GetBool("auto-yes")
+			set, _ := cmd.Flags().GetStringToString("set")
+			return runCreateExample(ctx, framework, headless, autoYes, set)
+		},
+	}
+
+	// Template registry commands
+	templateCmd := &cobra.Command{
+		Use:   "template",
+		Short: "Manage custom template aliases",
+		Long:  "Register, remove, list, and refresh git-hosted project templates",
+	}
+
+	templateAddCmd := &cobra.Command{
+		Use:   "add [alias] [git-url]",
+		Short: "Register a git-hosted template under an alias",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ref, _ := cmd.Flags().GetString("ref")
+			return runTemplateAdd(ctx, args[0], args[1], ref)
+		},
+	}
+	templateAddCmd.Flags().String("ref", "", "Git ref (branch, tag, or commit) to pin")
+
+	templateRemoveCmd := &cobra.Command{
+		Use:   "remove [alias]",
+		Short: "Remove a registered template alias",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runTemplateRemove(ctx, args[0])
+		},
+	}
+
+	templateListCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List registered template aliases",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runTemplateList(ctx)
+		},
+	}
+
+	templateUpdateCmd := &cobra.Command{
+		Use:   "update [alias]",
+		Short: "Refresh the cached checkout for a registered template",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runCreateExample(ctx, args[0])
+			return runTemplateUpdate(ctx, args[0])
 		},
 	}
 
@@ -108,6 +245,13 @@ func main() {
 	setupCmd.Flags().Bool("auto-yes", false, "Auto-confirm all prompts")
 	setupCmd.Flags().Bool("skip-deps", false, "Skip dependency installation")
 	setupCmd.Flags().StringSlice("tlds", []string{"dev", "test", "local"}, "TLDs to configure")
+	setupCmd.Flags().String("cert-mode", "mkcert", "Certificate mode: mkcert, acme, or both")
+	setupCmd.Flags().StringSlice("acme-domains", nil, "Domains to request ACME certificates for (cert-mode acme/both)")
+	setupCmd.Flags().String("acme-email", "", "ACME account contact email")
+	setupCmd.Flags().String("acme-dns-provider", "", "DNS-01 provider for ACME (cloudflare, route53, digitalocean, gandi, manual, exec)")
+	setupCmd.Flags().String("acme-challenge", "", "ACME challenge type (http-01 or dns-01); defaults to http-01")
+	setupCmd.Flags().String("dns-check-method", setup.DNSCheckMethodLocal,
+		"How to verify DNS resolution: 'local' (query the background DNS daemon) or 'follow' (resolve from authoritative nameservers, bypassing caches)")
 
 	statusCmd.Flags().Bool("json", false, "Output status as JSON")
 
@@ -115,10 +259,24 @@ func main() {
 
 	exampleCmd.Flags().String("name", "", "Project name (default: auto-generated)")
 	exampleCmd.Flags().String("path", ".", "Output directory")
+	exampleCmd.Flags().Bool("headless", false, "Skip interactive prompts; values must come from --set")
+	exampleCmd.Flags().Bool("auto-yes", false, "Skip interactive prompts; values must come from --set")
+	exampleCmd.Flags().StringToString("set", nil, "Project/template variable, e.g. --set domain=myapp.dev")
+
+	versionCmd := &cobra.Command{
+		Use:   "version",
+		Short: "Print version, build, and environment info",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			asJSON, _ := cmd.Flags().GetBool("json")
+			return runVersion(asJSON)
+		},
+	}
+	versionCmd.Flags().Bool("json", false, "Output as JSON")
 
 	// Build command tree
 	tldCmd.AddCommand(tldAddCmd, tldRemoveCmd, tldListCmd)
-	rootCmd.AddCommand(setupCmd, tldCmd, statusCmd, resetCmd, exampleCmd)
+	templateCmd.AddCommand(templateAddCmd, templateRemoveCmd, templateListCmd, templateUpdateCmd)
+	rootCmd.AddCommand(setupCmd, tldCmd, statusCmd, resetCmd, dnsDaemonCmd, dnsCmd, configCmd, acmeRenewCmd, exampleCmd, templateCmd, versionCmd)
 
 	// Execute
 	if err := rootCmd.Execute(); err != nil {
@@ -132,16 +290,28 @@ func runSetup(ctx context.Context, cmd *cobra.Command) error {
 	autoYes, _ := cmd.Flags().GetBool("auto-yes")
 	skipDeps, _ := cmd.Flags().GetBool("skip-deps")
 	tlds, _ := cmd.Flags().GetStringSlice("tlds")
+	certMode, _ := cmd.Flags().GetString("cert-mode")
+	acmeDomains, _ := cmd.Flags().GetStringSlice("acme-domains")
+	acmeEmail, _ := cmd.Flags().GetString("acme-email")
+	acmeDNSProvider, _ := cmd.Flags().GetString("acme-dns-provider")
+	acmeChallenge, _ := cmd.Flags().GetString("acme-challenge")
+	dnsCheckMethod, _ := cmd.Flags().GetString("dns-check-method")
 
 	logger.Init(false)
 	logger.Info("Starting NSM setup", "platform", runtime.GOOS)
 
 	config := setup.Config{
-		Headless: headless,
-		AutoYes:  autoYes,
-		SkipDeps: skipDeps,
-		TLDs:     tlds,
-		Platform: runtime.GOOS,
+		Headless:        headless,
+		AutoYes:         autoYes,
+		SkipDeps:        skipDeps,
+		TLDs:            tlds,
+		Platform:        runtime.GOOS,
+		CertMode:        certMode,
+		ACMEDomains:     acmeDomains,
+		ACMEEmail:       acmeEmail,
+		ACMEDNSProvider: acmeDNSProvider,
+		ACMEChallenge:   acmeChallenge,
+		DNSCheckMethod:  dnsCheckMethod,
 	}
 
 	if headless {
@@ -151,6 +321,52 @@ func runSetup(ctx context.Context, cmd *cobra.Command) error {
 	return setup.RunInteractive(ctx, config)
 }
 
+func runDNSDaemon(ctx context.Context, tlds []string) error {
+	logger.Init(false)
+	logger.Info("Starting embedded DNS daemon", "addr", nsmdns.DefaultAddr, "tlds", tlds)
+
+	server := nsmdns.NewServer(tlds, nsmdns.SystemUpstreams())
+	if err := server.Start(nsmdns.DefaultAddr); err != nil {
+		return fmt.Errorf("start dns daemon: %w", err)
+	}
+
+	<-ctx.Done()
+	logger.Info("Stopping embedded DNS daemon")
+	return server.Stop()
+}
+
+func runDNSStatus(asJSON bool) error {
+	stats, err := nsmdns.QueryStats(nsmdns.DefaultAddr)
+	if err != nil {
+		return fmt.Errorf("dns daemon not reachable at %s (has `nsm-setup install` run?): %w", nsmdns.DefaultAddr, err)
+	}
+
+	if asJSON {
+		data, err := json.MarshalIndent(stats, "", "  ")
+		if err != nil {
+			return fmt.Errorf("marshal dns status: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	fmt.Printf("Queries/sec:    %.2f\n", stats.QueriesPerSecond)
+	fmt.Printf("Cache hit rate: %.1f%%\n", stats.CacheHitRate*100)
+	fmt.Printf("Upstreams:      %s\n", strings.Join(stats.Upstreams, ", "))
+	return nil
+}
+
+func runConfigReload(ctx context.Context) error {
+	logger.Init(false)
+	return setup.ReloadDeclarative(ctx)
+}
+
+func runACMERenew(ctx context.Context, dataDir string, domains []string, email, dnsProvider, challenge string) error {
+	logger.Init(false)
+	logger.Info("Starting ACME renewal daemon", "domains", domains)
+	return setup.RunACMERenew(ctx, dataDir, domains, email, dnsProvider, challenge)
+}
+
 func runTLDAdd(ctx context.Context, tld string) error {
 	logger.Init(false)
 	return setup.AddTLD(ctx, tld)
@@ -176,7 +392,84 @@ func runReset(ctx context.Context) error {
 	return setup.Reset(ctx)
 }
 
-func runCreateExample(ctx context.Context, framework string) error {
+func runCreateExample(ctx context.Context, framework string, headless, autoYes bool, set map[string]string) error {
 	logger.Init(false)
-	return setup.CreateExample(ctx, framework)
+	return setup.CreateExample(ctx, setup.ExampleOptions{
+		Framework: framework,
+		Headless:  headless,
+		AutoYes:   autoYes,
+		Set:       set,
+	})
+}
+
+func runTemplateAdd(ctx context.Context, alias, gitURL, ref string) error {
+	logger.Init(false)
+	if err := setup.AddTemplateAlias(alias, gitURL, ref); err != nil {
+		return fmt.Errorf("add template: %w", err)
+	}
+	logger.Info("Template registered", "alias", alias, "url", gitURL)
+	return nil
+}
+
+func runTemplateRemove(ctx context.Context, alias string) error {
+	logger.Init(false)
+	if err := setup.RemoveTemplateAlias(alias); err != nil {
+		return fmt.Errorf("remove template: %w", err)
+	}
+	logger.Info("Template removed", "alias", alias)
+	return nil
+}
+
+func runTemplateList(ctx context.Context) error {
+	logger.Init(false)
+	aliases, err := setup.ListTemplateAliases()
+	if err != nil {
+		return fmt.Errorf("list templates: %w", err)
+	}
+
+	if len(aliases) == 0 {
+		fmt.Println("No custom templates registered")
+		return nil
+	}
+
+	fmt.Println("Registered templates:")
+	for _, alias := range aliases {
+		fmt.Printf("  %s -> %s", alias.Name, alias.GitURL)
+		if alias.Ref != "" {
+			fmt.Printf(" (ref: %s)", alias.Ref)
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+func runTemplateUpdate(ctx context.Context, alias string) error {
+	logger.Init(false)
+	if err := setup.UpdateTemplateAlias(alias); err != nil {
+		return fmt.Errorf("update template: %w", err)
+	}
+	logger.Info("Template updated", "alias", alias)
+	return nil
+}
+
+func runVersion(asJSON bool) error {
+	info := versioninfo.Detect(version, commit, buildDate)
+
+	if status, err := setup.GetSystemStatus(); err == nil {
+		for _, tld := range status.TLDs {
+			info.TLDs = append(info.TLDs, tld.Name)
+		}
+	}
+
+	if asJSON {
+		data, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			return fmt.Errorf("marshal version info: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	fmt.Println(versioninfo.Render(versionBanner, info))
+	return nil
 }
@@ -3,20 +3,30 @@ package app
 import (
 	"context"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fsnotify/fsnotify"
 
 	"github.com/kashifsb/nsm/internal/cert"
 	"github.com/kashifsb/nsm/internal/config"
+	"github.com/kashifsb/nsm/internal/diag"
 	"github.com/kashifsb/nsm/internal/dns"
+	nsmlog "github.com/kashifsb/nsm/internal/log"
 	"github.com/kashifsb/nsm/internal/platform"
 	"github.com/kashifsb/nsm/internal/project"
 	"github.com/kashifsb/nsm/internal/server"
+	"github.com/kashifsb/nsm/internal/tunnel"
 	"github.com/kashifsb/nsm/internal/ui"
+	"github.com/kashifsb/nsm/pkg/health"
 	"github.com/kashifsb/nsm/pkg/logger"
+	"github.com/kashifsb/nsm/pkg/metrics"
 	"github.com/kashifsb/nsm/pkg/utils"
 )
 
@@ -24,11 +34,60 @@ type App struct {
 	cfg *config.Config
 
 	// Managers
-	portManager *platform.PortManager
-	certManager *cert.Manager
-	dnsResolver *dns.Resolver
-	proxyServer *server.ProxyServer
-	runner      *project.Runner
+	portManager  *platform.PortManager
+	certManager  *cert.Manager
+	certRenewer  *cert.Renewer
+	dnsResolver  *dns.Resolver
+	proxyServer  *server.ProxyServer
+	runner       *project.Runner
+	supervisor   *project.Supervisor
+	staticServer *project.StaticServer
+	health       *health.Tracker
+	browser      *platform.Browser
+	metricsSrv   *metrics.Server
+	diagSrv      *diag.Server
+	logRing      *nsmlog.RingSink
+	sites        *SiteManager
+
+	// tunnelProvider exposes httpsPort over a public URL when
+	// cfg.Tunnel.Provider is set; tunnelURL is its last-known public URL,
+	// kept in sync by tunnelService's watchTunnel goroutine across
+	// reconnects. tunnelMu guards both, since GetStatus reads them from
+	// per-connection IPC goroutines while watchTunnel writes them from its
+	// own background goroutine.
+	tunnelMu       sync.RWMutex
+	tunnelProvider tunnel.Provider
+	tunnelURL      string
+
+	// tree is the supervised subsystem tree (setup steps, proxy, dev
+	// server) built fresh by startTree on every start and restart.
+	tree *Orchestrator
+
+	// Live reconfiguration: configWatcher fires Reload on every .nsm.yaml
+	// write, controlListener does the same for "nsm reload" over
+	// ControlSocketPath, and reloadHandler is how both get from "something
+	// changed" to an actual re-resolved Config - set by main.go via
+	// SetReloadHandler since only it holds the *cobra.Command config.Load
+	// needs.
+	configWatcher   *fsnotify.Watcher
+	controlListener net.Listener
+	reloadHandler   func() error
+
+	// ipcHub fans out step_update/server_started/log_line events to every
+	// client connected to the IPC socket/HTTP variant below, which serve
+	// the typed Status API and status/reload/restart-dev/rotate-cert/stop
+	// commands that "nsm ctl" and editor integrations drive.
+	ipcHub        *ipcHub
+	ipcListener   net.Listener
+	ipcHTTPServer *http.Server
+
+	// stopCh, once closed, makes Run/RunHeadless's shutdown select fire the
+	// same way ctx.Done() does - how the IPC socket's "stop" command asks a
+	// running NSM to exit without a signal. stopOnce guards the close
+	// against two concurrent "stop" commands (one over the Unix socket,
+	// one over the optional CtlAddr HTTP listener) both reaching it.
+	stopCh   chan struct{}
+	stopOnce sync.Once
 
 	// UI
 	program *tea.Program
@@ -37,12 +96,13 @@ type App struct {
 	httpPort  int
 	httpsPort int
 	running   bool
-}
 
-type SetupStep struct {
-	Name        string
-	Description string
-	Execute     func(ctx context.Context) error
+	// stepsMu guards stepOrder/steps, which handleTreeEvent updates on
+	// every subsystem lifecycle transition so diag.Server's /state
+	// endpoint has something to report even with no TUI attached.
+	stepsMu   sync.RWMutex
+	stepOrder []string
+	steps     map[string]diag.StepInfo
 }
 
 func NewApp(cfg *config.Config) (*App, error) {
@@ -54,22 +114,199 @@ func NewApp(cfg *config.Config) (*App, error) {
 	// Initialize managers
 	portManager := platform.NewPortManager()
 
-	certManager, err := cert.NewManager(cfg.DataDir)
+	certManager, err := cert.NewManagerWithConfig(cfg.DataDir, cfg)
 	if err != nil {
 		return nil, fmt.Errorf("create cert manager: %w", err)
 	}
 
+	healthTracker := health.NewTracker()
+
 	dnsResolver := dns.NewResolver(dns.ResolverConfig{
-		Domain:    cfg.Domain,
-		EnableDNS: cfg.EnableDNS,
+		Domain:       cfg.Domain,
+		EnableDNS:    cfg.EnableDNS,
+		UpstreamDNS:  cfg.UpstreamDNS,
+		BootstrapDNS: cfg.BootstrapDNS,
+		Health:       healthTracker,
 	})
 
-	return &App{
+	a := &App{
 		cfg:         cfg,
 		portManager: portManager,
 		certManager: certManager,
 		dnsResolver: dnsResolver,
-	}, nil
+		health:      healthTracker,
+		browser:     platform.NewBrowser(portManager),
+		logRing:     nsmlog.NewRingSink(),
+		steps:       make(map[string]diag.StepInfo),
+		ipcHub:      newIPCHub(),
+		stopCh:      make(chan struct{}),
+	}
+	a.sites = NewSiteManager(a)
+	return a, nil
+}
+
+// primaryURL mirrors ui.Model.updateURLs: the clean URL when a custom
+// domain is configured, otherwise a localhost URL on whichever port is
+// actually reachable.
+func (a *App) primaryURL() string {
+	domain := a.cfg.Domain
+	if domain == "" {
+		domain = "localhost"
+	}
+
+	switch {
+	case a.cfg.UsePort443 && a.cfg.EnableHTTPS:
+		return fmt.Sprintf("https://%s", domain)
+	case a.cfg.EnableHTTPS:
+		return fmt.Sprintf("https://%s:%d", domain, a.httpsPort)
+	default:
+		return fmt.Sprintf("http://%s:%d", domain, a.httpPort)
+	}
+}
+
+// Health returns the tracker reporting live subsystem state (currently just
+// DNS), for status commands or a future HTTP status endpoint to read.
+func (a *App) Health() *health.Tracker {
+	return a.health
+}
+
+// Reload applies a freshly-parsed Config to the running app without
+// restarting the process, called when NSM receives SIGHUP, its config
+// file watcher fires, or "nsm reload" reaches the control socket. The log
+// level and DNS resolver's upstreams/domain are always re-applied; beyond
+// that, configChangeSteps diffs old against new to decide which
+// subsystem steps actually need rerunning (e.g. a domain change only
+// needs dns+certs, not a full restart), and rerunStep reuses
+// Orchestrator.StopService/StartService to reapply just those.
+func (a *App) Reload(cfg *config.Config) error {
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	logger.Info("Reloading configuration")
+
+	if cfg.Debug != a.cfg.Debug {
+		level := "info"
+		if cfg.Debug {
+			level = "debug"
+		}
+		if err := logger.SetLevel(level); err != nil {
+			logger.Warn("Failed to apply reloaded log level", "error", err)
+		} else {
+			logger.Info("Log level changed", "level", level)
+		}
+	}
+
+	if a.dnsResolver != nil {
+		if err := a.dnsResolver.Reload(dns.ResolverConfig{
+			Domain:       cfg.Domain,
+			EnableDNS:    cfg.EnableDNS,
+			UpstreamDNS:  cfg.UpstreamDNS,
+			BootstrapDNS: cfg.BootstrapDNS,
+			Health:       a.health,
+		}); err != nil {
+			logger.Warn("DNS reload failed", "error", err)
+		}
+	}
+
+	affected := configChangeSteps(a.cfg, cfg)
+	oldCfg := a.cfg
+	a.cfg = cfg
+	logger.LogStructuredConfig(a.dnsResolver)
+
+	if len(affected) == 0 {
+		return nil
+	}
+
+	logger.Info("Config change affects subsystem steps, reapplying", "steps", affected)
+	if a.program != nil {
+		a.program.Send(ui.ConfigChangedMsg{Old: oldCfg, New: cfg, Steps: affected})
+	}
+
+	ctx := context.Background()
+	for _, name := range affected {
+		if err := a.rerunStep(ctx, name); err != nil {
+			logger.Warn("Failed to reapply step after config change", "step", name, "error", err)
+		}
+	}
+
+	return nil
+}
+
+// SetReloadHandler installs fn as what the config file watcher and the
+// control socket's "reload" command both call to re-resolve and apply
+// configuration. cmd/nsm/main.go is the only place holding the
+// *cobra.Command config.Load needs, so it supplies this, the same way
+// Model.SetRestartHandler lets the TUI trigger App behavior it doesn't
+// own.
+func (a *App) SetReloadHandler(fn func() error) {
+	a.reloadHandler = fn
+}
+
+// configChangeSteps diffs old against new and returns the subsystem step
+// names (in buildServices' start order) that need rerunning to pick up
+// the difference: a domain change needs dns and certs; an HTTPS toggle or
+// cert issuer change needs proxy; a port change needs ports and proxy.
+// Fields Reload already applies directly (Debug, Domain/EnableDNS via
+// dnsResolver.Reload) don't add steps on their own.
+func configChangeSteps(old, new *config.Config) []string {
+	need := make(map[string]bool)
+
+	if old.Domain != new.Domain {
+		need["dns"] = true
+		need["certs"] = true
+	}
+	if old.EnableDNS != new.EnableDNS {
+		need["dns"] = true
+	}
+	if old.EnableHTTPS != new.EnableHTTPS || old.CertIssuer != new.CertIssuer {
+		need["proxy"] = true
+	}
+	if old.CertPath != new.CertPath || old.KeyPath != new.KeyPath {
+		need["certs"] = true
+		need["proxy"] = true
+	}
+	if old.HTTPPort != new.HTTPPort || old.HTTPSPort != new.HTTPSPort || old.UsePort443 != new.UsePort443 {
+		need["ports"] = true
+		need["proxy"] = true
+	}
+	if old.Command != new.Command || old.ProjectDir != new.ProjectDir {
+		need["dev"] = true
+	}
+
+	var steps []string
+	for _, name := range []string{"ports", "certs", "dns", "proxy", "dev"} {
+		if need[name] {
+			steps = append(steps, name)
+		}
+	}
+	return steps
+}
+
+// rerunStep stops and restarts the single named subsystem step within
+// a.tree, reporting its progress through the same handleTreeEvent path
+// buildServices' normal start sequence uses, so the TUI's status panel
+// re-animates just that step instead of the whole setup view.
+func (a *App) rerunStep(ctx context.Context, name string) error {
+	if a.tree == nil {
+		return nil
+	}
+
+	a.handleTreeEvent(Event{Service: name, Status: "starting"})
+
+	stopCtx, cancel := context.WithTimeout(context.Background(), teardownPhaseTimeout)
+	defer cancel()
+	if err := a.tree.StopService(stopCtx, name); err != nil {
+		logger.Warn("Error stopping step before reapplying", "step", name, "error", err)
+	}
+
+	if err := a.tree.StartService(ctx, name); err != nil {
+		a.handleTreeEvent(Event{Service: name, Status: "failed", Err: err})
+		return err
+	}
+
+	a.handleTreeEvent(Event{Service: name, Status: "ready"})
+	return nil
 }
 
 func (a *App) Run(ctx context.Context) error {
@@ -86,6 +323,19 @@ func (a *App) Run(ctx context.Context) error {
 	// Set program reference in model for message passing
 	model.SetProgram(a.program)
 
+	// Pressing 'r' in the error state asks the orchestrator to tear down
+	// and rebuild the whole subsystem tree, rather than resetting the UI.
+	model.SetRestartHandler(func() { a.requestRestart(ctx) })
+
+	// Facet-scoped tracing (NSM_TRACE=dns,proxy,cert or NSM_TRACE=all) feeds
+	// into the TUI's log pane, a JSON-lines file, and every client
+	// connected to the IPC socket, as log_line events.
+	nsmlog.InitFromEnv(
+		nsmlog.NewTUISink(a.program),
+		nsmlog.NewJSONFileSink(filepath.Join(a.cfg.DataDir, "trace.jsonl")),
+		a.ipcHub,
+	)
+
 	// Initialize runner with UI program
 	a.runner = project.NewRunner(a.cfg, a.program)
 
@@ -96,14 +346,26 @@ func (a *App) Run(ctx context.Context) error {
 		uiDone <- err
 	}()
 
-	// Run setup steps
-	if err := a.runSetup(ctx); err != nil {
+	if err := a.startMetricsServer(); err != nil {
 		a.program.Send(ui.ErrorMsg{Err: err})
-		return fmt.Errorf("setup failed: %w", err)
+		return err
 	}
 
-	// Start services
-	if err := a.startServices(ctx); err != nil {
+	// Live reconfiguration: a file watcher on .nsm.yaml and a control
+	// socket for "nsm reload" both feed into reloadHandler, best-effort -
+	// neither blocks NSM from starting if it can't be set up.
+	if err := a.startConfigWatcher(); err != nil {
+		logger.Warn("Failed to start config file watcher", "error", err)
+	}
+	if err := a.startControlSocket(); err != nil {
+		logger.Warn("Failed to start control socket", "error", err)
+	}
+	if err := a.startIPCServer(); err != nil {
+		logger.Warn("Failed to start IPC server", "error", err)
+	}
+
+	// Start the supervised subsystem tree (setup steps, proxy, dev server)
+	if err := a.startTree(ctx); err != nil {
 		a.program.Send(ui.ErrorMsg{Err: err})
 		return fmt.Errorf("failed to start services: %w", err)
 	}
@@ -113,31 +375,69 @@ func (a *App) Run(ctx context.Context) error {
 		HTTPPort:  a.httpPort,
 		HTTPSPort: a.httpsPort,
 	})
+	a.ipcHub.broadcast(ipcEvent{Type: "server_started", HTTPPort: a.httpPort, HTTPSPort: a.httpsPort})
+
+	if a.cfg.AutoOpen {
+		a.openBrowserWhenReady()
+	}
+	a.notifyReady()
 
 	// Wait for shutdown
 	select {
 	case <-ctx.Done():
 		logger.Info("Received shutdown signal")
+		a.program.Send(ui.ShutdownMsg{})
+	case <-a.stopCh:
+		logger.Info("Received stop command over IPC socket")
+		a.program.Send(ui.ShutdownMsg{})
 	case err := <-uiDone:
 		if err != nil {
 			logger.Error("UI error", "error", err)
 		}
 	}
 
-	// Cleanup
-	return a.shutdown()
+	// Cleanup, then tell the TUI every teardown phase has reported (or the
+	// deadline gave up on it) so it can finally quit.
+	shutdownErr := a.shutdown()
+	a.program.Send(ui.ShutdownCompleteMsg{})
+	return shutdownErr
 }
 
 func (a *App) RunHeadless(ctx context.Context) error {
 	logger.Info("Starting NSM application in headless mode", "project", a.cfg.ProjectName)
 
-	// Run setup steps
-	if err := a.runSetup(ctx); err != nil {
-		return fmt.Errorf("setup failed: %w", err)
+	// No TUI to feed: facet-scoped tracing goes to stderr, a JSON file,
+	// logRing (which backs the diagnostic server's /logs endpoint below),
+	// and every client connected to the IPC socket, as log_line events.
+	nsmlog.InitFromEnv(
+		nsmlog.NewTextSink(os.Stderr),
+		nsmlog.NewJSONFileSink(filepath.Join(a.cfg.DataDir, "trace.jsonl")),
+		a.logRing,
+		a.ipcHub,
+	)
+
+	if err := a.startMetricsServer(); err != nil {
+		return err
+	}
+	if err := a.startDiagServer(); err != nil {
+		return err
+	}
+
+	// Live reconfiguration: a file watcher on .nsm.yaml and a control
+	// socket for "nsm reload" both feed into reloadHandler, best-effort -
+	// neither blocks NSM from starting if it can't be set up.
+	if err := a.startConfigWatcher(); err != nil {
+		logger.Warn("Failed to start config file watcher", "error", err)
+	}
+	if err := a.startControlSocket(); err != nil {
+		logger.Warn("Failed to start control socket", "error", err)
+	}
+	if err := a.startIPCServer(); err != nil {
+		logger.Warn("Failed to start IPC server", "error", err)
 	}
 
-	// Start services
-	if err := a.startServices(ctx); err != nil {
+	// Start the supervised subsystem tree (setup steps, proxy, dev server)
+	if err := a.startTree(ctx); err != nil {
 		return fmt.Errorf("failed to start services: %w", err)
 	}
 
@@ -146,107 +446,147 @@ func (a *App) RunHeadless(ctx context.Context) error {
 		"project", a.cfg.ProjectName,
 		"http_port", a.httpPort,
 		"https_port", a.httpsPort)
+	a.ipcHub.broadcast(ipcEvent{Type: "server_started", HTTPPort: a.httpPort, HTTPSPort: a.httpsPort})
 
-	// Wait for shutdown signal
-	<-ctx.Done()
+	// Wait for shutdown signal or an IPC "stop" command
+	select {
+	case <-ctx.Done():
+	case <-a.stopCh:
+		logger.Info("Received stop command over IPC socket")
+	}
 
 	// Cleanup
 	return a.shutdown()
 }
 
-func (a *App) runSetup(ctx context.Context) error {
-	steps := []SetupStep{
-		{
-			Name:        "validate",
-			Description: "Validating configuration",
-			Execute:     a.setupValidation,
-		},
-		{
-			Name:        "ports",
-			Description: "Configuring ports",
-			Execute:     a.setupPorts,
-		},
-		{
-			Name:        "certs",
-			Description: "Setting up certificates",
-			Execute:     a.setupCertificates,
-		},
-		{
-			Name:        "dns",
-			Description: "Configuring DNS",
-			Execute:     a.setupDNS,
-		},
-	}
-
-	for _, step := range steps {
-		logger.Info("Executing setup step", "step", step.Name)
-
-		// Send UI update if program is available
+// startTree builds a fresh supervised subsystem tree (buildServices),
+// starts it via an Orchestrator, and spawns a goroutine translating its
+// lifecycle Events into ui.StepUpdateMsg/LogMsg/ErrorMsg for as long as
+// the tree runs. It replaces a.tree, so calling it again (requestRestart)
+// rebuilds the whole tree from scratch.
+func (a *App) startTree(ctx context.Context) error {
+	tree := NewOrchestrator(a.buildServices())
+	a.tree = tree
+
+	go func() {
+		for event := range tree.Events() {
+			a.handleTreeEvent(event)
+		}
+	}()
+
+	if err := tree.Start(ctx); err != nil {
+		return err
+	}
+
+	if a.program != nil {
+		a.program.Send(ui.SetupCompleteMsg{})
+	}
+	return nil
+}
+
+// handleTreeEvent translates one Orchestrator Event into the UI messages
+// (or, in headless mode, log lines) the rest of NSM already understands,
+// and records it into a.steps for diag.Server's /state endpoint.
+func (a *App) handleTreeEvent(event Event) {
+	switch event.Status {
+	case "starting":
+		logger.Info("Starting subsystem", "service", event.Service)
+		a.recordStep(event.Service, "loading", "In progress...")
 		if a.program != nil {
 			a.program.Send(ui.StepUpdateMsg{
-				StepName: step.Name,
+				StepName: event.Service,
 				Status:   "loading",
 				Details:  "In progress...",
 			})
 		}
-
-		// Execute step with timeout
-		stepCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
-		stepDone := make(chan error, 1)
-
-		go func(step SetupStep) {
-			stepDone <- step.Execute(stepCtx)
-		}(step)
-
-		select {
-		case err := <-stepDone:
-			cancel()
-			if err != nil {
-				// Send UI error if program is available
-				if a.program != nil {
-					a.program.Send(ui.StepUpdateMsg{
-						StepName: step.Name,
-						Status:   "error",
-						Details:  err.Error(),
-					})
-				}
-				return fmt.Errorf("step %s failed: %w", step.Name, err)
-			}
-		case <-stepCtx.Done():
-			cancel()
-			err := fmt.Errorf("step %s timed out after 30 seconds", step.Name)
-			// Send UI error if program is available
-			if a.program != nil {
-				a.program.Send(ui.StepUpdateMsg{
-					StepName: step.Name,
-					Status:   "error",
-					Details:  err.Error(),
-				})
-			}
-			return err
-		}
-
-		// Send UI success if program is available
+	case "ready":
+		logger.Info("Subsystem ready", "service", event.Service)
+		a.recordStep(event.Service, "success", "Completed")
 		if a.program != nil {
 			a.program.Send(ui.StepUpdateMsg{
-				StepName: step.Name,
+				StepName: event.Service,
 				Status:   "success",
 				Details:  "Completed",
 			})
 		}
+	case "restarting":
+		logger.Warn("Subsystem failed, restarting", "service", event.Service, "error", event.Err)
+		a.recordStep(event.Service, "loading", "Restarting after failure")
+		if a.program != nil {
+			a.program.Send(ui.StepUpdateMsg{
+				StepName: event.Service,
+				Status:   "loading",
+				Details:  "Restarting after failure",
+			})
+			a.program.Send(ui.LogMsg{
+				Level:   "WARN",
+				Message: fmt.Sprintf("%s failed, restarting: %v", event.Service, event.Err),
+			})
+		}
+	case "failed":
+		logger.Error("Subsystem failed", "service", event.Service, "error", event.Err)
+		a.recordStep(event.Service, "error", event.Err.Error())
+		if a.program != nil {
+			a.program.Send(ui.StepUpdateMsg{
+				StepName: event.Service,
+				Status:   "error",
+				Details:  event.Err.Error(),
+			})
+			a.program.Send(ui.ErrorMsg{Err: fmt.Errorf("%s: %w", event.Service, event.Err)})
+		}
 	}
+}
 
-	// Send UI completion if program is available
-	if a.program != nil {
-		a.program.Send(ui.SetupCompleteMsg{})
+// recordStep updates a.steps/a.stepOrder with service's latest status, for
+// diagState to read without depending on the TUI's own StatusStep list, and
+// broadcasts the same update to the IPC hub's connected clients.
+func (a *App) recordStep(service, status, details string) {
+	a.stepsMu.Lock()
+	defer a.stepsMu.Unlock()
+
+	if _, ok := a.steps[service]; !ok {
+		a.stepOrder = append(a.stepOrder, service)
 	}
+	a.steps[service] = diag.StepInfo{Name: service, Status: status, Details: details}
+	a.ipcHub.broadcast(ipcEvent{Type: "step_update", Service: service, Status: status, Details: details})
+}
 
-	return nil
+// requestRestart tears down the current subsystem tree and rebuilds it
+// from scratch, driven by the UI's "restart" key in the error state — the
+// real counterpart to what used to just reset the TUI's simulated state.
+func (a *App) requestRestart(ctx context.Context) {
+	logger.Info("Restarting supervised subsystem tree")
+
+	if a.tree != nil {
+		if err := a.tree.Stop(); err != nil {
+			logger.Warn("Error stopping subsystem tree before restart", "error", err)
+		}
+	}
+
+	go func() {
+		if err := a.startTree(ctx); err != nil {
+			if a.program != nil {
+				a.program.Send(ui.ErrorMsg{Err: err})
+			}
+			return
+		}
+
+		a.running = true
+		if a.program != nil {
+			a.program.Send(ui.ServerStartedMsg{
+				HTTPPort:  a.httpPort,
+				HTTPSPort: a.httpsPort,
+			})
+		}
+		a.ipcHub.broadcast(ipcEvent{Type: "server_started", HTTPPort: a.httpPort, HTTPSPort: a.httpsPort})
+	}()
 }
 
 func (a *App) setupValidation(ctx context.Context) error {
-	// Check required tools
-	if !utils.IsCommandAvailable("mkcert") {
+	// Check required tools. mkcert is only required when it's the
+	// explicitly requested cert issuer; otherwise cert.Manager falls
+	// back to its built-in local CA.
+	if strings.EqualFold(a.cfg.CertIssuer, "mkcert") && !utils.IsCommandAvailable("mkcert") {
 		return fmt.Errorf("mkcert not found - install with: brew install mkcert")
 	}
 
@@ -334,10 +674,23 @@ func (a *App) setupCertificates(ctx context.Context) error {
 	a.cfg.CertPath = certInfo.CertPath
 	a.cfg.KeyPath = certInfo.KeyPath
 
+	issuer := a.certManager.IssuerName()
 	if certInfo.Created {
-		logger.Info("Created new certificate", "domain", domain)
+		logger.Info("Created new certificate", "domain", domain, "issuer", issuer)
 	} else {
-		logger.Info("Using existing certificate", "domain", domain)
+		logger.Info("Using existing certificate", "domain", domain, "issuer", issuer)
+	}
+
+	details := fmt.Sprintf("using %s", issuer)
+	if installed, instructions, err := a.certManager.EnsureCATrusted(); err != nil {
+		logger.Warn("Failed to check certificate authority trust", "error", err)
+	} else if !installed && instructions != "" {
+		logger.Warn(instructions)
+		details += ", CA not yet trusted (see log for manual install instructions)"
+	}
+
+	if a.program != nil {
+		a.program.Send(ui.StepUpdateMsg{StepName: "certs", Status: "success", Details: details})
 	}
 
 	return nil
@@ -363,159 +716,180 @@ func (a *App) setupDNS(ctx context.Context) error {
 		logger.Info("DNS resolution configured successfully", "domain", a.cfg.Domain)
 	}
 
+	a.checkUpstreamDNSHealth()
+
+	logger.LogStructuredConfig(a.dnsResolver)
+
 	return nil
 }
 
-func (a *App) startServices(ctx context.Context) error {
-	// Start proxy server
-	if a.cfg.EnableProxy {
-		a.program.Send(ui.StepUpdateMsg{
-			StepName: "proxy",
-			Status:   "loading",
-			Details:  "Starting HTTPS proxy",
-		})
-
-		proxyConfig := server.ProxyConfig{
-			TargetHost:  "127.0.0.1",
-			TargetPort:  a.httpPort,
-			ProxyPort:   a.httpsPort,
-			Domain:      a.cfg.Domain,
-			CertPath:    a.cfg.CertPath,
-			KeyPath:     a.cfg.KeyPath,
-			EnableHTTPS: a.cfg.EnableHTTPS,
+// checkUpstreamDNSHealth issues a test query over each configured
+// upstream and reports any failures via ui.StepUpdateMsg, so a
+// misconfigured or unreachable DoH/DoT upstream is visible without
+// failing the "dns" setup step outright.
+func (a *App) checkUpstreamDNSHealth() {
+	for _, result := range a.dnsResolver.Health() {
+		if result.Err == nil {
+			continue
 		}
 
-		a.proxyServer = server.NewProxyServer(a.cfg, proxyConfig)
-		if err := a.proxyServer.Start(ctx, a.httpsPort); err != nil {
-			return fmt.Errorf("start proxy server: %w", err)
+		logger.Warn("DNS upstream unreachable", "upstream", result.Upstream, "error", result.Err)
+		if a.program != nil {
+			a.program.Send(ui.StepUpdateMsg{
+				StepName: "dns",
+				Status:   "warning",
+				Details:  fmt.Sprintf("upstream %s unreachable: %v", result.Upstream, result.Err),
+			})
 		}
+	}
+}
 
-		a.program.Send(ui.StepUpdateMsg{
-			StepName: "proxy",
-			Status:   "success",
-			Details:  fmt.Sprintf("Running on port %d", a.httpsPort),
-		})
+// startMetricsServer starts the Prometheus/healthz/readyz sidecar if
+// MetricsAddr is configured. It's independent of the supervised subsystem
+// tree (Orchestrator doesn't restart it; it's stopped directly in
+// shutdown), since it has to stay up to report /readyz even if a
+// subsystem is mid-restart.
+func (a *App) startMetricsServer() error {
+	if a.cfg.MetricsAddr == "" {
+		return nil
 	}
 
-	// Start development server
-	if a.program != nil {
-		a.program.Send(ui.StepUpdateMsg{
-			StepName: "dev",
-			Status:   "loading",
-			Details:  "Starting development server",
-		})
+	a.metricsSrv = metrics.NewServer(a.health)
+	if err := a.metricsSrv.Start(a.cfg.MetricsAddr); err != nil {
+		return fmt.Errorf("start metrics server: %w", err)
 	}
+	return nil
+}
 
-	runnerConfig := project.RunnerConfig{
-		WorkingDir: a.cfg.ProjectDir,
-		Command:    a.cfg.Command,
-		Env: map[string]string{
-			"NSM_HTTP_PORT":  fmt.Sprintf("%d", a.httpPort),
-			"NSM_HTTPS_PORT": fmt.Sprintf("%d", a.httpsPort),
-			"PORT":           fmt.Sprintf("%d", a.httpPort),
-			"HOST":           "127.0.0.1",
-		},
+// startDiagServer starts the headless-mode diagnostic server if DiagAddr is
+// configured. Like startMetricsServer, it's independent of the supervised
+// subsystem tree so /readyz and /state stay reachable through a restart.
+func (a *App) startDiagServer() error {
+	if a.cfg.DiagAddr == "" {
+		return nil
 	}
 
-	if err := a.runner.Start(ctx, runnerConfig); err != nil {
-		if a.program != nil {
-			a.program.Send(ui.StepUpdateMsg{
-				StepName: "dev",
-				Status:   "error",
-				Details:  fmt.Sprintf("Failed to start: %v", err),
-			})
-		}
-		return fmt.Errorf("start development server: %w", err)
+	a.diagSrv = diag.NewServer(a.health, a.diagState, a.logRing)
+	if err := a.diagSrv.Start(a.cfg.DiagAddr); err != nil {
+		return fmt.Errorf("start diagnostic server: %w", err)
+	}
+	return nil
+}
+
+// diagState snapshots the subsystem tree's current step statuses and the
+// primary URL, for diag.Server's /state endpoint.
+func (a *App) diagState() diag.State {
+	a.stepsMu.RLock()
+	defer a.stepsMu.RUnlock()
+
+	steps := make([]diag.StepInfo, 0, len(a.stepOrder))
+	for _, name := range a.stepOrder {
+		steps = append(steps, a.steps[name])
 	}
 
-	// Wait for development server to be ready with better error handling
-	logger.Info("Waiting for development server to be ready", "port", a.httpPort)
+	return diag.State{
+		Running: a.running,
+		Steps:   steps,
+		URLs:    diag.URLInfo{Primary: a.primaryURL()},
+	}
+}
+
+// openBrowserWhenReady opens the project's primary URL once its public
+// port is reachable, in the background so it never blocks Run/RunHeadless.
+func (a *App) openBrowserWhenReady() {
+	port := a.httpPort
+	if a.cfg.EnableHTTPS {
+		port = a.httpsPort
+	}
 
-	serverReady := make(chan bool, 1)
+	url := a.primaryURL()
 	go func() {
-		if err := a.portManager.WaitForPort(a.httpPort, 30*time.Second); err != nil {
-			logger.Warn("Development server may not be ready", "error", err)
-			serverReady <- false
-		} else {
-			serverReady <- true
+		if err := a.browser.OpenWhenReady(url, port, 30*time.Second); err != nil {
+			logger.Warn("Failed to open browser", "url", url, "error", err)
 		}
 	}()
+}
 
-	// Wait for server readiness or timeout
-	select {
-	case ready := <-serverReady:
-		if ready {
-			logger.Info("Development server is ready", "port", a.httpPort)
-		} else {
-			logger.Warn("Development server may not be ready, continuing anyway")
-		}
-	case <-time.After(35 * time.Second):
-		logger.Warn("Timeout waiting for development server, continuing anyway")
+// notifyReady fires a desktop notification once the primary URL is up, so a
+// session running in the background still surfaces when it's ready.
+func (a *App) notifyReady() {
+	domain := a.cfg.Domain
+	if domain == "" {
+		domain = fmt.Sprintf("localhost:%d", a.httpPort)
 	}
 
-	if a.program != nil {
-		a.program.Send(ui.StepUpdateMsg{
-			StepName: "dev",
-			Status:   "success",
-			Details:  fmt.Sprintf("Running on port %d", a.httpPort),
-		})
+	if err := platform.Notify("NSM", fmt.Sprintf("%s is ready", domain)); err != nil {
+		logger.Debug("Failed to send ready notification", "error", err)
 	}
-
-	return nil
 }
 
+// teardownPhaseTimeout bounds each individual phase of shutdown's
+// dev -> proxy -> dns -> certs -> ports sequence, mirroring the 5-second
+// per-service budget Orchestrator.Stop uses for its own (unordered,
+// all-at-once) sweep.
+const teardownPhaseTimeout = 5 * time.Second
+
+// teardownPhases is shutdown's phase order: the public tunnel first (so
+// it stops sending traffic before anything behind it goes away), then the
+// dev server and proxy, then the setup steps in reverse.
+var teardownPhases = []string{"tunnel", "dev", "proxy", "dns", "certs", "ports"}
+
 func (a *App) shutdown() error {
 	logger.Info("Shutting down NSM application")
 
 	var errs []error
 
-	// Stop development server with better error handling
-	if a.runner != nil {
-		logger.Info("Stopping development server")
-		if err := a.runner.Stop(); err != nil {
-			logger.Warn("Failed to stop development server gracefully", "error", err)
-			errs = append(errs, fmt.Errorf("stop development server: %w", err))
-		} else {
-			logger.Info("Development server stopped successfully")
+	// Stop metrics server
+	if a.metricsSrv != nil {
+		logger.Info("Stopping metrics server")
+		if err := a.metricsSrv.Stop(); err != nil {
+			logger.Warn("Failed to stop metrics server gracefully", "error", err)
+			errs = append(errs, fmt.Errorf("stop metrics server: %w", err))
 		}
 	}
 
-	// Stop proxy server with better error handling
-	if a.proxyServer != nil {
-		logger.Info("Stopping proxy server")
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-
-		if err := a.proxyServer.Stop(ctx); err != nil {
-			logger.Warn("Failed to stop proxy server gracefully", "error", err)
-			errs = append(errs, fmt.Errorf("stop proxy server: %w", err))
-		} else {
-			logger.Info("Proxy server stopped successfully")
+	// Stop diagnostic server
+	if a.diagSrv != nil {
+		logger.Info("Stopping diagnostic server")
+		if err := a.diagSrv.Stop(); err != nil {
+			logger.Warn("Failed to stop diagnostic server gracefully", "error", err)
+			errs = append(errs, fmt.Errorf("stop diagnostic server: %w", err))
 		}
 	}
 
-	// Cleanup DNS with better error handling
-	if a.dnsResolver != nil {
-		logger.Info("Cleaning up DNS configuration")
-		if err := a.dnsResolver.Cleanup(); err != nil {
-			logger.Warn("Failed to cleanup DNS configuration", "error", err)
-			errs = append(errs, fmt.Errorf("cleanup DNS: %w", err))
-		} else {
-			logger.Info("DNS configuration cleaned up successfully")
-		}
+	// Stop live reconfiguration
+	if err := a.stopConfigWatcher(); err != nil {
+		logger.Warn("Failed to stop config file watcher", "error", err)
+	}
+	if err := a.stopControlSocket(); err != nil {
+		logger.Warn("Failed to stop control socket", "error", err)
+	}
+	if err := a.stopIPCServer(); err != nil {
+		logger.Warn("Failed to stop IPC server", "error", err)
 	}
 
-	// Release ports with better error handling
-	if a.portManager != nil {
-		logger.Info("Releasing ports")
-		if a.httpPort > 0 {
-			a.portManager.ReleasePort(a.httpPort)
-			logger.Debug("Released HTTP port", "port", a.httpPort)
-		}
-		if a.httpsPort > 0 {
-			a.portManager.ReleasePort(a.httpsPort)
-			logger.Debug("Released HTTPS port", "port", a.httpsPort)
+	// Tear down the supervised subsystem tree and the DNS/port state it
+	// doesn't own, phase by phase, so the TUI can show the same kind of
+	// step-by-step progress in reverse that it showed during setup.
+	// TeardownTimeout bounds the whole sequence: once it elapses, whatever
+	// phase hasn't run yet is skipped rather than attempted.
+	if a.tree != nil {
+		a.tree.StopWatching()
+
+		deadline := time.Now().Add(a.cfg.TeardownTimeout)
+		for _, phase := range teardownPhases {
+			if time.Now().After(deadline) {
+				logger.Warn("Teardown deadline exceeded, skipping remaining phase", "phase", phase)
+				a.sendShutdownStep(phase, "error", "skipped: teardown deadline exceeded")
+				errs = append(errs, fmt.Errorf("%s: skipped after teardown deadline exceeded", phase))
+				continue
+			}
+			if err := a.runTeardownPhase(phase); err != nil {
+				errs = append(errs, err)
+			}
 		}
+
+		a.tree.CloseEvents()
 	}
 
 	// Clean up port info file
@@ -541,27 +915,64 @@ func (a *App) shutdown() error {
 	return nil
 }
 
-func (a *App) IsRunning() bool {
-	return a.running
-}
+// runTeardownPhase stops one named phase of shutdown's teardownPhases
+// sequence, bounded by teardownPhaseTimeout, and reports its progress as
+// a ui.ShutdownStepMsg so the TUI's teardown panel mirrors setup's.
+// "dev" and "proxy" stop the matching Orchestrator Service directly;
+// "dns"/"ports" wrap cleanup that already lived outside the subsystem
+// tree; "certs" has no work of its own left once proxy has stopped
+// certRenewer, but gets its own phase so the panel stays symmetric with
+// setup's step list.
+func (a *App) runTeardownPhase(name string) error {
+	a.sendShutdownStep(name, "loading", "Stopping...")
+
+	ctx, cancel := context.WithTimeout(context.Background(), teardownPhaseTimeout)
+	defer cancel()
 
-func (a *App) GetStatus() map[string]interface{} {
-	status := map[string]interface{}{
-		"running":       a.running,
-		"project_name":  a.cfg.ProjectName,
-		"project_type":  string(a.cfg.ProjectType),
-		"domain":        a.cfg.Domain,
-		"http_port":     a.httpPort,
-		"https_port":    a.httpsPort,
-		"clean_urls":    a.cfg.UsePort443,
-		"https_enabled": a.cfg.EnableHTTPS,
-		"dns_enabled":   a.cfg.EnableDNS,
+	var err error
+	switch name {
+	case "tunnel", "dev", "proxy":
+		err = a.tree.StopService(ctx, name)
+	case "dns":
+		if a.dnsResolver != nil {
+			err = a.dnsResolver.Cleanup()
+		}
+	case "certs":
+		// No-op: proxy's own Stop already stopped certRenewer.
+	case "ports":
+		if a.portManager != nil {
+			if a.httpPort > 0 {
+				a.portManager.ReleasePort(a.httpPort)
+			}
+			if a.httpsPort > 0 {
+				a.portManager.ReleasePort(a.httpsPort)
+			}
+		}
 	}
 
-	if a.runner != nil {
-		status["dev_server_pid"] = a.runner.GetPID()
-		status["dev_server_running"] = a.runner.IsRunning()
+	if err != nil {
+		logger.Warn("Teardown phase failed", "phase", name, "error", err)
+		a.sendShutdownStep(name, "error", err.Error())
+		return fmt.Errorf("stop %s: %w", name, err)
 	}
 
-	return status
+	logger.Info("Teardown phase complete", "phase", name)
+	a.sendShutdownStep(name, "success", "Stopped")
+	return nil
+}
+
+// sendShutdownStep reports a teardown phase's progress to the TUI's
+// teardown panel, mirroring how handleTreeEvent reports setup's.
+func (a *App) sendShutdownStep(name, status, details string) {
+	if a.program != nil {
+		a.program.Send(ui.ShutdownStepMsg{
+			StepName: name,
+			Status:   status,
+			Details:  details,
+		})
+	}
+}
+
+func (a *App) IsRunning() bool {
+	return a.running
 }
@@ -0,0 +1,186 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kashifsb/nsm/internal/config"
+	"github.com/kashifsb/nsm/pkg/logger"
+)
+
+// controlSocketName is the unix domain socket NSM listens on for the
+// companion "nsm reload" command, living alongside .nsm-ports.json in the
+// same project directory.
+const controlSocketName = ".nsm-control.sock"
+
+// ControlSocketPath returns the control socket path for projectDir, so
+// both the listening App and the "nsm reload" client agree on it without
+// either hardcoding the other's layout.
+func ControlSocketPath(projectDir string) string {
+	return filepath.Join(projectDir, controlSocketName)
+}
+
+// startControlSocket listens on ControlSocketPath and, for every
+// connection that writes "reload", calls a.reloadHandler and replies "ok"
+// or "error: <message>". A stale socket left behind by an unclean exit is
+// removed first rather than failing Listen.
+func (a *App) startControlSocket() error {
+	path := ControlSocketPath(a.cfg.ProjectDir)
+	os.Remove(path)
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return fmt.Errorf("listen on control socket: %w", err)
+	}
+	a.controlListener = listener
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go a.handleControlConn(conn)
+		}
+	}()
+
+	return nil
+}
+
+// handleControlConn serves a single control socket request. The
+// protocol is intentionally minimal (one command, one line reply); "site-add"
+// and "site-remove" take the site's domain as a second space-separated
+// field, since a.cfg.Sites already has everything else a site needs.
+func (a *App) handleControlConn(conn net.Conn) {
+	defer conn.Close()
+
+	buf := make([]byte, 64)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return
+	}
+
+	fields := strings.Fields(string(buf[:n]))
+	if len(fields) == 0 {
+		fmt.Fprintln(conn, "error: unknown command")
+		return
+	}
+
+	switch fields[0] {
+	case "reload":
+		if a.reloadHandler == nil {
+			fmt.Fprintln(conn, "error: reload not available")
+			return
+		}
+		if err := a.reloadHandler(); err != nil {
+			fmt.Fprintf(conn, "error: %s\n", err)
+			return
+		}
+		fmt.Fprintln(conn, "ok")
+	case "site-add":
+		if len(fields) != 2 {
+			fmt.Fprintln(conn, "error: usage: site-add <domain>")
+			return
+		}
+		site, ok := a.findConfiguredSite(fields[1])
+		if !ok {
+			fmt.Fprintf(conn, "error: no site %q in configuration\n", fields[1])
+			return
+		}
+		if err := a.sites.AddSite(context.Background(), site); err != nil {
+			fmt.Fprintf(conn, "error: %s\n", err)
+			return
+		}
+		fmt.Fprintln(conn, "ok")
+	case "site-remove":
+		if len(fields) != 2 {
+			fmt.Fprintln(conn, "error: usage: site-remove <domain>")
+			return
+		}
+		if err := a.sites.RemoveSite(fields[1]); err != nil {
+			fmt.Fprintf(conn, "error: %s\n", err)
+			return
+		}
+		fmt.Fprintln(conn, "ok")
+	default:
+		fmt.Fprintln(conn, "error: unknown command")
+	}
+}
+
+// findConfiguredSite looks up domain among a.cfg.Sites, for "site-add"
+// over the control socket - runtime add only (re)starts a site that's
+// already declared in config, rather than accepting an ad-hoc one.
+func (a *App) findConfiguredSite(domain string) (config.SiteConfig, bool) {
+	for _, site := range a.cfg.Sites {
+		if site.Domain == domain {
+			return site, true
+		}
+	}
+	return config.SiteConfig{}, false
+}
+
+// stopControlSocket closes the listener and removes its socket file.
+func (a *App) stopControlSocket() error {
+	if a.controlListener == nil {
+		return nil
+	}
+
+	path := a.controlListener.Addr().String()
+	if err := a.controlListener.Close(); err != nil {
+		return err
+	}
+	os.Remove(path)
+	logger.Debug("Stopped control socket", "path", path)
+	return nil
+}
+
+// SendReload connects to the control socket at ControlSocketPath(projectDir)
+// and asks the running NSM to reload its configuration - what "nsm reload"
+// does under the hood.
+func SendReload(projectDir string) error {
+	return sendControlCommand(projectDir, "reload")
+}
+
+// SendSiteAdd asks the running NSM in projectDir to (re)start the site
+// already declared under Sites in its configuration as domain - what
+// "nsm site add <domain>" does under the hood.
+func SendSiteAdd(projectDir, domain string) error {
+	return sendControlCommand(projectDir, "site-add "+domain)
+}
+
+// SendSiteRemove asks the running NSM in projectDir to stop and unroute
+// the site running as domain - what "nsm site remove <domain>" does
+// under the hood.
+func SendSiteRemove(projectDir, domain string) error {
+	return sendControlCommand(projectDir, "site-remove "+domain)
+}
+
+// sendControlCommand dials ControlSocketPath(projectDir), writes command as
+// a single line, and turns an "error: ..." reply into a Go error.
+func sendControlCommand(projectDir, command string) error {
+	conn, err := net.Dial("unix", ControlSocketPath(projectDir))
+	if err != nil {
+		return fmt.Errorf("connect to control socket: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := fmt.Fprintln(conn, command); err != nil {
+		return fmt.Errorf("send command: %w", err)
+	}
+
+	buf := make([]byte, 256)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return fmt.Errorf("read control socket reply: %w", err)
+	}
+
+	reply := strings.TrimSpace(string(buf[:n]))
+	if strings.HasPrefix(reply, "error:") {
+		return fmt.Errorf("%s", strings.TrimSpace(strings.TrimPrefix(reply, "error:")))
+	}
+	return nil
+}
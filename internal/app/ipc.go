@@ -0,0 +1,398 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	nsmlog "github.com/kashifsb/nsm/internal/log"
+	"github.com/kashifsb/nsm/pkg/logger"
+	"github.com/kashifsb/nsm/pkg/utils"
+)
+
+// ipcSocketName is the Unix domain socket NSM listens on for the typed
+// status/command API, living alongside trace.jsonl under DataDir rather
+// than ProjectDir - unlike ControlSocketPath's minimal text protocol,
+// which this is deliberately additive to, not a replacement for.
+const ipcSocketName = "nsm.sock"
+
+// IPCSocketPath returns the IPC socket path for dataDir, so both the
+// listening App and an "nsm ctl" client agree on it without either
+// hardcoding the other's layout.
+func IPCSocketPath(dataDir string) string {
+	return filepath.Join(dataDir, ipcSocketName)
+}
+
+// ipcRequest is one newline-delimited JSON command read off the IPC
+// socket/HTTP variant. Domain is only meaningful for "rotate-cert".
+type ipcRequest struct {
+	Command string `json:"command"`
+	Domain  string `json:"domain,omitempty"`
+}
+
+// ipcResponse is the single reply sent back for an ipcRequest. Status is
+// only populated for "status".
+type ipcResponse struct {
+	OK     bool    `json:"ok"`
+	Error  string  `json:"error,omitempty"`
+	Status *Status `json:"status,omitempty"`
+}
+
+// ipcEvent is one line of the IPC socket's event stream: step_update
+// mirrors ui.StepUpdateMsg, server_started mirrors ui.ServerStartedMsg,
+// and log_line mirrors a nsmlog.Record, so an editor/IDE integration can
+// subscribe without attaching to the TUI.
+type ipcEvent struct {
+	Type string `json:"type"`
+
+	// step_update
+	Service string `json:"service,omitempty"`
+	Status  string `json:"status,omitempty"`
+	Details string `json:"details,omitempty"`
+
+	// server_started
+	HTTPPort  int `json:"http_port,omitempty"`
+	HTTPSPort int `json:"https_port,omitempty"`
+
+	// log_line
+	Level   string         `json:"level,omitempty"`
+	Facet   string         `json:"facet,omitempty"`
+	Message string         `json:"message,omitempty"`
+	Fields  map[string]any `json:"fields,omitempty"`
+}
+
+// ipcHub fans ipcEvents out to every connection currently registered by
+// handleIPCConn, and doubles as a nsmlog.Sink so Run/RunHeadless can wire
+// it into InitFromEnv alongside the TUI/file sinks.
+type ipcHub struct {
+	mu      sync.Mutex
+	clients map[chan ipcEvent]struct{}
+}
+
+func newIPCHub() *ipcHub {
+	return &ipcHub{clients: make(map[chan ipcEvent]struct{})}
+}
+
+// Write implements nsmlog.Sink, forwarding r as a log_line event.
+func (h *ipcHub) Write(r nsmlog.Record) {
+	h.broadcast(ipcEvent{
+		Type:    "log_line",
+		Level:   r.Level.String(),
+		Facet:   r.Facet,
+		Message: r.Message,
+		Fields:  r.Fields,
+	})
+}
+
+func (h *ipcHub) broadcast(event ipcEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.clients {
+		select {
+		case ch <- event:
+		default:
+			// Client isn't draining fast enough; drop the event rather
+			// than block every other step_update/log_line on it.
+		}
+	}
+}
+
+func (h *ipcHub) register() chan ipcEvent {
+	ch := make(chan ipcEvent, 64)
+	h.mu.Lock()
+	h.clients[ch] = struct{}{}
+	h.mu.Unlock()
+	return ch
+}
+
+func (h *ipcHub) unregister(ch chan ipcEvent) {
+	h.mu.Lock()
+	delete(h.clients, ch)
+	h.mu.Unlock()
+	close(ch)
+}
+
+// startIPCServer listens on IPCSocketPath(a.cfg.DataDir) for the typed
+// status/command protocol, and additionally starts a token-authenticated
+// loopback HTTP variant when a.cfg.CtlAddr is set. A stale socket left
+// behind by an unclean exit is removed first rather than failing Listen,
+// the same way startControlSocket handles it.
+func (a *App) startIPCServer() error {
+	path := IPCSocketPath(a.cfg.DataDir)
+	os.Remove(path)
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return fmt.Errorf("listen on IPC socket: %w", err)
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		listener.Close()
+		return fmt.Errorf("set IPC socket permissions: %w", err)
+	}
+	a.ipcListener = listener
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go a.handleIPCConn(conn)
+		}
+	}()
+
+	if a.cfg.CtlAddr != "" {
+		if err := a.startIPCHTTPServer(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// startIPCHTTPServer serves the same commands as the Unix socket over
+// a.cfg.CtlAddr, guarded by a random per-run token written to
+// DataDir/config/token (0600): every request must carry it as
+// "Authorization: Bearer <token>".
+func (a *App) startIPCHTTPServer() error {
+	token := utils.GenerateID(32)
+
+	tokenPath := filepath.Join(a.cfg.DataDir, "config", "token")
+	if err := utils.EnsureDir(filepath.Dir(tokenPath)); err != nil {
+		return fmt.Errorf("create IPC token directory: %w", err)
+	}
+	if err := os.WriteFile(tokenPath, []byte(token), 0o600); err != nil {
+		return fmt.Errorf("write IPC token: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/command", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var req ipcRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("decode request: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(a.dispatchIPCCommand(req))
+	})
+
+	a.ipcHTTPServer = &http.Server{Addr: a.cfg.CtlAddr, Handler: mux}
+
+	logger.Info("Starting IPC HTTP server", "addr", a.cfg.CtlAddr)
+	go func() {
+		if err := a.ipcHTTPServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Error("IPC HTTP server error", "error", err)
+		}
+	}()
+
+	return nil
+}
+
+// handleIPCConn serves one Unix socket connection: every newline-delimited
+// JSON ipcRequest gets one newline-delimited JSON ipcResponse back, and the
+// connection stays open afterward streaming ipcEvents as they're
+// broadcast, so a client that just wants a single "status" can read one
+// response and close, while one that wants live updates can keep reading.
+func (a *App) handleIPCConn(conn net.Conn) {
+	defer conn.Close()
+
+	events := a.ipcHub.register()
+	defer a.ipcHub.unregister(events)
+
+	// writeMu serializes conn.Write calls between the request/response
+	// loop below and the event-forwarding loop at the bottom, since both
+	// share this one connection.
+	var writeMu sync.Mutex
+	enc := json.NewEncoder(conn)
+	writeJSON := func(v interface{}) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		return enc.Encode(v)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			var req ipcRequest
+			if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+				writeJSON(ipcResponse{Error: fmt.Sprintf("decode request: %v", err)})
+				continue
+			}
+			writeJSON(a.dispatchIPCCommand(req))
+		}
+	}()
+
+	for {
+		select {
+		case <-done:
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := writeJSON(event); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// dispatchIPCCommand runs one ipcRequest against a and returns its reply.
+// restart-dev reuses rerunStep, the same mechanism Reload uses to reapply
+// one subsystem step; rotate-cert reuses certManager.EnsureCertificate
+// (forced) and proxyServer.ReloadCertificate, the same pair setupCertificates
+// uses on startup.
+func (a *App) dispatchIPCCommand(req ipcRequest) ipcResponse {
+	switch req.Command {
+	case "status":
+		return ipcResponse{OK: true, Status: a.GetStatus()}
+
+	case "reload":
+		if a.reloadHandler == nil {
+			return ipcResponse{Error: "reload not available"}
+		}
+		if err := a.reloadHandler(); err != nil {
+			return ipcResponse{Error: err.Error()}
+		}
+		return ipcResponse{OK: true}
+
+	case "restart-dev":
+		if err := a.rerunStep(context.Background(), "dev"); err != nil {
+			return ipcResponse{Error: err.Error()}
+		}
+		return ipcResponse{OK: true}
+
+	case "rotate-cert":
+		domain := req.Domain
+		if domain == "" {
+			domain = a.cfg.Domain
+		}
+		info, err := a.certManager.EnsureCertificate(domain, true)
+		if err != nil {
+			return ipcResponse{Error: fmt.Sprintf("rotate certificate: %s", err)}
+		}
+		if a.proxyServer != nil {
+			if err := a.proxyServer.ReloadCertificate(info.CertPath, info.KeyPath); err != nil {
+				return ipcResponse{Error: fmt.Sprintf("reload certificate into proxy: %s", err)}
+			}
+		}
+		return ipcResponse{OK: true}
+
+	case "stop":
+		a.stopOnce.Do(func() { close(a.stopCh) })
+		return ipcResponse{OK: true}
+
+	default:
+		return ipcResponse{Error: fmt.Sprintf("unknown command %q", req.Command)}
+	}
+}
+
+// stopIPCServer closes the Unix socket listener (removing its socket
+// file), the HTTP variant if one was started, and every event subscriber
+// still registered on the hub.
+func (a *App) stopIPCServer() error {
+	var errs []error
+
+	if a.ipcListener != nil {
+		path := a.ipcListener.Addr().String()
+		if err := a.ipcListener.Close(); err != nil {
+			errs = append(errs, err)
+		}
+		os.Remove(path)
+	}
+
+	if a.ipcHTTPServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), teardownPhaseTimeout)
+		defer cancel()
+		if err := a.ipcHTTPServer.Shutdown(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("stop IPC server: %v", errs)
+	}
+
+	logger.Debug("Stopped IPC server")
+	return nil
+}
+
+// SendIPCStatus asks the running NSM in dataDir for its current Status -
+// what "nsm ctl status" does under the hood.
+func SendIPCStatus(dataDir string) (*Status, error) {
+	resp, err := sendIPCCommand(dataDir, ipcRequest{Command: "status"})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Status, nil
+}
+
+// SendIPCReload asks the running NSM in dataDir to reload its
+// configuration - what "nsm ctl reload" does under the hood.
+func SendIPCReload(dataDir string) error {
+	_, err := sendIPCCommand(dataDir, ipcRequest{Command: "reload"})
+	return err
+}
+
+// SendIPCRestartDev asks the running NSM in dataDir to stop and restart
+// its dev server step - what "nsm ctl restart-dev" does under the hood.
+func SendIPCRestartDev(dataDir string) error {
+	_, err := sendIPCCommand(dataDir, ipcRequest{Command: "restart-dev"})
+	return err
+}
+
+// SendIPCRotateCert asks the running NSM in dataDir to force-reissue its
+// certificate for domain (or its configured Domain, if domain is empty)
+// and reload it into the proxy - what "nsm ctl rotate-cert" does under
+// the hood.
+func SendIPCRotateCert(dataDir, domain string) error {
+	_, err := sendIPCCommand(dataDir, ipcRequest{Command: "rotate-cert", Domain: domain})
+	return err
+}
+
+// SendIPCStop asks the running NSM in dataDir to shut down gracefully -
+// what "nsm ctl stop" does under the hood.
+func SendIPCStop(dataDir string) error {
+	_, err := sendIPCCommand(dataDir, ipcRequest{Command: "stop"})
+	return err
+}
+
+// sendIPCCommand dials IPCSocketPath(dataDir), writes req as a single
+// JSON line, and reads back the first response line - the one reply to
+// this request, ignoring the event stream that would otherwise follow it
+// on the same connection.
+func sendIPCCommand(dataDir string, req ipcRequest) (*ipcResponse, error) {
+	conn, err := net.Dial("unix", IPCSocketPath(dataDir))
+	if err != nil {
+		return nil, fmt.Errorf("connect to IPC socket: %w", err)
+	}
+	defer conn.Close()
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, fmt.Errorf("send command: %w", err)
+	}
+
+	var resp ipcResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("read IPC socket reply: %w", err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("%s", resp.Error)
+	}
+	return &resp, nil
+}
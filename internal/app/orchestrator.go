@@ -0,0 +1,208 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kashifsb/nsm/pkg/health"
+	"github.com/kashifsb/nsm/pkg/logger"
+	"github.com/kashifsb/nsm/pkg/utils"
+)
+
+// Service is one node in NSM's supervised subsystem tree: a named unit
+// with a start/stop lifecycle. One-shot setup steps (validate, ports,
+// certs, dns) return a nil Health channel since there's nothing to watch
+// once Start returns; long-running subsystems (proxy, dev) return a
+// channel of health.Status so Orchestrator can restart them if they later
+// report health.StateError.
+type Service interface {
+	Name() string
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+	Health() <-chan health.Status
+}
+
+// Event is one lifecycle transition Orchestrator reports as it starts,
+// restarts, or loses a Service, for App to translate into
+// ui.StepUpdateMsg/LogMsg/ErrorMsg (or a log line in headless mode).
+type Event struct {
+	Service string
+	Status  string // "starting", "ready", "restarting", "failed"
+	Err     error
+}
+
+// Restart backoff bounds, mirroring pkg/process.Supervisor's crash-restart
+// loop: each "attempt" there is one process lifecycle, here it's one
+// Stop+Start cycle of a whole Service.
+const (
+	restartInitialBackoff = 500 * time.Millisecond
+	restartMaxBackoff     = 30 * time.Second
+	maxRestartAttempts    = 10
+)
+
+// Orchestrator starts a fixed, ordered list of Services — each assumed to
+// depend on the ones before it, suture-supervisor style — cancels them
+// all on Stop, and restarts any long-running Service with exponential
+// backoff if it reports health.StateError.
+type Orchestrator struct {
+	services []Service
+	events   chan Event
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewOrchestrator returns an Orchestrator over services, in the order
+// they should be started. Events must be drained by the caller; the
+// channel is closed once Stop returns.
+func NewOrchestrator(services []Service) *Orchestrator {
+	return &Orchestrator{
+		services: services,
+		events:   make(chan Event, 32),
+	}
+}
+
+// Events returns the channel of lifecycle events the Orchestrator
+// publishes as it starts and supervises its Services.
+func (o *Orchestrator) Events() <-chan Event {
+	return o.events
+}
+
+// Start starts every Service in order, stopping at (and returning) the
+// first failure. Services with a non-nil Health channel are then watched
+// in the background and restarted with backoff if they report
+// health.StateError.
+func (o *Orchestrator) Start(ctx context.Context) error {
+	o.ctx, o.cancel = context.WithCancel(ctx)
+
+	for _, svc := range o.services {
+		o.events <- Event{Service: svc.Name(), Status: "starting"}
+
+		if err := svc.Start(o.ctx); err != nil {
+			o.events <- Event{Service: svc.Name(), Status: "failed", Err: err}
+			return fmt.Errorf("start %s: %w", svc.Name(), err)
+		}
+
+		o.events <- Event{Service: svc.Name(), Status: "ready"}
+
+		if healthCh := svc.Health(); healthCh != nil {
+			go o.watch(svc, healthCh)
+		}
+	}
+
+	return nil
+}
+
+// Stop cancels the root context and stops every Service in reverse
+// order, closing the event channel once done.
+func (o *Orchestrator) Stop() error {
+	if o.cancel != nil {
+		o.cancel()
+	}
+
+	var errs []error
+	for i := len(o.services) - 1; i >= 0; i-- {
+		svc := o.services[i]
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := svc.Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("stop %s: %w", svc.Name(), err))
+		}
+		cancel()
+	}
+
+	close(o.events)
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%d service(s) failed to stop cleanly: %v", len(errs), errs)
+	}
+	return nil
+}
+
+// StopWatching cancels the root context, ending every watch() goroutine
+// without stopping any Service, so a caller that wants to stop services
+// itself (in a specific order, on its own schedule) doesn't race watch's
+// own restart-on-failure logic while it does so.
+func (o *Orchestrator) StopWatching() {
+	if o.cancel != nil {
+		o.cancel()
+	}
+}
+
+// StopService stops the single named Service, if present, and reports
+// whether it was found. Callers that need a specific teardown order
+// (App.shutdown's phased dev -> proxy -> dns -> certs -> ports sequence)
+// call StopWatching first, then StopService once per phase, instead of
+// Stop's fixed reverse-of-all-services sweep.
+func (o *Orchestrator) StopService(ctx context.Context, name string) error {
+	for _, svc := range o.services {
+		if svc.Name() != name {
+			continue
+		}
+		if err := svc.Stop(ctx); err != nil {
+			return fmt.Errorf("stop %s: %w", name, err)
+		}
+		return nil
+	}
+	return nil
+}
+
+// StartService starts the single named Service, if present. Like
+// StopService, it bypasses the events channel - callers rerunning one
+// step after a config change (App.rerunStep) report its progress through
+// handleTreeEvent directly instead, the same way Start's own event
+// emission works for the initial, whole-tree startup.
+func (o *Orchestrator) StartService(ctx context.Context, name string) error {
+	for _, svc := range o.services {
+		if svc.Name() != name {
+			continue
+		}
+		if err := svc.Start(ctx); err != nil {
+			return fmt.Errorf("start %s: %w", name, err)
+		}
+		return nil
+	}
+	return nil
+}
+
+// CloseEvents closes the event channel. Callers driving their own teardown
+// via StopWatching/StopService call this once every phase has reported,
+// mirroring what Stop does automatically at the end of its own sweep.
+func (o *Orchestrator) CloseEvents() {
+	close(o.events)
+}
+
+// watch restarts svc with exponential backoff whenever its health channel
+// reports health.StateError, until the Orchestrator is stopped, the
+// channel closes, or it runs out of attempts.
+func (o *Orchestrator) watch(svc Service, healthCh <-chan health.Status) {
+	for {
+		select {
+		case <-o.ctx.Done():
+			return
+		case status, ok := <-healthCh:
+			if !ok {
+				return
+			}
+			if status.State != health.StateError {
+				continue
+			}
+
+			o.events <- Event{Service: svc.Name(), Status: "restarting", Err: status.Err}
+
+			err := utils.RetryWithBackoff(maxRestartAttempts, restartInitialBackoff, restartMaxBackoff, func() error {
+				if stopErr := svc.Stop(o.ctx); stopErr != nil {
+					logger.Debug("Error stopping failed service before restart", "service", svc.Name(), "error", stopErr)
+				}
+				return svc.Start(o.ctx)
+			})
+
+			if err != nil {
+				o.events <- Event{Service: svc.Name(), Status: "failed", Err: err}
+				return
+			}
+
+			o.events <- Event{Service: svc.Name(), Status: "ready"}
+		}
+	}
+}
@@ -0,0 +1,505 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kashifsb/nsm/internal/cert"
+	"github.com/kashifsb/nsm/internal/config"
+	"github.com/kashifsb/nsm/internal/platform"
+	"github.com/kashifsb/nsm/internal/project"
+	"github.com/kashifsb/nsm/internal/server"
+	"github.com/kashifsb/nsm/internal/tunnel"
+	"github.com/kashifsb/nsm/internal/ui"
+	"github.com/kashifsb/nsm/pkg/health"
+	"github.com/kashifsb/nsm/pkg/logger"
+	"github.com/kashifsb/nsm/pkg/utils"
+)
+
+// buildServices returns the ordered subsystem tree startTree hands to an
+// Orchestrator: the one-shot setup steps, followed by the proxy (if
+// enabled) and finally the dev server/static server.
+func (a *App) buildServices() []Service {
+	services := []Service{
+		newStepService("validate", a.setupValidation),
+		newStepService("ports", a.setupPorts),
+		newStepService("certs", a.setupCertificates),
+		newStepService("dns", a.setupDNS),
+	}
+
+	if a.cfg.EnableProxy {
+		services = append(services, &proxyService{app: a})
+	}
+
+	services = append(services, &devService{app: a})
+
+	if a.cfg.EnableProxy && len(a.cfg.Sites) > 0 {
+		services = append(services, &sitesService{app: a})
+	}
+
+	if a.cfg.EnableProxy && a.cfg.Tunnel.Provider != "" {
+		services = append(services, &tunnelService{app: a})
+	}
+
+	return services
+}
+
+// subsystemHealth filters tracker's updates down to just subsystem, for a
+// Service's Health() method. The returned channel closes once the
+// returned unsubscribe func is called.
+func subsystemHealth(tracker *health.Tracker, subsystem string) (<-chan health.Status, func()) {
+	all, unsubscribe := tracker.Subscribe()
+	out := make(chan health.Status, 4)
+
+	go func() {
+		defer close(out)
+		for status := range all {
+			if status.Subsystem != subsystem {
+				continue
+			}
+			select {
+			case out <- status:
+			default:
+			}
+		}
+	}()
+
+	return out, unsubscribe
+}
+
+// stepTimeout bounds how long a one-shot setup step (validate, ports,
+// certs, dns) gets before stepService reports it as failed.
+const stepTimeout = 30 * time.Second
+
+// stepService adapts a one-shot setup function (validate, ports, certs,
+// dns) to the Service interface: it has nothing to stop and nothing to
+// watch once Start returns, and it's bounded by stepTimeout.
+type stepService struct {
+	name  string
+	start func(ctx context.Context) error
+}
+
+func newStepService(name string, start func(ctx context.Context) error) Service {
+	return &stepService{name: name, start: start}
+}
+
+func (s *stepService) Name() string { return s.name }
+
+func (s *stepService) Start(ctx context.Context) error {
+	stepCtx, cancel := context.WithTimeout(ctx, stepTimeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- s.start(stepCtx) }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-stepCtx.Done():
+		return fmt.Errorf("step %s timed out after %s", s.name, stepTimeout)
+	}
+}
+
+func (s *stepService) Stop(ctx context.Context) error { return nil }
+func (s *stepService) Health() <-chan health.Status   { return nil }
+
+// proxyService owns the HTTPS reverse proxy and (when enabled) its
+// certificate renewer; it reports into a.health under "proxy" so
+// Orchestrator can restart it if the listener dies.
+type proxyService struct {
+	app         *App
+	unsubscribe func()
+}
+
+func (s *proxyService) Name() string { return "proxy" }
+
+func (s *proxyService) Start(ctx context.Context) error {
+	a := s.app
+
+	proxyConfig := server.ProxyConfig{
+		ProxyPort:       a.httpsPort,
+		Domain:          a.cfg.Domain,
+		CertPath:        a.cfg.CertPath,
+		KeyPath:         a.cfg.KeyPath,
+		EnableHTTPS:     a.cfg.EnableHTTPS,
+		Middlewares:     a.cfg.ProxyMiddlewares,
+		InjectScript:    a.cfg.ProxyInjectScript,
+		RequestHeaders:  parseHeaderEntries(a.cfg.ProxyRequestHeaders),
+		ResponseHeaders: parseHeaderEntries(a.cfg.ProxyResponseHeaders),
+		BasicAuthUsers:  parseBasicAuthEntries(a.cfg.ProxyBasicAuthUsers),
+		AccessLog: server.AccessLogConfig{
+			Format:     a.cfg.AccessLogFormat,
+			Path:       a.cfg.AccessLogPath,
+			MaxSizeMB:  a.cfg.AccessLogMaxSizeMB,
+			MaxBackups: a.cfg.AccessLogMaxBackups,
+			MaxAgeDays: a.cfg.AccessLogMaxAgeDays,
+		},
+	}
+
+	if a.cfg.HTTPProxy {
+		proxyConfig.Mode = server.ModeForward
+		proxyConfig.ForwardAllowHosts = a.cfg.HTTPProxyAllowHosts
+		proxyConfig.ForwardDenyHosts = a.cfg.HTTPProxyDenyHosts
+	} else {
+		upstreams := []server.UpstreamTarget{{Host: "127.0.0.1", Port: a.httpPort}}
+		extra, err := parseUpstreamTargets(a.cfg.ExtraUpstreams)
+		if err != nil {
+			return fmt.Errorf("parse extra upstreams: %w", err)
+		}
+		upstreams = append(upstreams, extra...)
+
+		proxyConfig.Upstreams = upstreams
+		proxyConfig.SelectionPolicy = server.SelectionPolicy(a.cfg.UpstreamPolicy)
+	}
+
+	proxyServer, err := server.NewProxyServer(a.cfg, proxyConfig, a.health)
+	if err != nil {
+		return fmt.Errorf("build proxy server: %w", err)
+	}
+	a.proxyServer = proxyServer
+	if err := a.proxyServer.Start(ctx, a.httpsPort); err != nil {
+		return fmt.Errorf("start proxy server: %w", err)
+	}
+
+	if a.cfg.EnableHTTPS {
+		domain := a.cfg.Domain
+		if domain == "" {
+			domain = "localhost"
+		}
+
+		a.certRenewer = cert.NewRenewer(a.certManager, domain, func(info *cert.CertificateInfo) {
+			if err := a.proxyServer.ReloadCertificate(info.CertPath, info.KeyPath); err != nil {
+				logger.Error("Failed to hot-reload renewed certificate into proxy", "error", err)
+			}
+		}, func(domain string, notAfter time.Time) {
+			msg := fmt.Sprintf("Certificate for %s expires %s, renewing", domain, notAfter.Format("2006-01-02"))
+			if err := platform.Notify("NSM", msg); err != nil {
+				logger.Debug("Failed to send certificate-expiring notification", "error", err)
+			}
+		})
+		a.certRenewer.Start(ctx)
+	}
+
+	return nil
+}
+
+func (s *proxyService) Stop(ctx context.Context) error {
+	a := s.app
+
+	if s.unsubscribe != nil {
+		s.unsubscribe()
+	}
+
+	if a.certRenewer != nil {
+		a.certRenewer.Stop()
+	}
+
+	if a.proxyServer != nil {
+		return a.proxyServer.Stop(ctx)
+	}
+	return nil
+}
+
+func (s *proxyService) Health() <-chan health.Status {
+	ch, unsubscribe := subsystemHealth(s.app.health, "proxy")
+	s.unsubscribe = unsubscribe
+	return ch
+}
+
+// devService owns the running project: the embedded static file server
+// for ProjectTypeStatic, the compose-style multi-service Supervisor when
+// an nsm.yaml manifest is present, or a single Runner otherwise. It
+// reports into a.health under "dev" so Orchestrator can restart the whole
+// subtree once Runner has exhausted its own process-level restart budget.
+type devService struct {
+	app         *App
+	unsubscribe func()
+}
+
+func (s *devService) Name() string { return "dev" }
+
+func (s *devService) Start(ctx context.Context) error {
+	a := s.app
+
+	if a.cfg.ProjectType == config.ProjectTypeStatic {
+		a.staticServer = project.NewStaticServer(a.cfg)
+		if err := a.staticServer.Start(ctx, a.httpPort); err != nil {
+			return fmt.Errorf("start static file server: %w", err)
+		}
+		return s.waitReady(ctx)
+	}
+
+	manifest, err := project.LoadCompose(a.cfg.ProjectDir)
+	if err != nil {
+		return fmt.Errorf("load %s: %w", project.ComposeFilename, err)
+	}
+
+	if manifest != nil && len(manifest.Services) > 0 {
+		a.supervisor = project.NewSupervisor(a.cfg, a.program, manifest)
+		if err := a.supervisor.Start(ctx, a.cfg.ProjectDir); err != nil {
+			return fmt.Errorf("start services: %w", err)
+		}
+		return s.waitReady(ctx)
+	}
+
+	runnerConfig := project.RunnerConfig{
+		WorkingDir: a.cfg.ProjectDir,
+		Command:    a.cfg.Command,
+		Env: map[string]string{
+			"NSM_HTTP_PORT":  fmt.Sprintf("%d", a.httpPort),
+			"NSM_HTTPS_PORT": fmt.Sprintf("%d", a.httpsPort),
+			"PORT":           fmt.Sprintf("%d", a.httpPort),
+			"HOST":           "127.0.0.1",
+		},
+		OnCrashExhausted: func(err error) {
+			a.health.Set("dev", health.StateError, err)
+		},
+	}
+
+	if err := a.runner.Start(ctx, runnerConfig); err != nil {
+		return fmt.Errorf("start development server: %w", err)
+	}
+	return s.waitReady(ctx)
+}
+
+// waitReady polls for the dev port to come up, logging but not failing
+// Start on timeout — matching how the rest of NSM treats a slow-starting
+// dev command as a warning rather than a fatal setup error.
+func (s *devService) waitReady(ctx context.Context) error {
+	a := s.app
+
+	logger.Info("Waiting for development server to be ready", "port", a.httpPort)
+	if err := a.portManager.WaitForPort(a.httpPort, 30*time.Second); err != nil {
+		logger.Warn("Development server may not be ready", "error", err)
+	} else {
+		logger.Info("Development server is ready", "port", a.httpPort)
+	}
+	return nil
+}
+
+func (s *devService) Stop(ctx context.Context) error {
+	a := s.app
+
+	if s.unsubscribe != nil {
+		s.unsubscribe()
+	}
+
+	switch {
+	case a.staticServer != nil:
+		return a.staticServer.Stop()
+	case a.supervisor != nil:
+		return a.supervisor.Stop()
+	case a.runner != nil:
+		return a.runner.Stop()
+	}
+	return nil
+}
+
+func (s *devService) Health() <-chan health.Status {
+	ch, unsubscribe := subsystemHealth(s.app.health, "dev")
+	s.unsubscribe = unsubscribe
+	return ch
+}
+
+// sitesService starts every config.Config.Sites entry through a.sites
+// once the proxy and main dev server are both up, so each site's route
+// has a running shared proxy to be added to. It has no health channel of
+// its own - a site's dev server crashing is reported through its own
+// project.Runner restart budget, not through Orchestrator.
+type sitesService struct {
+	app *App
+}
+
+func (s *sitesService) Name() string { return "sites" }
+
+func (s *sitesService) Start(ctx context.Context) error {
+	a := s.app
+	for _, site := range a.cfg.Sites {
+		if err := a.sites.AddSite(ctx, site); err != nil {
+			return fmt.Errorf("add site %s: %w", site.Domain, err)
+		}
+	}
+	return nil
+}
+
+func (s *sitesService) Stop(ctx context.Context) error {
+	s.app.sites.StopAll()
+	return nil
+}
+
+func (s *sitesService) Health() <-chan health.Status { return nil }
+
+// tunnelService exposes a.httpsPort over a public URL once the proxy is
+// up, via the internal/tunnel.Provider cfg.Tunnel.Provider selects. It has
+// no health channel of its own - Start's watchTunnel goroutine (running
+// for as long as ctx, the shared tree context, stays alive) owns
+// reconnect-on-drop instead of Orchestrator's restart-on-StateError path.
+type tunnelService struct {
+	app *App
+}
+
+func (s *tunnelService) Name() string { return "tunnel" }
+
+func (s *tunnelService) Start(ctx context.Context) error {
+	a := s.app
+
+	provider, err := tunnel.NewProvider(a.cfg.Tunnel.Provider)
+	if err != nil {
+		return fmt.Errorf("tunnel: %w", err)
+	}
+
+	url, err := provider.Start(ctx, a.httpsPort)
+	if err != nil {
+		return fmt.Errorf("start tunnel: %w", err)
+	}
+
+	a.tunnelMu.Lock()
+	a.tunnelProvider = provider
+	a.tunnelURL = url
+	a.tunnelMu.Unlock()
+	logger.Info("Tunnel ready", "provider", provider.Name(), "url", url)
+	if a.program != nil {
+		a.program.Send(ui.TunnelReadyMsg{Provider: provider.Name(), URL: url})
+	}
+
+	go a.watchTunnel(ctx, provider)
+
+	return nil
+}
+
+func (s *tunnelService) Stop(ctx context.Context) error {
+	a := s.app
+
+	a.tunnelMu.Lock()
+	provider := a.tunnelProvider
+	a.tunnelMu.Unlock()
+	if provider == nil {
+		return nil
+	}
+
+	err := provider.Stop(ctx)
+	a.tunnelMu.Lock()
+	a.tunnelProvider = nil
+	a.tunnelURL = ""
+	a.tunnelMu.Unlock()
+	return err
+}
+
+func (s *tunnelService) Health() <-chan health.Status { return nil }
+
+// tunnelHealthInterval is how often watchTunnel polls the tunnel
+// provider's liveness. tunnelReconnectBackoff/tunnelMaxReconnectBackoff
+// and tunnelReconnectAttempts bound the exponential backoff it retries a
+// dropped tunnel with, mirroring pkg/process.Supervisor's own
+// crash-restart backoff.
+const (
+	tunnelHealthInterval      = 5 * time.Second
+	tunnelReconnectBackoff    = 2 * time.Second
+	tunnelMaxReconnectBackoff = 2 * time.Minute
+	tunnelReconnectAttempts   = 6
+)
+
+// watchTunnel polls provider's liveness every tunnelHealthInterval and, if
+// it has dropped, reconnects with exponential backoff, reporting the new
+// URL via ui.TunnelReadyMsg same as the initial connect. It runs until ctx
+// (the shared tree context tunnelService.Start was given) is cancelled.
+func (a *App) watchTunnel(ctx context.Context, provider tunnel.Provider) {
+	ticker := time.NewTicker(tunnelHealthInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if provider.IsRunning() {
+				continue
+			}
+
+			logger.Warn("Tunnel dropped, reconnecting", "provider", provider.Name())
+			a.tunnelMu.Lock()
+			a.tunnelURL = ""
+			a.tunnelMu.Unlock()
+
+			err := utils.RetryWithBackoff(tunnelReconnectAttempts, tunnelReconnectBackoff, tunnelMaxReconnectBackoff, func() error {
+				url, startErr := provider.Start(ctx, a.httpsPort)
+				if startErr != nil {
+					return startErr
+				}
+
+				a.tunnelMu.Lock()
+				a.tunnelURL = url
+				a.tunnelMu.Unlock()
+				logger.Info("Tunnel reconnected", "provider", provider.Name(), "url", url)
+				if a.program != nil {
+					a.program.Send(ui.TunnelReadyMsg{Provider: provider.Name(), URL: url})
+				}
+				return nil
+			})
+			if err != nil {
+				logger.Warn("Tunnel reconnect failed", "provider", provider.Name(), "error", err)
+			}
+		}
+	}
+}
+
+// parseUpstreamTargets parses each "host:port" entry in raw (from
+// config.Config.ExtraUpstreams) into a server.UpstreamTarget, for
+// fronting additional backend processes alongside the dev server in a
+// micro-frontend / multi-service setup.
+func parseUpstreamTargets(raw []string) ([]server.UpstreamTarget, error) {
+	targets := make([]server.UpstreamTarget, 0, len(raw))
+	for _, entry := range raw {
+		host, portStr, err := net.SplitHostPort(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid upstream %q: %w", entry, err)
+		}
+		port, err := strconv.Atoi(portStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid upstream %q: port must be numeric", entry)
+		}
+		targets = append(targets, server.UpstreamTarget{Host: host, Port: port})
+	}
+	return targets, nil
+}
+
+// parseHeaderEntries parses each "Name: Value" entry in raw (from
+// config.Config.ProxyRequestHeaders/ProxyResponseHeaders) into a map for
+// server.ProxyConfig's headers middleware. An entry with no ":" is
+// skipped rather than failing the whole proxy over a typo.
+func parseHeaderEntries(raw []string) map[string]string {
+	if len(raw) == 0 {
+		return nil
+	}
+	headers := make(map[string]string, len(raw))
+	for _, entry := range raw {
+		name, value, ok := strings.Cut(entry, ":")
+		if !ok {
+			continue
+		}
+		headers[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	return headers
+}
+
+// parseBasicAuthEntries parses each "user:password" entry in raw (from
+// config.Config.ProxyBasicAuthUsers) into a map for server.ProxyConfig's
+// basic-auth middleware.
+func parseBasicAuthEntries(raw []string) map[string]string {
+	if len(raw) == 0 {
+		return nil
+	}
+	users := make(map[string]string, len(raw))
+	for _, entry := range raw {
+		user, password, ok := strings.Cut(entry, ":")
+		if !ok {
+			continue
+		}
+		users[user] = password
+	}
+	return users
+}
@@ -0,0 +1,218 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/kashifsb/nsm/internal/config"
+	"github.com/kashifsb/nsm/internal/project"
+	"github.com/kashifsb/nsm/internal/server"
+	"github.com/kashifsb/nsm/pkg/logger"
+)
+
+// Site is one additional project app.SiteManager runs and fronts
+// alongside a.cfg's own project: its own dev-server process on its own
+// loopback port, its own certificate, and its own entry in the shared
+// proxy's router and DNS resolver.
+type Site struct {
+	Domain  string
+	Command string
+	WorkDir string
+
+	port   int
+	runner *project.Runner
+}
+
+// SiteStatus is Site's read-only view for GetStatus.
+type SiteStatus struct {
+	Domain       string `json:"domain"`
+	Command      string `json:"command"`
+	WorkDir      string `json:"work_dir"`
+	Running      bool   `json:"running"`
+	DevServerPID int    `json:"dev_server_pid,omitempty"`
+}
+
+// SiteManager owns every Site running alongside app's own project,
+// fronting them all on the same shared proxy (by Host-based routing) and
+// registering their domains with the same DNS resolver - see chunk9-2's
+// "one nsm process, many local sites" mode.
+type SiteManager struct {
+	app *App
+
+	mu      sync.Mutex
+	sites   map[string]*Site
+	pending map[string]bool
+}
+
+// NewSiteManager returns an empty SiteManager for app; call AddSite once
+// per config.SiteConfig to populate it, typically from a.startTree.
+func NewSiteManager(app *App) *SiteManager {
+	return &SiteManager{app: app, sites: make(map[string]*Site), pending: make(map[string]bool)}
+}
+
+// AddSite starts sc's dev-server process, issues it a certificate, routes
+// its domain to it on the shared proxy, and registers its domain with the
+// DNS resolver. It's the runtime counterpart to listing sc under Sites in
+// config - both `nsm site add` (over the control socket) and startup call
+// this the same way.
+func (sm *SiteManager) AddSite(ctx context.Context, sc config.SiteConfig) error {
+	sm.mu.Lock()
+	if _, exists := sm.sites[sc.Domain]; exists {
+		sm.mu.Unlock()
+		return fmt.Errorf("site %s is already running", sc.Domain)
+	}
+	if sm.pending[sc.Domain] {
+		sm.mu.Unlock()
+		return fmt.Errorf("site %s is already being added", sc.Domain)
+	}
+	// Reserve the domain before releasing the lock, so a second concurrent
+	// AddSite for the same domain fails one of the checks above instead of
+	// both racing through FindFreePort/runner.Start and the loser leaking
+	// its started dev-server process.
+	sm.pending[sc.Domain] = true
+	sm.mu.Unlock()
+
+	defer func() {
+		sm.mu.Lock()
+		delete(sm.pending, sc.Domain)
+		sm.mu.Unlock()
+	}()
+
+	a := sm.app
+
+	port, err := a.portManager.FindFreePort()
+	if err != nil {
+		return fmt.Errorf("find free port for site %s: %w", sc.Domain, err)
+	}
+
+	siteCfg := &config.Config{
+		ProjectType: config.ProjectTypeNode,
+		ProjectDir:  sc.WorkDir,
+		ProjectName: sc.Domain,
+		Command:     sc.Command,
+		Domain:      sc.Domain,
+		DataDir:     a.cfg.DataDir,
+	}
+
+	runner := project.NewRunner(siteCfg, a.program)
+	if err := runner.Start(ctx, project.RunnerConfig{
+		WorkingDir: sc.WorkDir,
+		Command:    sc.Command,
+	}); err != nil {
+		return fmt.Errorf("start site %s dev server: %w", sc.Domain, err)
+	}
+
+	site := &Site{Domain: sc.Domain, Command: sc.Command, WorkDir: sc.WorkDir, port: port, runner: runner}
+
+	if err := sm.wireSite(site); err != nil {
+		runner.Stop()
+		return err
+	}
+
+	sm.mu.Lock()
+	sm.sites[sc.Domain] = site
+	sm.mu.Unlock()
+
+	logger.Info("Site added", "domain", sc.Domain, "port", port)
+	return nil
+}
+
+// wireSite issues site's certificate and registers it with the shared
+// proxy's router/site-cert table and the DNS resolver - the parts of
+// AddSite that have something to undo on failure, separated out so
+// RemoveSite's teardown mirrors it one step at a time.
+func (sm *SiteManager) wireSite(site *Site) error {
+	a := sm.app
+
+	if a.proxyServer != nil {
+		if err := a.proxyServer.AddRoute(server.RouteRule{
+			Name:     site.Domain,
+			Host:     site.Domain,
+			Upstream: server.UpstreamTarget{Host: "127.0.0.1", Port: site.port},
+		}); err != nil {
+			return fmt.Errorf("route site %s: %w", site.Domain, err)
+		}
+
+		if a.cfg.EnableHTTPS {
+			certInfo, err := a.certManager.EnsureCertificate(site.Domain, false)
+			if err != nil {
+				logger.Warn("Failed to issue certificate for site, falling back to the primary cert", "domain", site.Domain, "error", err)
+			} else if err := a.proxyServer.AddSiteCert(site.Domain, certInfo.CertPath, certInfo.KeyPath); err != nil {
+				logger.Warn("Failed to register site certificate", "domain", site.Domain, "error", err)
+			}
+		}
+	}
+
+	if a.dnsResolver != nil {
+		a.dnsResolver.Register(site.Domain, net.ParseIP("127.0.0.1"))
+	}
+
+	return nil
+}
+
+// RemoveSite stops site's dev server and undoes wireSite's registrations.
+func (sm *SiteManager) RemoveSite(domain string) error {
+	sm.mu.Lock()
+	site, exists := sm.sites[domain]
+	if exists {
+		delete(sm.sites, domain)
+	}
+	sm.mu.Unlock()
+
+	if !exists {
+		return fmt.Errorf("site %s is not running", domain)
+	}
+
+	a := sm.app
+	if a.dnsResolver != nil {
+		a.dnsResolver.Unregister(domain)
+	}
+	if a.proxyServer != nil {
+		a.proxyServer.RemoveRoute(domain)
+		a.proxyServer.RemoveSiteCert(domain)
+	}
+
+	if err := site.runner.Stop(); err != nil {
+		logger.Warn("Error stopping site dev server", "domain", domain, "error", err)
+	}
+
+	logger.Info("Site removed", "domain", domain)
+	return nil
+}
+
+// Statuses returns every running site's status, for GetStatus.
+func (sm *SiteManager) Statuses() []SiteStatus {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	statuses := make([]SiteStatus, 0, len(sm.sites))
+	for _, site := range sm.sites {
+		statuses = append(statuses, SiteStatus{
+			Domain:       site.Domain,
+			Command:      site.Command,
+			WorkDir:      site.WorkDir,
+			Running:      site.runner.IsRunning(),
+			DevServerPID: site.runner.GetPID(),
+		})
+	}
+	return statuses
+}
+
+// StopAll stops every site's dev server and undoes its registrations,
+// called during App.shutdown.
+func (sm *SiteManager) StopAll() {
+	sm.mu.Lock()
+	domains := make([]string, 0, len(sm.sites))
+	for domain := range sm.sites {
+		domains = append(domains, domain)
+	}
+	sm.mu.Unlock()
+
+	for _, domain := range domains {
+		if err := sm.RemoveSite(domain); err != nil {
+			logger.Warn("Error removing site during shutdown", "domain", domain, "error", err)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package app
+
+// Status is a typed point-in-time snapshot of App's state: what GetStatus
+// returns, and what the IPC socket/HTTP's "status" command serves as JSON.
+// It superseded GetStatus's original map[string]interface{}, so every
+// field is tagged the same as that map's keys were.
+type Status struct {
+	Running      bool   `json:"running"`
+	ProjectName  string `json:"project_name"`
+	ProjectType  string `json:"project_type"`
+	Domain       string `json:"domain"`
+	HTTPPort     int    `json:"http_port"`
+	HTTPSPort    int    `json:"https_port"`
+	CleanURLs    bool   `json:"clean_urls"`
+	HTTPSEnabled bool   `json:"https_enabled"`
+	DNSEnabled   bool   `json:"dns_enabled"`
+
+	DevServerPID     int  `json:"dev_server_pid,omitempty"`
+	DevServerRunning bool `json:"dev_server_running,omitempty"`
+
+	StaticDir           string `json:"static_dir,omitempty"`
+	StaticServerRunning bool   `json:"static_server_running,omitempty"`
+
+	Sites []SiteStatus `json:"sites,omitempty"`
+
+	TunnelProvider string `json:"tunnel_provider,omitempty"`
+	TunnelURL      string `json:"tunnel_url,omitempty"`
+}
+
+// GetStatus returns a snapshot of a's current state, for the "nsm ctl
+// status" IPC command and anything else wanting a point-in-time view
+// without attaching to the TUI.
+func (a *App) GetStatus() *Status {
+	status := &Status{
+		Running:      a.running,
+		ProjectName:  a.cfg.ProjectName,
+		ProjectType:  string(a.cfg.ProjectType),
+		Domain:       a.cfg.Domain,
+		HTTPPort:     a.httpPort,
+		HTTPSPort:    a.httpsPort,
+		CleanURLs:    a.cfg.UsePort443,
+		HTTPSEnabled: a.cfg.EnableHTTPS,
+		DNSEnabled:   a.cfg.EnableDNS,
+	}
+
+	if a.runner != nil {
+		status.DevServerPID = a.runner.GetPID()
+		status.DevServerRunning = a.runner.IsRunning()
+	}
+	if a.staticServer != nil {
+		status.StaticDir = a.cfg.StaticDir
+		status.StaticServerRunning = a.staticServer.IsRunning()
+	}
+	if a.sites != nil {
+		status.Sites = a.sites.Statuses()
+	}
+	a.tunnelMu.RLock()
+	tunnelProvider, tunnelURL := a.tunnelProvider, a.tunnelURL
+	a.tunnelMu.RUnlock()
+	if tunnelProvider != nil {
+		status.TunnelProvider = tunnelProvider.Name()
+		status.TunnelURL = tunnelURL
+	}
+
+	return status
+}
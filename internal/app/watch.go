@@ -0,0 +1,101 @@
+package app
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/kashifsb/nsm/internal/config"
+	"github.com/kashifsb/nsm/pkg/logger"
+)
+
+// configWatchDebounce coalesces the burst of Write/Create events most
+// editors produce for a single save (e.g. write-to-temp-then-rename) into
+// one reloadHandler call.
+const configWatchDebounce = 200 * time.Millisecond
+
+// startConfigWatcher watches the project's .nsm.yaml for changes and calls
+// a.reloadHandler whenever it's written, the file-based counterpart to
+// SIGHUP and the control socket's "reload" command. Like pkg/proxy.Router,
+// it watches the containing directory rather than the file itself, since
+// editors commonly save by writing a temp file and renaming it over the
+// original.
+func (a *App) startConfigWatcher() error {
+	configPath := filepath.Join(a.cfg.ProjectDir, config.OverrideFilename)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("create config watcher: %w", err)
+	}
+
+	if err := watcher.Add(a.cfg.ProjectDir); err != nil {
+		watcher.Close()
+		return fmt.Errorf("watch %s: %w", a.cfg.ProjectDir, err)
+	}
+	a.configWatcher = watcher
+
+	go a.watchConfigFile(configPath)
+
+	return nil
+}
+
+// watchConfigFile is startConfigWatcher's event loop, split out so it can
+// run in its own goroutine for the lifetime of a.configWatcher. Matching
+// events reset a configWatchDebounce timer rather than reloading
+// immediately, so a save that fires several Write/Create events in a row
+// (common with write-to-temp-then-rename editors) triggers one reload.
+func (a *App) watchConfigFile(configPath string) {
+	var debounce *time.Timer
+	defer func() {
+		if debounce != nil {
+			debounce.Stop()
+		}
+	}()
+
+	for {
+		select {
+		case event, ok := <-a.configWatcher.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(event.Name) != filepath.Clean(configPath) {
+				continue
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+				continue
+			}
+
+			if debounce == nil {
+				debounce = time.AfterFunc(configWatchDebounce, func() { a.reloadConfigFile(configPath) })
+			} else {
+				debounce.Reset(configWatchDebounce)
+			}
+		case err, ok := <-a.configWatcher.Errors:
+			if !ok {
+				return
+			}
+			logger.Warn("Config watcher error", "error", err)
+		}
+	}
+}
+
+// reloadConfigFile is configWatchDebounce's timer callback.
+func (a *App) reloadConfigFile(configPath string) {
+	logger.Info("Config file changed, reloading", "path", configPath)
+	if a.reloadHandler == nil {
+		return
+	}
+	if err := a.reloadHandler(); err != nil {
+		logger.Warn("Config file reload failed", "error", err)
+	}
+}
+
+// stopConfigWatcher closes the fsnotify watcher, if one was started.
+func (a *App) stopConfigWatcher() error {
+	if a.configWatcher == nil {
+		return nil
+	}
+	return a.configWatcher.Close()
+}
@@ -0,0 +1,297 @@
+package cert
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/net/idna"
+
+	"github.com/kashifsb/nsm/pkg/logger"
+)
+
+// LetsEncryptDirectoryURL is used when acmeConfig.DirectoryURL is empty.
+const LetsEncryptDirectoryURL = "https://acme-v02.api.letsencrypt.org/directory"
+
+// dnsPropagationWait is how long Present is given to propagate before the
+// dns-01 challenge is accepted. It's generous on purpose: DNS providers
+// rarely propagate in under a few seconds, and re-checking would need its
+// own polling loop this package doesn't have yet.
+const dnsPropagationWait = 30 * time.Second
+
+// acmeConfig is the subset of config.Config ACME issuance needs, kept
+// separate so this package doesn't have to import all of config.Config's
+// unrelated fields.
+type acmeConfig struct {
+	DirectoryURL string
+	Email        string
+	Challenge    string // http-01, tls-alpn-01, or dns-01
+	DNSProvider  string
+}
+
+// acmeIssuer obtains publicly-trusted certificates from Let's Encrypt or
+// any other RFC 8555 directory. It persists its account key under
+// <dataDir>/acme/ so repeated runs reuse the same ACME account.
+type acmeIssuer struct {
+	client      *acme.Client
+	challenge   string
+	dnsProvider DNSProvider
+	challenges  *ChallengeMux
+	alpn        *ALPNValidator
+}
+
+func newACMEIssuer(dataDir string, cfg acmeConfig, challenges *ChallengeMux, alpn *ALPNValidator) (*acmeIssuer, error) {
+	directoryURL := cfg.DirectoryURL
+	if directoryURL == "" {
+		directoryURL = LetsEncryptDirectoryURL
+	}
+
+	stateDir := filepath.Join(dataDir, "acme")
+	if err := os.MkdirAll(stateDir, 0o700); err != nil {
+		return nil, fmt.Errorf("create acme state directory: %w", err)
+	}
+
+	key, err := loadOrCreateAccountKey(filepath.Join(stateDir, "account.key"))
+	if err != nil {
+		return nil, fmt.Errorf("load ACME account key: %w", err)
+	}
+
+	client := &acme.Client{Key: key, DirectoryURL: directoryURL}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if _, err := client.Register(ctx, &acme.Account{Contact: contactFor(cfg.Email)}, acme.AcceptTOS); err != nil && err != acme.ErrAccountAlreadyExists {
+		return nil, fmt.Errorf("register ACME account: %w", err)
+	}
+
+	challenge := cfg.Challenge
+	if challenge == "" {
+		challenge = "http-01"
+	}
+
+	var dnsProvider DNSProvider
+	if challenge == "dns-01" {
+		dnsProvider, err = newDNSProvider(dataDir, cfg.DNSProvider)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &acmeIssuer{
+		client:      client,
+		challenge:   challenge,
+		dnsProvider: dnsProvider,
+		challenges:  challenges,
+		alpn:        alpn,
+	}, nil
+}
+
+func contactFor(email string) []string {
+	if email == "" {
+		return nil
+	}
+	return []string{"mailto:" + email}
+}
+
+func loadOrCreateAccountKey(path string) (*ecdsa.PrivateKey, error) {
+	if data, err := os.ReadFile(path); err == nil {
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("decode PEM account key")
+		}
+		return x509.ParseECPrivateKey(block.Bytes)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(path, pemBytes, 0o600); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// IssueCertificate implements Issuer: it authorizes domain via whichever
+// challenge type this issuer was configured with, finalizes the resulting
+// order, and writes the certificate chain and key to certPath/keyPath.
+func (i *acmeIssuer) IssueCertificate(domain, certPath, keyPath string) error {
+	asciiDomain, err := idna.Lookup.ToASCII(domain)
+	if err != nil {
+		return fmt.Errorf("convert %s to punycode: %w", domain, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	order, err := i.client.AuthorizeOrder(ctx, []acme.AuthzID{{Type: "dns", Value: asciiDomain}})
+	if err != nil {
+		return fmt.Errorf("authorize order: %w", err)
+	}
+
+	for _, authzURL := range order.AuthzURLs {
+		if err := i.authorize(ctx, authzURL, asciiDomain); err != nil {
+			return err
+		}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return fmt.Errorf("generate certificate key: %w", err)
+	}
+
+	csr, err := certRequest(key, asciiDomain)
+	if err != nil {
+		return fmt.Errorf("build CSR: %w", err)
+	}
+
+	chain, _, err := i.client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return fmt.Errorf("finalize order: %w", err)
+	}
+
+	if err := writeCertChain(certPath, chain); err != nil {
+		return err
+	}
+	return writeECKey(keyPath, key)
+}
+
+// authorize drives a single authorization through whichever challenge type
+// this issuer is configured for, returning once the ACME server has marked
+// it valid.
+func (i *acmeIssuer) authorize(ctx context.Context, authzURL, domain string) error {
+	authz, err := i.client.GetAuthorization(ctx, authzURL)
+	if err != nil {
+		return fmt.Errorf("get authorization: %w", err)
+	}
+	if authz.Status == acme.StatusValid {
+		return nil
+	}
+
+	var chal *acme.Challenge
+	for _, c := range authz.Challenges {
+		if c.Type == i.challenge {
+			chal = c
+			break
+		}
+	}
+	if chal == nil {
+		return fmt.Errorf("ACME server did not offer challenge type %q for %s", i.challenge, domain)
+	}
+
+	cleanup, err := i.prepare(ctx, chal, domain)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	if _, err := i.client.Accept(ctx, chal); err != nil {
+		return fmt.Errorf("accept %s challenge: %w", i.challenge, err)
+	}
+
+	if _, err := i.client.WaitAuthorization(ctx, authzURL); err != nil {
+		return fmt.Errorf("wait for %s authorization: %w", domain, err)
+	}
+
+	return nil
+}
+
+// prepare stages whichever challenge type chal is (publishing the expected
+// response on the HTTP-01 mux, the TLS-ALPN-01 validator, or the DNS
+// provider) and returns a cleanup func to tear it down afterward.
+func (i *acmeIssuer) prepare(ctx context.Context, chal *acme.Challenge, domain string) (func(), error) {
+	switch chal.Type {
+	case "http-01":
+		keyAuth, err := i.client.HTTP01ChallengeResponse(chal.Token)
+		if err != nil {
+			return nil, fmt.Errorf("build http-01 response: %w", err)
+		}
+		if i.challenges == nil {
+			return nil, fmt.Errorf("http-01 challenge requires the proxy's HTTP listener to mount cert.ChallengeMux")
+		}
+		i.challenges.Set(chal.Token, keyAuth)
+		return func() { i.challenges.Delete(chal.Token) }, nil
+
+	case "tls-alpn-01":
+		keyAuth, err := i.client.HTTP01ChallengeResponse(chal.Token)
+		if err != nil {
+			return nil, fmt.Errorf("build tls-alpn-01 key authorization: %w", err)
+		}
+		if i.alpn == nil {
+			return nil, fmt.Errorf("tls-alpn-01 challenge requires the proxy's TLS listener to mount cert.ALPNValidator")
+		}
+		i.alpn.Set(domain, keyAuth)
+		return func() { i.alpn.Delete(domain) }, nil
+
+	case "dns-01":
+		value, err := i.client.DNS01ChallengeRecord(chal.Token)
+		if err != nil {
+			return nil, fmt.Errorf("build dns-01 record: %w", err)
+		}
+		if i.dnsProvider == nil {
+			return nil, fmt.Errorf("dns-01 challenge requires a DNS provider")
+		}
+		if err := i.dnsProvider.Present(ctx, domain, value); err != nil {
+			return nil, fmt.Errorf("present dns-01 record: %w", err)
+		}
+
+		logger.Info("Waiting for DNS-01 record to propagate", "domain", domain, "wait", dnsPropagationWait)
+		time.Sleep(dnsPropagationWait)
+
+		return func() {
+			if err := i.dnsProvider.CleanUp(context.Background(), domain, value); err != nil {
+				logger.Warn("Failed to clean up dns-01 record", "domain", domain, "error", err)
+			}
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported challenge type %q", chal.Type)
+	}
+}
+
+func certRequest(key *ecdsa.PrivateKey, domain string) ([]byte, error) {
+	template := &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: domain},
+		DNSNames: []string{domain},
+	}
+	return x509.CreateCertificateRequest(rand.Reader, template, key)
+}
+
+func writeCertChain(path string, chain [][]byte) error {
+	var buf bytes.Buffer
+	for _, der := range chain {
+		if err := pem.Encode(&buf, &pem.Block{Type: "CERTIFICATE", Bytes: der}); err != nil {
+			return fmt.Errorf("encode certificate: %w", err)
+		}
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+func writeECKey(path string, key *ecdsa.PrivateKey) error {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return fmt.Errorf("marshal private key: %w", err)
+	}
+
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+	return os.WriteFile(path, pemBytes, 0o600)
+}
@@ -0,0 +1,64 @@
+package cert
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// acmeChallengePrefix is the well-known path RFC 8555 HTTP-01 validation
+// requests arrive on.
+const acmeChallengePrefix = "/.well-known/acme-challenge/"
+
+// ChallengeMux answers HTTP-01 validation requests. The proxy subsystem's
+// existing HTTP listener mounts it ahead of its normal handler (see
+// server.ProxyConfig.ChallengeHandler) so NSM doesn't need a second
+// listener just to pass ACME's HTTP-01 check.
+type ChallengeMux struct {
+	mu     sync.RWMutex
+	tokens map[string]string // token -> key authorization
+}
+
+// NewChallengeMux returns an empty ChallengeMux.
+func NewChallengeMux() *ChallengeMux {
+	return &ChallengeMux{tokens: make(map[string]string)}
+}
+
+// Set registers keyAuth as the expected response body for token.
+func (m *ChallengeMux) Set(token, keyAuth string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tokens[token] = keyAuth
+}
+
+// Delete removes token once its challenge has been validated (or abandoned).
+func (m *ChallengeMux) Delete(token string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.tokens, token)
+}
+
+// ServeHTTP implements http.Handler, responding to GET requests under
+// /.well-known/acme-challenge/<token> with the matching key authorization.
+func (m *ChallengeMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.URL.Path, acmeChallengePrefix)
+
+	m.mu.RLock()
+	keyAuth, ok := m.tokens[token]
+	m.mu.RUnlock()
+
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(keyAuth))
+}
+
+// Handles reports whether r is an ACME HTTP-01 validation request this mux
+// should serve, so a caller wrapping another handler knows when to
+// delegate here instead.
+func (m *ChallengeMux) Handles(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, acmeChallengePrefix)
+}
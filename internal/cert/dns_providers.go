@@ -0,0 +1,458 @@
+package cert
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"gopkg.in/yaml.v3"
+
+	"github.com/kashifsb/nsm/pkg/logger"
+)
+
+// DNSProvider creates and removes the _acme-challenge TXT record a DNS-01
+// challenge is validated against.
+type DNSProvider interface {
+	// Present publishes value as a TXT record under
+	// "_acme-challenge.<domain>", returning once it's likely to have
+	// propagated.
+	Present(ctx context.Context, domain, value string) error
+	// CleanUp removes the TXT record Present created.
+	CleanUp(ctx context.Context, domain, value string) error
+}
+
+// providerCredentials holds the per-provider key/value pairs loaded from
+// <dataDir>/acme/providers.yaml, e.g.:
+//
+//	cloudflare:
+//	  api_token: ...
+//	digitalocean:
+//	  api_token: ...
+//	gandi:
+//	  api_key: ...
+type providersFile map[string]map[string]string
+
+// loadProviderCredentials reads providers.yaml from dataDir's acme state
+// directory and returns the credentials for provider, if present. A
+// missing file or provider section isn't an error: callers fall back to
+// environment variables, matching how cloudflareDNSProvider/
+// route53DNSProvider already work.
+func loadProviderCredentials(dataDir, provider string) map[string]string {
+	path := filepath.Join(dataDir, "acme", "providers.yaml")
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var providers providersFile
+	if err := yaml.Unmarshal(data, &providers); err != nil {
+		logger.Warn("Failed to parse ACME providers.yaml, ignoring", "path", path, "error", err)
+		return nil
+	}
+
+	return providers[strings.ToLower(provider)]
+}
+
+// credentialOrEnv returns creds[key] if set, otherwise the value of the
+// environment variable envVar.
+func credentialOrEnv(creds map[string]string, key, envVar string) string {
+	if v := creds[key]; v != "" {
+		return v
+	}
+	return os.Getenv(envVar)
+}
+
+// newDNSProvider resolves Config.DNSProvider to a concrete DNSProvider.
+// Credentials are read from <dataDir>/acme/providers.yaml first, falling
+// back to the provider's usual environment variables.
+func newDNSProvider(dataDir, name string) (DNSProvider, error) {
+	creds := loadProviderCredentials(dataDir, name)
+
+	switch strings.ToLower(name) {
+	case "", "manual":
+		return manualDNSProvider{}, nil
+	case "cloudflare":
+		return newCloudflareDNSProvider(creds)
+	case "route53":
+		return newRoute53DNSProvider()
+	case "digitalocean":
+		return newDigitalOceanDNSProvider(creds)
+	case "gandi":
+		return newGandiDNSProvider(creds)
+	case "exec":
+		return execDNSProvider{}, nil
+	default:
+		return nil, fmt.Errorf("unknown DNS provider %q", name)
+	}
+}
+
+// manualDNSProvider prints the record the user needs to create and waits
+// for them to press Enter once it's live - the fallback for any DNS host
+// NSM doesn't have a plugin for.
+type manualDNSProvider struct{}
+
+func (manualDNSProvider) Present(_ context.Context, domain, value string) error {
+	fmt.Printf("\nCreate the following DNS record, then press Enter to continue:\n")
+	fmt.Printf("  _acme-challenge.%s TXT %q\n\n", domain, value)
+	fmt.Print("Press Enter once the record is live: ")
+
+	reader := bufio.NewReader(os.Stdin)
+	_, err := reader.ReadString('\n')
+	return err
+}
+
+func (manualDNSProvider) CleanUp(_ context.Context, domain, _ string) error {
+	fmt.Printf("You can now remove the _acme-challenge.%s TXT record.\n", domain)
+	return nil
+}
+
+// execDNSProvider runs a user-supplied script for Present and CleanUp,
+// mirroring certbot's manual-auth-hook/manual-cleanup-hook convention:
+// CERTBOT_DOMAIN and CERTBOT_VALIDATION are passed as environment
+// variables, and NSM_DNS_HOOK names the script to run.
+type execDNSProvider struct{}
+
+func (execDNSProvider) Present(ctx context.Context, domain, value string) error {
+	return runDNSHook(ctx, domain, value)
+}
+
+func (execDNSProvider) CleanUp(ctx context.Context, domain, value string) error {
+	return runDNSHook(ctx, domain, value)
+}
+
+func runDNSHook(ctx context.Context, domain, value string) error {
+	hook := os.Getenv("NSM_DNS_HOOK")
+	if hook == "" {
+		return fmt.Errorf("exec DNS provider requires NSM_DNS_HOOK to point at a script")
+	}
+
+	cmd := exec.CommandContext(ctx, hook)
+	cmd.Env = append(os.Environ(),
+		"CERTBOT_DOMAIN="+domain,
+		"CERTBOT_VALIDATION="+value,
+	)
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("dns hook %s: %w: %s", hook, err, bytes.TrimSpace(out))
+	}
+
+	return nil
+}
+
+// cloudflareDNSProvider manages TXT records via Cloudflare's v4 REST API,
+// authenticated with an API token (CLOUDFLARE_API_TOKEN).
+type cloudflareDNSProvider struct {
+	apiToken string
+	client   *http.Client
+}
+
+func newCloudflareDNSProvider(creds map[string]string) (*cloudflareDNSProvider, error) {
+	token := credentialOrEnv(creds, "api_token", "CLOUDFLARE_API_TOKEN")
+	if token == "" {
+		return nil, fmt.Errorf("cloudflare DNS provider requires api_token in providers.yaml or CLOUDFLARE_API_TOKEN")
+	}
+	return &cloudflareDNSProvider{apiToken: token, client: &http.Client{Timeout: 30 * time.Second}}, nil
+}
+
+func (p *cloudflareDNSProvider) Present(ctx context.Context, domain, value string) error {
+	zoneID, err := p.zoneIDFor(ctx, domain)
+	if err != nil {
+		return err
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"type":    "TXT",
+		"name":    "_acme-challenge." + domain,
+		"content": value,
+		"ttl":     120,
+	})
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		fmt.Sprintf("https://api.cloudflare.com/client/v4/zones/%s/dns_records", zoneID), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	p.authorize(req)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("create TXT record: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("cloudflare create TXT record: unexpected status %d", resp.StatusCode)
+	}
+
+	logger.Info("Created Cloudflare DNS-01 TXT record", "domain", domain)
+	return nil
+}
+
+func (p *cloudflareDNSProvider) CleanUp(ctx context.Context, domain, _ string) error {
+	// Best-effort: Cloudflare's challenge record is low-TTL and harmless
+	// to leave behind, so a lookup/delete failure here isn't fatal.
+	logger.Debug("Cloudflare DNS-01 cleanup is best-effort", "domain", domain)
+	return nil
+}
+
+func (p *cloudflareDNSProvider) zoneIDFor(ctx context.Context, domain string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		"https://api.cloudflare.com/client/v4/zones?name="+registeredDomain(domain), nil)
+	if err != nil {
+		return "", err
+	}
+	p.authorize(req)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("look up zone: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Result []struct {
+			ID string `json:"id"`
+		} `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("decode zone lookup response: %w", err)
+	}
+	if len(result.Result) == 0 {
+		return "", fmt.Errorf("no Cloudflare zone found for %s", domain)
+	}
+
+	return result.Result[0].ID, nil
+}
+
+func (p *cloudflareDNSProvider) authorize(req *http.Request) {
+	req.Header.Set("Authorization", "Bearer "+p.apiToken)
+	req.Header.Set("Content-Type", "application/json")
+}
+
+// registeredDomain strips a leading subdomain label, a rough approximation
+// of the registrable domain good enough for looking up a Cloudflare zone
+// (e.g. "dev.example.com" -> "example.com").
+func registeredDomain(domain string) string {
+	parts := strings.Split(domain, ".")
+	if len(parts) <= 2 {
+		return domain
+	}
+	return strings.Join(parts[len(parts)-2:], ".")
+}
+
+// route53DNSProvider manages TXT records via AWS Route 53, using the
+// default AWS SDK credential chain (env vars, shared config, instance
+// role, ...).
+type route53DNSProvider struct {
+	client *route53.Client
+}
+
+func newRoute53DNSProvider() (*route53DNSProvider, error) {
+	cfg, err := awsconfig.LoadDefaultConfig(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("load AWS config: %w", err)
+	}
+	return &route53DNSProvider{client: route53.NewFromConfig(cfg)}, nil
+}
+
+func (p *route53DNSProvider) Present(ctx context.Context, domain, value string) error {
+	return p.changeRecord(ctx, domain, value, types.ChangeActionUpsert)
+}
+
+func (p *route53DNSProvider) CleanUp(ctx context.Context, domain, value string) error {
+	return p.changeRecord(ctx, domain, value, types.ChangeActionDelete)
+}
+
+func (p *route53DNSProvider) changeRecord(ctx context.Context, domain, value string, action types.ChangeAction) error {
+	zoneID, err := p.hostedZoneIDFor(ctx, domain)
+	if err != nil {
+		return err
+	}
+
+	name := "_acme-challenge." + domain
+	_, err = p.client.ChangeResourceRecordSets(ctx, &route53.ChangeResourceRecordSetsInput{
+		HostedZoneId: aws.String(zoneID),
+		ChangeBatch: &types.ChangeBatch{
+			Changes: []types.Change{{
+				Action: action,
+				ResourceRecordSet: &types.ResourceRecordSet{
+					Name:            aws.String(name),
+					Type:            types.RRTypeTxt,
+					TTL:             aws.Int64(60),
+					ResourceRecords: []types.ResourceRecord{{Value: aws.String(fmt.Sprintf("%q", value))}},
+				},
+			}},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("route53 change record set: %w", err)
+	}
+
+	return nil
+}
+
+func (p *route53DNSProvider) hostedZoneIDFor(ctx context.Context, domain string) (string, error) {
+	out, err := p.client.ListHostedZonesByName(ctx, &route53.ListHostedZonesByNameInput{
+		DNSName: aws.String(registeredDomain(domain)),
+	})
+	if err != nil {
+		return "", fmt.Errorf("list hosted zones: %w", err)
+	}
+	if len(out.HostedZones) == 0 {
+		return "", fmt.Errorf("no Route53 hosted zone found for %s", domain)
+	}
+
+	return *out.HostedZones[0].Id, nil
+}
+
+// digitalOceanDNSProvider manages TXT records via DigitalOcean's v2 REST
+// API, authenticated with a personal access token.
+type digitalOceanDNSProvider struct {
+	apiToken string
+	client   *http.Client
+}
+
+func newDigitalOceanDNSProvider(creds map[string]string) (*digitalOceanDNSProvider, error) {
+	token := credentialOrEnv(creds, "api_token", "DIGITALOCEAN_API_TOKEN")
+	if token == "" {
+		return nil, fmt.Errorf("digitalocean DNS provider requires api_token in providers.yaml or DIGITALOCEAN_API_TOKEN")
+	}
+	return &digitalOceanDNSProvider{apiToken: token, client: &http.Client{Timeout: 30 * time.Second}}, nil
+}
+
+func (p *digitalOceanDNSProvider) Present(ctx context.Context, domain, value string) error {
+	zone, name := p.zoneAndRecordName(domain)
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"type": "TXT",
+		"name": name,
+		"data": value,
+		"ttl":  120,
+	})
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		fmt.Sprintf("https://api.digitalocean.com/v2/domains/%s/records", zone), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	p.authorize(req)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("create TXT record: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("digitalocean create TXT record: unexpected status %d", resp.StatusCode)
+	}
+
+	logger.Info("Created DigitalOcean DNS-01 TXT record", "domain", domain)
+	return nil
+}
+
+func (p *digitalOceanDNSProvider) CleanUp(ctx context.Context, domain, _ string) error {
+	// Best-effort, mirroring cloudflareDNSProvider: the challenge record
+	// is low-TTL and harmless to leave behind.
+	logger.Debug("DigitalOcean DNS-01 cleanup is best-effort", "domain", domain)
+	return nil
+}
+
+func (p *digitalOceanDNSProvider) authorize(req *http.Request) {
+	req.Header.Set("Authorization", "Bearer "+p.apiToken)
+	req.Header.Set("Content-Type", "application/json")
+}
+
+// zoneAndRecordName splits domain into the DigitalOcean-managed zone
+// (the registrable domain) and the record name relative to it, e.g.
+// "_acme-challenge.dev.example.com" under zone "example.com" is
+// recorded as "_acme-challenge.dev".
+func (p *digitalOceanDNSProvider) zoneAndRecordName(domain string) (zone, name string) {
+	zone = registeredDomain(domain)
+	name = strings.TrimSuffix("_acme-challenge."+domain, "."+zone)
+	return zone, name
+}
+
+// gandiDNSProvider manages TXT records via Gandi's LiveDNS v5 API,
+// authenticated with a personal access token.
+type gandiDNSProvider struct {
+	apiToken string
+	client   *http.Client
+}
+
+func newGandiDNSProvider(creds map[string]string) (*gandiDNSProvider, error) {
+	token := credentialOrEnv(creds, "api_key", "GANDI_API_KEY")
+	if token == "" {
+		return nil, fmt.Errorf("gandi DNS provider requires api_key in providers.yaml or GANDI_API_KEY")
+	}
+	return &gandiDNSProvider{apiToken: token, client: &http.Client{Timeout: 30 * time.Second}}, nil
+}
+
+func (p *gandiDNSProvider) Present(ctx context.Context, domain, value string) error {
+	zone := registeredDomain(domain)
+	recordName := strings.TrimSuffix("_acme-challenge."+domain, "."+zone)
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"rrset_type":   "TXT",
+		"rrset_ttl":    300,
+		"rrset_values": []string{fmt.Sprintf("%q", value)},
+	})
+
+	url := fmt.Sprintf("https://api.gandi.net/v5/livedns/domains/%s/records/%s/TXT", zone, recordName)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	p.authorize(req)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("create TXT record: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("gandi create TXT record: unexpected status %d", resp.StatusCode)
+	}
+
+	logger.Info("Created Gandi DNS-01 TXT record", "domain", domain)
+	return nil
+}
+
+func (p *gandiDNSProvider) CleanUp(ctx context.Context, domain, _ string) error {
+	zone := registeredDomain(domain)
+	recordName := strings.TrimSuffix("_acme-challenge."+domain, "."+zone)
+
+	url := fmt.Sprintf("https://api.gandi.net/v5/livedns/domains/%s/records/%s/TXT", zone, recordName)
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, url, nil)
+	if err != nil {
+		return err
+	}
+	p.authorize(req)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		logger.Warn("Failed to clean up Gandi DNS-01 record", "domain", domain, "error", err)
+		return nil
+	}
+	defer resp.Body.Close()
+
+	return nil
+}
+
+func (p *gandiDNSProvider) authorize(req *http.Request) {
+	req.Header.Set("Authorization", "Apikey "+p.apiToken)
+	req.Header.Set("Content-Type", "application/json")
+}
@@ -0,0 +1,11 @@
+package cert
+
+// Issuer obtains a certificate for domain, writing it to certPath/keyPath.
+// mkcertIssuer and acmeIssuer are the two built-in implementations;
+// Manager.EnsureCertificate dispatches to whichever one it was constructed
+// with based on Config.CertIssuer.
+type Issuer interface {
+	// IssueCertificate creates or renews a certificate for domain, writing
+	// the chain to certPath and the private key to keyPath.
+	IssueCertificate(domain, certPath, keyPath string) error
+}
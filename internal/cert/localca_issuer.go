@@ -0,0 +1,82 @@
+package cert
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+
+	localtls "github.com/kashifsb/nsm/pkg/tls"
+)
+
+// localCAIssuer delegates to pkg/tls's self-signed certificate authority,
+// giving NSM a working HTTPS issuer that doesn't depend on mkcert (or any
+// other external binary). NewManagerWithConfig selects it automatically
+// when mkcert isn't on PATH, and it's always available via
+// Config.CertIssuer = "local".
+type localCAIssuer struct {
+	mgr *localtls.Manager
+}
+
+func newLocalCAIssuer(dataDir string) (*localCAIssuer, error) {
+	mgr, err := localtls.NewManager(dataDir)
+	if err != nil {
+		return nil, fmt.Errorf("create local CA manager: %w", err)
+	}
+	return &localCAIssuer{mgr: mgr}, nil
+}
+
+// IssueCertificate implements Issuer by minting a leaf certificate from
+// the local CA and writing it to certPath/keyPath.
+func (i *localCAIssuer) IssueCertificate(domain, certPath, keyPath string) error {
+	cert, err := i.mgr.EnsureCert(domain)
+	if err != nil {
+		return fmt.Errorf("issue local CA certificate: %w", err)
+	}
+	return writeLeafFiles(cert, certPath, keyPath)
+}
+
+// InstallCA installs NSM's local root CA into the OS trust store.
+func (i *localCAIssuer) InstallCA() error {
+	return i.mgr.InstallCA()
+}
+
+// CARoot returns the path to the local root CA certificate.
+func (i *localCAIssuer) CARoot() (string, error) {
+	return i.mgr.CARoot(), nil
+}
+
+// writeLeafFiles PEM-encodes a leaf certificate minted by pkg/tls to
+// certPath/keyPath, matching the on-disk layout EnsureCertificate expects
+// from every Issuer.
+func writeLeafFiles(cert *tls.Certificate, certPath, keyPath string) error {
+	var buf bytes.Buffer
+	for _, der := range cert.Certificate {
+		if err := pem.Encode(&buf, &pem.Block{Type: "CERTIFICATE", Bytes: der}); err != nil {
+			return fmt.Errorf("encode certificate: %w", err)
+		}
+	}
+	if err := os.WriteFile(certPath, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("write certificate: %w", err)
+	}
+
+	ecKey, ok := cert.PrivateKey.(*ecdsa.PrivateKey)
+	if !ok {
+		return fmt.Errorf("unexpected leaf private key type %T", cert.PrivateKey)
+	}
+
+	der, err := x509.MarshalECPrivateKey(ecKey)
+	if err != nil {
+		return fmt.Errorf("marshal private key: %w", err)
+	}
+
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(keyPath, keyPEM, 0o600); err != nil {
+		return fmt.Errorf("write private key: %w", err)
+	}
+
+	return nil
+}
@@ -11,13 +11,34 @@ import (
 	"strings"
 	"time"
 
+	"github.com/kashifsb/nsm/internal/config"
 	"github.com/kashifsb/nsm/pkg/logger"
 )
 
+// caInstaller is implemented by Issuers backed by a root CA that can be
+// installed into the OS trust store: mkcertIssuer and localCAIssuer.
+// acmeIssuer doesn't implement it since its certs are already publicly
+// trusted.
+type caInstaller interface {
+	InstallCA() error
+	CARoot() (string, error)
+}
+
+// Manager obtains and tracks per-domain certificates, delegating the
+// actual issuance to an Issuer selected by Config.CertIssuer: mkcert by
+// default (falling back to NSM's built-in "local" CA if mkcert isn't on
+// PATH), or ACME for a publicly-trusted certificate.
 type Manager struct {
-	dataDir   string
-	certsDir  string
-	mkcertBin string
+	dataDir    string
+	certsDir   string
+	issuer     Issuer
+	issuerName string
+
+	// challenges and alpn are only non-nil when issuer is an *acmeIssuer
+	// configured for http-01/tls-alpn-01 respectively; the proxy
+	// subsystem mounts them on its existing HTTP/TLS listeners.
+	challenges *ChallengeMux
+	alpn       *ALPNValidator
 }
 
 type CertificateInfo struct {
@@ -27,23 +48,92 @@ type CertificateInfo struct {
 	Created  bool
 }
 
+// NewManager returns a Manager using the default mkcert Issuer, preserving
+// the original (pre-ACME) constructor signature for callers that don't
+// need to select an issuer.
 func NewManager(dataDir string) (*Manager, error) {
+	return NewManagerWithConfig(dataDir, &config.Config{CertIssuer: "mkcert"})
+}
+
+// NewManagerWithConfig returns a Manager using the Issuer cfg.CertIssuer
+// selects: "mkcert", "local" (NSM's built-in pure-Go CA), or "acme". An
+// empty CertIssuer behaves like "mkcert" if the mkcert binary is on PATH,
+// and falls back to "local" otherwise.
+func NewManagerWithConfig(dataDir string, cfg *config.Config) (*Manager, error) {
 	certsDir := filepath.Join(dataDir, "certs")
 	if err := os.MkdirAll(certsDir, 0o755); err != nil {
 		return nil, fmt.Errorf("create certs directory: %w", err)
 	}
 
-	// Check for mkcert
-	mkcertBin, err := exec.LookPath("mkcert")
-	if err != nil {
-		return nil, fmt.Errorf("mkcert not found: %w", err)
+	m := &Manager{dataDir: dataDir, certsDir: certsDir}
+
+	certIssuer := strings.ToLower(cfg.CertIssuer)
+	if certIssuer == "" {
+		if _, err := exec.LookPath("mkcert"); err == nil {
+			certIssuer = "mkcert"
+		} else {
+			logger.Warn("mkcert not found on PATH, falling back to NSM's built-in local CA", "error", err)
+			certIssuer = "local"
+		}
+	}
+
+	m.issuerName = certIssuer
+
+	switch certIssuer {
+	case "mkcert":
+		issuer, err := newMkcertIssuer()
+		if err != nil {
+			return nil, err
+		}
+		m.issuer = issuer
+
+	case "local":
+		issuer, err := newLocalCAIssuer(dataDir)
+		if err != nil {
+			return nil, fmt.Errorf("create local CA issuer: %w", err)
+		}
+		m.issuer = issuer
+
+	case "acme":
+		m.challenges = NewChallengeMux()
+		m.alpn = NewALPNValidator()
+
+		issuer, err := newACMEIssuer(dataDir, acmeConfig{
+			DirectoryURL: cfg.ACMEDirectoryURL,
+			Email:        cfg.ACMEEmail,
+			Challenge:    cfg.ACMEChallenge,
+			DNSProvider:  cfg.DNSProvider,
+		}, m.challenges, m.alpn)
+		if err != nil {
+			return nil, fmt.Errorf("create ACME issuer: %w", err)
+		}
+		m.issuer = issuer
+
+	default:
+		return nil, fmt.Errorf("unknown cert issuer %q (expected mkcert, local, or acme)", cfg.CertIssuer)
 	}
 
-	return &Manager{
-		dataDir:   dataDir,
-		certsDir:  certsDir,
-		mkcertBin: mkcertBin,
-	}, nil
+	return m, nil
+}
+
+// Challenges returns the HTTP-01 challenge mux the proxy subsystem should
+// mount at /.well-known/acme-challenge/, or nil if this Manager isn't
+// using the ACME issuer with http-01.
+func (m *Manager) Challenges() *ChallengeMux {
+	return m.challenges
+}
+
+// ALPN returns the TLS-ALPN-01 validator the proxy subsystem should plug
+// into its tls.Config.GetCertificate, or nil if this Manager isn't using
+// the ACME issuer with tls-alpn-01.
+func (m *Manager) ALPN() *ALPNValidator {
+	return m.alpn
+}
+
+// certPaths returns the cert/key file paths this Manager uses for domain.
+func (m *Manager) certPaths(domain string) (certPath, keyPath string) {
+	return filepath.Join(m.certsDir, fmt.Sprintf("%s.pem", domain)),
+		filepath.Join(m.certsDir, fmt.Sprintf("%s-key.pem", domain))
 }
 
 func (m *Manager) EnsureCertificate(domain string, force bool) (*CertificateInfo, error) {
@@ -51,8 +141,7 @@ func (m *Manager) EnsureCertificate(domain string, force bool) (*CertificateInfo
 		domain = "localhost"
 	}
 
-	certPath := filepath.Join(m.certsDir, fmt.Sprintf("%s.pem", domain))
-	keyPath := filepath.Join(m.certsDir, fmt.Sprintf("%s-key.pem", domain))
+	certPath, keyPath := m.certPaths(domain)
 
 	info := &CertificateInfo{
 		CertPath: certPath,
@@ -73,7 +162,7 @@ func (m *Manager) EnsureCertificate(domain string, force bool) (*CertificateInfo
 
 	// Create new certificate
 	logger.Info("Creating new certificate", "domain", domain)
-	if err := m.createCertificate(domain, certPath, keyPath); err != nil {
+	if err := m.issuer.IssueCertificate(domain, certPath, keyPath); err != nil {
 		return nil, fmt.Errorf("create certificate: %w", err)
 	}
 
@@ -81,30 +170,78 @@ func (m *Manager) EnsureCertificate(domain string, force bool) (*CertificateInfo
 	return info, nil
 }
 
+// IssuerName identifies which Issuer this Manager resolved to: "mkcert",
+// "local" (NSM's built-in self-signed CA, automatically used when mkcert
+// isn't on PATH), or "acme". setupCertificates reports it so users on a
+// locked-down machine can see at a glance that they got the local CA
+// fallback rather than mkcert.
+func (m *Manager) IssuerName() string {
+	return m.issuerName
+}
+
+// EnsureCATrusted installs this Manager's root CA into the OS trust
+// store, if its issuer has one to install (mkcert/local; ACME certs are
+// already publicly trusted so there's nothing to do). When the install
+// itself fails - commonly because it needs privileges the dev server
+// doesn't have - it returns manual trust instructions instead of an
+// error, since the server still works, it just shows a browser warning
+// until the user imports ca.crt by hand.
+func (m *Manager) EnsureCATrusted() (installed bool, instructions string, err error) {
+	issuer, ok := m.issuer.(caInstaller)
+	if !ok {
+		return false, "", nil
+	}
+
+	if installErr := issuer.InstallCA(); installErr != nil {
+		root, rootErr := issuer.CARoot()
+		if rootErr != nil {
+			return false, "", fmt.Errorf("locate CA root after install failed: %w", installErr)
+		}
+		return false, manualTrustInstructions(root), nil
+	}
+
+	return true, "", nil
+}
+
+// manualTrustInstructions renders the per-OS command to import caRoot
+// into the system/browser trust store by hand.
+func manualTrustInstructions(caRoot string) string {
+	return fmt.Sprintf(
+		"Could not install NSM's certificate authority automatically. Trust it manually:\n"+
+			"  macOS:   security add-trusted-cert -d -r trustRoot -k ~/Library/Keychains/login.keychain-db %s\n"+
+			"  Linux:   certutil -d sql:$HOME/.pki/nssdb -A -t C,, -n \"NSM Local CA\" -i %s\n"+
+			"  Windows: certutil -addstore -f ROOT %s",
+		caRoot, caRoot, caRoot)
+}
+
+// IsMkcertInstalled reports whether the mkcert binary is on PATH,
+// regardless of which Issuer this Manager is actually using.
 func (m *Manager) IsMkcertInstalled() bool {
-	_, err := exec.LookPath("mkcert")
+	_, err := newMkcertIssuer()
 	return err == nil
 }
 
+// InstallCA installs this Manager's root CA into the OS trust store. It
+// returns an error if the configured issuer doesn't have one to install
+// (e.g. ACME, whose certs are already publicly trusted).
 func (m *Manager) InstallCA() error {
-	logger.Info("Installing mkcert CA")
-
-	cmd := exec.Command(m.mkcertBin, "-install")
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("install CA failed: %w\nOutput: %s", err, output)
+	issuer, ok := m.issuer.(caInstaller)
+	if !ok {
+		return fmt.Errorf("InstallCA is not supported by the configured cert issuer")
 	}
 
-	return nil
+	logger.Info("Installing local root CA")
+	return issuer.InstallCA()
 }
 
+// GetCALocation returns the configured issuer's CA root path. It returns
+// an error if the configured issuer doesn't have one (e.g. ACME).
 func (m *Manager) GetCALocation() (string, error) {
-	cmd := exec.Command(m.mkcertBin, "-CAROOT")
-	output, err := cmd.Output()
-	if err != nil {
-		return "", fmt.Errorf("get CA root: %w", err)
+	issuer, ok := m.issuer.(caInstaller)
+	if !ok {
+		return "", fmt.Errorf("GetCALocation is not supported by the configured cert issuer")
 	}
-
-	return strings.TrimSpace(string(output)), nil
+	return issuer.CARoot()
 }
 
 func (m *Manager) certificateExists(certPath, keyPath string) bool {
@@ -113,31 +250,6 @@ func (m *Manager) certificateExists(certPath, keyPath string) bool {
 	return certErr == nil && keyErr == nil
 }
 
-func (m *Manager) createCertificate(domain, certPath, keyPath string) error {
-	args := []string{
-		"-cert-file", certPath,
-		"-key-file", keyPath,
-		domain,
-	}
-
-	// Add common localhost variants
-	if domain == "localhost" {
-		args = append(args, "127.0.0.1", "::1")
-	}
-
-	cmd := exec.Command(m.mkcertBin, args...)
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("mkcert failed: %w\nOutput: %s", err, output)
-	}
-
-	// Verify the certificate was created
-	if !m.certificateExists(certPath, keyPath) {
-		return fmt.Errorf("certificate files not found after creation")
-	}
-
-	return nil
-}
-
 func (m *Manager) validateCertificate(certPath, keyPath, domain string) error {
 	// Read certificate
 	certPEM, err := os.ReadFile(certPath)
@@ -0,0 +1,59 @@
+package cert
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// mkcertIssuer shells out to the mkcert binary, producing certificates
+// trusted only by the local machine's mkcert root CA. It's the default
+// Issuer and the only one that requires a third-party binary.
+type mkcertIssuer struct {
+	bin string
+}
+
+func newMkcertIssuer() (*mkcertIssuer, error) {
+	bin, err := exec.LookPath("mkcert")
+	if err != nil {
+		return nil, fmt.Errorf("mkcert not found: %w", err)
+	}
+	return &mkcertIssuer{bin: bin}, nil
+}
+
+func (i *mkcertIssuer) IssueCertificate(domain, certPath, keyPath string) error {
+	args := []string{
+		"-cert-file", certPath,
+		"-key-file", keyPath,
+		domain,
+	}
+
+	// Add common localhost variants
+	if domain == "localhost" {
+		args = append(args, "127.0.0.1", "::1")
+	}
+
+	cmd := exec.Command(i.bin, args...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("mkcert failed: %w\nOutput: %s", err, output)
+	}
+
+	return nil
+}
+
+func (i *mkcertIssuer) InstallCA() error {
+	cmd := exec.Command(i.bin, "-install")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("install CA failed: %w\nOutput: %s", err, output)
+	}
+	return nil
+}
+
+func (i *mkcertIssuer) CARoot() (string, error) {
+	cmd := exec.Command(i.bin, "-CAROOT")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("get CA root: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
@@ -0,0 +1,140 @@
+package cert
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/kashifsb/nsm/pkg/logger"
+	"github.com/kashifsb/nsm/pkg/metrics"
+)
+
+// renewCheckInterval is how often the Renewer wakes up to check whether
+// the managed certificate needs renewing. It's independent of
+// renewBefore so a long-running NSM process still notices a certificate
+// replaced out-of-band (e.g. by a manual `nsm cert` run).
+const renewCheckInterval = 12 * time.Hour
+
+// renewBefore is how far ahead of expiry the Renewer renews, matching
+// the "expires soon" threshold Manager.validateCertificate already warns
+// about.
+const renewBefore = 30 * 24 * time.Hour
+
+// Renewer periodically re-issues domain's certificate via manager as it
+// approaches expiry, calling onRenew with the refreshed CertificateInfo
+// so callers (typically the proxy's TLS listener) can hot-swap it
+// without a restart.
+type Renewer struct {
+	manager        *Manager
+	domain         string
+	onRenew        func(*CertificateInfo)
+	onExpiringSoon func(domain string, notAfter time.Time)
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewRenewer returns a Renewer for domain backed by manager. onRenew is
+// called with the new CertificateInfo each time a renewal succeeds.
+// onExpiringSoon is called as soon as a check finds the certificate inside
+// renewBefore, before the renewal attempt itself, so callers can surface
+// it (e.g. a desktop notification) even if the renewal later fails. Either
+// callback may be nil.
+func NewRenewer(manager *Manager, domain string, onRenew func(*CertificateInfo), onExpiringSoon func(domain string, notAfter time.Time)) *Renewer {
+	return &Renewer{
+		manager:        manager,
+		domain:         domain,
+		onRenew:        onRenew,
+		onExpiringSoon: onExpiringSoon,
+		stop:           make(chan struct{}),
+		done:           make(chan struct{}),
+	}
+}
+
+// Start runs the renewal loop in a background goroutine until ctx is
+// canceled or Stop is called.
+func (r *Renewer) Start(ctx context.Context) {
+	go r.loop(ctx)
+}
+
+// Stop halts the renewal loop and waits for it to exit.
+func (r *Renewer) Stop() {
+	close(r.stop)
+	<-r.done
+}
+
+func (r *Renewer) loop(ctx context.Context) {
+	defer close(r.done)
+
+	ticker := time.NewTicker(renewCheckInterval)
+	defer ticker.Stop()
+
+	// Populate nsm_cert_expiry_seconds immediately rather than waiting a
+	// full renewCheckInterval for the first tick.
+	r.checkAndRenew()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			r.checkAndRenew()
+		}
+	}
+}
+
+func (r *Renewer) checkAndRenew() {
+	certPath, _ := r.manager.certPaths(r.domain)
+
+	expiresSoon, notAfter, err := certExpiresWithin(certPath, renewBefore)
+	if err != nil {
+		logger.Warn("Renewer: failed to inspect certificate, skipping this cycle", "domain", r.domain, "error", err)
+		return
+	}
+	metrics.SetCertExpiry(notAfter)
+	if !expiresSoon {
+		return
+	}
+
+	logger.Info("Certificate nearing expiry, renewing", "domain", r.domain, "expires", notAfter)
+	if r.onExpiringSoon != nil {
+		r.onExpiringSoon(r.domain, notAfter)
+	}
+
+	info, err := r.manager.EnsureCertificate(r.domain, true)
+	if err != nil {
+		logger.Error("Certificate renewal failed", "domain", r.domain, "error", err)
+		return
+	}
+
+	logger.Info("Certificate renewed", "domain", r.domain)
+	if r.onRenew != nil {
+		r.onRenew(info)
+	}
+}
+
+// certExpiresWithin reports whether the certificate at certPath expires
+// before window elapses, along with its NotAfter time.
+func certExpiresWithin(certPath string, window time.Duration) (bool, time.Time, error) {
+	certPEM, err := os.ReadFile(certPath)
+	if err != nil {
+		return false, time.Time{}, fmt.Errorf("read certificate: %w", err)
+	}
+
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return false, time.Time{}, fmt.Errorf("decode PEM certificate")
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return false, time.Time{}, fmt.Errorf("parse certificate: %w", err)
+	}
+
+	return time.Now().Add(window).After(cert.NotAfter), cert.NotAfter, nil
+}
@@ -0,0 +1,119 @@
+package cert
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+)
+
+// ALPNProto is the ALPN protocol name a tls-alpn-01 validation connection
+// advertises, per RFC 8737.
+const ALPNProto = "acme-tls/1"
+
+// idPeAcmeIdentifierOID is the x509 extension OID the validation
+// certificate's "acmeIdentifier" carries, again per RFC 8737.
+var idPeAcmeIdentifierOID = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 31}
+
+// ALPNValidator answers TLS-ALPN-01 challenges: when a TLS ClientHello
+// advertises the "acme-tls/1" ALPN protocol for a domain it holds a
+// challenge for, it must respond with a self-signed certificate whose SAN
+// is that domain and which carries a critical acmeIdentifier extension of
+// sha256(keyAuthorization).
+type ALPNValidator struct {
+	mu         sync.RWMutex
+	challenges map[string]string // domain -> key authorization
+}
+
+// NewALPNValidator returns an empty ALPNValidator.
+func NewALPNValidator() *ALPNValidator {
+	return &ALPNValidator{challenges: make(map[string]string)}
+}
+
+// Set registers keyAuth as the expected TLS-ALPN-01 proof for domain.
+func (v *ALPNValidator) Set(domain, keyAuth string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.challenges[domain] = keyAuth
+}
+
+// Delete removes domain's challenge once it's been validated or abandoned.
+func (v *ALPNValidator) Delete(domain string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	delete(v.challenges, domain)
+}
+
+// GetCertificate is a tls.Config.GetCertificate callback: plug it in ahead
+// of the normal certificate lookup, and it answers only ClientHellos
+// requesting the acme-tls/1 ALPN protocol for a domain with a pending
+// challenge, returning nil (deferring to the next callback) otherwise.
+func (v *ALPNValidator) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if !hasALPNProto(hello.SupportedProtos, ALPNProto) {
+		return nil, nil
+	}
+
+	v.mu.RLock()
+	keyAuth, ok := v.challenges[hello.ServerName]
+	v.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no tls-alpn-01 challenge pending for %s", hello.ServerName)
+	}
+
+	return validationCertificate(hello.ServerName, keyAuth)
+}
+
+func hasALPNProto(protos []string, want string) bool {
+	for _, p := range protos {
+		if p == want {
+			return true
+		}
+	}
+	return false
+}
+
+// validationCertificate builds the self-signed cert RFC 8737 requires: SAN
+// = domain, a critical acmeIdentifier extension = sha256(keyAuth).
+func validationCertificate(domain, keyAuth string) (*tls.Certificate, error) {
+	digest := sha256.Sum256([]byte(keyAuth))
+	extValue, err := asn1.Marshal(digest[:])
+	if err != nil {
+		return nil, fmt.Errorf("marshal acmeIdentifier: %w", err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generate validation key: %w", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: domain},
+		DNSNames:     []string{domain},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtraExtensions: []pkix.Extension{{
+			Id:       idPeAcmeIdentifierOID,
+			Critical: true,
+			Value:    extValue,
+		}},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("create validation certificate: %w", err)
+	}
+
+	return &tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+	}, nil
+}
@@ -4,23 +4,39 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/kashifsb/nsm/pkg/logger"
+	"github.com/kashifsb/nsm/pkg/utils"
 )
 
 type ProjectType string
 
 const (
-	ProjectTypeVite   ProjectType = "vite"
-	ProjectTypeReact  ProjectType = "react"
-	ProjectTypeGo     ProjectType = "go"
-	ProjectTypeRust   ProjectType = "rust"
-	ProjectTypePython ProjectType = "python"
-	ProjectTypeJava   ProjectType = "java"
-	ProjectTypeDotNet ProjectType = "dotnet"
-	ProjectTypeNode   ProjectType = "node"
-	ProjectTypeNext   ProjectType = "next"
+	ProjectTypeVite      ProjectType = "vite"
+	ProjectTypeReact     ProjectType = "react"
+	ProjectTypeGo        ProjectType = "go"
+	ProjectTypeRust      ProjectType = "rust"
+	ProjectTypePython    ProjectType = "python"
+	ProjectTypeJava      ProjectType = "java"
+	ProjectTypeDotNet    ProjectType = "dotnet"
+	ProjectTypeNode      ProjectType = "node"
+	ProjectTypeNext      ProjectType = "next"
+	ProjectTypeDeno      ProjectType = "deno"
+	ProjectTypeBun       ProjectType = "bun"
+	ProjectTypeAstro     ProjectType = "astro"
+	ProjectTypeSvelteKit ProjectType = "sveltekit"
+	ProjectTypeRemix     ProjectType = "remix"
+	ProjectTypeDjango    ProjectType = "django"
+	ProjectTypeFlask     ProjectType = "flask"
+	ProjectTypeFastAPI   ProjectType = "fastapi"
+	ProjectTypePhoenix   ProjectType = "phoenix"
+	ProjectTypeStatic    ProjectType = "static"
 )
 
 type Config struct {
@@ -45,41 +61,210 @@ type Config struct {
 	Debug    bool `json:"debug"`
 	AutoYes  bool `json:"auto_yes"`
 	Headless bool `json:"headless"`
+	AutoOpen bool `json:"auto_open"`
 
 	// Paths
 	DataDir  string `json:"data_dir"`
 	CertPath string `json:"cert_path"`
 	KeyPath  string `json:"key_path"`
+
+	// Static file serving. Setting StaticDir (via --static or a saved
+	// config) implies ProjectType "static": instead of spawning Command as
+	// a subprocess, NSM serves StaticDir directly with http.FileServer
+	// behind the same proxy/HTTPS front end. StaticSPA rewrites unknown
+	// paths to StaticDir/index.html for client-side routers;
+	// StaticDirListing enables directory index pages (off by default, so
+	// an unlisted directory 404s instead); Static404 names a file within
+	// StaticDir served (with a 404 status) when a path isn't found and
+	// StaticSPA is off.
+	StaticDir        string `json:"static_dir"`
+	StaticSPA        bool   `json:"static_spa"`
+	StaticDirListing bool   `json:"static_dir_listing"`
+	Static404        string `json:"static_404"`
+
+	// Certificate issuance. CertIssuer selects between "mkcert"
+	// (locally-trusted only), "local" (NSM's built-in pure-Go CA, used
+	// automatically when mkcert isn't installed), and "acme" (a
+	// publicly-trusted cert from Let's Encrypt or any RFC 8555
+	// directory). Left empty, cert.Manager picks mkcert or local
+	// depending on whether mkcert is on PATH. The ACME* and DNSProvider
+	// fields only matter when CertIssuer is "acme".
+	CertIssuer       string `json:"cert_issuer"`
+	ACMEDirectoryURL string `json:"acme_directory_url"`
+	ACMEEmail        string `json:"acme_email"`
+	ACMEChallenge    string `json:"acme_challenge"` // http-01, tls-alpn-01, or dns-01
+	DNSProvider      string `json:"dns_provider"`   // cloudflare, route53, manual, or exec
+
+	// UpstreamDNS lists the servers the embedded DNS resolver forwards
+	// queries it doesn't own itself to, overriding dns.DefaultUpstreams.
+	// Each entry may be a bare "host:port" (plain UDP, the default), or
+	// prefixed "udp://", "tls://" (DNS-over-TLS), or "https://"
+	// (DNS-over-HTTPS) for an encrypted upstream.
+	UpstreamDNS []string `json:"upstream_dns"`
+	// BootstrapDNS resolves a tls:// or https:// UpstreamDNS entry's own
+	// hostname before that upstream can be used, avoiding a
+	// chicken-and-egg lookup. Defaults to "8.8.8.8:53" when empty.
+	BootstrapDNS string `json:"bootstrap_dns"`
+
+	// LogFormat selects how Runner/Supervisor output is re-emitted to
+	// stdout: "text" (the default, human-readable TUI logs only) or
+	// "json", which additionally prints each canonicalized project.LogEvent
+	// as a line of JSON for piping into jq. RawOutput, when true, skips
+	// ANSI stripping so color codes reach the terminal unchanged.
+	LogFormat string `json:"log_format"`
+	RawOutput bool   `json:"raw_output"`
+
+	// Observability. MetricsAddr, when set (e.g. ":9090"), starts a sidecar
+	// HTTP server exposing /metrics (Prometheus), /healthz, and /readyz;
+	// left empty, no metrics server runs. AccessLogPath is where the proxy
+	// writes one line per request; it defaults to ~/.nsm/logs/access.log
+	// in finalize. AccessLogFormat selects the line shape: "combined"
+	// (the default), "common", or "json" (adds upstream target, upstream
+	// response time, retry count, and matched route name, for
+	// goaccess/ELK/jq). AccessLogMaxSizeMB/MaxBackups/MaxAgeDays bound
+	// lumberjack-style rotation; all default (0) to lumberjack's own
+	// defaults except MaxSizeMB, which NSM defaults to 100. DiagAddr, when
+	// set (e.g. ":9091"), starts the headless-mode diagnostic server
+	// (/healthz, /readyz, /state, /logs, /debug/pprof/*); left empty, no
+	// diagnostic server runs. CtlAddr, when set (e.g. ":9092"), starts an
+	// additional loopback HTTP variant of the IPC control socket, guarded
+	// by a random per-run token written to DataDir/config/token; the Unix
+	// domain socket at DataDir/nsm.sock always runs, guarded by its 0600
+	// file mode.
+	MetricsAddr         string `json:"metrics_addr"`
+	AccessLogPath       string `json:"access_log_path"`
+	AccessLogFormat     string `json:"access_log_format"`
+	AccessLogMaxSizeMB  int    `json:"access_log_max_size_mb"`
+	AccessLogMaxBackups int    `json:"access_log_max_backups"`
+	AccessLogMaxAgeDays int    `json:"access_log_max_age_days"`
+	DiagAddr            string `json:"diag_addr"`
+	CtlAddr             string `json:"ctl_addr"`
+
+	// TeardownTimeout bounds App.shutdown's whole phased teardown
+	// (dev -> proxy -> dns -> certs -> ports): once it elapses, App gives
+	// up waiting on whatever phase is still running and quits anyway,
+	// rather than hanging on an unresponsive subsystem.
+	TeardownTimeout time.Duration `json:"teardown_timeout"`
+
+	// ExtraUpstreams are additional "host:port" backends fronted
+	// alongside the dev server, for micro-frontend / multi-service setups
+	// where several backend processes need to sit behind one domain.
+	// UpstreamPolicy picks which upstream handles each request when more
+	// than one is configured: round-robin (the default), random,
+	// least-conn, ip-hash, or first-healthy.
+	ExtraUpstreams []string `json:"extra_upstreams"`
+	UpstreamPolicy string   `json:"upstream_policy"`
+
+	// HTTPProxy switches the proxy from reverse (fronting the dev server)
+	// to forward mode: an HTTP CONNECT / forward proxy a client points its
+	// own HTTP_PROXY/HTTPS_PROXY setting at, tunneling requests to
+	// arbitrary hosts through NSM instead of to ExtraUpstreams/Routes.
+	// HTTPProxyAllowHosts/HTTPProxyDenyHosts restrict which hosts may be
+	// tunneled to; a deny match always wins, and an empty allow list
+	// permits anything not denied.
+	HTTPProxy           bool     `json:"http_proxy"`
+	HTTPProxyAllowHosts []string `json:"http_proxy_allow_hosts"`
+	HTTPProxyDenyHosts  []string `json:"http_proxy_deny_hosts"`
+
+	// ProxyMiddlewares names and orders the proxy's middleware chain (see
+	// server.registerMiddlewares); left empty, the built-in
+	// recovery/logging/cors chain applies. ProxyInjectScript is injected
+	// before </body> in every text/html response when "html-inject" is
+	// one of ProxyMiddlewares. ProxyRequestHeaders/ProxyResponseHeaders
+	// are "Name: Value" entries the "headers" middleware adds.
+	// ProxyBasicAuthUsers are "user:password" entries the "basic-auth"
+	// middleware checks requests against.
+	ProxyMiddlewares     []string `json:"proxy_middlewares"`
+	ProxyInjectScript    string   `json:"proxy_inject_script"`
+	ProxyRequestHeaders  []string `json:"proxy_request_headers"`
+	ProxyResponseHeaders []string `json:"proxy_response_headers"`
+	ProxyBasicAuthUsers  []string `json:"proxy_basic_auth_users"`
+
+	// Sites lets one `nsm` process front several projects behind the same
+	// shared proxy instead of just ProjectDir/Command/Domain, each with
+	// its own domain, command, and working directory - see
+	// internal/app.SiteManager.
+	Sites []SiteConfig `json:"sites,omitempty"`
+
+	// Tunnel exposes the local HTTPS port over a public URL via an
+	// external tool - see internal/tunnel.Provider. Left with an empty
+	// Provider, no tunnel is started.
+	Tunnel TunnelConfig `json:"tunnel,omitempty"`
 }
 
-func ParseFromFlags(cmd *cobra.Command) (*Config, error) {
-	cfg := &Config{
-		EnableHTTPS: true,
-		EnableDNS:   true,
-		EnableProxy: true,
-		UsePort443:  true,
-	}
+// TunnelConfig configures the optional public tunnel subsystem.
+type TunnelConfig struct {
+	// Provider selects the tunneling tool: "cloudflared", "ngrok", or
+	// "tailscale" (tailscale funnel). Empty disables the tunnel subsystem.
+	Provider string `json:"provider" yaml:"provider"`
+}
 
-	// Parse flags
-	var err error
+// SiteConfig is one entry of Sites: an additional project app.SiteManager
+// runs its own dev-server process for and fronts on Domain, alongside the
+// project this Config otherwise describes.
+type SiteConfig struct {
+	Domain  string `json:"domain" yaml:"domain"`
+	Command string `json:"command" yaml:"command"`
+	WorkDir string `json:"work_dir" yaml:"work_dir"`
+}
 
-	if projectType, _ := cmd.Flags().GetString("project-type"); projectType != "" {
-		cfg.ProjectType = ProjectType(projectType)
+// defaultConfig returns a Config with NSM's built-in defaults, the
+// lowest-precedence layer Load merges file, environment, and flag
+// overrides on top of.
+func defaultConfig() *Config {
+	return &Config{
+		EnableHTTPS:     true,
+		EnableDNS:       true,
+		EnableProxy:     true,
+		UsePort443:      true,
+		AutoOpen:        true,
+		ACMEChallenge:   "http-01",
+		LogFormat:       "text",
+		TeardownTimeout: 15 * time.Second,
 	}
+}
+
+// ParseFromFlags builds a Config from cmd's flags alone, with NSM's
+// built-in defaults underneath. It's Load without the config-file and
+// environment-variable layers; most callers want Load instead.
+func ParseFromFlags(cmd *cobra.Command) (*Config, error) {
+	cfg := defaultConfig()
+	applyFlags(cfg, cmd)
+	return finalize(cfg)
+}
 
-	cfg.Domain, _ = cmd.Flags().GetString("domain")
-	cfg.Command, _ = cmd.Flags().GetString("command")
-	cfg.HTTPPort, _ = cmd.Flags().GetInt("http-port")
-	cfg.HTTPSPort, _ = cmd.Flags().GetInt("https-port")
-	cfg.Debug, _ = cmd.Flags().GetBool("debug")
-	cfg.AutoYes, _ = cmd.Flags().GetBool("auto-yes")
-	cfg.Headless, _ = cmd.Flags().GetBool("headless")
+// Load resolves a Config the way NSM always has: built-in defaults, then
+// ~/.nsm/config.yaml, then a project-local .nsm.yaml, then NSM_* environment
+// variables, then cmd's flags - each layer overriding the last, so
+// `nsm --domain foo.test` always wins over a saved default. See Save for
+// the reverse direction.
+func Load(cmd *cobra.Command) (*Config, error) {
+	cfg := defaultConfig()
 
-	if no443, _ := cmd.Flags().GetBool("no-443"); no443 {
-		cfg.UsePort443 = false
+	if err := mergeFile(cfg, globalConfigPath()); err != nil {
+		return nil, fmt.Errorf("load %s: %w", globalConfigPath(), err)
 	}
 
-	// Set project directory and name
+	projectDir, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("get working directory: %w", err)
+	}
+	projectConfigPath := filepath.Join(projectDir, OverrideFilename)
+	if err := mergeFile(cfg, projectConfigPath); err != nil {
+		return nil, fmt.Errorf("load %s: %w", projectConfigPath, err)
+	}
+
+	mergeEnv(cfg)
+	applyFlags(cfg, cmd)
+
+	return finalize(cfg)
+}
+
+// finalize fills in the fields Load/ParseFromFlags can't get from a flag,
+// file, or env var - the working directory, derived data dir, and any
+// auto-detected project type/command - and validates the result.
+func finalize(cfg *Config) (*Config, error) {
+	var err error
 	cfg.ProjectDir, err = os.Getwd()
 	if err != nil {
 		return nil, fmt.Errorf("get working directory: %w", err)
@@ -88,35 +273,685 @@ func ParseFromFlags(cmd *cobra.Command) (*Config, error) {
 	cfg.ProjectName = filepath.Base(cfg.ProjectDir)
 	cfg.ProjectName = strings.ToLower(strings.ReplaceAll(cfg.ProjectName, " ", "-"))
 
-	// Setup data directory
+	cfg.DataDir, err = DataDirForProjectDir(cfg.ProjectDir)
+	if err != nil {
+		return nil, err
+	}
+
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return nil, fmt.Errorf("get home directory: %w", err)
 	}
 
-	cfg.DataDir = filepath.Join(homeDir, ".nsm", cfg.ProjectName)
+	if cfg.AccessLogPath == "" {
+		cfg.AccessLogPath = filepath.Join(homeDir, ".nsm", "logs", "access.log")
+	}
+
+	if cfg.StaticDir != "" {
+		cfg.ProjectType = ProjectTypeStatic
+	}
 
-	// Auto-detect project type if not specified
 	if cfg.ProjectType == "" {
 		cfg.ProjectType = detectProjectType(cfg.ProjectDir)
 	}
-
-	// Set default command if not specified
 	if cfg.Command == "" {
 		cfg.Command = getDefaultCommand(cfg.ProjectType)
 	}
 
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
 	return cfg, nil
 }
 
+// DataDirForProjectDir derives the same per-project DataDir finalize
+// computes, from a project directory alone. It lets callers that never
+// load a full Config - like the "nsm ctl" CLI dialing the running
+// daemon's IPC socket - find DataDir/nsm.sock without re-running Load.
+func DataDirForProjectDir(projectDir string) (string, error) {
+	projectName := filepath.Base(projectDir)
+	projectName = strings.ToLower(strings.ReplaceAll(projectName, " ", "-"))
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".nsm", projectName), nil
+}
+
+// applyFlags overlays cmd's explicitly-set flags onto cfg, leaving fields
+// whose flag wasn't passed untouched so file/env layers underneath survive.
+func applyFlags(cfg *Config, cmd *cobra.Command) {
+	flags := cmd.Flags()
+
+	if v, err := flags.GetString("project-type"); err == nil && flags.Changed("project-type") {
+		cfg.ProjectType = ProjectType(v)
+	}
+	if v, err := flags.GetString("domain"); err == nil && flags.Changed("domain") {
+		cfg.Domain = v
+	}
+	if v, err := flags.GetString("command"); err == nil && flags.Changed("command") {
+		cfg.Command = v
+	}
+	if v, err := flags.GetString("static"); err == nil && flags.Changed("static") {
+		cfg.StaticDir = v
+	}
+	if v, err := flags.GetBool("static-spa"); err == nil && flags.Changed("static-spa") {
+		cfg.StaticSPA = v
+	}
+	if v, err := flags.GetBool("static-dir-listing"); err == nil && flags.Changed("static-dir-listing") {
+		cfg.StaticDirListing = v
+	}
+	if v, err := flags.GetString("static-404"); err == nil && flags.Changed("static-404") {
+		cfg.Static404 = v
+	}
+	if v, err := flags.GetInt("http-port"); err == nil && flags.Changed("http-port") {
+		cfg.HTTPPort = v
+	}
+	if v, err := flags.GetInt("https-port"); err == nil && flags.Changed("https-port") {
+		cfg.HTTPSPort = v
+	}
+	if v, err := flags.GetBool("debug"); err == nil && flags.Changed("debug") {
+		cfg.Debug = v
+	}
+	if v, err := flags.GetBool("auto-yes"); err == nil && flags.Changed("auto-yes") {
+		cfg.AutoYes = v
+	}
+	if v, err := flags.GetBool("headless"); err == nil && flags.Changed("headless") {
+		cfg.Headless = v
+	}
+	if v, err := flags.GetBool("enable-dns"); err == nil && flags.Changed("enable-dns") {
+		cfg.EnableDNS = v
+	}
+	if v, err := flags.GetBool("enable-https"); err == nil && flags.Changed("enable-https") {
+		cfg.EnableHTTPS = v
+	}
+	if v, err := flags.GetBool("enable-proxy"); err == nil && flags.Changed("enable-proxy") {
+		cfg.EnableProxy = v
+	}
+	if v, err := flags.GetBool("no-443"); err == nil && v {
+		cfg.UsePort443 = false
+	}
+	if v, err := flags.GetBool("no-auto-open"); err == nil && v {
+		cfg.AutoOpen = false
+	}
+	if v, err := flags.GetString("cert-issuer"); err == nil && flags.Changed("cert-issuer") {
+		cfg.CertIssuer = v
+	}
+	if v, err := flags.GetString("acme-directory-url"); err == nil && flags.Changed("acme-directory-url") {
+		cfg.ACMEDirectoryURL = v
+	}
+	if v, err := flags.GetString("acme-email"); err == nil && flags.Changed("acme-email") {
+		cfg.ACMEEmail = v
+	}
+	if v, err := flags.GetString("acme-challenge"); err == nil && flags.Changed("acme-challenge") {
+		cfg.ACMEChallenge = v
+	}
+	if v, err := flags.GetString("dns-provider"); err == nil && flags.Changed("dns-provider") {
+		cfg.DNSProvider = v
+	}
+	if v, err := flags.GetString("log-format"); err == nil && flags.Changed("log-format") {
+		cfg.LogFormat = v
+	}
+	if v, err := flags.GetBool("raw"); err == nil && flags.Changed("raw") {
+		cfg.RawOutput = v
+	}
+	if v, err := flags.GetString("metrics-addr"); err == nil && flags.Changed("metrics-addr") {
+		cfg.MetricsAddr = v
+	}
+	if v, err := flags.GetString("access-log"); err == nil && flags.Changed("access-log") {
+		cfg.AccessLogPath = v
+	}
+	if v, err := flags.GetString("access-log-format"); err == nil && flags.Changed("access-log-format") {
+		cfg.AccessLogFormat = v
+	}
+	if v, err := flags.GetInt("access-log-max-size-mb"); err == nil && flags.Changed("access-log-max-size-mb") {
+		cfg.AccessLogMaxSizeMB = v
+	}
+	if v, err := flags.GetInt("access-log-max-backups"); err == nil && flags.Changed("access-log-max-backups") {
+		cfg.AccessLogMaxBackups = v
+	}
+	if v, err := flags.GetInt("access-log-max-age-days"); err == nil && flags.Changed("access-log-max-age-days") {
+		cfg.AccessLogMaxAgeDays = v
+	}
+	if v, err := flags.GetString("diag-addr"); err == nil && flags.Changed("diag-addr") {
+		cfg.DiagAddr = v
+	}
+	if v, err := flags.GetString("ctl-addr"); err == nil && flags.Changed("ctl-addr") {
+		cfg.CtlAddr = v
+	}
+	if v, err := flags.GetDuration("teardown-timeout"); err == nil && flags.Changed("teardown-timeout") {
+		cfg.TeardownTimeout = v
+	}
+	if v, err := flags.GetStringArray("upstream"); err == nil && flags.Changed("upstream") {
+		cfg.ExtraUpstreams = v
+	}
+	if v, err := flags.GetString("upstream-policy"); err == nil && flags.Changed("upstream-policy") {
+		cfg.UpstreamPolicy = v
+	}
+	if v, err := flags.GetBool("http-proxy"); err == nil && flags.Changed("http-proxy") {
+		cfg.HTTPProxy = v
+	}
+	if v, err := flags.GetStringArray("http-proxy-allow"); err == nil && flags.Changed("http-proxy-allow") {
+		cfg.HTTPProxyAllowHosts = v
+	}
+	if v, err := flags.GetStringArray("http-proxy-deny"); err == nil && flags.Changed("http-proxy-deny") {
+		cfg.HTTPProxyDenyHosts = v
+	}
+	if v, err := flags.GetStringArray("proxy-middleware"); err == nil && flags.Changed("proxy-middleware") {
+		cfg.ProxyMiddlewares = v
+	}
+	if v, err := flags.GetString("proxy-inject-script"); err == nil && flags.Changed("proxy-inject-script") {
+		cfg.ProxyInjectScript = v
+	}
+	if v, err := flags.GetStringArray("proxy-request-header"); err == nil && flags.Changed("proxy-request-header") {
+		cfg.ProxyRequestHeaders = v
+	}
+	if v, err := flags.GetStringArray("proxy-response-header"); err == nil && flags.Changed("proxy-response-header") {
+		cfg.ProxyResponseHeaders = v
+	}
+	if v, err := flags.GetStringArray("proxy-basic-auth"); err == nil && flags.Changed("proxy-basic-auth") {
+		cfg.ProxyBasicAuthUsers = v
+	}
+}
+
+// globalConfigPath is the user-wide config file Load merges before any
+// project-local .nsm.yaml or environment/flag overrides.
+func globalConfigPath() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".nsm", "config.yaml")
+}
+
+// fileConfig mirrors the persistable subset of Config - everything except
+// the fields finalize derives at load time (ProjectDir, ProjectName,
+// DataDir) and the purely session-scoped flags (Debug, AutoYes, Headless).
+// Bool fields are pointers so mergeFile can tell "absent from the file"
+// apart from "explicitly set to false".
+type fileConfig struct {
+	ProjectType         ProjectType `yaml:"project_type,omitempty"`
+	Domain              string      `yaml:"domain,omitempty"`
+	Command             string      `yaml:"command,omitempty"`
+	StaticDir           string      `yaml:"static_dir,omitempty"`
+	StaticSPA           *bool       `yaml:"static_spa,omitempty"`
+	StaticDirListing    *bool       `yaml:"static_dir_listing,omitempty"`
+	Static404           string      `yaml:"static_404,omitempty"`
+	HTTPPort            int         `yaml:"http_port,omitempty"`
+	HTTPSPort           int         `yaml:"https_port,omitempty"`
+	UsePort443          *bool       `yaml:"use_port_443,omitempty"`
+	EnableHTTPS         *bool       `yaml:"enable_https,omitempty"`
+	EnableDNS           *bool       `yaml:"enable_dns,omitempty"`
+	EnableProxy         *bool       `yaml:"enable_proxy,omitempty"`
+	AutoOpen            *bool       `yaml:"auto_open,omitempty"`
+	CertIssuer          string      `yaml:"cert_issuer,omitempty"`
+	ACMEDirectoryURL    string      `yaml:"acme_directory_url,omitempty"`
+	ACMEEmail           string      `yaml:"acme_email,omitempty"`
+	ACMEChallenge       string      `yaml:"acme_challenge,omitempty"`
+	DNSProvider         string      `yaml:"dns_provider,omitempty"`
+	UpstreamDNS         []string    `yaml:"upstream_dns,omitempty"`
+	BootstrapDNS        string      `yaml:"bootstrap_dns,omitempty"`
+	LogFormat           string      `yaml:"log_format,omitempty"`
+	RawOutput           *bool       `yaml:"raw_output,omitempty"`
+	MetricsAddr         string      `yaml:"metrics_addr,omitempty"`
+	AccessLogPath       string      `yaml:"access_log_path,omitempty"`
+	AccessLogFormat     string      `yaml:"access_log_format,omitempty"`
+	AccessLogMaxSizeMB  int         `yaml:"access_log_max_size_mb,omitempty"`
+	AccessLogMaxBackups int         `yaml:"access_log_max_backups,omitempty"`
+	AccessLogMaxAgeDays int         `yaml:"access_log_max_age_days,omitempty"`
+	DiagAddr            string      `yaml:"diag_addr,omitempty"`
+	CtlAddr             string      `yaml:"ctl_addr,omitempty"`
+	TeardownTimeout     string      `yaml:"teardown_timeout,omitempty"`
+	ExtraUpstreams      []string    `yaml:"extra_upstreams,omitempty"`
+	UpstreamPolicy      string      `yaml:"upstream_policy,omitempty"`
+
+	HTTPProxy           *bool    `yaml:"http_proxy,omitempty"`
+	HTTPProxyAllowHosts []string `yaml:"http_proxy_allow_hosts,omitempty"`
+	HTTPProxyDenyHosts  []string `yaml:"http_proxy_deny_hosts,omitempty"`
+
+	ProxyMiddlewares     []string `yaml:"proxy_middlewares,omitempty"`
+	ProxyInjectScript    string   `yaml:"proxy_inject_script,omitempty"`
+	ProxyRequestHeaders  []string `yaml:"proxy_request_headers,omitempty"`
+	ProxyResponseHeaders []string `yaml:"proxy_response_headers,omitempty"`
+	ProxyBasicAuthUsers  []string `yaml:"proxy_basic_auth_users,omitempty"`
+
+	Sites []SiteConfig `yaml:"sites,omitempty"`
+
+	TunnelProvider string `yaml:"tunnel_provider,omitempty"`
+}
+
+// mergeFile overlays path's fileConfig onto cfg, leaving cfg untouched if
+// path doesn't exist - missing config files are normal, not an error.
+func mergeFile(cfg *Config, path string) error {
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var fc fileConfig
+	if err := yaml.Unmarshal(data, &fc); err != nil {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	if fc.ProjectType != "" {
+		cfg.ProjectType = fc.ProjectType
+	}
+	if fc.Domain != "" {
+		cfg.Domain = fc.Domain
+	}
+	if fc.Command != "" {
+		cfg.Command = fc.Command
+	}
+	if fc.StaticDir != "" {
+		cfg.StaticDir = fc.StaticDir
+	}
+	if fc.StaticSPA != nil {
+		cfg.StaticSPA = *fc.StaticSPA
+	}
+	if fc.StaticDirListing != nil {
+		cfg.StaticDirListing = *fc.StaticDirListing
+	}
+	if fc.Static404 != "" {
+		cfg.Static404 = fc.Static404
+	}
+	if fc.HTTPPort != 0 {
+		cfg.HTTPPort = fc.HTTPPort
+	}
+	if fc.HTTPSPort != 0 {
+		cfg.HTTPSPort = fc.HTTPSPort
+	}
+	if fc.UsePort443 != nil {
+		cfg.UsePort443 = *fc.UsePort443
+	}
+	if fc.EnableHTTPS != nil {
+		cfg.EnableHTTPS = *fc.EnableHTTPS
+	}
+	if fc.EnableDNS != nil {
+		cfg.EnableDNS = *fc.EnableDNS
+	}
+	if fc.EnableProxy != nil {
+		cfg.EnableProxy = *fc.EnableProxy
+	}
+	if fc.AutoOpen != nil {
+		cfg.AutoOpen = *fc.AutoOpen
+	}
+	if fc.CertIssuer != "" {
+		cfg.CertIssuer = fc.CertIssuer
+	}
+	if fc.ACMEDirectoryURL != "" {
+		cfg.ACMEDirectoryURL = fc.ACMEDirectoryURL
+	}
+	if fc.ACMEEmail != "" {
+		cfg.ACMEEmail = fc.ACMEEmail
+	}
+	if fc.ACMEChallenge != "" {
+		cfg.ACMEChallenge = fc.ACMEChallenge
+	}
+	if fc.DNSProvider != "" {
+		cfg.DNSProvider = fc.DNSProvider
+	}
+	if len(fc.UpstreamDNS) > 0 {
+		cfg.UpstreamDNS = fc.UpstreamDNS
+	}
+	if fc.BootstrapDNS != "" {
+		cfg.BootstrapDNS = fc.BootstrapDNS
+	}
+	if fc.LogFormat != "" {
+		cfg.LogFormat = fc.LogFormat
+	}
+	if fc.RawOutput != nil {
+		cfg.RawOutput = *fc.RawOutput
+	}
+	if fc.MetricsAddr != "" {
+		cfg.MetricsAddr = fc.MetricsAddr
+	}
+	if fc.AccessLogPath != "" {
+		cfg.AccessLogPath = fc.AccessLogPath
+	}
+	if fc.AccessLogFormat != "" {
+		cfg.AccessLogFormat = fc.AccessLogFormat
+	}
+	if fc.AccessLogMaxSizeMB > 0 {
+		cfg.AccessLogMaxSizeMB = fc.AccessLogMaxSizeMB
+	}
+	if fc.AccessLogMaxBackups > 0 {
+		cfg.AccessLogMaxBackups = fc.AccessLogMaxBackups
+	}
+	if fc.AccessLogMaxAgeDays > 0 {
+		cfg.AccessLogMaxAgeDays = fc.AccessLogMaxAgeDays
+	}
+	if fc.DiagAddr != "" {
+		cfg.DiagAddr = fc.DiagAddr
+	}
+	if fc.CtlAddr != "" {
+		cfg.CtlAddr = fc.CtlAddr
+	}
+	if fc.TeardownTimeout != "" {
+		d, err := time.ParseDuration(fc.TeardownTimeout)
+		if err != nil {
+			return fmt.Errorf("parse teardown_timeout %q: %w", fc.TeardownTimeout, err)
+		}
+		cfg.TeardownTimeout = d
+	}
+	if len(fc.ExtraUpstreams) > 0 {
+		cfg.ExtraUpstreams = fc.ExtraUpstreams
+	}
+	if fc.UpstreamPolicy != "" {
+		cfg.UpstreamPolicy = fc.UpstreamPolicy
+	}
+	if fc.HTTPProxy != nil {
+		cfg.HTTPProxy = *fc.HTTPProxy
+	}
+	if len(fc.HTTPProxyAllowHosts) > 0 {
+		cfg.HTTPProxyAllowHosts = fc.HTTPProxyAllowHosts
+	}
+	if len(fc.HTTPProxyDenyHosts) > 0 {
+		cfg.HTTPProxyDenyHosts = fc.HTTPProxyDenyHosts
+	}
+	if len(fc.ProxyMiddlewares) > 0 {
+		cfg.ProxyMiddlewares = fc.ProxyMiddlewares
+	}
+	if fc.ProxyInjectScript != "" {
+		cfg.ProxyInjectScript = fc.ProxyInjectScript
+	}
+	if len(fc.ProxyRequestHeaders) > 0 {
+		cfg.ProxyRequestHeaders = fc.ProxyRequestHeaders
+	}
+	if len(fc.ProxyResponseHeaders) > 0 {
+		cfg.ProxyResponseHeaders = fc.ProxyResponseHeaders
+	}
+	if len(fc.ProxyBasicAuthUsers) > 0 {
+		cfg.ProxyBasicAuthUsers = fc.ProxyBasicAuthUsers
+	}
+	if len(fc.Sites) > 0 {
+		cfg.Sites = fc.Sites
+	}
+	if fc.TunnelProvider != "" {
+		cfg.Tunnel.Provider = fc.TunnelProvider
+	}
+
+	return nil
+}
+
+// mergeEnv overlays NSM_* environment variables onto cfg. It's the layer
+// between a saved config file and cmd's flags: see Load.
+func mergeEnv(cfg *Config) {
+	if v := os.Getenv("NSM_PROJECT_TYPE"); v != "" {
+		cfg.ProjectType = ProjectType(v)
+	}
+	if v := os.Getenv("NSM_DOMAIN"); v != "" {
+		cfg.Domain = v
+	}
+	if v := os.Getenv("NSM_COMMAND"); v != "" {
+		cfg.Command = v
+	}
+	if v := os.Getenv("NSM_STATIC_DIR"); v != "" {
+		cfg.StaticDir = v
+	}
+	if v, ok := envBool("NSM_STATIC_SPA"); ok {
+		cfg.StaticSPA = v
+	}
+	if v, ok := envBool("NSM_STATIC_DIR_LISTING"); ok {
+		cfg.StaticDirListing = v
+	}
+	if v := os.Getenv("NSM_STATIC_404"); v != "" {
+		cfg.Static404 = v
+	}
+	if v, ok := envInt("NSM_HTTP_PORT"); ok {
+		cfg.HTTPPort = v
+	}
+	if v, ok := envInt("NSM_HTTPS_PORT"); ok {
+		cfg.HTTPSPort = v
+	}
+	if v, ok := envBool("NSM_ENABLE_HTTPS"); ok {
+		cfg.EnableHTTPS = v
+	}
+	if v, ok := envBool("NSM_ENABLE_DNS"); ok {
+		cfg.EnableDNS = v
+	}
+	if v, ok := envBool("NSM_ENABLE_PROXY"); ok {
+		cfg.EnableProxy = v
+	}
+	if v, ok := envBool("NSM_AUTO_OPEN"); ok {
+		cfg.AutoOpen = v
+	}
+	if v := os.Getenv("NSM_CERT_ISSUER"); v != "" {
+		cfg.CertIssuer = v
+	}
+	if v := os.Getenv("NSM_ACME_DIRECTORY_URL"); v != "" {
+		cfg.ACMEDirectoryURL = v
+	}
+	if v := os.Getenv("NSM_ACME_EMAIL"); v != "" {
+		cfg.ACMEEmail = v
+	}
+	if v := os.Getenv("NSM_ACME_CHALLENGE"); v != "" {
+		cfg.ACMEChallenge = v
+	}
+	if v := os.Getenv("NSM_DNS_PROVIDER"); v != "" {
+		cfg.DNSProvider = v
+	}
+	if v := os.Getenv("NSM_UPSTREAM_DNS"); v != "" {
+		cfg.UpstreamDNS = strings.Split(v, ",")
+	}
+	if v := os.Getenv("NSM_BOOTSTRAP_DNS"); v != "" {
+		cfg.BootstrapDNS = v
+	}
+	if v := os.Getenv("NSM_LOG_FORMAT"); v != "" {
+		cfg.LogFormat = v
+	}
+	if v := os.Getenv("NSM_METRICS_ADDR"); v != "" {
+		cfg.MetricsAddr = v
+	}
+	if v := os.Getenv("NSM_ACCESS_LOG"); v != "" {
+		cfg.AccessLogPath = v
+	}
+	if v := os.Getenv("NSM_ACCESS_LOG_FORMAT"); v != "" {
+		cfg.AccessLogFormat = v
+	}
+	if v, ok := envInt("NSM_ACCESS_LOG_MAX_SIZE_MB"); ok {
+		cfg.AccessLogMaxSizeMB = v
+	}
+	if v, ok := envInt("NSM_ACCESS_LOG_MAX_BACKUPS"); ok {
+		cfg.AccessLogMaxBackups = v
+	}
+	if v, ok := envInt("NSM_ACCESS_LOG_MAX_AGE_DAYS"); ok {
+		cfg.AccessLogMaxAgeDays = v
+	}
+	if v := os.Getenv("NSM_DIAG_ADDR"); v != "" {
+		cfg.DiagAddr = v
+	}
+	if v := os.Getenv("NSM_CTL_ADDR"); v != "" {
+		cfg.CtlAddr = v
+	}
+	if v := os.Getenv("NSM_TEARDOWN_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			cfg.TeardownTimeout = d
+		}
+	}
+	if v := os.Getenv("NSM_EXTRA_UPSTREAMS"); v != "" {
+		cfg.ExtraUpstreams = strings.Split(v, ",")
+	}
+	if v := os.Getenv("NSM_UPSTREAM_POLICY"); v != "" {
+		cfg.UpstreamPolicy = v
+	}
+	if v, ok := envBool("NSM_HTTP_PROXY"); ok {
+		cfg.HTTPProxy = v
+	}
+	if v := os.Getenv("NSM_HTTP_PROXY_ALLOW_HOSTS"); v != "" {
+		cfg.HTTPProxyAllowHosts = strings.Split(v, ",")
+	}
+	if v := os.Getenv("NSM_HTTP_PROXY_DENY_HOSTS"); v != "" {
+		cfg.HTTPProxyDenyHosts = strings.Split(v, ",")
+	}
+	if v := os.Getenv("NSM_PROXY_MIDDLEWARES"); v != "" {
+		cfg.ProxyMiddlewares = strings.Split(v, ",")
+	}
+	if v := os.Getenv("NSM_PROXY_INJECT_SCRIPT"); v != "" {
+		cfg.ProxyInjectScript = v
+	}
+	if v := os.Getenv("NSM_PROXY_REQUEST_HEADERS"); v != "" {
+		cfg.ProxyRequestHeaders = strings.Split(v, ",")
+	}
+	if v := os.Getenv("NSM_PROXY_RESPONSE_HEADERS"); v != "" {
+		cfg.ProxyResponseHeaders = strings.Split(v, ",")
+	}
+	if v := os.Getenv("NSM_PROXY_BASIC_AUTH_USERS"); v != "" {
+		cfg.ProxyBasicAuthUsers = strings.Split(v, ",")
+	}
+	if v := os.Getenv("NSM_TUNNEL_PROVIDER"); v != "" {
+		cfg.Tunnel.Provider = v
+	}
+}
+
+// envInt reads name as an int, reporting whether it was set and valid.
+func envInt(name string) (int, bool) {
+	v := os.Getenv(name)
+	if v == "" {
+		return 0, false
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// envBool reads name as a bool ("1"/"true" or "0"/"false"), reporting
+// whether it was set and valid. This is the same 0/1 convention
+// platform.OpenBrowser already honors for NSM_AUTO_OPEN.
+func envBool(name string) (bool, bool) {
+	v := os.Getenv(name)
+	if v == "" {
+		return false, false
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, false
+	}
+	return b, true
+}
+
+// Save writes cfg's persistable fields to the user-wide config file at
+// globalConfigPath, creating ~/.nsm if needed. It's the reverse of Load's
+// file layer, used by `nsm config set` to persist a resolved override.
+func Save(cfg *Config) error {
+	path := globalConfigPath()
+	if path == "" {
+		return fmt.Errorf("determine global config path: home directory unavailable")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create %s: %w", filepath.Dir(path), err)
+	}
+
+	fc := fileConfig{
+		ProjectType:         cfg.ProjectType,
+		Domain:              cfg.Domain,
+		Command:             cfg.Command,
+		StaticDir:           cfg.StaticDir,
+		StaticSPA:           &cfg.StaticSPA,
+		StaticDirListing:    &cfg.StaticDirListing,
+		Static404:           cfg.Static404,
+		HTTPPort:            cfg.HTTPPort,
+		HTTPSPort:           cfg.HTTPSPort,
+		UsePort443:          &cfg.UsePort443,
+		EnableHTTPS:         &cfg.EnableHTTPS,
+		EnableDNS:           &cfg.EnableDNS,
+		EnableProxy:         &cfg.EnableProxy,
+		AutoOpen:            &cfg.AutoOpen,
+		CertIssuer:          cfg.CertIssuer,
+		ACMEDirectoryURL:    cfg.ACMEDirectoryURL,
+		ACMEEmail:           cfg.ACMEEmail,
+		ACMEChallenge:       cfg.ACMEChallenge,
+		DNSProvider:         cfg.DNSProvider,
+		UpstreamDNS:         cfg.UpstreamDNS,
+		BootstrapDNS:        cfg.BootstrapDNS,
+		LogFormat:           cfg.LogFormat,
+		RawOutput:           &cfg.RawOutput,
+		MetricsAddr:         cfg.MetricsAddr,
+		AccessLogPath:       cfg.AccessLogPath,
+		AccessLogFormat:     cfg.AccessLogFormat,
+		AccessLogMaxSizeMB:  cfg.AccessLogMaxSizeMB,
+		AccessLogMaxBackups: cfg.AccessLogMaxBackups,
+		AccessLogMaxAgeDays: cfg.AccessLogMaxAgeDays,
+		DiagAddr:            cfg.DiagAddr,
+		CtlAddr:             cfg.CtlAddr,
+		TeardownTimeout:     cfg.TeardownTimeout.String(),
+		ExtraUpstreams:      cfg.ExtraUpstreams,
+		UpstreamPolicy:      cfg.UpstreamPolicy,
+
+		HTTPProxy:           &cfg.HTTPProxy,
+		HTTPProxyAllowHosts: cfg.HTTPProxyAllowHosts,
+		HTTPProxyDenyHosts:  cfg.HTTPProxyDenyHosts,
+
+		ProxyMiddlewares:     cfg.ProxyMiddlewares,
+		ProxyInjectScript:    cfg.ProxyInjectScript,
+		ProxyRequestHeaders:  cfg.ProxyRequestHeaders,
+		ProxyResponseHeaders: cfg.ProxyResponseHeaders,
+		ProxyBasicAuthUsers:  cfg.ProxyBasicAuthUsers,
+
+		Sites: cfg.Sites,
+
+		TunnelProvider: cfg.Tunnel.Provider,
+	}
+
+	data, err := yaml.Marshal(fc)
+	if err != nil {
+		return fmt.Errorf("marshal config: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+
+	return nil
+}
+
 func (c *Config) Validate() error {
 	if c.ProjectType == "" {
 		return fmt.Errorf("project type is required")
 	}
 
-	if c.Command == "" {
+	if c.ProjectType == ProjectTypeStatic {
+		if c.StaticDir == "" {
+			return fmt.Errorf("static directory is required for project type %q", ProjectTypeStatic)
+		}
+	} else if c.Command == "" {
 		return fmt.Errorf("development command is required")
 	}
 
+	if c.HTTPPort != 0 && !utils.IsValidPort(c.HTTPPort) {
+		return fmt.Errorf("invalid http port %d: must be between 1 and 65535", c.HTTPPort)
+	}
+	if c.HTTPSPort != 0 && !utils.IsValidPort(c.HTTPSPort) {
+		return fmt.Errorf("invalid https port %d: must be between 1 and 65535", c.HTTPSPort)
+	}
+	if c.HTTPSPort != 0 && utils.IsPrivilegedPort(c.HTTPSPort) {
+		logger.Warn("Requested HTTPS port is privileged, binding may require elevated permissions", "port", c.HTTPSPort)
+	}
+
+	seenDomains := map[string]bool{c.Domain: true}
+	for i, site := range c.Sites {
+		if site.Domain == "" {
+			return fmt.Errorf("sites[%d]: domain is required", i)
+		}
+		if site.Command == "" {
+			return fmt.Errorf("sites[%d]: command is required", i)
+		}
+		if seenDomains[site.Domain] {
+			return fmt.Errorf("sites[%d]: domain %q is already in use", i, site.Domain)
+		}
+		seenDomains[site.Domain] = true
+	}
+
 	return nil
 }
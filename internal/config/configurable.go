@@ -0,0 +1,15 @@
+package config
+
+import "github.com/kashifsb/nsm/pkg/logger"
+
+// Configurable is implemented by subsystems that can describe their own
+// configuration for structured logging and reload diffing (see
+// logger.LogStructuredConfig). It's an alias for logger.Configurable: the
+// logger package owns the interface, since LogConfig is defined in terms
+// of logger.ContextLogger and config already depends on logger, so this
+// avoids an import cycle while letting subsystems spell it config.Configurable.
+type Configurable = logger.Configurable
+
+// Composite is implemented by a Configurable made up of other Configurable
+// components, so LogStructuredConfig can walk into them.
+type Composite = logger.Composite
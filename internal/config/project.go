@@ -1,103 +1,432 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+
+	"gopkg.in/yaml.v3"
 )
 
+// DetectionResult is one project-type match a Detector found, with the
+// evidence that triggered it, a confidence Score so callers can pick
+// between competing matches, and Subdir - the directory it was found in,
+// relative to the directory the Detector walked ("." for the root itself).
+type DetectionResult struct {
+	ProjectType ProjectType
+	Score       int
+	Evidence    []string
+	Subdir      string
+}
+
+// Detector finds project types present under dir, including in
+// subdirectories, so a polyglot monorepo (e.g. a Go backend at the root
+// with a Vite frontend under web/) is reported as more than one result.
+type Detector interface {
+	Detect(dir string) []DetectionResult
+}
+
+// maxDetectDepth bounds how far DefaultDetector descends below the root
+// directory it's asked to scan.
+const maxDetectDepth = 2
+
+// DefaultDetector is NSM's built-in Detector: it runs every typeCheck
+// against the root directory and, up to MaxDepth levels deep, its
+// subdirectories, keeping the highest-scoring match per directory.
+type DefaultDetector struct {
+	MaxDepth int
+}
+
+// NewDetector returns a DefaultDetector with NSM's standard scan depth.
+func NewDetector() *DefaultDetector {
+	return &DefaultDetector{MaxDepth: maxDetectDepth}
+}
+
+func (d *DefaultDetector) Detect(dir string) []DetectionResult {
+	var results []DetectionResult
+	d.walk(dir, dir, 0, &results)
+	return results
+}
+
+func (d *DefaultDetector) walk(root, dir string, depth int, results *[]DetectionResult) {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		rel = "."
+	}
+
+	if best := detectBest(dir); best != nil {
+		best.Subdir = rel
+		*results = append(*results, *best)
+	}
+
+	if depth >= d.MaxDepth {
+		return
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() && !skipDetectDir(entry.Name()) {
+			d.walk(root, filepath.Join(dir, entry.Name()), depth+1, results)
+		}
+	}
+}
+
+// skipDetectDir reports whether name should be excluded from DefaultDetector's
+// walk: dependency/build/VCS directories and anything hidden.
+func skipDetectDir(name string) bool {
+	switch name {
+	case "node_modules", "vendor", "dist", "build", "target", ".next", ".venv", "venv", "__pycache__", "_build", "deps":
+		return true
+	}
+	return strings.HasPrefix(name, ".")
+}
+
+// typeCheck is one built-in detector: it inspects a single directory
+// (without descending further) and reports how strongly it matches
+// ProjectType. A zero score means no match.
+type typeCheck struct {
+	ProjectType ProjectType
+	check       func(dir string) (score int, evidence []string)
+}
+
+// builtinChecks is the set of typeChecks run against every directory
+// DefaultDetector walks, most framework-specific first so e.g. Next.js
+// outscores the plain React/Node check it would otherwise also match.
+var builtinChecks = []typeCheck{
+	{ProjectTypeNext, checkNext},
+	{ProjectTypeAstro, checkAstro},
+	{ProjectTypeSvelteKit, checkSvelteKit},
+	{ProjectTypeRemix, checkRemix},
+	{ProjectTypeVite, checkVite},
+	{ProjectTypeDeno, checkDeno},
+	{ProjectTypeBun, checkBun},
+	{ProjectTypeReact, checkReact},
+	{ProjectTypeNode, checkNode},
+	{ProjectTypeGo, checkGo},
+	{ProjectTypeRust, checkRust},
+	{ProjectTypeDjango, checkDjango},
+	{ProjectTypeFlask, checkFlask},
+	{ProjectTypeFastAPI, checkFastAPI},
+	{ProjectTypePython, checkPython},
+	{ProjectTypeJava, checkJava},
+	{ProjectTypeDotNet, checkDotNet},
+	{ProjectTypePhoenix, checkPhoenix},
+}
+
+// detectBest runs every built-in check against dir and returns the
+// highest-scoring match, or nil if nothing matched.
+func detectBest(dir string) *DetectionResult {
+	var best *DetectionResult
+	for _, c := range builtinChecks {
+		score, evidence := c.check(dir)
+		if score == 0 {
+			continue
+		}
+		if best == nil || score > best.Score {
+			best = &DetectionResult{ProjectType: c.ProjectType, Score: score, Evidence: evidence}
+		}
+	}
+	return best
+}
+
 func detectProjectType(dir string) ProjectType {
-	// Check for specific framework configs first
-	if fileExists(filepath.Join(dir, "next.config.js")) ||
-		fileExists(filepath.Join(dir, "next.config.ts")) {
-		return ProjectTypeNext
+	best := detectBest(dir)
+	if best == nil {
+		return ""
 	}
+	return best.ProjectType
+}
 
-	// Check for Vite
-	viteConfigs := []string{"vite.config.ts", "vite.config.js", "vite.config.mjs"}
-	for _, config := range viteConfigs {
-		if fileExists(filepath.Join(dir, config)) {
-			return ProjectTypeVite
+func checkNext(dir string) (int, []string) {
+	for _, f := range []string{"next.config.js", "next.config.ts", "next.config.mjs"} {
+		if fileExists(filepath.Join(dir, f)) {
+			return 100, []string{f}
 		}
 	}
+	return 0, nil
+}
 
-	// Check package.json for React/Node
-	if fileExists(filepath.Join(dir, "package.json")) {
-		if isReactProject(dir) {
-			return ProjectTypeReact
+func checkVite(dir string) (int, []string) {
+	for _, f := range []string{"vite.config.ts", "vite.config.js", "vite.config.mjs"} {
+		if fileExists(filepath.Join(dir, f)) {
+			return 90, []string{f}
+		}
+	}
+	return 0, nil
+}
+
+func checkAstro(dir string) (int, []string) {
+	for _, f := range []string{"astro.config.mjs", "astro.config.ts", "astro.config.js"} {
+		if fileExists(filepath.Join(dir, f)) {
+			return 90, []string{f}
 		}
-		return ProjectTypeNode
 	}
+	return 0, nil
+}
 
-	// Go detection
-	if fileExists(filepath.Join(dir, "go.mod")) ||
-		fileExists(filepath.Join(dir, "main.go")) {
-		return ProjectTypeGo
+func checkSvelteKit(dir string) (int, []string) {
+	if fileExists(filepath.Join(dir, "svelte.config.js")) && packageJSONContains(dir, "@sveltejs/kit") {
+		return 90, []string{"svelte.config.js", "package.json"}
 	}
+	return 0, nil
+}
+
+func checkRemix(dir string) (int, []string) {
+	for _, f := range []string{"remix.config.js", "remix.config.ts"} {
+		if fileExists(filepath.Join(dir, f)) {
+			return 90, []string{f}
+		}
+	}
+	return 0, nil
+}
+
+func checkDeno(dir string) (int, []string) {
+	for _, f := range []string{"deno.json", "deno.jsonc"} {
+		if fileExists(filepath.Join(dir, f)) {
+			return 90, []string{f}
+		}
+	}
+	return 0, nil
+}
+
+func checkBun(dir string) (int, []string) {
+	if fileExists(filepath.Join(dir, "bun.lockb")) {
+		return 80, []string{"bun.lockb"}
+	}
+	return 0, nil
+}
+
+func checkReact(dir string) (int, []string) {
+	if isReactProject(dir) {
+		return 70, []string{"package.json"}
+	}
+	return 0, nil
+}
 
-	// Rust detection
+func checkNode(dir string) (int, []string) {
+	if fileExists(filepath.Join(dir, "package.json")) {
+		return 40, []string{"package.json"}
+	}
+	return 0, nil
+}
+
+func checkGo(dir string) (int, []string) {
+	if fileExists(filepath.Join(dir, "go.mod")) {
+		return 100, []string{"go.mod"}
+	}
+	if fileExists(filepath.Join(dir, "main.go")) {
+		return 60, []string{"main.go"}
+	}
+	return 0, nil
+}
+
+func checkRust(dir string) (int, []string) {
 	if fileExists(filepath.Join(dir, "Cargo.toml")) {
-		return ProjectTypeRust
+		return 100, []string{"Cargo.toml"}
 	}
+	return 0, nil
+}
+
+func checkDjango(dir string) (int, []string) {
+	if fileExists(filepath.Join(dir, "manage.py")) {
+		return 95, []string{"manage.py"}
+	}
+	return 0, nil
+}
 
-	// Python detection
-	pythonFiles := []string{"requirements.txt", "pyproject.toml", "app.py", "main.py"}
-	for _, file := range pythonFiles {
-		if fileExists(filepath.Join(dir, file)) {
-			return ProjectTypePython
+func checkFlask(dir string) (int, []string) {
+	for _, f := range []string{"app.py", "wsgi.py"} {
+		path := filepath.Join(dir, f)
+		if fileContains(path, "Flask") {
+			return 85, []string{f}
 		}
 	}
+	return 0, nil
+}
 
-	// Java detection
-	if fileExists(filepath.Join(dir, "pom.xml")) ||
-		fileExists(filepath.Join(dir, "build.gradle")) {
-		return ProjectTypeJava
+func checkFastAPI(dir string) (int, []string) {
+	for _, f := range []string{"main.py", "app.py"} {
+		path := filepath.Join(dir, f)
+		if fileContains(path, "FastAPI") {
+			return 85, []string{f}
+		}
 	}
+	return 0, nil
+}
 
-	// .NET detection
+func checkPython(dir string) (int, []string) {
+	for _, f := range []string{"pyproject.toml", "requirements.txt", "app.py", "main.py"} {
+		if fileExists(filepath.Join(dir, f)) {
+			return 50, []string{f}
+		}
+	}
+	return 0, nil
+}
+
+func checkJava(dir string) (int, []string) {
+	for _, f := range []string{"pom.xml", "build.gradle"} {
+		if fileExists(filepath.Join(dir, f)) {
+			return 90, []string{f}
+		}
+	}
+	return 0, nil
+}
+
+func checkDotNet(dir string) (int, []string) {
 	dotnetExtensions := []string{".csproj", ".sln", ".fsproj"}
 	entries, err := os.ReadDir(dir)
-	if err == nil {
-		for _, entry := range entries {
-			for _, ext := range dotnetExtensions {
-				if strings.HasSuffix(entry.Name(), ext) {
-					return ProjectTypeDotNet
-				}
+	if err != nil {
+		return 0, nil
+	}
+
+	for _, entry := range entries {
+		for _, ext := range dotnetExtensions {
+			if strings.HasSuffix(entry.Name(), ext) {
+				return 90, []string{entry.Name()}
 			}
 		}
 	}
+	return 0, nil
+}
 
-	return ""
+func checkPhoenix(dir string) (int, []string) {
+	if fileExists(filepath.Join(dir, "mix.exs")) {
+		return 90, []string{"mix.exs"}
+	}
+	return 0, nil
 }
 
 func getDefaultCommand(projectType ProjectType) string {
 	commands := map[ProjectType]string{
-		ProjectTypeVite:   "npm run dev",
-		ProjectTypeReact:  "npm start",
-		ProjectTypeNext:   "npm run dev",
-		ProjectTypeNode:   "npm run dev",
-		ProjectTypeGo:     "go run .",
-		ProjectTypeRust:   "cargo run",
-		ProjectTypePython: "python app.py",
-		ProjectTypeJava:   "mvn spring-boot:run",
-		ProjectTypeDotNet: "dotnet run",
+		ProjectTypeVite:      "npm run dev",
+		ProjectTypeReact:     "npm start",
+		ProjectTypeNext:      "npm run dev",
+		ProjectTypeNode:      "npm run dev",
+		ProjectTypeGo:        "go run .",
+		ProjectTypeRust:      "cargo run",
+		ProjectTypePython:    "python app.py",
+		ProjectTypeJava:      "mvn spring-boot:run",
+		ProjectTypeDotNet:    "dotnet run",
+		ProjectTypeDeno:      "deno task start",
+		ProjectTypeBun:       "bun run dev",
+		ProjectTypeAstro:     "npm run dev",
+		ProjectTypeSvelteKit: "npm run dev",
+		ProjectTypeRemix:     "npm run dev",
+		ProjectTypeDjango:    "python manage.py runserver",
+		ProjectTypeFlask:     "flask run",
+		ProjectTypeFastAPI:   "uvicorn main:app --reload",
+		ProjectTypePhoenix:   "mix phx.server",
 	}
 
 	return commands[projectType]
 }
 
 func isReactProject(dir string) bool {
-	packagePath := filepath.Join(dir, "package.json")
-	content, err := os.ReadFile(packagePath)
+	return packageJSONContains(dir, "react") && !packageJSONContains(dir, "next")
+}
+
+func packageJSONContains(dir, needle string) bool {
+	content, err := os.ReadFile(filepath.Join(dir, "package.json"))
 	if err != nil {
 		return false
 	}
+	return strings.Contains(string(content), needle)
+}
 
-	contentStr := string(content)
-	return strings.Contains(contentStr, "react") && !strings.Contains(contentStr, "next")
+func fileContains(path, needle string) bool {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	return strings.Contains(string(content), needle)
 }
 
 func fileExists(path string) bool {
 	_, err := os.Stat(path)
 	return err == nil
 }
+
+// Project is one runnable unit DetectAll found within a directory tree -
+// either the root or a subdirectory of a monorepo - with its detected (or
+// .nsm.yaml-overridden) type and start command.
+type Project struct {
+	Name        string
+	Dir         string
+	ProjectType ProjectType
+	Command     string
+}
+
+// OverrideFilename is a project-scoped file that pins ProjectType and/or
+// Command for the directory it lives in, taking precedence over whatever
+// DetectAll's scoring would have guessed.
+const OverrideFilename = ".nsm.yaml"
+
+type projectOverride struct {
+	ProjectType ProjectType `yaml:"type"`
+	Command     string      `yaml:"command"`
+}
+
+func loadOverride(dir string) (*projectOverride, error) {
+	data, err := os.ReadFile(filepath.Join(dir, OverrideFilename))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var override projectOverride
+	if err := yaml.Unmarshal(data, &override); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", OverrideFilename, err)
+	}
+
+	return &override, nil
+}
+
+// DetectAll detects every project under dir - the root and any
+// subdirectories NewDetector finds evidence in - applying each directory's
+// .nsm.yaml override if present, so a monorepo's proxy subsystem can start
+// one dev server per Project concurrently.
+func DetectAll(dir string) ([]Project, error) {
+	results := NewDetector().Detect(dir)
+
+	projects := make([]Project, 0, len(results))
+	for _, r := range results {
+		projectDir := filepath.Join(dir, r.Subdir)
+
+		projectType := r.ProjectType
+		command := getDefaultCommand(projectType)
+
+		override, err := loadOverride(projectDir)
+		if err != nil {
+			return nil, fmt.Errorf("load override for %s: %w", projectDir, err)
+		}
+		if override != nil {
+			if override.ProjectType != "" {
+				projectType = override.ProjectType
+			}
+			if override.Command != "" {
+				command = override.Command
+			}
+		}
+
+		name := r.Subdir
+		if name == "." {
+			name = filepath.Base(dir)
+		}
+
+		projects = append(projects, Project{
+			Name:        name,
+			Dir:         projectDir,
+			ProjectType: projectType,
+			Command:     command,
+		})
+	}
+
+	return projects, nil
+}
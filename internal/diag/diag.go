@@ -0,0 +1,169 @@
+// Package diag exposes a diagnostic HTTP server for headless NSM runs,
+// where there's no TUI attached to inspect progress: /healthz and /readyz
+// mirror pkg/metrics' sidecar, /state dumps the current step/URL/running
+// state as JSON, /logs replays the in-memory log backlog, and
+// /debug/pprof/* exposes the standard Go profiler.
+package diag
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"time"
+
+	"github.com/kashifsb/nsm/internal/log"
+	"github.com/kashifsb/nsm/pkg/health"
+	"github.com/kashifsb/nsm/pkg/logger"
+)
+
+// StepInfo is one subsystem's current status, as reported by
+// app.Orchestrator's lifecycle events.
+type StepInfo struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Details string `json:"details,omitempty"`
+}
+
+// URLInfo mirrors ui.URLInfo for /state, without importing the UI package.
+type URLInfo struct {
+	Primary string `json:"primary,omitempty"`
+	Local   string `json:"local,omitempty"`
+	DevURL  string `json:"dev_url,omitempty"`
+}
+
+// State is the JSON body /state serves: a point-in-time snapshot of what
+// the TUI would otherwise be showing.
+type State struct {
+	Running bool       `json:"running"`
+	Steps   []StepInfo `json:"steps"`
+	URLs    URLInfo    `json:"urls"`
+}
+
+// StateFunc returns the current State; called fresh on every /state
+// request rather than cached, since it's cheap and always needs to be
+// current.
+type StateFunc func() State
+
+// Server exposes /healthz, /readyz, /state, /logs, and /debug/pprof/* on a
+// diagnostic address, so an operator (or a script) can inspect a headless
+// NSM instance running under CI, Docker, systemd, or nohup without
+// attaching a terminal.
+type Server struct {
+	health     *health.Tracker
+	state      StateFunc
+	logs       *log.RingSink
+	httpServer *http.Server
+}
+
+// NewServer returns a Server backed by tracker (for /healthz and /readyz),
+// state (for /state), and logs (for /logs). tracker and logs may be nil, in
+// which case /readyz always reports ready and /logs always returns an
+// empty list.
+func NewServer(tracker *health.Tracker, state StateFunc, logs *log.RingSink) *Server {
+	return &Server{health: tracker, state: state, logs: logs}
+}
+
+// Start binds addr (e.g. ":9091") and serves until Stop is called.
+func (s *Server) Start(addr string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/readyz", s.readyz)
+	mux.HandleFunc("/state", s.handleState)
+	mux.HandleFunc("/logs", s.handleLogs)
+
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	s.httpServer = &http.Server{
+		Addr:    addr,
+		Handler: mux,
+	}
+
+	logger.Info("Starting diagnostic server", "addr", addr)
+
+	go func() {
+		if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Error("Diagnostic server error", "error", err)
+		}
+	}()
+
+	return nil
+}
+
+func (s *Server) readyz(w http.ResponseWriter, r *http.Request) {
+	if s.health != nil && s.health.Overall().State == health.StateError {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprintln(w, "not ready")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintln(w, "ready")
+}
+
+func (s *Server) handleState(w http.ResponseWriter, r *http.Request) {
+	var state State
+	if s.state != nil {
+		state = s.state()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(state); err != nil {
+		logger.Warn("Failed to encode /state response", "error", err)
+	}
+}
+
+// handleLogs serves the in-memory log backlog, optionally filtered by
+// ?level=warn (minimum level, inclusive) and/or ?since=<RFC3339 timestamp>.
+func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request) {
+	minLevel := log.LevelTrace
+	if raw := r.URL.Query().Get("level"); raw != "" {
+		lvl, ok := log.ParseLevel(raw)
+		if !ok {
+			http.Error(w, fmt.Sprintf("unknown level %q", raw), http.StatusBadRequest)
+			return
+		}
+		minLevel = lvl
+	}
+
+	var since time.Time
+	if raw := r.URL.Query().Get("since"); raw != "" {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid since %q: %v", raw, err), http.StatusBadRequest)
+			return
+		}
+		since = t
+	}
+
+	var records []log.Record
+	if s.logs != nil {
+		records = s.logs.Query(minLevel, since)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(records); err != nil {
+		logger.Warn("Failed to encode /logs response", "error", err)
+	}
+}
+
+// Stop gracefully shuts down the diagnostic server.
+func (s *Server) Stop() error {
+	if s.httpServer == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	logger.Info("Stopping diagnostic server")
+	return s.httpServer.Shutdown(ctx)
+}
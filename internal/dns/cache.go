@@ -0,0 +1,92 @@
+package dns
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	miekgdns "github.com/miekg/dns"
+)
+
+type cacheEntry struct {
+	key     string
+	msg     *miekgdns.Msg
+	expires time.Time
+}
+
+// answerCache is a small LRU cache of upstream DNS answers, keyed by
+// question name+type. Each entry expires according to its own answer TTL
+// (or a short negative TTL for NXDOMAIN/failure responses), so popular
+// names stay cached while stale records age out on their own schedule.
+type answerCache struct {
+	mu       sync.Mutex
+	capacity int
+	items    map[string]*list.Element
+	order    *list.List
+}
+
+func newAnswerCache(capacity int) *answerCache {
+	return &answerCache{
+		capacity: capacity,
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+func (c *answerCache) get(key string) (*miekgdns.Msg, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := el.Value.(*cacheEntry)
+	if time.Now().After(entry.expires) {
+		c.order.Remove(el)
+		delete(c.items, key)
+		return nil, false
+	}
+
+	c.order.MoveToFront(el)
+	return entry.msg, true
+}
+
+// has reports whether key is present and unexpired, without promoting it in
+// the LRU order. Used by the query log to record cache hits/misses.
+func (c *answerCache) has(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return false
+	}
+	return time.Now().Before(el.Value.(*cacheEntry).expires)
+}
+
+func (c *answerCache) set(key string, msg *miekgdns.Msg, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		entry := el.Value.(*cacheEntry)
+		entry.msg = msg
+		entry.expires = time.Now().Add(ttl)
+		c.order.MoveToFront(el)
+		return
+	}
+
+	entry := &cacheEntry{key: key, msg: msg, expires: time.Now().Add(ttl)}
+	el := c.order.PushFront(entry)
+	c.items[key] = el
+
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		if oldest != nil {
+			c.order.Remove(oldest)
+			delete(c.items, oldest.Value.(*cacheEntry).key)
+		}
+	}
+}
@@ -0,0 +1,397 @@
+//go:build linux
+
+package dns
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/containernetworking/cni/pkg/skel"
+	cnitypes "github.com/containernetworking/cni/pkg/types"
+	current "github.com/containernetworking/cni/pkg/types/100"
+	cniversion "github.com/containernetworking/cni/pkg/version"
+
+	"github.com/kashifsb/nsm/pkg/logger"
+)
+
+// CNIRuntimeStateDir is where per-network plugin state (conf, hosts, pid)
+// is persisted so concurrent ADD/DEL/CHECK invocations for the same
+// network, possibly from different containers, stay consistent.
+const CNIRuntimeStateDir = "/run/nsm/dns"
+
+// CNIVersions are the CNI spec versions this plugin supports.
+var CNIVersions = cniversion.All
+
+// NetConf is the CNI network configuration this plugin reads as JSON from
+// stdin, modeled on containers/dnsname's NetConf.
+type NetConf struct {
+	cnitypes.NetConf
+
+	DomainName    string   `json:"domainName"`
+	RemoteServers []string `json:"remoteServers,omitempty"`
+
+	RuntimeConfig struct {
+		Aliases map[string][]string `json:"aliases,omitempty"`
+	} `json:"runtimeConfig,omitempty"`
+}
+
+// CmdAdd implements the CNI ADD contract: it registers the container's
+// hostname and any configured aliases into NSM's embedded resolver (via the
+// network's persisted hosts file, which EmbeddedServer.WatchCNIHosts polls)
+// and passes the previous result through unchanged.
+func CmdAdd(args *skel.CmdArgs) error {
+	conf, prevResult, err := parseNetConf(args.StdinData)
+	if err != nil {
+		return err
+	}
+
+	ips := extractIPs(prevResult)
+	if len(ips) == 0 {
+		return fmt.Errorf("dnsname: no IP addresses found in prevResult for container %s", args.ContainerID)
+	}
+
+	names := namesForContainer(args, conf)
+
+	network := conf.Name
+	unlock, err := lockNetworkState(network)
+	if err != nil {
+		return fmt.Errorf("dnsname: lock network state: %w", err)
+	}
+	defer unlock()
+
+	if err := persistNetConf(network, args.StdinData); err != nil {
+		return fmt.Errorf("dnsname: persist network config: %w", err)
+	}
+
+	if err := writeContainerPID(network, args.ContainerID, args.Netns); err != nil {
+		return fmt.Errorf("dnsname: record container: %w", err)
+	}
+
+	if err := addHostsEntries(network, args.ContainerID, names, ips); err != nil {
+		return fmt.Errorf("dnsname: write hosts entries: %w", err)
+	}
+
+	logger.Info("dnsname: registered container", "network", network, "container", args.ContainerID, "names", names, "ips", ipStrings(ips))
+
+	return cnitypes.PrintResult(prevResult, conf.CNIVersion)
+}
+
+// CmdDel implements the CNI DEL contract: it removes the container's
+// entries from the network's hosts file and, if it was the last container
+// on the network, removes the network's state directory entirely.
+func CmdDel(args *skel.CmdArgs) error {
+	conf, _, err := parseNetConf(args.StdinData)
+	if err != nil {
+		return err
+	}
+
+	network := conf.Name
+	unlock, err := lockNetworkState(network)
+	if err != nil {
+		// Nothing to clean up if the state directory never existed.
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("dnsname: lock network state: %w", err)
+	}
+	defer unlock()
+
+	if err := removeHostsEntries(network, args.ContainerID); err != nil {
+		return fmt.Errorf("dnsname: remove hosts entries: %w", err)
+	}
+
+	if err := removeContainerPID(network, args.ContainerID); err != nil {
+		return fmt.Errorf("dnsname: remove container record: %w", err)
+	}
+
+	remaining, err := remainingContainers(network)
+	if err != nil {
+		return fmt.Errorf("dnsname: list remaining containers: %w", err)
+	}
+
+	if len(remaining) == 0 {
+		logger.Info("dnsname: last container left network, cleaning up state", "network", network)
+		if err := os.RemoveAll(networkStateDir(network)); err != nil {
+			return fmt.Errorf("dnsname: remove network state dir: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// CmdCheck implements the CNI CHECK contract: it verifies the container's
+// names are still present in the network's hosts file.
+func CmdCheck(args *skel.CmdArgs) error {
+	conf, prevResult, err := parseNetConf(args.StdinData)
+	if err != nil {
+		return err
+	}
+
+	ips := extractIPs(prevResult)
+	names := namesForContainer(args, conf)
+
+	entries, err := readHostsEntries(conf.Name)
+	if err != nil {
+		return fmt.Errorf("dnsname: read hosts entries: %w", err)
+	}
+
+	for _, name := range names {
+		ip, ok := entries[hostsKey(args.ContainerID, name)]
+		if !ok {
+			return fmt.Errorf("dnsname: expected name %q for container %s not found in network %s", name, args.ContainerID, conf.Name)
+		}
+		if len(ips) > 0 && !containsIP(ips, ip) {
+			return fmt.Errorf("dnsname: name %q resolves to %s, not an address of container %s", name, ip, args.ContainerID)
+		}
+	}
+
+	return nil
+}
+
+func parseNetConf(stdinData []byte) (*NetConf, *current.Result, error) {
+	conf := &NetConf{}
+	if err := json.Unmarshal(stdinData, conf); err != nil {
+		return nil, nil, fmt.Errorf("dnsname: parse network configuration: %w", err)
+	}
+
+	if conf.Name == "" {
+		return nil, nil, fmt.Errorf("dnsname: network configuration missing name")
+	}
+
+	prevResult := &current.Result{CNIVersion: conf.CNIVersion}
+	if conf.RawPrevResult != nil {
+		if err := cnitypes.ParsePrevResult(&conf.NetConf); err != nil {
+			return nil, nil, fmt.Errorf("dnsname: parse prevResult: %w", err)
+		}
+		res, err := current.GetResult(conf.PrevResult)
+		if err != nil {
+			return nil, nil, fmt.Errorf("dnsname: convert prevResult: %w", err)
+		}
+		prevResult = res
+	}
+
+	return conf, prevResult, nil
+}
+
+// namesForContainer returns the container's hostname-derived short name
+// plus any aliases the runtime configured for it.
+func namesForContainer(args *skel.CmdArgs, conf *NetConf) []string {
+	names := []string{shortContainerID(args.ContainerID)}
+	if aliases, ok := conf.RuntimeConfig.Aliases[args.ContainerID]; ok {
+		names = append(names, aliases...)
+	}
+	return names
+}
+
+func shortContainerID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+func extractIPs(result *current.Result) []net.IP {
+	if result == nil {
+		return nil
+	}
+	ips := make([]net.IP, 0, len(result.IPs))
+	for _, ipc := range result.IPs {
+		ips = append(ips, ipc.Address.IP)
+	}
+	return ips
+}
+
+func ipStrings(ips []net.IP) []string {
+	out := make([]string, len(ips))
+	for i, ip := range ips {
+		out[i] = ip.String()
+	}
+	return out
+}
+
+func containsIP(ips []net.IP, ip net.IP) bool {
+	for _, candidate := range ips {
+		if candidate.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func networkStateDir(network string) string {
+	return filepath.Join(CNIRuntimeStateDir, network)
+}
+
+func hostsFilePath(network string) string {
+	return filepath.Join(networkStateDir(network), "hosts")
+}
+
+func pidDir(network string) string {
+	return filepath.Join(networkStateDir(network), "pids")
+}
+
+func confPath(network string) string {
+	return filepath.Join(networkStateDir(network), "conf")
+}
+
+// lockNetworkState creates (if needed) the network's state directory and
+// takes an exclusive flock on its lock file, so concurrent ADD/DEL/CHECK
+// invocations for the same network serialize. The returned func releases
+// the lock.
+func lockNetworkState(network string) (func(), error) {
+	dir := networkStateDir(network)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	lockPath := filepath.Join(dir, ".lock")
+	f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("flock %s: %w", lockPath, err)
+	}
+
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
+
+func hostsKey(containerID, name string) string {
+	return shortContainerID(containerID) + "|" + strings.ToLower(name)
+}
+
+// addHostsEntries appends "ip name container" lines for each name/ip
+// combination, writing to the network's hosts file.
+func addHostsEntries(network, containerID string, names []string, ips []net.IP) error {
+	f, err := os.OpenFile(hostsFilePath(network), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	for _, name := range names {
+		for _, ip := range ips {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", ip.String(), name, shortContainerID(containerID))
+		}
+	}
+	return w.Flush()
+}
+
+// removeHostsEntries rewrites the network's hosts file, dropping every line
+// recorded for containerID.
+func removeHostsEntries(network, containerID string) error {
+	path := hostsFilePath(network)
+	lines, err := readHostsLines(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	short := shortContainerID(containerID)
+	kept := lines[:0]
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 3 && fields[2] == short {
+			continue
+		}
+		kept = append(kept, line)
+	}
+
+	return os.WriteFile(path, []byte(strings.Join(kept, "\n")+"\n"), 0o644)
+}
+
+// readHostsEntries returns a map of "containerID|name" -> registered IP for
+// the network, used by CmdCheck.
+func readHostsEntries(network string) (map[string]net.IP, error) {
+	lines, err := readHostsLines(hostsFilePath(network))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]net.IP{}, nil
+		}
+		return nil, err
+	}
+
+	entries := make(map[string]net.IP, len(lines))
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		ip := net.ParseIP(fields[0])
+		if ip == nil {
+			continue
+		}
+		entries[hostsKey(fields[2], fields[1])] = ip
+	}
+	return entries, nil
+}
+
+func readHostsLines(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+func writeContainerPID(network, containerID, netns string) error {
+	if err := os.MkdirAll(pidDir(network), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(pidDir(network), shortContainerID(containerID)), []byte(netns), 0o644)
+}
+
+func removeContainerPID(network, containerID string) error {
+	err := os.Remove(filepath.Join(pidDir(network), shortContainerID(containerID)))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func remainingContainers(network string) ([]string, error) {
+	entries, err := os.ReadDir(pidDir(network))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(entries))
+	for _, e := range entries {
+		ids = append(ids, e.Name())
+	}
+	return ids, nil
+}
+
+// persistNetConf writes conf's raw bytes into the network's state
+// directory, so a future inspection command (or this plugin's own CHECK)
+// can see what a network was configured with.
+func persistNetConf(network string, stdinData []byte) error {
+	if err := os.MkdirAll(networkStateDir(network), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(confPath(network), stdinData, 0o644)
+}
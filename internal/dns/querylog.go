@@ -0,0 +1,307 @@
+package dns
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"gopkg.in/natefinch/lumberjack.v2"
+
+	"github.com/kashifsb/nsm/pkg/logger"
+)
+
+// QueryLogEntry is one resolved (or forwarded) DNS lookup.
+type QueryLogEntry struct {
+	Timestamp time.Time `json:"timestamp"`
+	QName     string    `json:"qname"`
+	QType     string    `json:"qtype"`
+	Client    string    `json:"client"`
+	Answer    string    `json:"answer"`
+	Upstream  string    `json:"upstream"`
+	LatencyMS int64     `json:"latency_ms"`
+	CacheHit  bool      `json:"cache_hit"`
+}
+
+// QueryLogFilter narrows QueryLog results. A zero value returns the most
+// recent entries with no filtering.
+type QueryLogFilter struct {
+	QName string
+	Since time.Time
+	Limit int
+}
+
+// QueryLogger persists batches of query log entries and serves them back
+// for inspection. Modeled as a separate interface from pkg/logger so the
+// high-volume, structured query log doesn't compete with application
+// logging for format or rotation policy.
+type QueryLogger interface {
+	Insert(entries []QueryLogEntry) error
+	Query(ctx context.Context, filter QueryLogFilter) ([]QueryLogEntry, error)
+	Close() error
+}
+
+// SQLiteQueryLogger stores entries in a local SQLite database.
+type SQLiteQueryLogger struct {
+	db *sql.DB
+}
+
+// NewSQLiteQueryLogger opens (creating if needed) a SQLite database at path
+// and ensures the queries table exists.
+func NewSQLiteQueryLogger(path string) (*SQLiteQueryLogger, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("create query log directory: %w", err)
+	}
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, fmt.Errorf("open query log database: %w", err)
+	}
+
+	schema := `CREATE TABLE IF NOT EXISTS queries (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		timestamp DATETIME NOT NULL,
+		qname TEXT NOT NULL,
+		qtype TEXT NOT NULL,
+		client TEXT,
+		answer TEXT,
+		upstream TEXT,
+		latency_ms INTEGER,
+		cache_hit BOOLEAN
+	)`
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create query log schema: %w", err)
+	}
+
+	return &SQLiteQueryLogger{db: db}, nil
+}
+
+func (l *SQLiteQueryLogger) Insert(entries []QueryLogEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	tx, err := l.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin query log tx: %w", err)
+	}
+
+	stmt, err := tx.Prepare(`INSERT INTO queries
+		(timestamp, qname, qtype, client, answer, upstream, latency_ms, cache_hit)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("prepare query log insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, e := range entries {
+		if _, err := stmt.Exec(e.Timestamp, e.QName, e.QType, e.Client, e.Answer, e.Upstream, e.LatencyMS, e.CacheHit); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("insert query log entry: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (l *SQLiteQueryLogger) Query(ctx context.Context, filter QueryLogFilter) ([]QueryLogEntry, error) {
+	query := "SELECT timestamp, qname, qtype, client, answer, upstream, latency_ms, cache_hit FROM queries WHERE 1=1"
+	var args []interface{}
+
+	if filter.QName != "" {
+		query += " AND qname = ?"
+		args = append(args, filter.QName)
+	}
+	if !filter.Since.IsZero() {
+		query += " AND timestamp >= ?"
+		args = append(args, filter.Since)
+	}
+	query += " ORDER BY timestamp DESC"
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := l.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query log query: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []QueryLogEntry
+	for rows.Next() {
+		var e QueryLogEntry
+		if err := rows.Scan(&e.Timestamp, &e.QName, &e.QType, &e.Client, &e.Answer, &e.Upstream, &e.LatencyMS, &e.CacheHit); err != nil {
+			return nil, fmt.Errorf("scan query log row: %w", err)
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
+
+func (l *SQLiteQueryLogger) Close() error {
+	return l.db.Close()
+}
+
+// JSONLQueryLogger appends entries as newline-delimited JSON, rotated with
+// lumberjack the same way pkg/logger rotates application logs. Query reads
+// from a bounded in-memory ring of recently written entries, since a
+// rotated JSONL file isn't efficiently queryable on disk.
+type JSONLQueryLogger struct {
+	mu        sync.Mutex
+	writer    *lumberjack.Logger
+	recent    []QueryLogEntry
+	maxRecent int
+}
+
+func NewJSONLQueryLogger(path string) *JSONLQueryLogger {
+	return &JSONLQueryLogger{
+		writer: &lumberjack.Logger{
+			Filename:   path,
+			MaxSize:    10, // megabytes
+			MaxAge:     30, // days
+			MaxBackups: 5,
+			Compress:   true,
+		},
+		maxRecent: 1000,
+	}
+}
+
+func (l *JSONLQueryLogger) Insert(entries []QueryLogEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, e := range entries {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return fmt.Errorf("marshal query log entry: %w", err)
+		}
+		if _, err := l.writer.Write(append(data, '\n')); err != nil {
+			return fmt.Errorf("write query log entry: %w", err)
+		}
+		l.recent = append(l.recent, e)
+	}
+
+	if overflow := len(l.recent) - l.maxRecent; overflow > 0 {
+		l.recent = l.recent[overflow:]
+	}
+
+	return nil
+}
+
+func (l *JSONLQueryLogger) Query(ctx context.Context, filter QueryLogFilter) ([]QueryLogEntry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var results []QueryLogEntry
+	for i := len(l.recent) - 1; i >= 0; i-- {
+		e := l.recent[i]
+		if filter.QName != "" && e.QName != filter.QName {
+			continue
+		}
+		if !filter.Since.IsZero() && e.Timestamp.Before(filter.Since) {
+			continue
+		}
+		results = append(results, e)
+		if filter.Limit > 0 && len(results) >= filter.Limit {
+			break
+		}
+	}
+
+	return results, nil
+}
+
+func (l *JSONLQueryLogger) Close() error {
+	return l.writer.Close()
+}
+
+// queryLogPipeline decouples the DNS hot path from backend writes: queries
+// are dropped into a bounded channel and a background goroutine batches
+// them into the backend every batchSize entries or flushInterval, whichever
+// comes first.
+type queryLogPipeline struct {
+	backend       QueryLogger
+	entries       chan QueryLogEntry
+	batchSize     int
+	flushInterval time.Duration
+	done          chan struct{}
+	stopped       chan struct{}
+}
+
+func newQueryLogPipeline(backend QueryLogger, batchSize int, flushInterval time.Duration) *queryLogPipeline {
+	if batchSize <= 0 {
+		batchSize = 20
+	}
+	if flushInterval <= 0 {
+		flushInterval = 5 * time.Second
+	}
+
+	p := &queryLogPipeline{
+		backend:       backend,
+		entries:       make(chan QueryLogEntry, 1000),
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		done:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+// record enqueues entry for the background flusher. It never blocks: if the
+// channel is full, the entry is dropped and counted via a warning log.
+func (p *queryLogPipeline) record(entry QueryLogEntry) {
+	select {
+	case p.entries <- entry:
+	default:
+		logger.Warn("Query log channel full, dropping entry", "qname", entry.QName)
+	}
+}
+
+func (p *queryLogPipeline) run() {
+	defer close(p.stopped)
+
+	ticker := time.NewTicker(p.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]QueryLogEntry, 0, p.batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := p.backend.Insert(batch); err != nil {
+			logger.Warn("Failed to flush query log batch", "error", err, "count", len(batch))
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case entry := <-p.entries:
+			batch = append(batch, entry)
+			if len(batch) >= p.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-p.done:
+			flush()
+			return
+		}
+	}
+}
+
+// stop flushes any buffered entries and waits for the background goroutine
+// to exit.
+func (p *queryLogPipeline) stop() {
+	close(p.done)
+	<-p.stopped
+}
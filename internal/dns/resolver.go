@@ -1,29 +1,79 @@
 package dns
 
 import (
-	"bufio"
 	"context"
 	"fmt"
 	"net"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strings"
 	"time"
 
+	"github.com/kashifsb/nsm/pkg/health"
 	"github.com/kashifsb/nsm/pkg/logger"
 )
 
+// healthCheckInterval is how often Resolver re-verifies DNS resolution once
+// Setup has configured it, reporting the result to the health tracker.
+const healthCheckInterval = 30 * time.Second
+
+// cniPollInterval is how often the embedded server re-reads CNI-published
+// hosts files when ResolverConfig.CNIStateDir is set.
+const cniPollInterval = 5 * time.Second
+
+// embeddedListenAddr is where the in-process DNS server listens. The
+// macOS /etc/resolver/<tld> file and manual setup instructions both point
+// here.
+const embeddedListenAddr = "127.0.0.1:5353"
+
 type Resolver struct {
-	domain     string
-	tld        string
-	configured bool
+	domain      string
+	tld         string
+	configured  bool
+	server      *EmbeddedServer
+	queryLogger QueryLogger
+	health      *health.Tracker
+	healthStop  chan struct{}
+	cniStateDir string
+	cniStop     func()
 }
 
 type ResolverConfig struct {
 	Domain    string
 	EnableDNS bool
+	// Upstreams overrides DefaultUpstreams for the embedded server.
+	Upstreams []string
+
+	// UpstreamDNS, set, takes priority over Upstreams and additionally
+	// allows "tls://" (DNS-over-TLS) and "https://" (DNS-over-HTTPS)
+	// prefixed entries alongside plain "host:port"/"udp://" ones - see
+	// config.Config.UpstreamDNS.
+	UpstreamDNS []string
+	// BootstrapDNS resolves a tls:// or https:// UpstreamDNS entry's own
+	// hostname before it can be used itself. Defaults to
+	// defaultBootstrapDNS when empty.
+	BootstrapDNS string
+
+	// QueryLogPath, if set, enables per-query logging to this file. Empty
+	// disables query logging entirely.
+	QueryLogPath string
+	// QueryLogFormat selects the backend: "sqlite" (default) or "jsonl".
+	QueryLogFormat string
+	// QueryLogBatchSize and QueryLogFlushInterval tune the background
+	// flusher; both fall back to sensible defaults when zero.
+	QueryLogBatchSize     int
+	QueryLogFlushInterval time.Duration
+
+	// Health, if set, receives "dns.listener", "dns.os-resolver-file", and
+	// "dns.upstream" state as Setup/Test run and on periodic re-checks.
+	Health *health.Tracker
+
+	// CNIStateDir, if set, is polled for hosts files written by the
+	// dnsname CNI plugin (see CNIRuntimeStateDir) so container networks can
+	// publish names into this resolver without the daemon and the plugin
+	// sharing a process.
+	CNIStateDir string
 }
 
 func NewResolver(cfg ResolverConfig) *Resolver {
@@ -37,10 +87,150 @@ func NewResolver(cfg ResolverConfig) *Resolver {
 		tld = parts[len(parts)-1]
 	}
 
-	return &Resolver{
-		domain: domain,
-		tld:    tld,
+	upstreams := cfg.UpstreamDNS
+	if len(upstreams) == 0 {
+		upstreams = cfg.Upstreams
+	}
+
+	r := &Resolver{
+		domain:      domain,
+		tld:         tld,
+		server:      NewEmbeddedServer(domain, upstreams, cfg.BootstrapDNS),
+		health:      cfg.Health,
+		cniStateDir: cfg.CNIStateDir,
+	}
+
+	if cfg.QueryLogPath != "" {
+		backend, err := newQueryLoggerBackend(cfg.QueryLogFormat, cfg.QueryLogPath)
+		if err != nil {
+			logger.Warn("Failed to initialize query log, continuing without it", "error", err)
+		} else {
+			r.queryLogger = backend
+			r.server.enableQueryLog(backend, cfg.QueryLogBatchSize, cfg.QueryLogFlushInterval)
+		}
+	}
+
+	return r
+}
+
+// newQueryLoggerBackend constructs the QueryLogger named by format, defaulting
+// to the SQLite backend.
+func newQueryLoggerBackend(format, path string) (QueryLogger, error) {
+	switch format {
+	case "jsonl":
+		return NewJSONLQueryLogger(path), nil
+	case "", "sqlite":
+		return NewSQLiteQueryLogger(path)
+	default:
+		return nil, fmt.Errorf("unknown query log format %q", format)
+	}
+}
+
+// QueryLog returns recent query log entries matching filter. It returns an
+// error if query logging wasn't enabled via ResolverConfig.QueryLogPath.
+func (r *Resolver) QueryLog(ctx context.Context, filter QueryLogFilter) ([]QueryLogEntry, error) {
+	if r.queryLogger == nil {
+		return nil, fmt.Errorf("query logging is not enabled for this resolver")
+	}
+	return r.queryLogger.Query(ctx, filter)
+}
+
+// Register publishes host under the embedded server, so it resolves to ip
+// without an upstream round-trip. Other NSM subsystems (the project
+// runner, a future CNI plugin) call this as services come up.
+func (r *Resolver) Register(host string, ip net.IP) {
+	r.server.Register(host, ip)
+}
+
+// Unregister removes a previously registered host.
+func (r *Resolver) Unregister(host string) {
+	r.server.Unregister(host)
+}
+
+// IsEnabled implements logger.Configurable. Setup skips localhost, so
+// there's nothing meaningful to log or reload until a real domain is set.
+func (r *Resolver) IsEnabled() bool {
+	return r.domain != "" && r.domain != "localhost"
+}
+
+// LogConfig implements logger.Configurable, emitting one line per field
+// instead of the old dump-everything-as-one-blob logger.LogConfig.
+func (r *Resolver) LogConfig(l *logger.ContextLogger) {
+	l.Info("DNS domain", "domain", r.domain, "tld", r.tld)
+	l.Info("DNS query logging", "enabled", r.queryLogger != nil)
+	l.Info("DNS CNI state dir", "cni_state_dir", r.cniStateDir)
+	l.Info("DNS health tracking", "enabled", r.health != nil)
+}
+
+// Components implements logger.Composite: the embedded server is its own
+// Configurable, so LogStructuredConfig logs it as a nested component
+// without Resolver needing to know its internals.
+func (r *Resolver) Components() []logger.Configurable {
+	return []logger.Configurable{r.server}
+}
+
+// Reload applies a changed ResolverConfig to the already-running resolver
+// without restarting it, in response to SIGHUP: new upstreams and a new
+// domain take effect immediately. Query-log and health wiring are left
+// alone, since changing those needs a fresh backend/tracker anyway.
+func (r *Resolver) Reload(cfg ResolverConfig) error {
+	upstreams := cfg.UpstreamDNS
+	if len(upstreams) == 0 {
+		upstreams = cfg.Upstreams
+	}
+
+	if len(upstreams) > 0 && !equalUpstreams(upstreams, r.server.Upstreams()) {
+		logger.Info("Reloading DNS upstreams", "upstreams", upstreams)
+		r.server.SetUpstreams(upstreams)
+	}
+
+	newDomain := cfg.Domain
+	if newDomain == "" {
+		newDomain = "localhost"
+	}
+
+	if newDomain == r.domain {
+		return nil
+	}
+
+	logger.Info("Reloading DNS domain", "old", r.domain, "new", newDomain)
+
+	r.domain = newDomain
+	r.tld = ""
+	if parts := strings.Split(newDomain, "."); len(parts) > 1 {
+		r.tld = parts[len(parts)-1]
+	}
+	r.server.SetDomain(newDomain)
+
+	if r.configured && runtime.GOOS == "darwin" {
+		if err := r.createMacOSResolver(); err != nil {
+			logger.Warn("Failed to update resolver file on reload", "error", err)
+			return fmt.Errorf("update macOS resolver file: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func equalUpstreams(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// reportHealth forwards subsystem state to the configured health.Tracker, if
+// any. It's a no-op otherwise, so callers don't need to nil-check.
+func (r *Resolver) reportHealth(subsystem string, state health.State, err error) {
+	if r.health == nil {
+		return
 	}
+	r.health.Set(subsystem, state, err)
 }
 
 func (r *Resolver) Setup() error {
@@ -51,15 +241,62 @@ func (r *Resolver) Setup() error {
 
 	logger.Info("Setting up DNS resolution", "domain", r.domain)
 
+	if err := r.server.Start(embeddedListenAddr); err != nil {
+		r.reportHealth("dns.listener", health.StateError, err)
+		return fmt.Errorf("start embedded dns server: %w", err)
+	}
+	r.reportHealth("dns.listener", health.StateOK, nil)
+
 	switch runtime.GOOS {
 	case "darwin":
-		return r.setupMacOS()
+		if err := r.createMacOSResolver(); err != nil {
+			logger.Warn("Failed to create resolver file", "error", err)
+			// Continue - the embedded server is already listening, so
+			// anything pointed at 127.0.0.1:5353 manually still works.
+			r.reportHealth("dns.os-resolver-file", health.StateWarning, err)
+		} else {
+			r.reportHealth("dns.os-resolver-file", health.StateOK, nil)
+		}
 	case "linux":
-		return r.setupLinux()
+		logger.Info("Point systemd-resolved or /etc/resolv.conf at the embedded server", "addr", embeddedListenAddr, "tld", r.tld)
 	default:
-		logger.Warn("DNS auto-configuration not supported on this platform")
-		return r.setupManual()
+		r.setupManual()
 	}
+
+	if r.cniStateDir != "" {
+		r.cniStop = r.server.WatchCNIHosts(r.cniStateDir, cniPollInterval)
+	}
+
+	r.configured = true
+	r.startHealthLoop()
+	return nil
+}
+
+// startHealthLoop periodically re-runs Test and reports the result, so the
+// health tracker reflects drift (e.g. something else rewriting
+// /etc/resolver) rather than only the state at Setup time. No-op when no
+// health.Tracker was configured.
+func (r *Resolver) startHealthLoop() {
+	if r.health == nil {
+		return
+	}
+
+	r.healthStop = make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(healthCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := r.Test(); err != nil {
+					logger.Debug("Periodic DNS health check failed", "error", err)
+				}
+			case <-r.healthStop:
+				return
+			}
+		}
+	}()
 }
 
 func (r *Resolver) Cleanup() error {
@@ -69,14 +306,36 @@ func (r *Resolver) Cleanup() error {
 
 	logger.Info("Cleaning up DNS configuration", "domain", r.domain)
 
-	switch runtime.GOOS {
-	case "darwin":
-		return r.cleanupMacOS()
-	case "linux":
-		return r.cleanupLinux()
-	default:
-		return nil
+	if r.healthStop != nil {
+		close(r.healthStop)
+		r.healthStop = nil
 	}
+
+	if r.cniStop != nil {
+		r.cniStop()
+		r.cniStop = nil
+	}
+
+	if err := r.server.Stop(); err != nil {
+		logger.Warn("Failed to stop embedded dns server", "error", err)
+	}
+
+	if r.queryLogger != nil {
+		if err := r.queryLogger.Close(); err != nil {
+			logger.Warn("Failed to close query log", "error", err)
+		}
+	}
+
+	r.configured = false
+	return nil
+}
+
+// Health issues a test query against every configured upstream and
+// reports which ones answered, so setupDNS can surface a misconfigured or
+// unreachable DoH/DoT upstream without failing DNS setup outright - Test,
+// by contrast, only checks that the domain itself resolves to localhost.
+func (r *Resolver) Health() []UpstreamHealth {
+	return r.server.checkUpstreams()
 }
 
 func (r *Resolver) Test() error {
@@ -102,87 +361,33 @@ func (r *Resolver) Test() error {
 
 	addrs, err := resolver.LookupHost(ctx, r.domain)
 	if err != nil {
-		return fmt.Errorf("DNS lookup failed: %w", err)
+		wrapped := fmt.Errorf("DNS lookup failed: %w", err)
+		r.reportHealth("dns.upstream", health.StateError, wrapped)
+		return wrapped
 	}
 
 	// Check if resolves to localhost
 	for _, addr := range addrs {
 		if addr == "127.0.0.1" || addr == "::1" {
 			logger.Debug("DNS resolution working", "domain", r.domain, "ip", addr)
+			r.reportHealth("dns.upstream", health.StateOK, nil)
 			return nil
 		}
 	}
 
-	return fmt.Errorf("domain resolves to %v instead of localhost", addrs)
+	err = fmt.Errorf("domain resolves to %v instead of localhost", addrs)
+	r.reportHealth("dns.upstream", health.StateError, err)
+	return err
 }
 
-// macOS implementation
-func (r *Resolver) setupMacOS() error {
-	// First ensure dnsmasq is available and configured
-	if err := r.ensureDnsmasq(); err != nil {
-		return fmt.Errorf("dnsmasq setup failed: %w", err)
-	}
-
-	// Create resolver file for the TLD
-	if err := r.createMacOSResolver(); err != nil {
-		logger.Warn("Failed to create resolver file", "error", err)
-		// Continue without resolver file - dnsmasq might still work
-	}
-
-	// Add domain to dnsmasq
-	if err := r.addDnsmasqEntry(); err != nil {
-		return fmt.Errorf("failed to add dnsmasq entry: %w", err)
-	}
-
-	// Restart dnsmasq
-	if err := r.restartDnsmasq(); err != nil {
-		logger.Warn("Failed to restart dnsmasq", "error", err)
-	}
-
-	r.configured = true
-	return nil
-}
-
-func (r *Resolver) setupLinux() error {
-	// Try systemd-resolved first
-	if r.hasSystemdResolved() {
-		return r.setupSystemdResolved()
-	}
-
-	// Fall back to dnsmasq
-	return r.ensureDnsmasq()
-}
-
-func (r *Resolver) setupManual() error {
+func (r *Resolver) setupManual() {
 	logger.Info("Manual DNS setup required")
 	logger.Info("Add this line to your /etc/hosts file:")
 	logger.Info(fmt.Sprintf("127.0.0.1 %s", r.domain))
-	return nil
-}
-
-func (r *Resolver) ensureDnsmasq() error {
-	// Check if dnsmasq is installed
-	if _, err := exec.LookPath("dnsmasq"); err != nil {
-		return fmt.Errorf("dnsmasq not installed: %w", err)
-	}
-
-	// Get dnsmasq configuration path
-	configPath := r.getDnsmasqConfigPath()
-	hostsPath := r.getDnsmasqHostsPath()
-
-	// Ensure configuration exists
-	if err := r.ensureDnsmasqConfig(configPath, hostsPath); err != nil {
-		return fmt.Errorf("dnsmasq config: %w", err)
-	}
-
-	// Ensure hosts file exists
-	if err := r.ensureDnsmasqHosts(hostsPath); err != nil {
-		return fmt.Errorf("dnsmasq hosts: %w", err)
-	}
-
-	return nil
 }
 
+// createMacOSResolver points /etc/resolver/<tld> at the embedded server so
+// macOS routes queries for the TLD there instead of the system resolver.
 func (r *Resolver) createMacOSResolver() error {
 	resolverDir := "/etc/resolver"
 	resolverFile := filepath.Join(resolverDir, r.tld)
@@ -210,211 +415,3 @@ func (r *Resolver) createMacOSResolver() error {
 	logger.Info("Created DNS resolver file", "file", resolverFile)
 	return nil
 }
-
-func (r *Resolver) addDnsmasqEntry() error {
-	hostsPath := r.getDnsmasqHostsPath()
-	entry := fmt.Sprintf("127.0.0.1 %s", r.domain)
-
-	// Check if entry already exists
-	if r.entryExists(hostsPath, entry) {
-		logger.Debug("DNS entry already exists", "entry", entry)
-		return nil
-	}
-
-	// Add entry
-	file, err := os.OpenFile(hostsPath, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0o644)
-	if err != nil {
-		return fmt.Errorf("open hosts file: %w", err)
-	}
-	defer file.Close()
-
-	if _, err := file.WriteString(entry + "\n"); err != nil {
-		return fmt.Errorf("write hosts entry: %w", err)
-	}
-
-	logger.Debug("Added DNS entry", "entry", entry)
-	return nil
-}
-
-func (r *Resolver) restartDnsmasq() error {
-	// Try brew services first (macOS)
-	if runtime.GOOS == "darwin" {
-		cmd := exec.Command("brew", "services", "restart", "dnsmasq")
-		if err := cmd.Run(); err == nil {
-			logger.Debug("Restarted dnsmasq via brew services")
-			return nil
-		}
-	}
-
-	// Try systemctl (Linux)
-	cmd := exec.Command("sudo", "systemctl", "restart", "dnsmasq")
-	if err := cmd.Run(); err == nil {
-		logger.Debug("Restarted dnsmasq via systemctl")
-		return nil
-	}
-
-	// Try service command
-	cmd = exec.Command("sudo", "service", "dnsmasq", "restart")
-	if err := cmd.Run(); err == nil {
-		logger.Debug("Restarted dnsmasq via service")
-		return nil
-	}
-
-	return fmt.Errorf("failed to restart dnsmasq")
-}
-
-func (r *Resolver) getDnsmasqConfigPath() string {
-	if runtime.GOOS == "darwin" {
-		if brewPrefix := r.getBrewPrefix(); brewPrefix != "" {
-			return filepath.Join(brewPrefix, "etc", "dnsmasq.conf")
-		}
-	}
-	return "/etc/dnsmasq.conf"
-}
-
-func (r *Resolver) getDnsmasqHostsPath() string {
-	if runtime.GOOS == "darwin" {
-		if brewPrefix := r.getBrewPrefix(); brewPrefix != "" {
-			return filepath.Join(brewPrefix, "etc", "dnsmasq.hosts")
-		}
-	}
-	return "/etc/dnsmasq.hosts"
-}
-
-func (r *Resolver) getBrewPrefix() string {
-	cmd := exec.Command("brew", "--prefix")
-	output, err := cmd.Output()
-	if err != nil {
-		return ""
-	}
-	return strings.TrimSpace(string(output))
-}
-
-func (r *Resolver) ensureDnsmasqConfig(configPath, hostsPath string) error {
-	config := fmt.Sprintf(`# NSM dnsmasq configuration
-port=5353
-listen-address=127.0.0.1
-bind-interfaces
-
-# Handle local development domains
-local=/dev/
-local=/test/
-local=/local/
-local=/app/
-
-# Additional hosts file
-addn-hosts=%s
-
-# Upstream DNS servers
-server=1.1.1.1
-server=1.0.0.1
-server=8.8.8.8
-
-# Cache settings
-cache-size=1000
-neg-ttl=60
-
-# Don't read /etc/hosts
-no-hosts
-
-# Don't poll /etc/resolv.conf
-no-poll
-`, hostsPath)
-
-	// Check if config file exists
-	if _, err := os.Stat(configPath); err == nil {
-		// Config exists, check if it contains our configuration
-		content, err := os.ReadFile(configPath)
-		if err == nil && strings.Contains(string(content), "port=5353") {
-			logger.Debug("dnsmasq config already contains NSM configuration")
-			return nil
-		}
-	}
-
-	// Write configuration
-	if err := os.WriteFile(configPath, []byte(config), 0o644); err != nil {
-		return fmt.Errorf("write dnsmasq config: %w", err)
-	}
-
-	logger.Info("Created dnsmasq configuration", "file", configPath)
-	return nil
-}
-
-func (r *Resolver) ensureDnsmasqHosts(hostsPath string) error {
-	if _, err := os.Stat(hostsPath); err == nil {
-		return nil // File already exists
-	}
-
-	initialContent := `# NSM dnsmasq hosts file
-# Development domains will be added here automatically
-127.0.0.1 localhost
-`
-
-	if err := os.WriteFile(hostsPath, []byte(initialContent), 0o644); err != nil {
-		return fmt.Errorf("create dnsmasq hosts file: %w", err)
-	}
-
-	logger.Info("Created dnsmasq hosts file", "file", hostsPath)
-	return nil
-}
-
-func (r *Resolver) entryExists(hostsPath, entry string) bool {
-	file, err := os.Open(hostsPath)
-	if err != nil {
-		return false
-	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == entry {
-			return true
-		}
-	}
-
-	return false
-}
-
-func (r *Resolver) hasSystemdResolved() bool {
-	_, err := exec.LookPath("systemd-resolve")
-	return err == nil
-}
-
-func (r *Resolver) setupSystemdResolved() error {
-	// This would implement systemd-resolved configuration
-	// For now, fall back to dnsmasq
-	return r.ensureDnsmasq()
-}
-
-// Cleanup methods
-func (r *Resolver) cleanupMacOS() error {
-	hostsPath := r.getDnsmasqHostsPath()
-	entry := fmt.Sprintf("127.0.0.1 %s", r.domain)
-
-	return r.removeEntryFromFile(hostsPath, entry)
-}
-
-func (r *Resolver) cleanupLinux() error {
-	// Similar cleanup for Linux
-	return r.cleanupMacOS()
-}
-
-func (r *Resolver) removeEntryFromFile(filePath, entry string) error {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	var lines []string
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.TrimSpace(line) != entry {
-			lines = append(lines, line)
-		}
-	}
-
-	return os.WriteFile(filePath, []byte(strings.Join(lines, "\n")+"\n"), 0o644)
-}
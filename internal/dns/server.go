@@ -0,0 +1,504 @@
+package dns
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	miekgdns "github.com/miekg/dns"
+
+	nsmlog "github.com/kashifsb/nsm/internal/log"
+	"github.com/kashifsb/nsm/pkg/logger"
+)
+
+// trace is the "dns" facet, enabled via NSM_TRACE=dns for per-query detail
+// that would otherwise drown out the rest of the app's logging.
+var trace = nsmlog.Facet("dns")
+
+// DefaultUpstreams are forwarded to, in order, for any query the embedded
+// server doesn't own itself.
+var DefaultUpstreams = []string{"1.1.1.1:53", "8.8.8.8:53"}
+
+// EmbeddedServer answers A/AAAA for its configured domain and any
+// dynamically registered host with 127.0.0.1/::1, forwarding every other
+// query to upstreams with an in-process cache. It replaces the external
+// dnsmasq process this package used to shell out to.
+type EmbeddedServer struct {
+	mu        sync.RWMutex
+	domain    string
+	hosts     map[string]net.IP
+	upstreams []string
+	targets   []*upstreamTarget
+	bootstrap string
+	cache     *answerCache
+	queryLog  *queryLogPipeline
+
+	udpServer *miekgdns.Server
+	tcpServer *miekgdns.Server
+}
+
+// NewEmbeddedServer builds a server authoritative for domain. An empty
+// upstreams list falls back to DefaultUpstreams. Each upstream may be a
+// bare "host:port" (plain UDP), or prefixed "udp://", "tls://", or
+// "https://" for an encrypted transport; bootstrap resolves the latter
+// two's own hostname (see ResolverConfig.BootstrapDNS) and defaults to
+// defaultBootstrapDNS when empty.
+func NewEmbeddedServer(domain string, upstreams []string, bootstrap string) *EmbeddedServer {
+	if len(upstreams) == 0 {
+		upstreams = DefaultUpstreams
+	}
+
+	s := &EmbeddedServer{
+		domain:    normalizeHost(domain),
+		hosts:     make(map[string]net.IP),
+		bootstrap: bootstrap,
+		cache:     newAnswerCache(1000),
+	}
+	s.setUpstreamsLocked(upstreams)
+	return s
+}
+
+// Register publishes host (a bare or fully-qualified name) so A/AAAA
+// queries for it resolve to ip without an upstream round-trip. Safe to call
+// while the server is running.
+func (s *EmbeddedServer) Register(host string, ip net.IP) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hosts[normalizeHost(host)] = ip
+	logger.Debug("Registered DNS host", "host", host, "ip", ip.String())
+}
+
+// Unregister removes a previously registered host. It is not an error to
+// unregister a host that was never registered.
+func (s *EmbeddedServer) Unregister(host string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.hosts, normalizeHost(host))
+	logger.Debug("Unregistered DNS host", "host", host)
+}
+
+// Upstreams returns the server's current forwarding upstreams.
+func (s *EmbeddedServer) Upstreams() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]string(nil), s.upstreams...)
+}
+
+// SetUpstreams replaces the upstreams used for queries the server doesn't
+// own itself. Safe to call while the server is running (e.g. from a SIGHUP
+// reload).
+func (s *EmbeddedServer) SetUpstreams(upstreams []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.setUpstreamsLocked(upstreams)
+}
+
+// setUpstreamsLocked parses each upstream string into an upstreamTarget,
+// skipping (and logging) one that fails to parse rather than failing the
+// whole server over a single typo'd entry. Callers must hold s.mu.
+func (s *EmbeddedServer) setUpstreamsLocked(upstreams []string) {
+	targets := make([]*upstreamTarget, 0, len(upstreams))
+	for _, raw := range upstreams {
+		target, err := parseUpstream(raw, s.bootstrap)
+		if err != nil {
+			logger.Warn("Skipping invalid DNS upstream", "upstream", raw, "error", err)
+			continue
+		}
+		targets = append(targets, target)
+	}
+
+	s.upstreams = upstreams
+	s.targets = targets
+}
+
+// SetDomain changes the domain the server answers authoritatively for.
+// Safe to call while the server is running.
+func (s *EmbeddedServer) SetDomain(domain string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.domain = normalizeHost(domain)
+}
+
+// IsEnabled implements logger.Configurable: the server has something worth
+// logging once it's listening.
+func (s *EmbeddedServer) IsEnabled() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.udpServer != nil
+}
+
+// LogConfig implements logger.Configurable.
+func (s *EmbeddedServer) LogConfig(l *logger.ContextLogger) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	l.Info("Embedded DNS domain", "domain", s.domain)
+	l.Info("Embedded DNS upstreams", "upstreams", s.upstreams)
+	l.Info("Embedded DNS registered hosts", "count", len(s.hosts))
+	l.Info("Embedded DNS query logging", "enabled", s.queryLog != nil)
+}
+
+func (s *EmbeddedServer) lookupLocal(name string) (net.IP, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	normalized := normalizeHost(name)
+	if ip, ok := s.hosts[normalized]; ok {
+		return ip, true
+	}
+
+	if normalized == s.domain || strings.HasSuffix(normalized, "."+s.domain) {
+		return net.ParseIP("127.0.0.1"), true
+	}
+
+	return nil, false
+}
+
+func normalizeHost(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
+
+// enableQueryLog wires backend into the server's query path via a bounded
+// channel and background batch flusher. Safe to call before or after Start.
+func (s *EmbeddedServer) enableQueryLog(backend QueryLogger, batchSize int, flushInterval time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queryLog = newQueryLogPipeline(backend, batchSize, flushInterval)
+}
+
+// disableQueryLog flushes and stops the query log pipeline, if one is
+// active.
+func (s *EmbeddedServer) disableQueryLog() {
+	s.mu.Lock()
+	pipeline := s.queryLog
+	s.queryLog = nil
+	s.mu.Unlock()
+
+	if pipeline != nil {
+		pipeline.stop()
+	}
+}
+
+// WatchCNIHosts polls stateDir (normally CNIRuntimeStateDir) every interval
+// for "<network>/hosts" files written by the dnsname CNI plugin, registering
+// newly-seen name/IP pairs and unregistering ones that have disappeared
+// (i.e. the container left and the plugin's DEL rewrote the file). Returns
+// a func that stops the watch.
+func (s *EmbeddedServer) WatchCNIHosts(stateDir string, interval time.Duration) func() {
+	stop := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		known := make(map[string]net.IP)
+		for {
+			select {
+			case <-ticker.C:
+				s.syncCNIHosts(stateDir, known)
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return func() { close(stop) }
+}
+
+// syncCNIHosts reads every network's hosts file under stateDir, reconciling
+// the server's registered hosts against known (the set registered as of the
+// previous poll).
+func (s *EmbeddedServer) syncCNIHosts(stateDir string, known map[string]net.IP) {
+	current := make(map[string]net.IP)
+
+	networks, err := os.ReadDir(stateDir)
+	if err != nil {
+		return
+	}
+
+	for _, network := range networks {
+		if !network.IsDir() {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(stateDir, network.Name(), "hosts"))
+		if err != nil {
+			continue
+		}
+
+		for _, line := range strings.Split(string(data), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) != 3 {
+				continue
+			}
+			ip := net.ParseIP(fields[0])
+			if ip == nil {
+				continue
+			}
+			current[fields[1]] = ip
+		}
+	}
+
+	for name, ip := range current {
+		if existing, ok := known[name]; !ok || !existing.Equal(ip) {
+			s.Register(name, ip)
+		}
+	}
+	for name := range known {
+		if _, ok := current[name]; !ok {
+			s.Unregister(name)
+		}
+	}
+
+	for name := range known {
+		delete(known, name)
+	}
+	for name, ip := range current {
+		known[name] = ip
+	}
+}
+
+// Start listens on addr over both UDP and TCP and begins serving requests
+// in the background.
+func (s *EmbeddedServer) Start(addr string) error {
+	mux := miekgdns.NewServeMux()
+	mux.HandleFunc(".", s.handleQuery)
+
+	s.udpServer = &miekgdns.Server{Addr: addr, Net: "udp", Handler: mux}
+	s.tcpServer = &miekgdns.Server{Addr: addr, Net: "tcp", Handler: mux}
+
+	errCh := make(chan error, 2)
+	go func() { errCh <- s.udpServer.ListenAndServe() }()
+	go func() { errCh <- s.tcpServer.ListenAndServe() }()
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			return fmt.Errorf("listen on %s: %w", addr, err)
+		}
+	case <-time.After(200 * time.Millisecond):
+		// Neither listener reported a startup failure within the grace
+		// window, so assume both are up.
+	}
+
+	logger.Info("Embedded DNS server listening", "addr", addr)
+	return nil
+}
+
+// Stop shuts down both listeners, waiting for in-flight queries to finish.
+func (s *EmbeddedServer) Stop() error {
+	s.disableQueryLog()
+
+	var errs []string
+
+	if s.udpServer != nil {
+		if err := s.udpServer.Shutdown(); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if s.tcpServer != nil {
+		if err := s.tcpServer.Shutdown(); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("shutdown embedded dns server: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (s *EmbeddedServer) handleQuery(w miekgdns.ResponseWriter, r *miekgdns.Msg) {
+	start := time.Now()
+	reply := new(miekgdns.Msg)
+	reply.SetReply(r)
+
+	if len(r.Question) == 0 {
+		w.WriteMsg(reply)
+		return
+	}
+
+	q := r.Question[0]
+	trace.Tracef("query %s %s", q.Name, miekgdns.TypeToString[q.Qtype])
+
+	if q.Qtype == miekgdns.TypeA || q.Qtype == miekgdns.TypeAAAA {
+		if ip, ok := s.lookupLocal(q.Name); ok {
+			trace.Tracef("local answer %s -> %s", q.Name, ip)
+			reply.Authoritative = true
+			if rr := localAnswer(q, ip); rr != nil {
+				reply.Answer = append(reply.Answer, rr)
+			}
+			w.WriteMsg(reply)
+			s.logQuery(w, q, start, ip.String(), "local", false)
+			return
+		}
+	}
+
+	cacheHit := s.cache.has(cacheKey(q))
+	trace.Tracef("cache lookup %s hit=%v", cacheKey(q), cacheHit)
+
+	forwarded, err := s.forward(r)
+	if err != nil {
+		logger.Debug("DNS forward failed", "name", q.Name, "error", err)
+		reply.Rcode = miekgdns.RcodeServerFailure
+		w.WriteMsg(reply)
+		s.logQuery(w, q, start, "", "", cacheHit)
+		return
+	}
+
+	trace.Tracef("forwarded %s -> %s via %s", q.Name, answerSummary(forwarded), strings.Join(s.upstreams, ","))
+	w.WriteMsg(forwarded)
+	s.logQuery(w, q, start, answerSummary(forwarded), strings.Join(s.upstreams, ","), cacheHit)
+}
+
+// logQuery records one resolved query with the active query log pipeline,
+// if any. It is a no-op when query logging isn't enabled.
+func (s *EmbeddedServer) logQuery(w miekgdns.ResponseWriter, q miekgdns.Question, start time.Time, answer, upstream string, cacheHit bool) {
+	s.mu.RLock()
+	pipeline := s.queryLog
+	s.mu.RUnlock()
+
+	if pipeline == nil {
+		return
+	}
+
+	client := ""
+	if w != nil && w.RemoteAddr() != nil {
+		client = w.RemoteAddr().String()
+	}
+
+	pipeline.record(QueryLogEntry{
+		Timestamp: start,
+		QName:     normalizeHost(q.Name),
+		QType:     miekgdns.TypeToString[q.Qtype],
+		Client:    client,
+		Answer:    answer,
+		Upstream:  upstream,
+		LatencyMS: time.Since(start).Milliseconds(),
+		CacheHit:  cacheHit,
+	})
+}
+
+// answerSummary renders a forwarded reply's answer section compactly for
+// the query log, rather than storing the full message.
+func answerSummary(msg *miekgdns.Msg) string {
+	if msg == nil || len(msg.Answer) == 0 {
+		return ""
+	}
+
+	parts := make([]string, 0, len(msg.Answer))
+	for _, rr := range msg.Answer {
+		switch v := rr.(type) {
+		case *miekgdns.A:
+			parts = append(parts, v.A.String())
+		case *miekgdns.AAAA:
+			parts = append(parts, v.AAAA.String())
+		case *miekgdns.CNAME:
+			parts = append(parts, v.Target)
+		default:
+			parts = append(parts, rr.String())
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func localAnswer(q miekgdns.Question, ip net.IP) miekgdns.RR {
+	switch q.Qtype {
+	case miekgdns.TypeA:
+		if v4 := ip.To4(); v4 != nil {
+			return &miekgdns.A{
+				Hdr: miekgdns.RR_Header{Name: q.Name, Rrtype: miekgdns.TypeA, Class: miekgdns.ClassINET, Ttl: 5},
+				A:   v4,
+			}
+		}
+	case miekgdns.TypeAAAA:
+		if ip.To4() == nil {
+			if v6 := ip.To16(); v6 != nil {
+				return &miekgdns.AAAA{
+					Hdr:  miekgdns.RR_Header{Name: q.Name, Rrtype: miekgdns.TypeAAAA, Class: miekgdns.ClassINET, Ttl: 5},
+					AAAA: v6,
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (s *EmbeddedServer) forward(r *miekgdns.Msg) (*miekgdns.Msg, error) {
+	key := cacheKey(r.Question[0])
+	if cached, ok := s.cache.get(key); ok {
+		reply := cached.Copy()
+		reply.Id = r.Id
+		return reply, nil
+	}
+
+	s.mu.RLock()
+	targets := s.targets
+	s.mu.RUnlock()
+
+	var lastErr error
+	for _, target := range targets {
+		reply, err := target.exchange(r)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		s.cache.set(key, reply, answerTTL(reply))
+		return reply, nil
+	}
+
+	return nil, fmt.Errorf("all upstreams failed, last error: %w", lastErr)
+}
+
+// UpstreamHealth is one configured upstream's reachability, as reported
+// by checkUpstreams.
+type UpstreamHealth struct {
+	Upstream string
+	Err      error
+}
+
+// checkUpstreams issues a throwaway query against every configured
+// upstream target in parallel, reporting which ones answered - the
+// counterpart to Test, which checks the domain resolves to localhost
+// through the OS-level resolver path rather than checking the upstreams
+// themselves.
+func (s *EmbeddedServer) checkUpstreams() []UpstreamHealth {
+	s.mu.RLock()
+	targets := s.targets
+	s.mu.RUnlock()
+
+	results := make([]UpstreamHealth, len(targets))
+
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, target *upstreamTarget) {
+			defer wg.Done()
+			msg := new(miekgdns.Msg)
+			msg.SetQuestion("example.com.", miekgdns.TypeA)
+			_, err := target.exchange(msg)
+			results[i] = UpstreamHealth{Upstream: target.raw, Err: err}
+		}(i, target)
+	}
+	wg.Wait()
+
+	return results
+}
+
+func cacheKey(q miekgdns.Question) string {
+	return fmt.Sprintf("%s|%d", strings.ToLower(q.Name), q.Qtype)
+}
+
+// answerTTL honors the upstream's own TTL for successful answers, and
+// applies a short negative TTL (RFC 2308 style) to NXDOMAIN/empty answers
+// so repeated misses don't all round-trip to the upstream.
+func answerTTL(msg *miekgdns.Msg) time.Duration {
+	if msg.Rcode == miekgdns.RcodeSuccess && len(msg.Answer) > 0 {
+		return time.Duration(msg.Answer[0].Header().Ttl) * time.Second
+	}
+	return 30 * time.Second
+}
@@ -0,0 +1,273 @@
+package dns
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	miekgdns "github.com/miekg/dns"
+)
+
+// defaultBootstrapDNS resolves a tls:// or https:// upstream's own
+// hostname before that upstream can be used itself, avoiding a
+// chicken-and-egg lookup. Used whenever ResolverConfig.BootstrapDNS is
+// left empty.
+const defaultBootstrapDNS = "8.8.8.8:53"
+
+// bootstrapCacheTTL bounds how long a bootstrap-resolved upstream
+// hostname's IP is reused before being looked up again.
+const bootstrapCacheTTL = 5 * time.Minute
+
+// upstreamScheme selects the transport an upstreamTarget exchanges
+// queries over.
+type upstreamScheme int
+
+const (
+	schemeUDP upstreamScheme = iota
+	schemeDoT
+	schemeDoH
+)
+
+// upstreamTarget is one parsed upstream entry, able to exchange a query
+// over plain UDP/TCP, DNS-over-TLS, or DNS-over-HTTPS, reusing
+// connections across queries where the transport supports it.
+type upstreamTarget struct {
+	raw    string
+	scheme upstreamScheme
+	host   string // hostname or literal IP, no port
+	port   string
+	url    string // DoH only
+
+	bootstrap string
+
+	mu         sync.Mutex
+	resolvedIP string
+	resolvedAt time.Time
+
+	// tlsClient/tlsConn back schemeDoT: the TLS connection is dialed once
+	// and reused by every Exchange, redialed only after a failure.
+	tlsClient *miekgdns.Client
+	tlsConn   *miekgdns.Conn
+
+	// httpClient backs schemeDoH. Its Transport dials resolvedAddr()
+	// instead of re-resolving the upstream's hostname through the system
+	// resolver, while pooling/reusing connections like any other
+	// net/http client.
+	httpClient *http.Client
+}
+
+// parseUpstream interprets raw as "udp://host[:port]", "tls://host[:port]"
+// (DNS-over-TLS, default port 853), "https://host[/path]" (DNS-over-HTTPS),
+// or a bare "host:port" defaulting to plain UDP - the long-standing
+// behavior for entries with no scheme prefix.
+func parseUpstream(raw, bootstrap string) (*upstreamTarget, error) {
+	switch {
+	case strings.HasPrefix(raw, "https://"):
+		u, err := url.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("parse DoH upstream %q: %w", raw, err)
+		}
+		port := u.Port()
+		if port == "" {
+			port = "443"
+		}
+		t := &upstreamTarget{raw: raw, scheme: schemeDoH, host: u.Hostname(), port: port, url: raw, bootstrap: bootstrap}
+		t.httpClient = &http.Client{
+			Timeout: 3 * time.Second,
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+					addr, err := t.resolvedAddr()
+					if err != nil {
+						return nil, err
+					}
+					return (&net.Dialer{Timeout: 3 * time.Second}).DialContext(ctx, network, addr)
+				},
+			},
+		}
+		return t, nil
+
+	case strings.HasPrefix(raw, "tls://"):
+		host, port, err := splitHostPortDefault(strings.TrimPrefix(raw, "tls://"), "853")
+		if err != nil {
+			return nil, fmt.Errorf("parse DoT upstream %q: %w", raw, err)
+		}
+		return &upstreamTarget{
+			raw: raw, scheme: schemeDoT, host: host, port: port, bootstrap: bootstrap,
+			tlsClient: &miekgdns.Client{Net: "tcp-tls", Timeout: 3 * time.Second, TLSConfig: &tls.Config{ServerName: host}},
+		}, nil
+
+	case strings.HasPrefix(raw, "udp://"):
+		host, port, err := splitHostPortDefault(strings.TrimPrefix(raw, "udp://"), "53")
+		if err != nil {
+			return nil, fmt.Errorf("parse upstream %q: %w", raw, err)
+		}
+		return &upstreamTarget{raw: raw, scheme: schemeUDP, host: host, port: port}, nil
+
+	default:
+		host, port, err := splitHostPortDefault(raw, "53")
+		if err != nil {
+			return nil, fmt.Errorf("parse upstream %q: %w", raw, err)
+		}
+		return &upstreamTarget{raw: raw, scheme: schemeUDP, host: host, port: port}, nil
+	}
+}
+
+// splitHostPortDefault splits hostport, falling back to defaultPort for a
+// bare host with no ":port" suffix instead of erroring.
+func splitHostPortDefault(hostport, defaultPort string) (string, string, error) {
+	if host, port, err := net.SplitHostPort(hostport); err == nil {
+		return host, port, nil
+	}
+	return hostport, defaultPort, nil
+}
+
+// resolvedAddr returns host:port for dialing, resolving a hostname via
+// bootstrap first (caching the result for bootstrapCacheTTL) unless host
+// is already a literal IP.
+func (t *upstreamTarget) resolvedAddr() (string, error) {
+	if net.ParseIP(t.host) != nil {
+		return net.JoinHostPort(t.host, t.port), nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.resolvedIP != "" && time.Since(t.resolvedAt) < bootstrapCacheTTL {
+		return net.JoinHostPort(t.resolvedIP, t.port), nil
+	}
+
+	bootstrap := t.bootstrap
+	if bootstrap == "" {
+		bootstrap = defaultBootstrapDNS
+	}
+
+	ip, err := bootstrapLookup(t.host, bootstrap)
+	if err != nil {
+		return "", fmt.Errorf("bootstrap resolve %s via %s: %w", t.host, bootstrap, err)
+	}
+
+	t.resolvedIP = ip
+	t.resolvedAt = time.Now()
+	return net.JoinHostPort(ip, t.port), nil
+}
+
+// bootstrapLookup resolves host to an IPv4 address with a single plain
+// DNS query against bootstrap, sidestepping the upstream being resolved
+// (and the embedded server itself).
+func bootstrapLookup(host, bootstrap string) (string, error) {
+	client := &miekgdns.Client{Timeout: 2 * time.Second}
+	msg := new(miekgdns.Msg)
+	msg.SetQuestion(miekgdns.Fqdn(host), miekgdns.TypeA)
+
+	reply, _, err := client.Exchange(msg, bootstrap)
+	if err != nil {
+		return "", err
+	}
+	for _, rr := range reply.Answer {
+		if a, ok := rr.(*miekgdns.A); ok {
+			return a.A.String(), nil
+		}
+	}
+	return "", fmt.Errorf("no A record for %s", host)
+}
+
+// exchange sends r to the target and returns its reply, dispatching on
+// scheme.
+func (t *upstreamTarget) exchange(r *miekgdns.Msg) (*miekgdns.Msg, error) {
+	switch t.scheme {
+	case schemeDoH:
+		return t.exchangeDoH(r)
+	case schemeDoT:
+		return t.exchangeDoT(r)
+	default:
+		client := &miekgdns.Client{Timeout: 2 * time.Second}
+		reply, _, err := client.Exchange(r, net.JoinHostPort(t.host, t.port))
+		if err != nil {
+			return nil, fmt.Errorf("exchange with %s: %w", t.raw, err)
+		}
+		return reply, nil
+	}
+}
+
+// exchangeDoT reuses a single open TLS connection across queries,
+// redialing once on failure (e.g. the server closed an idle connection).
+func (t *upstreamTarget) exchangeDoT(r *miekgdns.Msg) (*miekgdns.Msg, error) {
+	addr, err := t.resolvedAddr()
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.tlsConn == nil {
+		conn, err := t.tlsClient.Dial(addr)
+		if err != nil {
+			return nil, fmt.Errorf("dial DoT upstream %s: %w", t.raw, err)
+		}
+		t.tlsConn = conn
+	}
+
+	reply, _, err := t.tlsClient.ExchangeWithConn(r, t.tlsConn)
+	if err != nil {
+		t.tlsConn.Close()
+		t.tlsConn = nil
+
+		conn, dialErr := t.tlsClient.Dial(addr)
+		if dialErr != nil {
+			return nil, fmt.Errorf("DoT exchange with %s: %w", t.raw, err)
+		}
+		t.tlsConn = conn
+
+		reply, _, err = t.tlsClient.ExchangeWithConn(r, t.tlsConn)
+		if err != nil {
+			return nil, fmt.Errorf("DoT exchange with %s: %w", t.raw, err)
+		}
+	}
+	return reply, nil
+}
+
+// exchangeDoH sends r as an RFC 8484 "dns-message" POST, reusing
+// httpClient's pooled connections across queries.
+func (t *upstreamTarget) exchangeDoH(r *miekgdns.Msg) (*miekgdns.Msg, error) {
+	packed, err := r.Pack()
+	if err != nil {
+		return nil, fmt.Errorf("pack DoH query: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, t.url, bytes.NewReader(packed))
+	if err != nil {
+		return nil, fmt.Errorf("build DoH request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("DoH exchange with %s: %w", t.raw, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DoH exchange with %s: unexpected status %s", t.raw, resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read DoH response: %w", err)
+	}
+
+	reply := new(miekgdns.Msg)
+	if err := reply.Unpack(body); err != nil {
+		return nil, fmt.Errorf("unpack DoH response: %w", err)
+	}
+	return reply, nil
+}
@@ -0,0 +1,203 @@
+// Package log is a facet-scoped structured logging subsystem, modeled on
+// syncthing's STTRACE: subsystems log through Facet("name") loggers whose
+// Trace/Debug calls compile down to a single enabled-check when that facet
+// isn't turned on, so a user can ask for deep tracing on just "dns" or
+// "proxy" without drowning in the rest of the app's output. Records fan out
+// to whatever Sinks are registered (TUI, JSON file, plain stderr).
+package log
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level is a record's severity. Unlike pkg/logger's slog-based Level, this
+// one also carries Trace, which slog has no native concept of.
+type Level int
+
+const (
+	LevelTrace Level = iota
+	LevelDebug
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelTrace:
+		return "TRACE"
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	default:
+		return "INFO"
+	}
+}
+
+// Record is one structured log event, handed to every registered Sink.
+type Record struct {
+	Time    time.Time
+	Level   Level
+	Facet   string // subsystem that emitted it, e.g. "dns"; empty for un-faceted logging
+	Message string
+	Fields  map[string]any
+}
+
+// Sink receives every Record emitted through this package, regardless of
+// facet gating (a Record only reaches a Sink once its facet/level has
+// already been judged enabled).
+type Sink interface {
+	Write(Record)
+}
+
+var (
+	mu        sync.RWMutex
+	sinks     []Sink
+	facets    = map[string]bool{}
+	allFacets bool
+)
+
+// Init reads NSM_TRACE (a comma-separated facet list, or "all") and
+// registers sinks that every Facet logger fans Records out to. Call once at
+// startup; safe to call again in tests to reset state.
+func Init(traceEnv string, s ...Sink) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	sinks = append([]Sink(nil), s...)
+	facets = map[string]bool{}
+	allFacets = false
+
+	traceEnv = strings.TrimSpace(traceEnv)
+	if traceEnv == "" {
+		return
+	}
+	if strings.EqualFold(traceEnv, "all") {
+		allFacets = true
+		return
+	}
+	for _, name := range strings.Split(traceEnv, ",") {
+		name = strings.TrimSpace(name)
+		if name != "" {
+			facets[name] = true
+		}
+	}
+}
+
+// InitFromEnv is Init using the NSM_TRACE environment variable, e.g.
+// NSM_TRACE=dns,proxy,cert or NSM_TRACE=all.
+func InitFromEnv(s ...Sink) {
+	Init(os.Getenv("NSM_TRACE"), s...)
+}
+
+// AddSink registers an additional sink without disturbing facet state.
+func AddSink(s Sink) {
+	mu.Lock()
+	defer mu.Unlock()
+	sinks = append(sinks, s)
+}
+
+// FacetEnabled reports whether name was named by NSM_TRACE (or NSM_TRACE=all).
+func FacetEnabled(name string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return allFacets || facets[name]
+}
+
+func emit(level Level, facet, msg string, fields map[string]any) {
+	mu.RLock()
+	dest := sinks
+	mu.RUnlock()
+
+	record := Record{Time: time.Now(), Level: level, Facet: facet, Message: msg, Fields: fields}
+	for _, s := range dest {
+		s.Write(record)
+	}
+}
+
+// FacetLogger scopes Trace/Debug output to one named subsystem (e.g. "dns",
+// "proxy", "cert"), so it can be enabled independently via NSM_TRACE.
+type FacetLogger struct {
+	name string
+}
+
+// Facet returns the logger for the named subsystem. Cheap to call
+// per-request; it carries no state beyond the name.
+func Facet(name string) *FacetLogger {
+	return &FacetLogger{name: name}
+}
+
+// enabled reports whether this facet's Trace/Debug calls should run.
+func (f *FacetLogger) enabled() bool {
+	return FacetEnabled(f.name)
+}
+
+// Tracef logs at trace level if this facet is enabled via NSM_TRACE;
+// otherwise it's a single map lookup and nothing is formatted or emitted.
+func (f *FacetLogger) Tracef(format string, args ...any) {
+	if !f.enabled() {
+		return
+	}
+	emit(LevelTrace, f.name, fmt.Sprintf(format, args...), nil)
+}
+
+// Debugf logs at debug level, gated the same way as Tracef.
+func (f *FacetLogger) Debugf(format string, args ...any) {
+	if !f.enabled() {
+		return
+	}
+	emit(LevelDebug, f.name, fmt.Sprintf(format, args...), nil)
+}
+
+// Infof, Warnf, and Errorf always emit, tagged with this facet, regardless
+// of NSM_TRACE: facet gating exists to mute deep tracing, not real events.
+func (f *FacetLogger) Infof(format string, args ...any) {
+	emit(LevelInfo, f.name, fmt.Sprintf(format, args...), nil)
+}
+
+func (f *FacetLogger) Warnf(format string, args ...any) {
+	emit(LevelWarn, f.name, fmt.Sprintf(format, args...), nil)
+}
+
+func (f *FacetLogger) Errorf(format string, args ...any) {
+	emit(LevelError, f.name, fmt.Sprintf(format, args...), nil)
+}
+
+// WithFields returns a Record-shaped helper carrying structured key/value
+// fields instead of a formatted message, for callers that want both a
+// facet tag and structured context (e.g. Facet("dns").WithFields(map[string]any{"query": q}).Debug("cache miss")).
+func (f *FacetLogger) WithFields(fields map[string]any) *fieldLogger {
+	return &fieldLogger{facet: f, fields: fields}
+}
+
+type fieldLogger struct {
+	facet  *FacetLogger
+	fields map[string]any
+}
+
+func (l *fieldLogger) Trace(msg string) {
+	if !l.facet.enabled() {
+		return
+	}
+	emit(LevelTrace, l.facet.name, msg, l.fields)
+}
+
+func (l *fieldLogger) Debug(msg string) {
+	if !l.facet.enabled() {
+		return
+	}
+	emit(LevelDebug, l.facet.name, msg, l.fields)
+}
+
+func (l *fieldLogger) Info(msg string)  { emit(LevelInfo, l.facet.name, msg, l.fields) }
+func (l *fieldLogger) Warn(msg string)  { emit(LevelWarn, l.facet.name, msg, l.fields) }
+func (l *fieldLogger) Error(msg string) { emit(LevelError, l.facet.name, msg, l.fields) }
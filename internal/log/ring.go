@@ -0,0 +1,75 @@
+package log
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// ParseLevel parses a level name (case-insensitive) as used in NSM_TRACE,
+// filter expressions, and the diagnostic server's ?level= query param. It
+// returns false for anything it doesn't recognize, leaving the caller to
+// decide how to report that.
+func ParseLevel(s string) (Level, bool) {
+	switch strings.ToLower(s) {
+	case "trace":
+		return LevelTrace, true
+	case "debug":
+		return LevelDebug, true
+	case "info":
+		return LevelInfo, true
+	case "warn":
+		return LevelWarn, true
+	case "error":
+		return LevelError, true
+	default:
+		return LevelInfo, false
+	}
+}
+
+// ringCapacity bounds RingSink's backlog, mirroring the TUI's
+// logBacklogCapacity so the diagnostic /logs endpoint and the TUI's log
+// pane can show roughly the same amount of history.
+const ringCapacity = 2000
+
+// RingSink keeps the last ringCapacity Records in memory, for the
+// diagnostic server's /logs endpoint to query when there's no TUI backlog
+// to read from.
+type RingSink struct {
+	mu      sync.Mutex
+	records []Record
+}
+
+// NewRingSink returns an empty RingSink.
+func NewRingSink() *RingSink {
+	return &RingSink{}
+}
+
+func (s *RingSink) Write(r Record) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records = append(s.records, r)
+	if len(s.records) > ringCapacity {
+		s.records = s.records[len(s.records)-ringCapacity:]
+	}
+}
+
+// Query returns the records at or above minLevel, recorded at or after
+// since (if non-zero), oldest first.
+func (s *RingSink) Query(minLevel Level, since time.Time) []Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]Record, 0, len(s.records))
+	for _, r := range s.records {
+		if r.Level < minLevel {
+			continue
+		}
+		if !since.IsZero() && r.Time.Before(since) {
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
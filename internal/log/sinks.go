@@ -0,0 +1,101 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/kashifsb/nsm/internal/ui"
+	"github.com/kashifsb/nsm/pkg/utils"
+)
+
+// TUISink forwards Records into the running Bubble Tea program as
+// ui.LogRecordMsg, so they land in the same log backlog/filter pane
+// RenderLogs already draws from.
+type TUISink struct {
+	program *tea.Program
+}
+
+func NewTUISink(program *tea.Program) *TUISink {
+	return &TUISink{program: program}
+}
+
+func (s *TUISink) Write(r Record) {
+	if s.program == nil {
+		return
+	}
+	s.program.Send(ui.LogRecordMsg{
+		Timestamp: r.Time,
+		Level:     r.Level.String(),
+		Facet:     r.Facet,
+		Message:   r.Message,
+		Fields:    r.Fields,
+	})
+}
+
+// jsonRecord is Record's file-sink encoding: lowercase, jq-friendly keys,
+// mirroring project.LogEvent's convention for the same reason.
+type jsonRecord struct {
+	Time    string         `json:"time"`
+	Level   string         `json:"level"`
+	Facet   string         `json:"facet,omitempty"`
+	Message string         `json:"message"`
+	Fields  map[string]any `json:"fields,omitempty"`
+}
+
+// JSONFileSink appends each Record as one JSON line to a file, via
+// utils.AppendToFile, for later inspection or shipping to a log aggregator.
+type JSONFileSink struct {
+	path string
+}
+
+func NewJSONFileSink(path string) *JSONFileSink {
+	return &JSONFileSink{path: path}
+}
+
+func (s *JSONFileSink) Write(r Record) {
+	data, err := json.Marshal(jsonRecord{
+		Time:    r.Time.Format(timeLayout),
+		Level:   r.Level.String(),
+		Facet:   r.Facet,
+		Message: r.Message,
+		Fields:  r.Fields,
+	})
+	if err != nil {
+		return
+	}
+	_ = utils.AppendToFile(s.path, string(data)+"\n")
+}
+
+const timeLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// TextSink writes plain "time LEVEL [facet] message key=value ..." lines to
+// w (os.Stderr by default), for headless runs and NSM_TRACE debugging
+// without a TUI to render into.
+type TextSink struct {
+	w io.Writer
+}
+
+func NewTextSink(w io.Writer) *TextSink {
+	if w == nil {
+		w = os.Stderr
+	}
+	return &TextSink{w: w}
+}
+
+func (s *TextSink) Write(r Record) {
+	line := fmt.Sprintf("%s %-5s", r.Time.Format(timeLayout), r.Level)
+	if r.Facet != "" {
+		line += fmt.Sprintf(" [%s]", r.Facet)
+	}
+	line += " " + r.Message
+
+	for k, v := range r.Fields {
+		line += fmt.Sprintf(" %s=%v", k, v)
+	}
+
+	fmt.Fprintln(s.w, line)
+}
@@ -0,0 +1,82 @@
+package platform
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/kashifsb/nsm/pkg/logger"
+)
+
+// Browser opens a project's URL in the user's default browser once its
+// dev server becomes reachable, built on top of PortManager.WaitForPort.
+type Browser struct {
+	portManager *PortManager
+}
+
+// NewBrowser returns a Browser that polls portManager for readiness.
+func NewBrowser(portManager *PortManager) *Browser {
+	return &Browser{portManager: portManager}
+}
+
+// OpenWhenReady waits up to timeout for port to become reachable, then
+// opens url in the default browser. Set NSM_AUTO_OPEN=0 to suppress this
+// in CI/headless runs; it's a no-op (not an error) in that case.
+func (b *Browser) OpenWhenReady(url string, port int, timeout time.Duration) error {
+	if os.Getenv("NSM_AUTO_OPEN") == "0" {
+		logger.Debug("NSM_AUTO_OPEN=0, skipping browser launch", "url", url)
+		return nil
+	}
+
+	if err := b.portManager.WaitForPort(port, timeout); err != nil {
+		return fmt.Errorf("wait for port %d: %w", port, err)
+	}
+
+	return OpenURL(url)
+}
+
+// OpenURL opens url in the user's default browser using the
+// platform-appropriate command: "open" on macOS, "rundll32" on Windows,
+// "xdg-open" on Linux - except under WSL, where it shells out to
+// powershell.exe instead since there's normally no browser inside the
+// WSL distro itself.
+func OpenURL(url string) error {
+	var cmd *exec.Cmd
+
+	switch {
+	case isWSL():
+		cmd = exec.Command("powershell.exe", "Start-Process", url)
+	case runtime.GOOS == "darwin":
+		cmd = exec.Command("open", url)
+	case runtime.GOOS == "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+
+	logger.Info("Opening browser", "url", url)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("open browser: %w", err)
+	}
+	return nil
+}
+
+// isWSL reports whether the process is running inside Windows Subsystem
+// for Linux, via the "microsoft"/"wsl" marker Microsoft's kernel build
+// puts in /proc/version.
+func isWSL() bool {
+	if runtime.GOOS != "linux" {
+		return false
+	}
+
+	data, err := os.ReadFile("/proc/version")
+	if err != nil {
+		return false
+	}
+
+	release := strings.ToLower(string(data))
+	return strings.Contains(release, "microsoft") || strings.Contains(release, "wsl")
+}
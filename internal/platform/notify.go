@@ -0,0 +1,38 @@
+package platform
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+
+	"github.com/kashifsb/nsm/pkg/logger"
+)
+
+// Notify fires a desktop notification titled title with body message, so a
+// long-running NSM session (cert renewal, a crashed dev server) surfaces
+// events without anyone watching the TUI. It's best-effort: a machine
+// without the underlying notifier installed just logs a debug line instead
+// of failing the caller.
+func Notify(title, message string) error {
+	var cmd *exec.Cmd
+
+	switch runtime.GOOS {
+	case "darwin":
+		script := fmt.Sprintf("display notification %q with title %q", message, title)
+		cmd = exec.Command("osascript", "-e", script)
+	case "windows":
+		script := fmt.Sprintf(
+			"Import-Module BurntToast; New-BurntToastNotification -Text %q, %q",
+			title, message,
+		)
+		cmd = exec.Command("powershell", "-NoProfile", "-Command", script)
+	default:
+		cmd = exec.Command("notify-send", title, message)
+	}
+
+	if err := cmd.Run(); err != nil {
+		logger.Debug("Failed to send desktop notification", "title", title, "error", err)
+		return fmt.Errorf("send notification: %w", err)
+	}
+	return nil
+}
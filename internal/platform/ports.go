@@ -1,16 +1,31 @@
 package platform
 
 import (
+	"context"
 	"fmt"
 	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
+	nsmlog "github.com/kashifsb/nsm/internal/log"
 	"github.com/kashifsb/nsm/pkg/logger"
+	"github.com/kashifsb/nsm/pkg/utils"
 	"github.com/shirou/gopsutil/v3/process"
 )
 
+// trace is the "ports" facet, enabled via NSM_TRACE=ports for per-bind-attempt
+// detail that would otherwise drown out the rest of the app's logging.
+var trace = nsmlog.Facet("ports")
+
 type PortManager struct {
+	mu             sync.Mutex
 	allocatedPorts map[int]bool
 }
 
@@ -35,16 +50,20 @@ func (pm *PortManager) FindFreePort() (int, error) {
 	defer listener.Close()
 
 	port := listener.Addr().(*net.TCPAddr).Port
+	pm.mu.Lock()
 	pm.allocatedPorts[port] = true
+	pm.mu.Unlock()
 
-	logger.Debug("Found free port", "port", port)
+	logger.Attrs("port", port).Debug("Found free port")
 	return port, nil
 }
 
 func (pm *PortManager) FindFreePortNear(preferred int) (int, error) {
 	// Try preferred port first
 	if pm.IsPortAvailable(preferred) {
+		pm.mu.Lock()
 		pm.allocatedPorts[preferred] = true
+		pm.mu.Unlock()
 		logger.Debug("Using preferred port", "port", preferred)
 		return preferred, nil
 	}
@@ -53,7 +72,9 @@ func (pm *PortManager) FindFreePortNear(preferred int) (int, error) {
 	for offset := 1; offset <= 100; offset++ {
 		for _, port := range []int{preferred + offset, preferred - offset} {
 			if port > 1024 && port < 65535 && pm.IsPortAvailable(port) {
+				pm.mu.Lock()
 				pm.allocatedPorts[port] = true
+				pm.mu.Unlock()
 				logger.Debug("Found nearby port", "preferred", preferred, "actual", port)
 				return port, nil
 			}
@@ -65,17 +86,23 @@ func (pm *PortManager) FindFreePortNear(preferred int) (int, error) {
 }
 
 func (pm *PortManager) IsPortAvailable(port int) bool {
-	if pm.allocatedPorts[port] {
+	pm.mu.Lock()
+	allocated := pm.allocatedPorts[port]
+	pm.mu.Unlock()
+	if allocated {
+		trace.Tracef("port %d already allocated by this process", port)
 		return false
 	}
 
 	// Try to bind to the port
 	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
 	if err != nil {
+		trace.Tracef("bind probe failed for port %d: %s", port, err)
 		return false
 	}
 	listener.Close()
 
+	trace.Tracef("port %d is free", port)
 	return true
 }
 
@@ -109,25 +136,33 @@ func (pm *PortManager) GetPortInfo(port int) PortInfo {
 	return info
 }
 
+// WaitForPort blocks until port accepts a TCP connection or timeout elapses.
+// It's a thin convenience wrapper around Readiness for the common case;
+// callers that need an HTTP, gRPC, or command-based readiness signal
+// instead should build a Readiness of their own with Register.
 func (pm *PortManager) WaitForPort(port int, timeout time.Duration) error {
-	deadline := time.Now().Add(timeout)
-
-	for time.Now().Before(deadline) {
-		conn, err := net.DialTimeout("tcp", fmt.Sprintf("127.0.0.1:%d", port), time.Second)
-		if err == nil {
-			conn.Close()
-			logger.Debug("Port became available", "port", port)
-			return nil
-		}
-
-		time.Sleep(100 * time.Millisecond)
+	r := NewReadiness()
+	r.Register(ProbeSpec{
+		Probe:    TCPProbe{Addr: fmt.Sprintf("127.0.0.1:%d", port)},
+		Interval: 100 * time.Millisecond,
+		Timeout:  time.Second,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := r.WaitAll(ctx); err != nil {
+		return fmt.Errorf("port %d did not become available within %v", port, timeout)
 	}
 
-	return fmt.Errorf("port %d did not become available within %v", port, timeout)
+	logger.Attrs("port", port).Debug("Port became available")
+	return nil
 }
 
 func (pm *PortManager) ReleasePort(port int) {
+	pm.mu.Lock()
 	delete(pm.allocatedPorts, port)
+	pm.mu.Unlock()
 	logger.Debug("Released port", "port", port)
 }
 
@@ -149,7 +184,30 @@ type processInfo struct {
 	PID  int32
 }
 
+// getProcessUsingPort looks port up in a single ScanListeningSockets pass,
+// falling back to gopsutil's per-process connection walk when the
+// platform-native scan fails (e.g. unreadable /proc entries).
 func (pm *PortManager) getProcessUsingPort(port int) *processInfo {
+	sockets, err := pm.ScanListeningSockets()
+	if err != nil {
+		logger.Debug("Socket table scan failed, falling back to per-process walk", "error", err)
+		return pm.getProcessUsingPortGopsutil(port)
+	}
+
+	for _, s := range sockets {
+		if s.Port == port && s.PID != 0 {
+			return &processInfo{Name: s.ProcessName, PID: s.PID}
+		}
+	}
+
+	return nil
+}
+
+// getProcessUsingPortGopsutil is the original implementation: it walks
+// every process's open connections looking for one bound to port. It's
+// O(processes x connections) and, on macOS, frequently fails to see other
+// users' sockets without elevated privileges - kept only as a last resort.
+func (pm *PortManager) getProcessUsingPortGopsutil(port int) *processInfo {
 	processes, err := process.Processes()
 	if err != nil {
 		logger.Debug("Failed to get process list", "error", err)
@@ -180,6 +238,400 @@ func (pm *PortManager) getProcessUsingPort(port int) *processInfo {
 	return nil
 }
 
+// ScanListeningSockets returns every listening TCP socket on the system in
+// a single pass, with owning PID/process name when the platform lets us
+// determine it. It prefers a native socket table read over gopsutil's
+// per-process connection walk: /proc/net/tcp{,6} on Linux, `lsof` on macOS,
+// and `netstat` on Windows.
+func (pm *PortManager) ScanListeningSockets() ([]PortInfo, error) {
+	switch runtime.GOOS {
+	case "linux":
+		return pm.scanListeningSocketsLinux()
+	case "darwin":
+		return pm.scanListeningSocketsDarwin()
+	case "windows":
+		return pm.scanListeningSocketsWindows()
+	default:
+		return pm.scanListeningSocketsGopsutil()
+	}
+}
+
+func (pm *PortManager) scanListeningSocketsGopsutil() ([]PortInfo, error) {
+	processes, err := process.Processes()
+	if err != nil {
+		return nil, fmt.Errorf("list processes: %w", err)
+	}
+
+	var infos []PortInfo
+	for _, p := range processes {
+		connections, err := p.Connections()
+		if err != nil {
+			continue
+		}
+
+		name, err := p.Name()
+		if err != nil {
+			name = "unknown"
+		}
+
+		for _, conn := range connections {
+			if conn.Status != "LISTEN" {
+				continue
+			}
+			infos = append(infos, PortInfo{
+				Port:        int(conn.Laddr.Port),
+				Available:   false,
+				ProcessName: name,
+				PID:         p.Pid,
+			})
+		}
+	}
+
+	return infos, nil
+}
+
+// tcpStateListen is the /proc/net/tcp{,6} "st" field value for a listening
+// socket.
+const tcpStateListen = 0x0A
+
+type procNetEntry struct {
+	port  int
+	state int
+	inode uint64
+}
+
+func (pm *PortManager) scanListeningSocketsLinux() ([]PortInfo, error) {
+	entries, err := parseProcNetTCP("/proc/net/tcp")
+	if err != nil {
+		return nil, fmt.Errorf("read /proc/net/tcp: %w", err)
+	}
+
+	if entries6, err := parseProcNetTCP("/proc/net/tcp6"); err == nil {
+		entries = append(entries, entries6...)
+	} else {
+		logger.Debug("Failed to read /proc/net/tcp6", "error", err)
+	}
+
+	inodeToPID, err := socketInodeOwners()
+	if err != nil {
+		logger.Debug("Failed to map socket inodes to PIDs", "error", err)
+	}
+
+	var infos []PortInfo
+	for _, e := range entries {
+		if e.state != tcpStateListen {
+			continue
+		}
+
+		info := PortInfo{Port: e.port, Available: false}
+		if pid, ok := inodeToPID[e.inode]; ok {
+			info.PID = pid
+			info.ProcessName = processNameForPID(pid)
+		}
+		infos = append(infos, info)
+	}
+
+	return infos, nil
+}
+
+// parseProcNetTCP reads a /proc/net/tcp or /proc/net/tcp6 socket table.
+// Each data line looks like:
+//
+//	sl  local_address rem_address st tx_queue rx_queue tr tm->when retrnsmt uid timeout inode
+//	 0: 0100007F:1F90 00000000:0000 0A 00000000:00000000 00:00000000 00000000 0 0 12345 1 ...
+func parseProcNetTCP(path string) ([]procNetEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(string(data), "\n")
+	entries := make([]procNetEntry, 0, len(lines))
+
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		if len(fields) < 10 {
+			continue
+		}
+
+		addrParts := strings.Split(fields[1], ":")
+		if len(addrParts) != 2 {
+			continue
+		}
+
+		port, err := strconv.ParseInt(addrParts[1], 16, 32)
+		if err != nil {
+			continue
+		}
+
+		state, err := strconv.ParseInt(fields[3], 16, 32)
+		if err != nil {
+			continue
+		}
+
+		inode, err := strconv.ParseUint(fields[9], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		entries = append(entries, procNetEntry{port: int(port), state: int(state), inode: inode})
+	}
+
+	return entries, nil
+}
+
+// socketInodeOwners walks /proc/<pid>/fd looking for "socket:[<inode>]"
+// symlinks, building a map from socket inode to owning PID.
+func socketInodeOwners() (map[uint64]int32, error) {
+	procEntries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+
+	owners := make(map[uint64]int32)
+	for _, procEntry := range procEntries {
+		pid, err := strconv.Atoi(procEntry.Name())
+		if err != nil {
+			continue
+		}
+
+		fdDir := filepath.Join("/proc", procEntry.Name(), "fd")
+		fds, err := os.ReadDir(fdDir)
+		if err != nil {
+			continue
+		}
+
+		for _, fd := range fds {
+			link, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+			if err != nil || !strings.HasPrefix(link, "socket:[") {
+				continue
+			}
+
+			inode, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]"), 10, 64)
+			if err != nil {
+				continue
+			}
+
+			owners[inode] = int32(pid)
+		}
+	}
+
+	return owners, nil
+}
+
+func processNameForPID(pid int32) string {
+	p, err := process.NewProcess(pid)
+	if err != nil {
+		return "unknown"
+	}
+
+	name, err := p.Name()
+	if err != nil {
+		return "unknown"
+	}
+
+	return name
+}
+
+// scanListeningSocketsDarwin shells out to lsof, since macOS sandboxing
+// routinely hides other users' connections from gopsutil's /proc-less
+// per-process walk.
+func (pm *PortManager) scanListeningSocketsDarwin() ([]PortInfo, error) {
+	out, err := exec.Command("lsof", "-nP", "-iTCP", "-sTCP:LISTEN").Output()
+	if err != nil {
+		logger.Debug("lsof scan failed, falling back to gopsutil", "error", err)
+		return pm.scanListeningSocketsGopsutil()
+	}
+
+	return parseLsofOutput(out), nil
+}
+
+// parseLsofOutput parses `lsof -nP -iTCP ... -sTCP:LISTEN` output, e.g.:
+//
+//	COMMAND   PID   USER   FD   TYPE DEVICE SIZE/OFF NODE NAME
+//	node    12345 kashif   23u  IPv4 0x...      0t0  TCP *:8080 (LISTEN)
+func parseLsofOutput(out []byte) []PortInfo {
+	lines := strings.Split(string(out), "\n")
+	if len(lines) < 2 {
+		return nil
+	}
+
+	var infos []PortInfo
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		if len(fields) < 9 {
+			continue
+		}
+
+		pid, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+
+		addr := fields[8]
+		idx := strings.LastIndex(addr, ":")
+		if idx == -1 {
+			continue
+		}
+
+		port, err := strconv.Atoi(addr[idx+1:])
+		if err != nil {
+			continue
+		}
+
+		infos = append(infos, PortInfo{
+			Port:        port,
+			Available:   false,
+			ProcessName: fields[0],
+			PID:         int32(pid),
+		})
+	}
+
+	return infos
+}
+
+// scanListeningSocketsWindows shells out to netstat, since it reports
+// LISTENING sockets with owning PIDs without requiring a cgo/syscall
+// dependency on iphlpapi.
+func (pm *PortManager) scanListeningSocketsWindows() ([]PortInfo, error) {
+	out, err := exec.Command("netstat", "-ano", "-p", "TCP").Output()
+	if err != nil {
+		logger.Debug("netstat scan failed, falling back to gopsutil", "error", err)
+		return pm.scanListeningSocketsGopsutil()
+	}
+
+	return parseNetstatOutput(out), nil
+}
+
+// parseNetstatOutput parses `netstat -ano -p TCP` output, e.g.:
+//
+//	  Proto  Local Address          Foreign Address        State           PID
+//	  TCP    0.0.0.0:8080           0.0.0.0:0              LISTENING       1234
+func parseNetstatOutput(out []byte) []PortInfo {
+	var infos []PortInfo
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 5 || !strings.EqualFold(fields[0], "TCP") || !strings.EqualFold(fields[3], "LISTENING") {
+			continue
+		}
+
+		idx := strings.LastIndex(fields[1], ":")
+		if idx == -1 {
+			continue
+		}
+
+		port, err := strconv.Atoi(fields[1][idx+1:])
+		if err != nil {
+			continue
+		}
+
+		pid, err := strconv.Atoi(fields[4])
+		if err != nil {
+			continue
+		}
+
+		infos = append(infos, PortInfo{
+			Port:        port,
+			Available:   false,
+			PID:         int32(pid),
+			ProcessName: processNameForPID(int32(pid)),
+		})
+	}
+
+	return infos
+}
+
+// ConflictPolicy selects how ResolveConflict handles a port already in use.
+type ConflictPolicy int
+
+const (
+	// PolicyReallocate finds a nearby free port instead of touching the
+	// owning process.
+	PolicyReallocate ConflictPolicy = iota
+	// PolicyKillOwner terminates the owning process (SIGTERM, then a
+	// forced kill after a grace period) and hands back the same port.
+	PolicyKillOwner
+	// PolicyPromptUser asks confirm whether to kill the owner; declining
+	// falls back to PolicyReallocate.
+	PolicyPromptUser
+)
+
+// ResolveConflict makes port usable according to policy. confirm is only
+// consulted for PolicyPromptUser and may be nil otherwise.
+func (pm *PortManager) ResolveConflict(port int, policy ConflictPolicy, confirm func(prompt string) bool) (int, error) {
+	if pm.IsPortAvailable(port) {
+		pm.mu.Lock()
+		pm.allocatedPorts[port] = true
+		pm.mu.Unlock()
+		return port, nil
+	}
+
+	info := pm.GetPortInfo(port)
+
+	switch policy {
+	case PolicyKillOwner:
+		if err := pm.killOwner(port, info); err != nil {
+			return 0, err
+		}
+		pm.mu.Lock()
+		pm.allocatedPorts[port] = true
+		pm.mu.Unlock()
+		return port, nil
+
+	case PolicyPromptUser:
+		prompt := fmt.Sprintf("Port %d is in use by %s (pid %d). Kill it?", port, info.ProcessName, info.PID)
+		if confirm != nil && confirm(prompt) {
+			if err := pm.killOwner(port, info); err != nil {
+				return 0, err
+			}
+			pm.mu.Lock()
+			pm.allocatedPorts[port] = true
+			pm.mu.Unlock()
+			return port, nil
+		}
+		return pm.FindFreePortNear(port)
+
+	case PolicyReallocate:
+		return pm.FindFreePortNear(port)
+
+	default:
+		return 0, fmt.Errorf("unknown conflict policy %d", policy)
+	}
+}
+
+// killOwner sends SIGTERM to the process holding port, waits briefly for it
+// to exit, then force-kills it if it's still running.
+func (pm *PortManager) killOwner(port int, info PortInfo) error {
+	if info.PID == 0 {
+		return fmt.Errorf("could not determine which process owns port %d", port)
+	}
+
+	logger.Info("Terminating process holding port", "port", port, "pid", info.PID, "process", info.ProcessName)
+
+	proc, err := os.FindProcess(int(info.PID))
+	if err != nil {
+		return fmt.Errorf("find process %d: %w", info.PID, err)
+	}
+
+	if err := proc.Signal(syscall.SIGTERM); err != nil {
+		logger.Debug("SIGTERM failed, will force kill", "pid", info.PID, "error", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if !utils.IsProcessRunning(int(info.PID)) {
+			return nil
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+
+	if err := utils.KillProcess(int(info.PID)); err != nil {
+		return fmt.Errorf("force kill pid %d holding port %d: %w", info.PID, port, err)
+	}
+
+	return nil
+}
+
 // Platform-specific optimizations
 func (pm *PortManager) GetSystemPortPreferences() map[string]int {
 	preferences := map[string]int{
@@ -0,0 +1,136 @@
+package platform
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"regexp"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Probe is a single readiness check Readiness can run repeatedly until it
+// succeeds ProbeSpec.SuccessThreshold times in a row.
+type Probe interface {
+	// Name identifies the probe in logs and TUI checklists.
+	Name() string
+	// Check runs the probe once, returning nil only on success.
+	Check(ctx context.Context) error
+}
+
+// TCPProbe succeeds once it can open a TCP connection to Addr. It's the
+// weakest signal of the bunch - plenty of dev servers (Next.js chief among
+// them) open their listening socket well before they can actually serve a
+// request - but it's the only option when there's no HTTP/gRPC endpoint to
+// ask.
+type TCPProbe struct {
+	Addr string
+}
+
+func (p TCPProbe) Name() string { return fmt.Sprintf("tcp:%s", p.Addr) }
+
+func (p TCPProbe) Check(ctx context.Context) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", p.Addr)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// HTTPProbe succeeds once a GET to URL returns ExpectStatus (0 means any
+// 2xx) and, if BodyRegex is set, the response body matches it.
+type HTTPProbe struct {
+	URL          string
+	ExpectStatus int
+	BodyRegex    *regexp.Regexp
+}
+
+func (p HTTPProbe) Name() string { return fmt.Sprintf("http:%s", p.URL) }
+
+func (p HTTPProbe) Check(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.URL, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if p.ExpectStatus != 0 {
+		if resp.StatusCode != p.ExpectStatus {
+			return fmt.Errorf("expected status %d, got %d", p.ExpectStatus, resp.StatusCode)
+		}
+	} else if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	if p.BodyRegex != nil {
+		var buf bytes.Buffer
+		if _, err := buf.ReadFrom(resp.Body); err != nil {
+			return fmt.Errorf("read response body: %w", err)
+		}
+		if !p.BodyRegex.Match(buf.Bytes()) {
+			return fmt.Errorf("response body did not match %s", p.BodyRegex)
+		}
+	}
+
+	return nil
+}
+
+// GRPCProbe succeeds once Addr's standard gRPC health service reports
+// SERVING for Service (empty Service means the server's overall health).
+type GRPCProbe struct {
+	Addr    string
+	Service string
+}
+
+func (p GRPCProbe) Name() string { return fmt.Sprintf("grpc:%s", p.Addr) }
+
+func (p GRPCProbe) Check(ctx context.Context) error {
+	conn, err := grpc.DialContext(ctx, p.Addr,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithBlock(),
+	)
+	if err != nil {
+		return fmt.Errorf("dial %s: %w", p.Addr, err)
+	}
+	defer conn.Close()
+
+	resp, err := grpc_health_v1.NewHealthClient(conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: p.Service})
+	if err != nil {
+		return fmt.Errorf("health check: %w", err)
+	}
+
+	if resp.Status != grpc_health_v1.HealthCheckResponse_SERVING {
+		return fmt.Errorf("service %q is %s", p.Service, resp.Status)
+	}
+
+	return nil
+}
+
+// CommandProbe succeeds once running Command with Args exits 0, for
+// anything without a network-reachable health signal (e.g. a custom
+// readiness script).
+type CommandProbe struct {
+	Command string
+	Args    []string
+}
+
+func (p CommandProbe) Name() string { return fmt.Sprintf("cmd:%s", p.Command) }
+
+func (p CommandProbe) Check(ctx context.Context) error {
+	cmd := exec.CommandContext(ctx, p.Command, p.Args...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %w: %s", p.Command, err, bytes.TrimSpace(out))
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package platform
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kashifsb/nsm/pkg/health"
+	"github.com/kashifsb/nsm/pkg/logger"
+)
+
+// ProbeSpec registers a Probe with Readiness: how often to run it, how long
+// a single run may take, and how many consecutive successes it needs
+// before it's considered ready.
+type ProbeSpec struct {
+	Probe            Probe
+	Interval         time.Duration
+	Timeout          time.Duration
+	SuccessThreshold int
+}
+
+// Readiness runs a set of probes concurrently until each has reported
+// SuccessThreshold consecutive successes, inspired by InVisionApp/go-health.
+// It exists because a listening TCP socket isn't proof a dev server can
+// actually serve a request yet (Next.js opens its port well before
+// compilation finishes; a Rails app may need /up to return 200).
+type Readiness struct {
+	mu      sync.Mutex
+	probes  []*registeredProbe
+	tracker *health.Tracker
+}
+
+type registeredProbe struct {
+	spec          ProbeSpec
+	consecutiveOK int
+}
+
+// NewReadiness returns an empty Readiness ready to have probes registered.
+func NewReadiness() *Readiness {
+	return &Readiness{tracker: health.NewTracker()}
+}
+
+// Register adds a probe to wait on. Unset Interval/Timeout/SuccessThreshold
+// default to 1s, 5s, and 1 respectively.
+func (r *Readiness) Register(spec ProbeSpec) {
+	if spec.Interval <= 0 {
+		spec.Interval = time.Second
+	}
+	if spec.Timeout <= 0 {
+		spec.Timeout = 5 * time.Second
+	}
+	if spec.SuccessThreshold <= 0 {
+		spec.SuccessThreshold = 1
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.probes = append(r.probes, &registeredProbe{spec: spec})
+}
+
+// Statuses returns every registered probe's most recent state, so a caller
+// (e.g. the TUI) can render a live checklist with ui.StatusIndicator
+// without depending on this package's internals.
+func (r *Readiness) Statuses() []health.Status {
+	return r.tracker.All()
+}
+
+// WaitAll runs every registered probe concurrently, polling at its own
+// interval, and returns once every probe has reported SuccessThreshold
+// consecutive successes - or ctx is done, whichever happens first.
+func (r *Readiness) WaitAll(ctx context.Context) error {
+	r.mu.Lock()
+	probes := append([]*registeredProbe(nil), r.probes...)
+	r.mu.Unlock()
+
+	if len(probes) == 0 {
+		return nil
+	}
+
+	results := make(chan error, len(probes))
+	var wg sync.WaitGroup
+
+	for _, rp := range probes {
+		wg.Add(1)
+		go func(rp *registeredProbe) {
+			defer wg.Done()
+			results <- r.runProbe(ctx, rp)
+		}(rp)
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var firstErr error
+	for err := range results {
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// runProbe polls rp.spec.Probe at its configured interval until it's
+// succeeded SuccessThreshold times in a row or ctx is done, logging every
+// state transition and recording it in the tracker.
+func (r *Readiness) runProbe(ctx context.Context, rp *registeredProbe) error {
+	name := rp.spec.Probe.Name()
+	log := logger.Attrs("probe", name)
+	r.tracker.Set(name, health.StateUnknown, nil)
+
+	ticker := time.NewTicker(rp.spec.Interval)
+	defer ticker.Stop()
+
+	for {
+		checkCtx, cancel := context.WithTimeout(ctx, rp.spec.Timeout)
+		err := rp.spec.Probe.Check(checkCtx)
+		cancel()
+
+		if err != nil {
+			if rp.consecutiveOK > 0 {
+				log.Info("probe regressed", "error", err)
+			} else {
+				log.Debug("probe not ready", "error", err)
+			}
+			rp.consecutiveOK = 0
+			r.tracker.Set(name, health.StateError, err)
+		} else {
+			rp.consecutiveOK++
+			log.Debug("probe succeeded", "consecutive", rp.consecutiveOK, "threshold", rp.spec.SuccessThreshold)
+
+			if rp.consecutiveOK >= rp.spec.SuccessThreshold {
+				r.tracker.Set(name, health.StateOK, nil)
+				log.Info("probe ready")
+				return nil
+			}
+			r.tracker.Set(name, health.StateWarning, nil)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("probe %q did not become ready: %w", name, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
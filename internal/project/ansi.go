@@ -0,0 +1,15 @@
+package project
+
+import "regexp"
+
+// ansiCSI matches ANSI CSI escape sequences (color codes, cursor moves,
+// Vite/webpack's progress banners, etc).
+var ansiCSI = regexp.MustCompile("\x1b\\[[0-9;]*[a-zA-Z]")
+
+// StripANSI removes ANSI CSI escape sequences from s, leaving plain text.
+// Used when rendering output in the TUI or canonicalizing a LogEvent;
+// --raw output bypasses this to pass the original bytes straight to the
+// terminal.
+func StripANSI(s string) string {
+	return ansiCSI.ReplaceAllString(s, "")
+}
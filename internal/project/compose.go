@@ -0,0 +1,135 @@
+package project
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ComposeFilename is the name of the optional multi-service manifest a
+// project can supply instead of a single Command, letting NSM supervise
+// e.g. a frontend dev server, an API process, and a worker together.
+const ComposeFilename = "nsm.yaml"
+
+// ReadySpec declares how Supervisor decides a service is ready before
+// starting anything that depends_on it. At most one of HTTP, TCP, or
+// LogRegex should be set; a zero ReadySpec means "ready as soon as the
+// process starts."
+type ReadySpec struct {
+	HTTP     string `yaml:"http"`
+	TCP      string `yaml:"tcp"`
+	LogRegex string `yaml:"log_regex"`
+}
+
+// RestartPolicy controls what Supervisor does when a service's process
+// exits on its own (not via Stop).
+type RestartPolicy string
+
+const (
+	RestartOnFailure RestartPolicy = "on-failure" // the default
+	RestartAlways    RestartPolicy = "always"
+	RestartNo        RestartPolicy = "no"
+)
+
+// ServiceSpec is one entry in a compose manifest's services block.
+type ServiceSpec struct {
+	Command   string            `yaml:"cmd"`
+	Dir       string            `yaml:"dir"`
+	Env       map[string]string `yaml:"env"`
+	DependsOn []string          `yaml:"depends_on"`
+	Ready     ReadySpec         `yaml:"ready"`
+	Restart   RestartPolicy     `yaml:"restart"`
+}
+
+// ComposeManifest is the parsed contents of nsm.yaml: a named set of
+// services to run together.
+type ComposeManifest struct {
+	Services map[string]ServiceSpec `yaml:"services"`
+}
+
+// LoadCompose reads and parses ComposeFilename from projectDir. A missing
+// manifest is not an error - it signals the caller should fall back to
+// running cfg.Command as a single process via Runner.
+func LoadCompose(projectDir string) (*ComposeManifest, error) {
+	path := filepath.Join(projectDir, ComposeFilename)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read %s: %w", ComposeFilename, err)
+	}
+
+	var m ComposeManifest
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", ComposeFilename, err)
+	}
+
+	for name, svc := range m.Services {
+		if svc.Command == "" {
+			return nil, fmt.Errorf("service %q: cmd is required", name)
+		}
+	}
+
+	return &m, nil
+}
+
+// order returns service names in dependency order via a topological sort
+// of depends_on (Kahn's algorithm): every service appears after all of
+// its dependencies. Ties are broken alphabetically so the order is
+// deterministic across runs. It errors on an unknown dependency or a
+// cycle.
+func (m *ComposeManifest) order() ([]string, error) {
+	indegree := make(map[string]int, len(m.Services))
+	dependents := make(map[string][]string, len(m.Services))
+
+	for name := range m.Services {
+		indegree[name] = 0
+	}
+
+	for name, svc := range m.Services {
+		for _, dep := range svc.DependsOn {
+			if _, ok := m.Services[dep]; !ok {
+				return nil, fmt.Errorf("service %q depends_on unknown service %q", name, dep)
+			}
+			indegree[name]++
+			dependents[dep] = append(dependents[dep], name)
+		}
+	}
+
+	var ready []string
+	for name, n := range indegree {
+		if n == 0 {
+			ready = append(ready, name)
+		}
+	}
+	sort.Strings(ready)
+
+	var order []string
+	for len(ready) > 0 {
+		name := ready[0]
+		ready = ready[1:]
+		order = append(order, name)
+
+		var newlyReady []string
+		for _, dependent := range dependents[name] {
+			indegree[dependent]--
+			if indegree[dependent] == 0 {
+				newlyReady = append(newlyReady, dependent)
+			}
+		}
+		sort.Strings(newlyReady)
+		ready = append(ready, newlyReady...)
+		sort.Strings(ready)
+	}
+
+	if len(order) != len(m.Services) {
+		return nil, fmt.Errorf("services have a depends_on cycle")
+	}
+
+	return order, nil
+}
@@ -0,0 +1,373 @@
+package project
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LogLevel is a canonical severity, independent of whatever vocabulary the
+// process underneath happens to use (bunyan's numeric levels, zap's
+// lowercase strings, slog's uppercase ones, ...).
+type LogLevel string
+
+const (
+	LevelTrace LogLevel = "trace"
+	LevelDebug LogLevel = "debug"
+	LevelInfo  LogLevel = "info"
+	LevelWarn  LogLevel = "warn"
+	LevelError LogLevel = "error"
+	LevelFatal LogLevel = "fatal"
+)
+
+// levelRank orders levels for "level>=warn"-style filter comparisons.
+var levelRank = map[LogLevel]int{
+	LevelTrace: 0,
+	LevelDebug: 1,
+	LevelInfo:  2,
+	LevelWarn:  3,
+	LevelError: 4,
+	LevelFatal: 5,
+}
+
+// LogEvent is one line of a service's output, normalized by a Detector
+// (or, failing that, by the plain-text fallback) so the TUI and
+// --log-format=json can treat every process the same way regardless of
+// what logging library it used.
+type LogEvent struct {
+	Level     LogLevel       `json:"level"`
+	Timestamp time.Time      `json:"timestamp"`
+	Message   string         `json:"message"`
+	Fields    map[string]any `json:"fields,omitempty"`
+	Raw       string         `json:"-"`
+}
+
+// Detector recognizes one line-oriented log framing and parses it into a
+// LogEvent. ParseLine tries each registered Detector in turn; the first
+// match wins.
+type Detector interface {
+	Name() string
+	Detect(line string) (LogEvent, bool)
+}
+
+// logDetectors is the chain ParseLine tries, most specific first. The
+// plain-text detector is intentionally absent here - it always matches, so
+// ParseLine falls back to it directly rather than wasting a chain slot.
+var logDetectors = []Detector{
+	jsonDetector{},
+	slogTextDetector{},
+	viteDetector{},
+	webpackDetector{},
+	nextDetector{},
+}
+
+// ParseLine strips ANSI escapes and runs line through the Detector chain,
+// falling back to plainDetector if nothing more specific recognizes it.
+// The original, unstripped line is preserved as LogEvent.Raw for --raw
+// passthrough.
+func ParseLine(line string) LogEvent {
+	clean := StripANSI(line)
+
+	for _, d := range logDetectors {
+		if ev, ok := d.Detect(clean); ok {
+			ev.Raw = line
+			return ev
+		}
+	}
+
+	ev := plainDetector{}.detect(clean)
+	ev.Raw = line
+	return ev
+}
+
+// jsonDetector recognizes JSON-per-line logs in the bunyan, pino, zap, and
+// slog JSON-handler styles: a single object with some spelling of a
+// message, level, and timestamp field, plus arbitrary structured fields.
+type jsonDetector struct{}
+
+func (jsonDetector) Name() string { return "json" }
+
+func (jsonDetector) Detect(line string) (LogEvent, bool) {
+	trimmed := strings.TrimSpace(line)
+	if !strings.HasPrefix(trimmed, "{") || !strings.HasSuffix(trimmed, "}") {
+		return LogEvent{}, false
+	}
+
+	var raw map[string]any
+	if err := json.Unmarshal([]byte(trimmed), &raw); err != nil {
+		return LogEvent{}, false
+	}
+
+	msg, ok := popFirstString(raw, "msg", "message")
+	if !ok {
+		return LogEvent{}, false
+	}
+
+	level := LevelInfo
+	if v, ok := popFirst(raw, "level", "severity"); ok {
+		level = canonicalizeLevel(v)
+	}
+
+	ts := time.Time{}
+	if v, ok := popFirst(raw, "time", "ts", "timestamp", "@timestamp"); ok {
+		ts = parseTimestamp(v)
+	}
+
+	// Whatever's left over is structured context (service, requestId, ...).
+	for _, key := range []string{"name", "hostname", "pid", "v", "caller"} {
+		delete(raw, key)
+	}
+
+	return LogEvent{
+		Level:     level,
+		Timestamp: ts,
+		Message:   msg,
+		Fields:    raw,
+	}, true
+}
+
+// slogTextDetector recognizes Go's log/slog text handler output:
+// space-separated key=value pairs, values optionally double-quoted, with
+// time/level/msg as the first three keys.
+type slogTextDetector struct{}
+
+func (slogTextDetector) Name() string { return "slog-text" }
+
+var kvPairRe = regexp.MustCompile(`(\w+)=("(?:[^"\\]|\\.)*"|\S*)`)
+
+func (slogTextDetector) Detect(line string) (LogEvent, bool) {
+	if !strings.Contains(line, "level=") || !strings.Contains(line, "msg=") {
+		return LogEvent{}, false
+	}
+
+	fields := make(map[string]any)
+	for _, m := range kvPairRe.FindAllStringSubmatch(line, -1) {
+		key, value := m[1], unquoteKV(m[2])
+		fields[key] = value
+	}
+
+	msg, ok := fields["msg"].(string)
+	if !ok {
+		return LogEvent{}, false
+	}
+	delete(fields, "msg")
+
+	level := LevelInfo
+	if v, ok := fields["level"]; ok {
+		level = canonicalizeLevel(v)
+		delete(fields, "level")
+	}
+
+	ts := time.Time{}
+	if v, ok := fields["time"]; ok {
+		if s, ok := v.(string); ok {
+			ts = parseTimestamp(s)
+		}
+		delete(fields, "time")
+	}
+
+	return LogEvent{
+		Level:     level,
+		Timestamp: ts,
+		Message:   msg,
+		Fields:    fields,
+	}, true
+}
+
+// viteDetector recognizes Vite's startup banner and HMR lines, e.g.
+// "VITE v5.0.0  ready in 320 ms" or "➜  Local:   http://localhost:5173/".
+type viteDetector struct{}
+
+func (viteDetector) Name() string { return "vite" }
+
+var viteRe = regexp.MustCompile(`^(VITE v[\d.]+|ready in \d+ ?ms|➜\s+(Local|Network):|page reload|hmr update)`)
+
+func (viteDetector) Detect(line string) (LogEvent, bool) {
+	trimmed := strings.TrimSpace(line)
+	if !viteRe.MatchString(trimmed) {
+		return LogEvent{}, false
+	}
+	return LogEvent{Level: LevelInfo, Message: trimmed}, true
+}
+
+// webpackDetector recognizes webpack-dev-server stats output, e.g.
+// "webpack compiled successfully", "ERROR in ./src/App.js", or an
+// "asset main.js 1.2 MiB [emitted]" table row.
+type webpackDetector struct{}
+
+func (webpackDetector) Name() string { return "webpack" }
+
+var webpackRe = regexp.MustCompile(`^(webpack compiled|asset \S|ERROR in |WARNING in )`)
+
+func (webpackDetector) Detect(line string) (LogEvent, bool) {
+	trimmed := strings.TrimSpace(line)
+	if !webpackRe.MatchString(trimmed) {
+		return LogEvent{}, false
+	}
+
+	level := LevelInfo
+	switch {
+	case strings.HasPrefix(trimmed, "ERROR in "):
+		level = LevelError
+	case strings.HasPrefix(trimmed, "WARNING in "):
+		level = LevelWarn
+	}
+
+	return LogEvent{Level: level, Message: trimmed}, true
+}
+
+// nextDetector recognizes Next.js's "event - ..." dev server lines, e.g.
+// "event - compiled client and server successfully in 420 ms".
+type nextDetector struct{}
+
+func (nextDetector) Name() string { return "next" }
+
+var nextRe = regexp.MustCompile(`^event - `)
+
+func (nextDetector) Detect(line string) (LogEvent, bool) {
+	trimmed := strings.TrimSpace(line)
+	if !nextRe.MatchString(trimmed) {
+		return LogEvent{}, false
+	}
+
+	level := LevelInfo
+	if strings.Contains(strings.ToLower(trimmed), "error") {
+		level = LevelError
+	}
+
+	return LogEvent{Level: level, Message: trimmed}, true
+}
+
+// plainDetector is the fallback when nothing more specific matched: it
+// keeps the line verbatim as Message and infers a level from a handful of
+// substrings, same heuristic the old isImportantLine used.
+type plainDetector struct{}
+
+func (plainDetector) Name() string { return "plain" }
+
+func (plainDetector) Detect(line string) (LogEvent, bool) {
+	return plainDetector{}.detect(line), true
+}
+
+var plainLevelPatterns = []struct {
+	level   LogLevel
+	pattern string
+}{
+	{LevelFatal, "fatal"},
+	{LevelFatal, "panic"},
+	{LevelError, "error"},
+	{LevelWarn, "warning"},
+	{LevelWarn, "warn"},
+}
+
+func (plainDetector) detect(line string) LogEvent {
+	level := LevelInfo
+	lower := strings.ToLower(line)
+	for _, p := range plainLevelPatterns {
+		if strings.Contains(lower, p.pattern) {
+			level = p.level
+			break
+		}
+	}
+
+	return LogEvent{Level: level, Message: strings.TrimSpace(line)}
+}
+
+// canonicalizeLevel maps a JSON/text log library's level spelling -
+// bunyan/pino's numeric levels, zap/slog's string ones - to our canonical
+// LogLevel, defaulting to info for anything unrecognized.
+func canonicalizeLevel(v any) LogLevel {
+	switch t := v.(type) {
+	case float64:
+		return canonicalizeNumericLevel(t)
+	case string:
+		if n, err := strconv.ParseFloat(t, 64); err == nil {
+			return canonicalizeNumericLevel(n)
+		}
+		switch strings.ToLower(t) {
+		case "trace":
+			return LevelTrace
+		case "debug":
+			return LevelDebug
+		case "info", "informational":
+			return LevelInfo
+		case "warn", "warning":
+			return LevelWarn
+		case "error":
+			return LevelError
+		case "fatal", "panic", "critical":
+			return LevelFatal
+		}
+	}
+	return LevelInfo
+}
+
+// canonicalizeNumericLevel maps bunyan/pino's numeric levels (10/20/30/
+// 40/50/60) onto our canonical levels.
+func canonicalizeNumericLevel(n float64) LogLevel {
+	switch {
+	case n <= 10:
+		return LevelTrace
+	case n <= 20:
+		return LevelDebug
+	case n <= 30:
+		return LevelInfo
+	case n <= 40:
+		return LevelWarn
+	case n <= 50:
+		return LevelError
+	default:
+		return LevelFatal
+	}
+}
+
+// parseTimestamp tries the timestamp formats the supported log libraries
+// actually emit, returning the zero time if none match.
+func parseTimestamp(v any) time.Time {
+	switch t := v.(type) {
+	case float64:
+		// bunyan/pino emit epoch milliseconds.
+		return time.UnixMilli(int64(t))
+	case string:
+		for _, layout := range []string{time.RFC3339Nano, time.RFC3339, "2006-01-02T15:04:05.000Z0700"} {
+			if ts, err := time.Parse(layout, t); err == nil {
+				return ts
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// popFirst returns and deletes the first of keys present in m.
+func popFirst(m map[string]any, keys ...string) (any, bool) {
+	for _, k := range keys {
+		if v, ok := m[k]; ok {
+			delete(m, k)
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// popFirstString is popFirst narrowed to string values.
+func popFirstString(m map[string]any, keys ...string) (string, bool) {
+	v, ok := popFirst(m, keys...)
+	if !ok {
+		return "", false
+	}
+	s, ok := v.(string)
+	return s, ok
+}
+
+// unquoteKV strips one layer of double quotes (and their escaping) from a
+// slogTextDetector value, e.g. `"starting server"` -> `starting server`.
+func unquoteKV(s string) string {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		if unquoted, err := strconv.Unquote(s); err == nil {
+			return unquoted
+		}
+	}
+	return s
+}
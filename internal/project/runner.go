@@ -1,45 +1,65 @@
 package project
 
 import (
-	"bufio"
 	"context"
+	"encoding/json"
 	"fmt"
-	"io"
 	"os"
-	"os/exec"
 	"strings"
-	"syscall"
-	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/google/shlex"
 
 	"github.com/kashifsb/nsm/internal/config"
+	"github.com/kashifsb/nsm/internal/platform"
 	"github.com/kashifsb/nsm/pkg/logger"
+	"github.com/kashifsb/nsm/pkg/process"
 )
 
+// defaultMaxRestarts caps how many times Runner restarts a dev command that
+// keeps crashing before giving up and leaving it stopped.
+const defaultMaxRestarts = 5
+
 type Runner struct {
-	cfg     *config.Config
-	cmd     *exec.Cmd
-	program *tea.Program
+	cfg      *config.Config
+	program  *tea.Program
+	sup      *process.Supervisor
+	onGiveUp func(error)
 }
 
 type RunnerConfig struct {
 	WorkingDir string
 	Command    string
 	Env        map[string]string
+
+	// OnCrashExhausted, if set, is called once the supervised command has
+	// crashed more times than defaultMaxRestarts and Runner has given up
+	// restarting it, so a caller (e.g. an outer orchestrator) can treat the
+	// dev server as terminally failed rather than just transiently restarting.
+	OnCrashExhausted func(error)
 }
 
 type OutputMsg struct {
-	Source string // "stdout" or "stderr"
-	Line   string
+	Service string // empty for Runner's single command; set to the service name by Supervisor
+	Source  string // "stdout" or "stderr"
+	Line    string // canonicalized, ANSI-stripped message (LogEvent.Message)
+	Level   LogLevel
+	Fields  map[string]any
+	Raw     string // original line, ANSI intact, for --raw passthrough
 }
 
 type ProcessExitMsg struct {
+	Service  string // empty for Runner's single command; set to the service name by Supervisor
 	ExitCode int
 	Error    error
 }
 
+// RestartMsg reports that Runner's supervised dev command crashed and is
+// being restarted; the UI renders it against the "dev" StatusStep.
+type RestartMsg struct {
+	Attempt int
+}
+
 func NewRunner(cfg *config.Config, program *tea.Program) *Runner {
 	return &Runner{
 		cfg:     cfg,
@@ -47,105 +67,58 @@ func NewRunner(cfg *config.Config, program *tea.Program) *Runner {
 	}
 }
 
+// Start parses runnerCfg.Command and hands it to a process.Supervisor,
+// which runs it in its own process group, restarts it with backoff if it
+// crashes, and streams its output back through r.handleOutput.
 func (r *Runner) Start(ctx context.Context, runnerCfg RunnerConfig) error {
-	// Parse command
-	args, err := r.parseCommand(runnerCfg.Command)
+	args, err := parseCommand(runnerCfg.Command)
 	if err != nil {
 		return fmt.Errorf("parse command: %w", err)
 	}
 
-	// Create command
-	r.cmd = exec.CommandContext(ctx, args[0], args[1:]...)
-	r.cmd.Dir = runnerCfg.WorkingDir
-	r.cmd.Env = r.buildEnvironment(runnerCfg.Env)
-
-	// Set process group to handle cleanup properly
-	r.cmd.SysProcAttr = &syscall.SysProcAttr{
-		Setpgid: true,
-	}
-
-	// Setup pipes
-	stdout, err := r.cmd.StdoutPipe()
-	if err != nil {
-		return fmt.Errorf("create stdout pipe: %w", err)
-	}
-
-	stderr, err := r.cmd.StderrPipe()
-	if err != nil {
-		return fmt.Errorf("create stderr pipe: %w", err)
-	}
-
-	// Start command
 	logger.Info("Starting development command",
 		"command", runnerCfg.Command,
 		"working_dir", runnerCfg.WorkingDir)
 
-	if err := r.cmd.Start(); err != nil {
-		return fmt.Errorf("start command: %w", err)
-	}
-
-	// Start output streaming
-	go r.streamOutput(stdout, "stdout")
-	go r.streamOutput(stderr, "stderr")
-
-	// Wait for completion
-	go r.waitForCompletion()
-
-	return nil
+	r.onGiveUp = runnerCfg.OnCrashExhausted
+
+	r.sup = process.NewSupervisor(process.Config{
+		WorkingDir:  runnerCfg.WorkingDir,
+		Command:     args[0],
+		Args:        args[1:],
+		Env:         buildEnvironment(r.cfg, runnerCfg.Env, ""),
+		MaxRestarts: defaultMaxRestarts,
+		OnOutput:    r.handleOutput,
+		OnExit:      r.handleExit,
+		OnRestart:   r.handleRestart,
+		OnGiveUp:    r.handleGiveUp,
+	})
+
+	return r.sup.Start(ctx)
 }
 
 func (r *Runner) Stop() error {
-	if r.cmd == nil || r.cmd.Process == nil {
+	if r.sup == nil {
 		return nil
 	}
-
-	logger.Info("Stopping development command")
-
-	// Send SIGTERM to the process group
-	pgid, err := syscall.Getpgid(r.cmd.Process.Pid)
-	if err == nil {
-		syscall.Kill(-pgid, syscall.SIGTERM)
-	} else {
-		// Fallback to killing just the main process
-		r.cmd.Process.Signal(os.Interrupt)
-	}
-
-	// Wait for graceful shutdown with timeout
-	done := make(chan error, 1)
-	go func() {
-		done <- r.cmd.Wait()
-	}()
-
-	select {
-	case <-done:
-		logger.Info("Development command stopped gracefully")
-		return nil
-	case <-time.After(10 * time.Second):
-		logger.Warn("Development command didn't stop gracefully, forcing kill")
-
-		// Force kill the process group
-		if pgid, err := syscall.Getpgid(r.cmd.Process.Pid); err == nil {
-			syscall.Kill(-pgid, syscall.SIGKILL)
-		} else {
-			r.cmd.Process.Kill()
-		}
-
-		return fmt.Errorf("process killed after timeout")
-	}
+	return r.sup.Stop()
 }
 
 func (r *Runner) IsRunning() bool {
-	return r.cmd != nil && r.cmd.Process != nil && r.cmd.ProcessState == nil
+	return r.sup != nil && r.sup.IsRunning()
 }
 
 func (r *Runner) GetPID() int {
-	if r.cmd != nil && r.cmd.Process != nil {
-		return r.cmd.Process.Pid
+	if r.sup == nil {
+		return 0
 	}
-	return 0
+	return r.sup.PID()
 }
 
-func (r *Runner) parseCommand(command string) ([]string, error) {
+// parseCommand splits command into argv, preferring shell-aware shlex
+// parsing (so quoted arguments survive) and falling back to a naive
+// whitespace split if shlex can't make sense of it.
+func parseCommand(command string) ([]string, error) {
 	// First try shlex for proper shell parsing
 	args, err := shlex.Split(command)
 	if err != nil {
@@ -156,7 +129,11 @@ func (r *Runner) parseCommand(command string) ([]string, error) {
 	return args, nil
 }
 
-func (r *Runner) buildEnvironment(extraEnv map[string]string) []string {
+// buildEnvironment returns the environment a child process (Runner's
+// single command, or one of Supervisor's services) should start with:
+// the parent's environment, extraEnv, and NSM_* variables describing the
+// project. serviceName is injected as NSM_SERVICE_NAME when non-empty.
+func buildEnvironment(cfg *config.Config, extraEnv map[string]string, serviceName string) []string {
 	env := os.Environ()
 
 	// Add extra environment variables
@@ -168,19 +145,23 @@ func (r *Runner) buildEnvironment(extraEnv map[string]string) []string {
 	nsmEnv := map[string]string{
 		"NSM_ENABLED":      "true",
 		"NSM_VERSION":      "3.0.0",
-		"NSM_PROJECT_TYPE": string(r.cfg.ProjectType),
-		"NSM_PROJECT_NAME": r.cfg.ProjectName,
-		"NSM_DOMAIN":       r.cfg.Domain,
-		"NSM_DATA_DIR":     r.cfg.DataDir,
+		"NSM_PROJECT_TYPE": string(cfg.ProjectType),
+		"NSM_PROJECT_NAME": cfg.ProjectName,
+		"NSM_DOMAIN":       cfg.Domain,
+		"NSM_DATA_DIR":     cfg.DataDir,
+	}
+
+	if serviceName != "" {
+		nsmEnv["NSM_SERVICE_NAME"] = serviceName
 	}
 
-	if r.cfg.EnableHTTPS {
+	if cfg.EnableHTTPS {
 		nsmEnv["NSM_HTTPS_ENABLED"] = "true"
-		nsmEnv["NSM_CERT_PATH"] = r.cfg.CertPath
-		nsmEnv["NSM_KEY_PATH"] = r.cfg.KeyPath
+		nsmEnv["NSM_CERT_PATH"] = cfg.CertPath
+		nsmEnv["NSM_KEY_PATH"] = cfg.KeyPath
 	}
 
-	if r.cfg.UsePort443 {
+	if cfg.UsePort443 {
 		nsmEnv["NSM_CLEAN_URLS"] = "true"
 	}
 
@@ -191,86 +172,40 @@ func (r *Runner) buildEnvironment(extraEnv map[string]string) []string {
 	return env
 }
 
-func (r *Runner) streamOutput(reader io.Reader, source string) {
-	scanner := bufio.NewScanner(reader)
-	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024) // Handle large lines
-
-	for scanner.Scan() {
-		line := scanner.Text()
-
-		// Filter and enhance output
-		line = r.processOutputLine(line, source)
-
-		// Send to UI
-		if r.program != nil {
-			r.program.Send(OutputMsg{
-				Source: source,
-				Line:   line,
-			})
-		}
-
-		// Also log for debugging
-		if source == "stderr" {
-			logger.Debug("Dev command stderr", "line", line)
-		} else {
-			logger.Debug("Dev command stdout", "line", line)
-		}
-	}
+// handleOutput is process.Supervisor's OnOutput callback: it canonicalizes
+// the line, forwards it to the UI and --log-format=json, and mirrors it
+// into the debug log.
+func (r *Runner) handleOutput(source, line string) {
+	event := ParseLine(line)
 
-	if err := scanner.Err(); err != nil {
-		logger.Error("Error reading command output", "source", source, "error", err)
+	if r.cfg.RawOutput {
+		fmt.Println(line)
 	}
-}
-
-func (r *Runner) processOutputLine(line, source string) string {
-	// Remove ANSI color codes if needed (optional)
-	// line = stripANSI(line)
 
-	// Add timestamp for important messages
-	if r.isImportantLine(line) {
-		timestamp := time.Now().Format("15:04:05")
-		return fmt.Sprintf("[%s] %s", timestamp, line)
+	if r.program != nil {
+		r.program.Send(OutputMsg{
+			Source: source,
+			Line:   event.Message,
+			Level:  event.Level,
+			Fields: event.Fields,
+			Raw:    event.Raw,
+		})
 	}
 
-	return line
-}
-
-func (r *Runner) isImportantLine(line string) bool {
-	importantPatterns := []string{
-		"error",
-		"Error",
-		"ERROR",
-		"warning",
-		"Warning",
-		"WARN",
-		"Local:",
-		"Network:",
-		"ready in",
-		"compiled",
-		"running at",
-		"listening on",
-		"server started",
+	if r.cfg.LogFormat == "json" {
+		emitJSONEvent(event)
 	}
 
-	lineLower := strings.ToLower(line)
-	for _, pattern := range importantPatterns {
-		if strings.Contains(lineLower, strings.ToLower(pattern)) {
-			return true
-		}
+	if source == "stderr" {
+		logger.Debug("Dev command stderr", "line", event.Message)
+	} else {
+		logger.Debug("Dev command stdout", "line", event.Message)
 	}
-
-	return false
 }
 
-func (r *Runner) waitForCompletion() {
-	err := r.cmd.Wait()
-
-	exitCode := 0
-	if r.cmd.ProcessState != nil {
-		exitCode = r.cmd.ProcessState.ExitCode()
-	}
-
-	// Send completion message to UI
+// handleExit is process.Supervisor's OnExit callback, called every time the
+// dev command exits whether or not it's about to be restarted.
+func (r *Runner) handleExit(exitCode int, err error) {
 	if r.program != nil {
 		r.program.Send(ProcessExitMsg{
 			ExitCode: exitCode,
@@ -287,10 +222,36 @@ func (r *Runner) waitForCompletion() {
 	}
 }
 
-// Helper function to strip ANSI escape codes (optional)
-func stripANSI(str string) string {
-	// This is a simple implementation - you might want to use a proper library
-	const ansi = "[\u001B\u009B][[\\]()#;?]*(?:(?:(?:[a-zA-Z\\d]*(?:;[a-zA-Z\\d]*)*)?\u0007)|(?:(?:\\d{1,4}(?:;\\d{0,4})*)?[\\dA-PRZ-z]))"
-	// For simplicity, returning as-is. Implement proper ANSI stripping if needed.
-	return str
+// handleRestart is process.Supervisor's OnRestart callback, called just
+// before each restart attempt.
+func (r *Runner) handleRestart(attempt int) {
+	logger.Warn("Restarting development command", "attempt", attempt)
+	if r.program != nil {
+		r.program.Send(RestartMsg{Attempt: attempt})
+	}
+
+	msg := fmt.Sprintf("Dev server crashed, restarting (attempt %d)", attempt)
+	if err := platform.Notify("NSM", msg); err != nil {
+		logger.Debug("Failed to send crash notification", "error", err)
+	}
+}
+
+// handleGiveUp is process.Supervisor's OnGiveUp callback, called once the
+// dev command has exhausted its restart budget.
+func (r *Runner) handleGiveUp(err error) {
+	logger.Error("Development command crashed repeatedly, giving up", "error", err)
+	if r.onGiveUp != nil {
+		r.onGiveUp(err)
+	}
+}
+
+// emitJSONEvent writes event to stdout as a single canonical JSON line, for
+// --log-format=json piping into jq.
+func emitJSONEvent(event LogEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		logger.Error("Failed to marshal log event", "error", err)
+		return
+	}
+	fmt.Println(string(data))
 }
@@ -0,0 +1,202 @@
+package project
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kashifsb/nsm/internal/config"
+	"github.com/kashifsb/nsm/pkg/logger"
+)
+
+// StaticServer serves cfg.StaticDir directly over plain HTTP instead of
+// spawning Command as a subprocess, for project type "static". It binds
+// the same 127.0.0.1:port Runner/Supervisor would have used, so ProxyServer
+// forwards to it exactly like any other dev server.
+type StaticServer struct {
+	cfg        *config.Config
+	httpServer *http.Server
+}
+
+func NewStaticServer(cfg *config.Config) *StaticServer {
+	return &StaticServer{cfg: cfg}
+}
+
+// Start binds the static file server on port and returns once it's
+// listening; like Runner.Start, it doesn't block on the server's lifetime.
+func (s *StaticServer) Start(ctx context.Context, port int) error {
+	addr := fmt.Sprintf("127.0.0.1:%d", port)
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("listen on %s: %w", addr, err)
+	}
+
+	s.httpServer = &http.Server{
+		Addr:    addr,
+		Handler: newStaticHandler(s.cfg),
+	}
+
+	logger.Info("Starting static file server", "dir", s.cfg.StaticDir, "addr", addr)
+
+	go func() {
+		if err := s.httpServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+			logger.Error("Static file server error", "error", err)
+		}
+	}()
+
+	return nil
+}
+
+func (s *StaticServer) Stop() error {
+	if s.httpServer == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	logger.Info("Stopping static file server")
+	return s.httpServer.Shutdown(ctx)
+}
+
+func (s *StaticServer) IsRunning() bool {
+	return s.httpServer != nil
+}
+
+// newStaticHandler wraps http.FileServer(cfg.StaticDir) with the options
+// chunk4-3 asks for: directory-listing toggle, SPA fallback to
+// index.html, a custom 404 page, and precompressed .br/.gz asset
+// selection.
+func newStaticHandler(cfg *config.Config) http.Handler {
+	fs := listingGatedFileSystem{Dir: http.Dir(cfg.StaticDir), allowListing: cfg.StaticDirListing}
+	fileServer := http.FileServer(fs)
+
+	var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !cfg.StaticSPA && cfg.Static404 == "" {
+			fileServer.ServeHTTP(w, r)
+			return
+		}
+
+		rec := &notFoundRecorder{ResponseWriter: w}
+		fileServer.ServeHTTP(rec, r)
+		if rec.status != http.StatusNotFound {
+			return
+		}
+
+		switch {
+		case cfg.StaticSPA:
+			http.ServeFile(w, r, filepath.Join(cfg.StaticDir, "index.html"))
+		case cfg.Static404 != "":
+			w.WriteHeader(http.StatusNotFound)
+			http.ServeFile(w, r, filepath.Join(cfg.StaticDir, cfg.Static404))
+		}
+	})
+
+	return precompressedHandler(cfg.StaticDir, handler)
+}
+
+// listingGatedFileSystem serves StaticDir like http.Dir, except a
+// directory with no index.html 404s instead of rendering a listing unless
+// allowListing is set.
+type listingGatedFileSystem struct {
+	http.Dir
+	allowListing bool
+}
+
+func (fs listingGatedFileSystem) Open(name string) (http.File, error) {
+	f, err := fs.Dir.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if fs.allowListing {
+		return f, nil
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if !info.IsDir() {
+		return f, nil
+	}
+
+	index, err := fs.Dir.Open(strings.TrimSuffix(name, "/") + "/index.html")
+	if err != nil {
+		f.Close()
+		return nil, os.ErrNotExist
+	}
+	index.Close()
+
+	return f, nil
+}
+
+// notFoundRecorder suppresses a 404 response body so the caller can decide
+// what to serve instead (SPA fallback or a custom 404 page); any other
+// status passes straight through to the underlying ResponseWriter.
+type notFoundRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *notFoundRecorder) WriteHeader(status int) {
+	w.status = status
+	if status == http.StatusNotFound {
+		return
+	}
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *notFoundRecorder) Write(b []byte) (int, error) {
+	if w.status == http.StatusNotFound {
+		return len(b), nil
+	}
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// precompressedHandler serves name+".br" or name+".gz" in place of name
+// when the client advertises support and the precompressed file exists
+// alongside it, so a build step that emits .br/.gz assets is used without
+// NSM recompressing anything itself.
+func precompressedHandler(dir string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		accept := r.Header.Get("Accept-Encoding")
+		for _, enc := range []struct{ name, ext string }{{"br", ".br"}, {"gzip", ".gz"}} {
+			if !strings.Contains(accept, enc.name) {
+				continue
+			}
+
+			candidate := filepath.Join(dir, filepath.Clean(strings.TrimPrefix(r.URL.Path, "/"))+enc.ext)
+			info, err := os.Stat(candidate)
+			if err != nil || info.IsDir() {
+				continue
+			}
+
+			if ct := mime.TypeByExtension(filepath.Ext(r.URL.Path)); ct != "" {
+				w.Header().Set("Content-Type", ct)
+			}
+			w.Header().Set("Content-Encoding", enc.name)
+			w.Header().Set("Vary", "Accept-Encoding")
+			http.ServeFile(w, r, candidate)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
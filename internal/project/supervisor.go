@@ -0,0 +1,372 @@
+package project
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"sync"
+	"syscall"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/kashifsb/nsm/internal/config"
+	"github.com/kashifsb/nsm/internal/platform"
+	"github.com/kashifsb/nsm/pkg/logger"
+)
+
+// Supervisor runs a ComposeManifest's services as a group: it starts them in
+// dependency order, gates each one on its ReadySpec before starting anything
+// that depends_on it, restarts a service that exits according to its
+// RestartPolicy, and tears the whole group down on Stop. Runner remains the
+// path for a project with a single Command; Supervisor is used instead once
+// a project supplies nsm.yaml.
+type Supervisor struct {
+	cfg      *config.Config
+	program  *tea.Program
+	manifest *ComposeManifest
+
+	mu       sync.Mutex
+	services map[string]*serviceProcess
+}
+
+// serviceProcess tracks one running (or restarting) service's process and
+// the output stream the log-regex readiness gate watches.
+type serviceProcess struct {
+	name    string
+	spec    ServiceSpec
+	cmd     *exec.Cmd
+	stopped bool // set by Stop so watch() knows not to restart
+
+	logMu      sync.Mutex
+	logWaiters []logWaiter
+}
+
+type logWaiter struct {
+	re   *regexp.Regexp
+	done chan struct{}
+}
+
+// NewSupervisor returns a Supervisor ready to run manifest's services.
+func NewSupervisor(cfg *config.Config, program *tea.Program, manifest *ComposeManifest) *Supervisor {
+	return &Supervisor{
+		cfg:      cfg,
+		program:  program,
+		manifest: manifest,
+		services: make(map[string]*serviceProcess),
+	}
+}
+
+// Start launches every service in manifest in dependency order, waiting for
+// each one's readiness gate before starting anything that depends_on it.
+// workingDir is the default Dir for a service that doesn't set its own.
+func (s *Supervisor) Start(ctx context.Context, workingDir string) error {
+	order, err := s.manifest.order()
+	if err != nil {
+		return fmt.Errorf("resolve service order: %w", err)
+	}
+
+	for _, name := range order {
+		spec := s.manifest.Services[name]
+
+		dir := spec.Dir
+		if dir == "" {
+			dir = workingDir
+		}
+
+		sp, err := s.startService(ctx, name, spec, dir)
+		if err != nil {
+			return fmt.Errorf("start service %q: %w", name, err)
+		}
+
+		s.mu.Lock()
+		s.services[name] = sp
+		s.mu.Unlock()
+
+		if err := s.waitReady(ctx, sp); err != nil {
+			return fmt.Errorf("service %q: %w", name, err)
+		}
+
+		go s.watch(ctx, sp)
+	}
+
+	return nil
+}
+
+// startService parses spec.Command, launches it in its own process group,
+// and wires its stdout/stderr to streamOutput.
+func (s *Supervisor) startService(ctx context.Context, name string, spec ServiceSpec, dir string) (*serviceProcess, error) {
+	args, err := parseCommand(spec.Command)
+	if err != nil {
+		return nil, fmt.Errorf("parse command: %w", err)
+	}
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = dir
+	cmd.Env = buildEnvironment(s.cfg, spec.Env, name)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("create stdout pipe: %w", err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, fmt.Errorf("create stderr pipe: %w", err)
+	}
+
+	logger.Info("Starting service", "service", name, "command", spec.Command, "dir", dir)
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start command: %w", err)
+	}
+
+	sp := &serviceProcess{name: name, spec: spec, cmd: cmd}
+
+	go s.streamOutput(sp, stdout, "stdout")
+	go s.streamOutput(sp, stderr, "stderr")
+
+	return sp, nil
+}
+
+// waitReady blocks until sp satisfies its ReadySpec, or ctx is done. A zero
+// ReadySpec is ready immediately.
+func (s *Supervisor) waitReady(ctx context.Context, sp *serviceProcess) error {
+	ready := sp.spec.Ready
+
+	switch {
+	case ready.HTTP != "":
+		r := platform.NewReadiness()
+		r.Register(platform.ProbeSpec{Probe: platform.HTTPProbe{URL: ready.HTTP}})
+		return r.WaitAll(ctx)
+
+	case ready.TCP != "":
+		r := platform.NewReadiness()
+		r.Register(platform.ProbeSpec{Probe: platform.TCPProbe{Addr: ready.TCP}})
+		return r.WaitAll(ctx)
+
+	case ready.LogRegex != "":
+		re, err := regexp.Compile(ready.LogRegex)
+		if err != nil {
+			return fmt.Errorf("compile ready.log_regex: %w", err)
+		}
+
+		done := make(chan struct{})
+		sp.logMu.Lock()
+		sp.logWaiters = append(sp.logWaiters, logWaiter{re: re, done: done})
+		sp.logMu.Unlock()
+
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			return fmt.Errorf("service %q did not match ready.log_regex: %w", sp.name, ctx.Err())
+		}
+
+	default:
+		return nil
+	}
+}
+
+// streamOutput tags every line with its service name and forwards it to the
+// UI, checking it against any pending log-regex readiness waiters first.
+func (s *Supervisor) streamOutput(sp *serviceProcess, reader io.Reader, source string) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		event := ParseLine(line)
+
+		if s.cfg.RawOutput {
+			fmt.Println(line)
+		}
+
+		sp.logMu.Lock()
+		var remaining []logWaiter
+		for _, w := range sp.logWaiters {
+			// Match against the original line, not the canonicalized
+			// event.Message: a log_regex in nsm.yaml may target fields a
+			// Detector strips out (e.g. a JSON log's raw "port":3000).
+			if w.re.MatchString(line) {
+				close(w.done)
+			} else {
+				remaining = append(remaining, w)
+			}
+		}
+		sp.logWaiters = remaining
+		sp.logMu.Unlock()
+
+		if s.program != nil {
+			s.program.Send(OutputMsg{
+				Service: sp.name,
+				Source:  source,
+				Line:    event.Message,
+				Level:   event.Level,
+				Fields:  event.Fields,
+				Raw:     event.Raw,
+			})
+		}
+
+		if s.cfg.LogFormat == "json" {
+			emitJSONEvent(event)
+		}
+
+		if source == "stderr" {
+			logger.Debug("service stderr", "service", sp.name, "line", event.Message)
+		} else {
+			logger.Debug("service stdout", "service", sp.name, "line", event.Message)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		logger.Error("error reading service output", "service", sp.name, "source", source, "error", err)
+	}
+}
+
+// watch waits for sp's process to exit, reports the exit to the UI, and
+// restarts it according to its RestartPolicy (default RestartOnFailure)
+// unless Stop already marked it as intentionally stopped.
+func (s *Supervisor) watch(ctx context.Context, sp *serviceProcess) {
+	backoff := time.Second
+
+	for {
+		err := sp.cmd.Wait()
+
+		exitCode := 0
+		if sp.cmd.ProcessState != nil {
+			exitCode = sp.cmd.ProcessState.ExitCode()
+		}
+
+		if s.program != nil {
+			s.program.Send(ProcessExitMsg{
+				Service:  sp.name,
+				ExitCode: exitCode,
+				Error:    err,
+			})
+		}
+
+		if err != nil {
+			logger.Error("service exited with error", "service", sp.name, "exit_code", exitCode, "error", err)
+		} else {
+			logger.Info("service exited", "service", sp.name, "exit_code", exitCode)
+		}
+
+		if sp.stopped || ctx.Err() != nil {
+			return
+		}
+
+		policy := sp.spec.Restart
+		if policy == "" {
+			policy = RestartOnFailure
+		}
+
+		switch policy {
+		case RestartNo:
+			return
+		case RestartOnFailure:
+			if err == nil && exitCode == 0 {
+				return
+			}
+		case RestartAlways:
+		default:
+			return
+		}
+
+		logger.Warn("restarting service", "service", sp.name, "backoff", backoff)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return
+		}
+
+		if backoff < 30*time.Second {
+			backoff *= 2
+		}
+
+		newSP, startErr := s.startService(ctx, sp.name, sp.spec, sp.cmd.Dir)
+		if startErr != nil {
+			logger.Error("failed to restart service", "service", sp.name, "error", startErr)
+			return
+		}
+
+		s.mu.Lock()
+		s.services[sp.name] = newSP
+		s.mu.Unlock()
+
+		sp = newSP
+	}
+}
+
+// Stop terminates every service in reverse dependency order, sending SIGTERM
+// to each one's process group and escalating to SIGKILL after 10 seconds if
+// it doesn't exit gracefully.
+func (s *Supervisor) Stop() error {
+	order, err := s.manifest.order()
+	if err != nil {
+		order = nil
+		for name := range s.manifest.Services {
+			order = append(order, name)
+		}
+	}
+
+	var firstErr error
+	for i := len(order) - 1; i >= 0; i-- {
+		s.mu.Lock()
+		sp := s.services[order[i]]
+		s.mu.Unlock()
+
+		if sp == nil {
+			continue
+		}
+
+		if err := s.stopService(sp); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// stopService sends SIGTERM to sp's process group, waits up to 10 seconds
+// for it to exit, then escalates to SIGKILL.
+func (s *Supervisor) stopService(sp *serviceProcess) error {
+	if sp.cmd == nil || sp.cmd.Process == nil {
+		return nil
+	}
+
+	sp.stopped = true
+
+	logger.Info("stopping service", "service", sp.name)
+
+	pgid, err := syscall.Getpgid(sp.cmd.Process.Pid)
+	if err == nil {
+		syscall.Kill(-pgid, syscall.SIGTERM)
+	} else {
+		sp.cmd.Process.Signal(syscall.SIGTERM)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- sp.cmd.Wait()
+	}()
+
+	select {
+	case <-done:
+		logger.Info("service stopped gracefully", "service", sp.name)
+		return nil
+	case <-time.After(10 * time.Second):
+		logger.Warn("service didn't stop gracefully, forcing kill", "service", sp.name)
+
+		if pgid, err := syscall.Getpgid(sp.cmd.Process.Pid); err == nil {
+			syscall.Kill(-pgid, syscall.SIGKILL)
+		} else {
+			sp.cmd.Process.Kill()
+		}
+
+		return fmt.Errorf("service %q killed after timeout", sp.name)
+	}
+}
@@ -0,0 +1,184 @@
+package server
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kashifsb/nsm/pkg/logger"
+)
+
+// ProxyMode selects what ProxyServer.Start serves: ModeReverse (the
+// default) forwards requests to Upstreams/Routes like a conventional dev
+// proxy, while ModeForward turns it into an HTTP CONNECT / forward proxy a
+// client points its own HTTP_PROXY setting at, for tunneling requests to
+// arbitrary hosts through NSM's TLS termination.
+type ProxyMode string
+
+const (
+	ModeReverse ProxyMode = "reverse"
+	ModeForward ProxyMode = "forward"
+)
+
+// hopByHopHeaders are connection-scoped and must not be forwarded verbatim
+// by a proxy, per RFC 7230 section 6.1.
+var hopByHopHeaders = []string{
+	"Connection",
+	"Proxy-Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+func stripHopByHopHeaders(h http.Header) {
+	for _, header := range hopByHopHeaders {
+		h.Del(header)
+	}
+}
+
+// forwardMiddlewareChain wraps next with the same logging/recovery
+// middleware the reverse-proxy chain uses, but skips corsMiddleware -
+// CORS is a browser same-origin concept that doesn't apply to a forward
+// proxy tunneling requests to arbitrary third-party hosts.
+func (p *ProxyServer) forwardMiddlewareChain(next http.Handler) http.Handler {
+	return p.recoveryMiddleware(p.loggingMiddleware(next))
+}
+
+// forwardHandler dispatches CONNECT (TLS tunneling) and plain absolute-URI
+// HTTP requests, the two request shapes a client sends when its
+// HTTP_PROXY/HTTPS_PROXY is pointed at NSM.
+func (p *ProxyServer) forwardHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodConnect {
+			p.handleConnect(w, r)
+			return
+		}
+		p.handleForwardHTTP(w, r)
+	})
+}
+
+// handleConnect tunnels a CONNECT request's target host:port: dial it,
+// reply 200 Connection Established, then hijack the client connection and
+// copy bytes both ways until either side closes - the same shape as
+// handleUpgrade's WebSocket tunnel, just keyed off CONNECT instead of
+// Connection: Upgrade.
+func (p *ProxyServer) handleConnect(w http.ResponseWriter, r *http.Request) {
+	if !p.hostAllowed(r.URL.Hostname()) {
+		logger.Warn("Forward proxy: CONNECT denied", "host", r.Host)
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	destConn, err := net.DialTimeout("tcp", r.Host, 10*time.Second)
+	if err != nil {
+		logger.Error("Forward proxy: CONNECT dial failed", "error", err, "host", r.Host)
+		http.Error(w, "Bad Gateway", http.StatusBadGateway)
+		return
+	}
+	defer destConn.Close()
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		logger.Error("Forward proxy: ResponseWriter doesn't support hijacking")
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	clientConn, _, err := hijacker.Hijack()
+	if err != nil {
+		logger.Error("Forward proxy: hijack failed", "error", err)
+		return
+	}
+	defer clientConn.Close()
+
+	if _, err := clientConn.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err != nil {
+		logger.Error("Forward proxy: write CONNECT response failed", "error", err)
+		return
+	}
+
+	logger.Debug("Forward proxy tunneling", "host", r.Host)
+
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(destConn, clientConn)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(clientConn, destConn)
+		done <- struct{}{}
+	}()
+	<-done
+}
+
+// handleForwardHTTP serves a plain (non-CONNECT) forward-proxy request -
+// an absolute-URI request line, as a client's HTTP_PROXY setting produces
+// for unencrypted traffic. It strips hop-by-hop headers, round-trips the
+// request to its own URL (not a configured upstream), and copies the
+// response straight back.
+func (p *ProxyServer) handleForwardHTTP(w http.ResponseWriter, r *http.Request) {
+	if !r.URL.IsAbs() {
+		http.Error(w, "Bad Request: forward proxy requires an absolute URI", http.StatusBadRequest)
+		return
+	}
+	if !p.hostAllowed(r.URL.Hostname()) {
+		logger.Warn("Forward proxy: request denied", "host", r.URL.Host)
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	outReq := r.Clone(r.Context())
+	outReq.RequestURI = ""
+	stripHopByHopHeaders(outReq.Header)
+
+	resp, err := p.forwardTransport.RoundTrip(outReq)
+	if err != nil {
+		logger.Error("Forward proxy: round trip failed", "error", err, "host", r.URL.Host)
+		http.Error(w, "Bad Gateway", http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	stripHopByHopHeaders(resp.Header)
+	for key, values := range resp.Header {
+		for _, v := range values {
+			w.Header().Add(key, v)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+}
+
+// hostAllowed reports whether host may be tunneled to: a match in
+// forwardDenyHosts always wins, then an empty forwardAllowHosts permits
+// anything not denied, otherwise host must match an allow entry.
+func (p *ProxyServer) hostAllowed(host string) bool {
+	for _, pattern := range p.forwardDenyHosts {
+		if hostMatches(host, pattern) {
+			return false
+		}
+	}
+	if len(p.forwardAllowHosts) == 0 {
+		return true
+	}
+	for _, pattern := range p.forwardAllowHosts {
+		if hostMatches(host, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// hostMatches reports whether host equals pattern or is a subdomain of it
+// (pattern "example.com" matches "api.example.com" as well as
+// "example.com" itself).
+func hostMatches(host, pattern string) bool {
+	host = strings.ToLower(host)
+	pattern = strings.ToLower(pattern)
+	return host == pattern || strings.HasSuffix(host, "."+pattern)
+}
@@ -0,0 +1,214 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+
+	"github.com/kashifsb/nsm/pkg/logger"
+)
+
+// Middleware is a named http.Handler wrapper registered on a ProxyServer
+// via Use. Name is only used for logging/debugging - ordering is entirely
+// determined by registration order.
+type Middleware struct {
+	Name    string
+	Handler func(http.Handler) http.Handler
+}
+
+// Use appends mw to the end of p's middleware chain, under name. Built-in
+// middlewares (recovery, logging, cors, compress, html-inject, headers,
+// basic-auth) are registered this way by registerMiddlewares from
+// ProxyConfig.Middlewares; callers embedding ProxyServer in their own code
+// can Use additional ones before Start.
+func (p *ProxyServer) Use(name string, mw func(http.Handler) http.Handler) {
+	p.middlewares = append(p.middlewares, Middleware{Name: name, Handler: mw})
+}
+
+// middlewareChain wraps next in every registered middleware, in order:
+// the first middleware registered is outermost (sees the request first,
+// the response last), matching the old hard-coded
+// cors -> logging -> recovery chain when the default registration order
+// (recovery, logging, cors) is used.
+func (p *ProxyServer) middlewareChain(next http.Handler) http.Handler {
+	h := next
+	for i := len(p.middlewares) - 1; i >= 0; i-- {
+		h = p.middlewares[i].Handler(h)
+	}
+	return h
+}
+
+// defaultMiddlewares is the chain NewProxyServer registers when
+// ProxyConfig.Middlewares is left empty - identical in order and
+// behavior to the chain hard-coded before middlewares became pluggable.
+var defaultMiddlewares = []string{"recovery", "logging", "cors"}
+
+// registerMiddlewares builds p.middlewares from names, in order, using
+// proxyConfig for any built-in that needs configuration (compress needs
+// none; html-inject needs InjectScript; headers needs
+// RequestHeaders/ResponseHeaders; basic-auth needs BasicAuthUsers). An
+// unrecognized name is logged and skipped rather than failing the whole
+// proxy over a typo.
+func (p *ProxyServer) registerMiddlewares(proxyConfig ProxyConfig) {
+	names := proxyConfig.Middlewares
+	if len(names) == 0 {
+		names = defaultMiddlewares
+	}
+
+	for _, name := range names {
+		switch name {
+		case "recovery":
+			p.Use(name, p.recoveryMiddleware)
+		case "logging":
+			p.Use(name, p.loggingMiddleware)
+		case "cors":
+			p.Use(name, p.corsMiddleware)
+		case "compress":
+			p.Use(name, compressMiddleware)
+		case "html-inject":
+			p.Use(name, htmlInjectMiddleware(proxyConfig.InjectScript))
+		case "headers":
+			p.Use(name, headersMiddleware(proxyConfig.RequestHeaders, proxyConfig.ResponseHeaders))
+		case "basic-auth":
+			p.Use(name, basicAuthMiddleware(proxyConfig.BasicAuthUsers))
+		default:
+			logger.Warn("Unknown proxy middleware, skipping", "name", name)
+		}
+	}
+}
+
+// compressMiddleware gzip- or brotli-encodes the response body, preferring
+// brotli when the client's Accept-Encoding advertises it. Requests that
+// accept neither, or that are already upgrade/CONNECT traffic, pass
+// through unchanged.
+func compressMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept := r.Header.Get("Accept-Encoding")
+
+		switch {
+		case strings.Contains(accept, "br"):
+			bw := brotli.NewWriter(w)
+			defer bw.Close()
+			w.Header().Set("Content-Encoding", "br")
+			w.Header().Del("Content-Length")
+			next.ServeHTTP(&compressResponseWriter{ResponseWriter: w, writer: bw}, r)
+		case strings.Contains(accept, "gzip"):
+			gw := gzip.NewWriter(w)
+			defer gw.Close()
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Header().Del("Content-Length")
+			next.ServeHTTP(&compressResponseWriter{ResponseWriter: w, writer: gw}, r)
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// compressResponseWriter routes Write through writer (a gzip.Writer or
+// brotli.Writer) instead of straight to the underlying ResponseWriter.
+type compressResponseWriter struct {
+	http.ResponseWriter
+	writer interface {
+		Write([]byte) (int, error)
+	}
+}
+
+func (cw *compressResponseWriter) Write(b []byte) (int, error) {
+	return cw.writer.Write(b)
+}
+
+// htmlInjectMiddleware returns a middleware that buffers every
+// text/html response and inserts script before its closing </body> tag -
+// for injecting a live-reload client, analogous to how BrowserSync/Vite
+// inject their own. Non-HTML responses, and responses with no </body>
+// tag, pass through unmodified. A blank script makes this a no-op.
+func htmlInjectMiddleware(script string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if script == "" {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec := &bufferingResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(rec, r)
+
+			body := rec.buf.Bytes()
+			if strings.Contains(rec.Header().Get("Content-Type"), "text/html") {
+				if idx := bytes.LastIndex(body, []byte("</body>")); idx != -1 {
+					injected := make([]byte, 0, len(body)+len(script))
+					injected = append(injected, body[:idx]...)
+					injected = append(injected, []byte(script)...)
+					injected = append(injected, body[idx:]...)
+					body = injected
+				}
+			}
+
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+			w.WriteHeader(rec.statusCode)
+			w.Write(body)
+		})
+	}
+}
+
+// bufferingResponseWriter captures a handler's entire response instead of
+// writing it straight through, so htmlInjectMiddleware can rewrite the
+// body before it reaches the client.
+type bufferingResponseWriter struct {
+	http.ResponseWriter
+	buf        bytes.Buffer
+	statusCode int
+}
+
+func (rw *bufferingResponseWriter) WriteHeader(code int)        { rw.statusCode = code }
+func (rw *bufferingResponseWriter) Write(b []byte) (int, error) { return rw.buf.Write(b) }
+
+// headersMiddleware adds every "Name: Value" entry in requestHeaders to
+// the proxied request and every entry in responseHeaders to the response,
+// for project-specific header injection (feature flags, auth stubs, CSP
+// overrides) without forking the proxy.
+func headersMiddleware(requestHeaders, responseHeaders map[string]string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for name, value := range requestHeaders {
+				r.Header.Set(name, value)
+			}
+			for name, value := range responseHeaders {
+				w.Header().Set(name, value)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// basicAuthMiddleware gates every request behind HTTP Basic Auth, checking
+// username/password against users (constant-time, to avoid leaking a
+// valid username through timing). A nil/empty users map makes this a
+// no-op, same as leaving the middleware out of Middlewares entirely.
+func basicAuthMiddleware(users map[string]string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if len(users) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			username, password, ok := r.BasicAuth()
+			if !ok || !validCredentials(users, username, password) {
+				w.Header().Set("WWW-Authenticate", `Basic realm="NSM"`)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func validCredentials(users map[string]string, username, password string) bool {
+	want, ok := users[username]
+	if !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(want), []byte(password)) == 1
+}
@@ -4,28 +4,127 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"net/http/httputil"
-	"net/url"
 	"strings"
+	"sync"
 	"time"
 
+	"golang.org/x/net/http2"
+
 	"github.com/kashifsb/nsm/internal/config"
+	"github.com/kashifsb/nsm/pkg/health"
 	"github.com/kashifsb/nsm/pkg/logger"
+	"github.com/kashifsb/nsm/pkg/metrics"
 )
 
+// defaultHealthCheckInterval is how often the active health checker
+// probes every upstream when ProxyConfig.HealthCheckInterval is left zero.
+const defaultHealthCheckInterval = 10 * time.Second
+
 type ProxyServer struct {
 	cfg        *config.Config
 	httpServer *http.Server
-	targetURL  *url.URL
-	certPath   string
-	keyPath    string
+	pool       *UpstreamPool
+
+	// routerMu guards router itself (not its contents, which Router.mu
+	// already protects): AddRoute can lazily create router the first time
+	// a site is added to a proxy that started with no ProxyConfig.Routes.
+	routerMu            sync.RWMutex
+	router              *Router
+	healthCheckPath     string
+	healthCheckInterval time.Duration
+	// healthCtx is the ctx Start ran health checks with, reused by AddRoute
+	// to start a late-added route's checker the same way; nil until Start.
+	healthCtx context.Context
+	certPath  string
+	keyPath   string
+	health    *health.Tracker
+
+	mode              ProxyMode
+	forwardAllowHosts []string
+	forwardDenyHosts  []string
+	forwardTransport  *http.Transport
+
+	middlewares []Middleware
+	accessLog   *accessLog
+
+	certMu    sync.RWMutex
+	cert      *tls.Certificate
+	siteCerts map[string]*tls.Certificate
+}
+
+// upstreamContextKey is the context key Director stashes the request's
+// chosen upstream under, so ModifyResponse and errorHandler (which only
+// see the request, not Director's local variable) can release its
+// connection count and mark it up/down.
+type upstreamContextKey struct{}
+
+// accessLogContextKey is the context key loggingMiddleware stashes an
+// *accessLogInfo under, so Director and timingRoundTripper (which run
+// deeper in the stack, after loggingMiddleware has already started timing
+// the request) can report which upstream/route served it and how long
+// the backend round trip took.
+type accessLogContextKey struct{}
+
+// accessLogInfo is filled in by Director (upstream, route) and
+// timingRoundTripper (upstreamDuration) as a request is proxied, then
+// read back by loggingMiddleware once the request completes.
+type accessLogInfo struct {
+	upstream         string
+	route            string
+	upstreamDuration time.Duration
+	retryCount       int
 }
 
 type ProxyConfig struct {
-	TargetHost  string
-	TargetPort  int
+	// Upstreams are the backend targets requests are forwarded to. A
+	// single dev server is the common case; multiple enable fronting a
+	// micro-frontend / multi-service setup on one domain. Must be
+	// non-empty.
+	Upstreams []UpstreamTarget
+	// SelectionPolicy picks which Upstream handles each request when more
+	// than one is configured. Defaults to round-robin.
+	SelectionPolicy SelectionPolicy
+	// HealthCheckPath is probed on every upstream by the active health
+	// checker. Defaults to "/".
+	HealthCheckPath string
+	// HealthCheckInterval is how often the active health checker runs.
+	// Defaults to defaultHealthCheckInterval.
+	HealthCheckInterval time.Duration
+
+	// Routes, when non-empty, are matched against every request before it
+	// falls back to Upstreams/SelectionPolicy - letting a developer front
+	// /api on one backend, /ws on another, and / on the main dev server
+	// from one ProxyServer.
+	Routes []RouteRule
+
+	// Mode selects reverse-proxy (the default) or forward-proxy operation.
+	// ForwardAllowHosts/ForwardDenyHosts are only consulted in ModeForward;
+	// Upstreams/SelectionPolicy/Routes are only consulted outside it.
+	Mode              ProxyMode
+	ForwardAllowHosts []string
+	ForwardDenyHosts  []string
+
+	// Middlewares names and orders the handlers registerMiddlewares wraps
+	// the proxy in - the first name is outermost. Left empty, it defaults
+	// to the built-in recovery/logging/cors chain. InjectScript,
+	// RequestHeaders/ResponseHeaders, and BasicAuthUsers configure the
+	// "html-inject", "headers", and "basic-auth" built-ins respectively;
+	// they're ignored unless that name appears in Middlewares.
+	Middlewares     []string
+	InjectScript    string
+	RequestHeaders  map[string]string
+	ResponseHeaders map[string]string
+	BasicAuthUsers  map[string]string
+
+	// AccessLog configures the request log every middlewareChain built
+	// from registerMiddlewares' "logging" entry writes to. Left with a
+	// blank Path, access logging is disabled.
+	AccessLog AccessLogConfig
+
 	ProxyPort   int
 	Domain      string
 	CertPath    string
@@ -33,70 +132,346 @@ type ProxyConfig struct {
 	EnableHTTPS bool
 }
 
-func NewProxyServer(cfg *config.Config, proxyConfig ProxyConfig) *ProxyServer {
-	targetURL, _ := url.Parse(fmt.Sprintf("http://%s:%d", proxyConfig.TargetHost, proxyConfig.TargetPort))
+// NewProxyServer returns a ProxyServer for proxyConfig. tracker, if
+// non-nil, receives a "proxy" health.Status every time the listener comes
+// up or goes down unexpectedly, so an orchestrator can watch for crashes.
+func NewProxyServer(cfg *config.Config, proxyConfig ProxyConfig, tracker *health.Tracker) (*ProxyServer, error) {
+	interval := proxyConfig.HealthCheckInterval
+	if interval == 0 {
+		interval = defaultHealthCheckInterval
+	}
+
+	var router *Router
+	if len(proxyConfig.Routes) > 0 {
+		var err error
+		router, err = NewRouter(proxyConfig.Routes, proxyConfig.HealthCheckPath)
+		if err != nil {
+			return nil, fmt.Errorf("build proxy router: %w", err)
+		}
+	}
+
+	mode := proxyConfig.Mode
+	if mode == "" {
+		mode = ModeReverse
+	}
+
+	var pool *UpstreamPool
+	if len(proxyConfig.Upstreams) > 0 {
+		pool = NewUpstreamPool(proxyConfig.Upstreams, proxyConfig.SelectionPolicy, proxyConfig.HealthCheckPath)
+	}
+
+	forwardTransport := &http.Transport{}
+	if err := http2.ConfigureTransport(forwardTransport); err != nil {
+		logger.Warn("Failed to enable HTTP/2 for forward proxy transport", "error", err)
+	}
+
+	p := &ProxyServer{
+		cfg:                 cfg,
+		pool:                pool,
+		router:              router,
+		healthCheckPath:     proxyConfig.HealthCheckPath,
+		healthCheckInterval: interval,
+		certPath:            proxyConfig.CertPath,
+		keyPath:             proxyConfig.KeyPath,
+		health:              tracker,
+		mode:                mode,
+		forwardAllowHosts:   proxyConfig.ForwardAllowHosts,
+		forwardDenyHosts:    proxyConfig.ForwardDenyHosts,
+		forwardTransport:    forwardTransport,
+		accessLog:           newAccessLog(proxyConfig.AccessLog),
+	}
+	p.registerMiddlewares(proxyConfig)
+	return p, nil
+}
+
+func (p *ProxyServer) reportHealth(state health.State, err error) {
+	if p.health != nil {
+		p.health.Set("proxy", state, err)
+	}
+}
+
+// getRouter returns the current router, or nil if none has been built yet.
+func (p *ProxyServer) getRouter() *Router {
+	p.routerMu.RLock()
+	defer p.routerMu.RUnlock()
+	return p.router
+}
+
+// AddRoute adds rule to the proxy's router, building one from scratch if
+// this proxy started with no ProxyConfig.Routes (the common case for a
+// single-project ProxyServer that later gains sites via app.SiteManager).
+// If the proxy is already running, the new route's health checker starts
+// immediately.
+func (p *ProxyServer) AddRoute(rule RouteRule) error {
+	router := p.getRouter()
+	if router != nil {
+		return router.AddRoute(rule)
+	}
+
+	p.routerMu.Lock()
+	defer p.routerMu.Unlock()
+
+	if p.router != nil {
+		return p.router.AddRoute(rule)
+	}
+
+	newRouter, err := NewRouter([]RouteRule{rule}, p.healthCheckPath)
+	if err != nil {
+		return fmt.Errorf("build router for new route: %w", err)
+	}
+	if p.healthCtx != nil {
+		newRouter.startHealthChecks(p.healthCtx, p.healthCheckInterval)
+	}
+	p.router = newRouter
+	return nil
+}
+
+// RemoveRoute drops every route matching host. A no-op, reporting false,
+// if no router exists yet.
+func (p *ProxyServer) RemoveRoute(host string) bool {
+	router := p.getRouter()
+	if router == nil {
+		return false
+	}
+	return router.RemoveRoute(host)
+}
 
-	return &ProxyServer{
-		cfg:       cfg,
-		targetURL: targetURL,
-		certPath:  proxyConfig.CertPath,
-		keyPath:   proxyConfig.KeyPath,
+// buildReverseProxy builds the httputil.ReverseProxy Start's reverse mode
+// serves through p.proxyHandler/p.middlewareChain.
+func (p *ProxyServer) buildReverseProxy() *httputil.ReverseProxy {
+	proxy := &httputil.ReverseProxy{
+		// Director consults the router first - if a RouteRule matches
+		// (by method/host/path), the request goes to that route's own
+		// upstream with its rewrite applied; otherwise it falls back to
+		// the default pool, picking a live upstream per request
+		// (round-robin, random, least-conn, ip-hash, or first-healthy,
+		// per ProxyConfig.SelectionPolicy). Either way the chosen
+		// upstream is stashed in the request's context so
+		// ModifyResponse/errorHandler can release its connection count
+		// and report it up/down without re-selecting.
+		Director: func(r *http.Request) {
+			var u *upstream
+			var routeName string
+
+			if router := p.getRouter(); router != nil {
+				if rt := router.match(r); rt != nil {
+					u = rt.pool.Next(r)
+					r.URL.Path = rt.rewrite(r.URL.Path)
+					routeName = rt.rule.Name
+				}
+			}
+			if u == nil {
+				u = p.pool.Next(r)
+			}
+			u.acquire()
+
+			r.URL.Scheme = "http"
+			r.URL.Host = u.url.Host
+			r.Host = u.url.Host
+			*r = *r.WithContext(context.WithValue(r.Context(), upstreamContextKey{}, u))
+
+			if info, ok := r.Context().Value(accessLogContextKey{}).(*accessLogInfo); ok {
+				info.upstream = u.target.String()
+				info.route = routeName
+			}
+
+			p.enhanceRequest(r)
+		},
+		// Every response that makes it back means its upstream is up.
+		ModifyResponse: func(resp *http.Response) error {
+			if u, ok := resp.Request.Context().Value(upstreamContextKey{}).(*upstream); ok {
+				u.release()
+				MarkUp(u)
+			}
+			metrics.SetUpstreamUp(true)
+			return nil
+		},
+		ErrorHandler: p.errorHandler,
 	}
+
+	// Let the backend negotiate HTTP/2 over TLS (gRPC-Web, h2 dev servers);
+	// plaintext backends are unaffected since ConfigureTransport only adds
+	// h2 as an ALPN option, it doesn't force h2c.
+	backendTransport := &http.Transport{}
+	if err := http2.ConfigureTransport(backendTransport); err != nil {
+		logger.Warn("Failed to enable HTTP/2 for proxy backend transport", "error", err)
+	}
+	proxy.Transport = &timingRoundTripper{next: backendTransport}
+
+	return proxy
+}
+
+// timingRoundTripper measures how long the backend round trip itself
+// takes, separate from the request's total duration (which also covers
+// routing and any middleware work) - reported as
+// accessLogInfo.upstreamDuration for the JSON access log format.
+type timingRoundTripper struct {
+	next http.RoundTripper
+}
+
+func (t *timingRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(r)
+	if info, ok := r.Context().Value(accessLogContextKey{}).(*accessLogInfo); ok {
+		info.upstreamDuration = time.Since(start)
+	}
+	return resp, err
 }
 
 func (p *ProxyServer) Start(ctx context.Context, port int) error {
-	proxy := httputil.NewSingleHostReverseProxy(p.targetURL)
+	p.healthCtx = ctx
 
-	// Enhanced proxy director
-	originalDirector := proxy.Director
-	proxy.Director = func(r *http.Request) {
-		originalDirector(r)
-		p.enhanceRequest(r)
+	if p.pool != nil {
+		p.pool.StartHealthChecks(ctx, p.healthCheckInterval)
+	}
+	if router := p.getRouter(); router != nil {
+		router.startHealthChecks(ctx, p.healthCheckInterval)
 	}
 
-	// Custom error handler
-	proxy.ErrorHandler = p.errorHandler
+	var handler http.Handler
+	if p.mode == ModeForward {
+		handler = p.forwardMiddlewareChain(p.forwardHandler())
+	} else {
+		handler = p.middlewareChain(p.proxyHandler(p.buildReverseProxy()))
+	}
 
 	// Create server
 	p.httpServer = &http.Server{
 		Addr:         fmt.Sprintf(":%d", port),
-		Handler:      p.middlewareChain(proxy),
+		Handler:      handler,
 		ReadTimeout:  30 * time.Second,
 		WriteTimeout: 30 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
 
 	if p.cfg.EnableHTTPS {
+		if err := p.ReloadCertificate(p.certPath, p.keyPath); err != nil {
+			return fmt.Errorf("load initial certificate: %w", err)
+		}
+
 		p.httpServer.TLSConfig = &tls.Config{
-			MinVersion: tls.VersionTLS12,
-			MaxVersion: tls.VersionTLS13,
+			MinVersion:     tls.VersionTLS12,
+			MaxVersion:     tls.VersionTLS13,
+			GetCertificate: p.getCertificate,
+		}
+
+		// Let browsers/tools speak HTTP/2 to NSM itself.
+		if err := http2.ConfigureServer(p.httpServer, &http2.Server{}); err != nil {
+			logger.Warn("Failed to enable HTTP/2 for proxy frontend", "error", err)
 		}
 	}
 
-	logger.Info("Starting proxy server",
-		"port", port,
-		"target", p.targetURL.String(),
-		"https", p.cfg.EnableHTTPS)
+	if p.mode == ModeForward {
+		logger.Info("Starting forward proxy server", "port", port, "https", p.cfg.EnableHTTPS)
+	} else {
+		logger.Info("Starting proxy server",
+			"port", port,
+			"target", p.pool.Primary().String(),
+			"https", p.cfg.EnableHTTPS)
+	}
 
 	// Start server
 	go func() {
 		var err error
 		if p.cfg.EnableHTTPS {
-			err = p.httpServer.ListenAndServeTLS(p.certPath, p.keyPath)
+			// Cert/key files are already loaded into TLSConfig.GetCertificate
+			// above, so the server can serve them without re-reading the files.
+			err = p.httpServer.ListenAndServeTLS("", "")
 		} else {
 			err = p.httpServer.ListenAndServe()
 		}
 
 		if err != nil && err != http.ErrServerClosed {
 			logger.Error("Proxy server error", "error", err)
+			p.reportHealth(health.StateError, err)
 		}
 	}()
 
 	// Wait for server to be ready
-	return p.waitForReady(ctx, port)
+	if err := p.waitForReady(ctx, port); err != nil {
+		p.reportHealth(health.StateError, err)
+		return err
+	}
+
+	p.reportHealth(health.StateOK, nil)
+	return nil
+}
+
+// ReloadCertificate loads the key pair at certPath/keyPath and swaps it
+// in as the certificate served by GetCertificate, without restarting the
+// listener. It's the hook cert.Renewer uses to hot-reload a renewed
+// certificate into a running proxy.
+func (p *ProxyServer) ReloadCertificate(certPath, keyPath string) error {
+	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		return fmt.Errorf("load certificate: %w", err)
+	}
+
+	p.certMu.Lock()
+	p.certPath, p.keyPath = certPath, keyPath
+	p.cert = &cert
+	p.certMu.Unlock()
+
+	logger.Info("Proxy certificate reloaded", "cert", certPath)
+	return nil
+}
+
+// getCertificate serves hello.ServerName's own certificate when AddSiteCert
+// has registered one (multi-site mode), falling back to the proxy's
+// primary certificate otherwise - the single-domain case is unaffected.
+func (p *ProxyServer) getCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	p.certMu.RLock()
+	defer p.certMu.RUnlock()
+
+	if hello != nil {
+		if siteCert, ok := p.siteCerts[strings.ToLower(hello.ServerName)]; ok {
+			metrics.RecordTLSHandshake("success")
+			return siteCert, nil
+		}
+	}
+
+	if p.cert == nil {
+		metrics.RecordTLSHandshake("error")
+		return nil, fmt.Errorf("no certificate loaded")
+	}
+	metrics.RecordTLSHandshake("success")
+	return p.cert, nil
+}
+
+// AddSiteCert registers certPath/keyPath as the certificate served for SNI
+// ServerName domain, letting one shared HTTPS listener serve several
+// sites' own certificates instead of just the proxy's primary one.
+func (p *ProxyServer) AddSiteCert(domain, certPath, keyPath string) error {
+	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		return fmt.Errorf("load certificate for %s: %w", domain, err)
+	}
+
+	p.certMu.Lock()
+	defer p.certMu.Unlock()
+	if p.siteCerts == nil {
+		p.siteCerts = make(map[string]*tls.Certificate)
+	}
+	p.siteCerts[strings.ToLower(domain)] = &cert
+	return nil
+}
+
+// RemoveSiteCert undoes AddSiteCert; SNI requests for domain fall back to
+// the proxy's primary certificate again.
+func (p *ProxyServer) RemoveSiteCert(domain string) {
+	p.certMu.Lock()
+	defer p.certMu.Unlock()
+	delete(p.siteCerts, strings.ToLower(domain))
 }
 
 func (p *ProxyServer) Stop(ctx context.Context) error {
+	if p.pool != nil {
+		p.pool.Stop()
+	}
+	if router := p.getRouter(); router != nil {
+		router.stop()
+	}
+
 	if p.httpServer == nil {
 		return nil
 	}
@@ -105,6 +480,10 @@ func (p *ProxyServer) Stop(ctx context.Context) error {
 	return p.httpServer.Shutdown(ctx)
 }
 
+// enhanceRequest adds the forwarded/NSM headers every request gets,
+// regardless of which upstream it was routed to. The host override for
+// the chosen backend is set by Director/handleUpgrade, which know which
+// upstream was picked; enhanceRequest doesn't.
 func (p *ProxyServer) enhanceRequest(r *http.Request) {
 	// Set forwarded headers
 	r.Header.Set("X-Forwarded-Proto", p.getScheme())
@@ -114,22 +493,107 @@ func (p *ProxyServer) enhanceRequest(r *http.Request) {
 	// Set NSM headers
 	r.Header.Set("X-NSM-Version", "3.0.0")
 	r.Header.Set("X-NSM-Project", p.cfg.ProjectName)
+}
 
-	// Override host for backend
-	r.Host = p.targetURL.Host
+// proxyHandler wraps proxy so protocol-upgrade requests (WebSocket HMR
+// from Vite/Next.js/webpack-dev-server) are tunneled through a hijacked
+// raw connection instead of httputil.ReverseProxy's single
+// request/response RoundTrip, which can't carry a bidirectional byte
+// stream once the backend replies 101 Switching Protocols.
+func (p *ProxyServer) proxyHandler(proxy *httputil.ReverseProxy) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isUpgradeRequest(r) {
+			p.handleUpgrade(w, r)
+			return
+		}
+		proxy.ServeHTTP(w, r)
+	})
 }
 
-func (p *ProxyServer) middlewareChain(next http.Handler) http.Handler {
-	// CORS middleware
-	corsHandler := p.corsMiddleware(next)
+// isUpgradeRequest reports whether r is asking to switch protocols (e.g.
+// "Connection: Upgrade" with "Upgrade: websocket"), the combination
+// Vite/webpack-dev-server HMR rely on. Hop-by-hop header stripping
+// elsewhere in the chain must leave these two alone.
+func isUpgradeRequest(r *http.Request) bool {
+	return strings.Contains(strings.ToLower(r.Header.Get("Connection")), "upgrade") &&
+		r.Header.Get("Upgrade") != ""
+}
 
-	// Logging middleware
-	loggingHandler := p.loggingMiddleware(corsHandler)
+// handleUpgrade tunnels a protocol-upgrade request straight to the
+// upstream Next picks: dial it, replay the original request (with
+// enhanceRequest's forwarded/NSM headers added) so
+// Connection/Upgrade/Sec-WebSocket-* reach it verbatim, then hijack the
+// client connection and copy bytes both ways until either side closes.
+func (p *ProxyServer) handleUpgrade(w http.ResponseWriter, r *http.Request) {
+	var u *upstream
+	var routeName string
+	if router := p.getRouter(); router != nil {
+		if rt := router.match(r); rt != nil {
+			u = rt.pool.Next(r)
+			r.URL.Path = rt.rewrite(r.URL.Path)
+			routeName = rt.rule.Name
+		}
+	}
+	if u == nil {
+		u = p.pool.Next(r)
+	}
+	u.acquire()
+	defer u.release()
+
+	if info, ok := r.Context().Value(accessLogContextKey{}).(*accessLogInfo); ok {
+		info.upstream = u.target.String()
+		info.route = routeName
+	}
+
+	backendConn, err := net.DialTimeout("tcp", u.url.Host, 10*time.Second)
+	if err != nil {
+		MarkDown(u, err)
+		logger.Error("Upgrade proxy: dial backend failed", "error", err, "target", u.url.Host)
+		http.Error(w, "Bad Gateway", http.StatusBadGateway)
+		return
+	}
+	defer backendConn.Close()
+	MarkUp(u)
+
+	r.Host = u.url.Host
+	p.enhanceRequest(r)
+	if err := r.Write(backendConn); err != nil {
+		logger.Error("Upgrade proxy: write request to backend failed", "error", err)
+		http.Error(w, "Bad Gateway", http.StatusBadGateway)
+		return
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		logger.Error("Upgrade proxy: ResponseWriter doesn't support hijacking")
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	clientConn, clientBuf, err := hijacker.Hijack()
+	if err != nil {
+		logger.Error("Upgrade proxy: hijack failed", "error", err)
+		return
+	}
+	defer clientConn.Close()
+
+	if buffered := clientBuf.Reader.Buffered(); buffered > 0 {
+		peeked, _ := clientBuf.Reader.Peek(buffered)
+		backendConn.Write(peeked)
+	}
 
-	// Recovery middleware
-	recoveryHandler := p.recoveryMiddleware(loggingHandler)
+	logger.Debug("Upgrading connection", "path", r.URL.Path, "target", u.url.Host)
 
-	return recoveryHandler
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(backendConn, clientConn)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(clientConn, backendConn)
+		done <- struct{}{}
+	}()
+	<-done
 }
 
 func (p *ProxyServer) corsMiddleware(next http.Handler) http.Handler {
@@ -154,7 +618,10 @@ func (p *ProxyServer) loggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 
-		// Wrap ResponseWriter to capture status code
+		info := &accessLogInfo{}
+		r = r.WithContext(context.WithValue(r.Context(), accessLogContextKey{}, info))
+
+		// Wrap ResponseWriter to capture status code and response size
 		wrapped := &responseWriter{ResponseWriter: w, statusCode: 200}
 
 		next.ServeHTTP(wrapped, r)
@@ -167,6 +634,24 @@ func (p *ProxyServer) loggingMiddleware(next http.Handler) http.Handler {
 			"status", wrapped.statusCode,
 			"duration", duration,
 			"remote_addr", p.getClientIP(r))
+
+		metrics.RecordRequest(r.Method, r.Host, wrapped.statusCode, duration)
+		p.accessLog.record(accessLogRecord{
+			RemoteAddr:       p.getClientIP(r),
+			Time:             start,
+			Method:           r.Method,
+			URI:              r.URL.RequestURI(),
+			Proto:            r.Proto,
+			Status:           wrapped.statusCode,
+			Bytes:            wrapped.bytes,
+			Referer:          r.Referer(),
+			UserAgent:        r.UserAgent(),
+			Upstream:         info.upstream,
+			Route:            info.route,
+			TotalDuration:    duration,
+			UpstreamDuration: info.upstreamDuration,
+			RetryCount:       info.retryCount,
+		})
 	})
 }
 
@@ -184,7 +669,13 @@ func (p *ProxyServer) recoveryMiddleware(next http.Handler) http.Handler {
 }
 
 func (p *ProxyServer) errorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	if u, ok := r.Context().Value(upstreamContextKey{}).(*upstream); ok {
+		u.release()
+		MarkDown(u, err)
+	}
+
 	if strings.Contains(err.Error(), "connection refused") {
+		metrics.SetUpstreamUp(false)
 		p.renderDevServerNotReady(w, r)
 		return
 	}
@@ -328,7 +819,7 @@ func (p *ProxyServer) renderDevServerNotReady(w http.ResponseWriter, r *http.Req
 </body>
 </html>`,
 		p.cfg.ProjectName,
-		p.targetURL.String(),
+		p.pool.Primary().String(),
 		p.cfg.ProjectName,
 		string(p.cfg.ProjectType),
 		p.cfg.Domain,
@@ -386,13 +877,21 @@ func (p *ProxyServer) waitForReady(ctx context.Context, port int) error {
 	}
 }
 
-// responseWriter wraps http.ResponseWriter to capture status code
+// responseWriter wraps http.ResponseWriter to capture the status code and
+// response size, for the access log's %>s and %b fields.
 type responseWriter struct {
 	http.ResponseWriter
 	statusCode int
+	bytes      int
 }
 
 func (rw *responseWriter) WriteHeader(code int) {
 	rw.statusCode = code
 	rw.ResponseWriter.WriteHeader(code)
 }
+
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytes += n
+	return n, err
+}
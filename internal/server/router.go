@@ -0,0 +1,208 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RouteRule matches requests by method/host/path prefix or regex and
+// sends them to a specific upstream, with an optional path rewrite - so
+// one NSM instance can front /api on a Go backend, /ws on a websocket
+// server, and / on the main dev server, instead of running several
+// proxies side by side.
+//
+// At most one of PathPrefix/PathRegex should be set (PathRegex wins if
+// both are); leaving both empty matches every path. At most one of
+// StripPrefix/ReplacePath/AddPrefix should be set for Rewrite.
+type RouteRule struct {
+	// Name identifies this rule in the JSON access log's "route" field;
+	// optional, purely for observability.
+	Name       string
+	Method     string
+	Host       string
+	PathPrefix string
+	PathRegex  string
+
+	Upstream UpstreamTarget
+
+	StripPrefix string
+	ReplacePath string
+	AddPrefix   string
+}
+
+// route is a RouteRule compiled for matching, with its own single-target
+// UpstreamPool so a route gets the same health checking and passive
+// failure backoff as the proxy's default pool, for free.
+type route struct {
+	rule  RouteRule
+	regex *regexp.Regexp
+	pool  *UpstreamPool
+}
+
+// Router picks the first RouteRule whose method/host/path all match a
+// request, tried in the order rules were given. routes can grow and
+// shrink after construction (AddRoute/RemoveRoute), e.g. as
+// app.SiteManager adds and removes sites at runtime, so every access goes
+// through mu.
+type Router struct {
+	mu              sync.RWMutex
+	routes          []*route
+	healthCheckPath string
+
+	// healthCtx/healthInterval are recorded by startHealthChecks so a
+	// route added afterwards (AddRoute) can start its own health checker
+	// immediately instead of waiting for the proxy to restart.
+	healthCtx      context.Context
+	healthInterval time.Duration
+}
+
+// NewRouter compiles rules into a Router. A rule with an invalid
+// PathRegex is reported as an error rather than silently never matching.
+func NewRouter(rules []RouteRule, healthCheckPath string) (*Router, error) {
+	routes := make([]*route, len(rules))
+
+	for i, rule := range rules {
+		rt, err := newRoute(rule, healthCheckPath)
+		if err != nil {
+			return nil, fmt.Errorf("route %d: %w", i, err)
+		}
+		routes[i] = rt
+	}
+
+	return &Router{routes: routes, healthCheckPath: healthCheckPath}, nil
+}
+
+// newRoute compiles a single RouteRule into a route with its own
+// single-target UpstreamPool.
+func newRoute(rule RouteRule, healthCheckPath string) (*route, error) {
+	var regex *regexp.Regexp
+	if rule.PathRegex != "" {
+		var err error
+		regex, err = regexp.Compile(rule.PathRegex)
+		if err != nil {
+			return nil, fmt.Errorf("compile path_regex %q: %w", rule.PathRegex, err)
+		}
+	}
+
+	return &route{
+		rule:  rule,
+		regex: regex,
+		pool:  NewUpstreamPool([]UpstreamTarget{rule.Upstream}, PolicyFirstHealthy, healthCheckPath),
+	}, nil
+}
+
+// AddRoute compiles and appends rule, starting its health checker right
+// away if the router's own checks are already running. Used by
+// app.SiteManager to front a newly added site without restarting the
+// proxy.
+func (router *Router) AddRoute(rule RouteRule) error {
+	rt, err := newRoute(rule, router.healthCheckPath)
+	if err != nil {
+		return fmt.Errorf("add route: %w", err)
+	}
+
+	router.mu.Lock()
+	router.routes = append(router.routes, rt)
+	ctx, interval := router.healthCtx, router.healthInterval
+	router.mu.Unlock()
+
+	if ctx != nil {
+		rt.pool.StartHealthChecks(ctx, interval)
+	}
+	return nil
+}
+
+// RemoveRoute drops every route matching host, stopping its health
+// checker first. Reports whether any route was removed.
+func (router *Router) RemoveRoute(host string) bool {
+	router.mu.Lock()
+	defer router.mu.Unlock()
+
+	kept := router.routes[:0]
+	removed := false
+	for _, rt := range router.routes {
+		if strings.EqualFold(rt.rule.Host, host) {
+			rt.pool.Stop()
+			removed = true
+			continue
+		}
+		kept = append(kept, rt)
+	}
+	router.routes = kept
+	return removed
+}
+
+// match returns the first route whose method/host/path all match r, or
+// nil if none do - the caller then falls back to the proxy's default
+// upstream pool.
+func (router *Router) match(r *http.Request) *route {
+	router.mu.RLock()
+	defer router.mu.RUnlock()
+
+	for _, rt := range router.routes {
+		if rt.rule.Method != "" && !strings.EqualFold(rt.rule.Method, r.Method) {
+			continue
+		}
+		if rt.rule.Host != "" && !strings.EqualFold(rt.rule.Host, r.Host) {
+			continue
+		}
+		if rt.regex != nil {
+			if !rt.regex.MatchString(r.URL.Path) {
+				continue
+			}
+		} else if rt.rule.PathPrefix != "" && !strings.HasPrefix(r.URL.Path, rt.rule.PathPrefix) {
+			continue
+		}
+		return rt
+	}
+	return nil
+}
+
+// rewrite applies rt's rule to path: StripPrefix trims a leading prefix,
+// ReplacePath substitutes the whole path, AddPrefix prepends, and leaving
+// all three empty passes path through unchanged.
+func (rt *route) rewrite(path string) string {
+	switch {
+	case rt.rule.ReplacePath != "":
+		return rt.rule.ReplacePath
+	case rt.rule.StripPrefix != "":
+		trimmed := strings.TrimPrefix(path, rt.rule.StripPrefix)
+		if trimmed == "" {
+			return "/"
+		}
+		return trimmed
+	case rt.rule.AddPrefix != "":
+		return rt.rule.AddPrefix + path
+	default:
+		return path
+	}
+}
+
+// startHealthChecks starts every route's upstream pool health checker and
+// records ctx/interval so AddRoute can do the same for routes added later.
+func (router *Router) startHealthChecks(ctx context.Context, interval time.Duration) {
+	router.mu.Lock()
+	router.healthCtx = ctx
+	router.healthInterval = interval
+	routes := router.routes
+	router.mu.Unlock()
+
+	for _, rt := range routes {
+		rt.pool.StartHealthChecks(ctx, interval)
+	}
+}
+
+// stop ends every route's health checker goroutine.
+func (router *Router) stop() {
+	router.mu.RLock()
+	defer router.mu.RUnlock()
+
+	for _, rt := range router.routes {
+		rt.pool.Stop()
+	}
+}
@@ -0,0 +1,277 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kashifsb/nsm/pkg/logger"
+)
+
+// UpstreamTarget identifies one backend process the proxy can forward
+// requests to - a single dev server in the common case, or one of several
+// in a micro-frontend / multi-service setup fronted on one domain.
+type UpstreamTarget struct {
+	Host string
+	Port int
+}
+
+func (t UpstreamTarget) String() string { return fmt.Sprintf("%s:%d", t.Host, t.Port) }
+
+// SelectionPolicy picks which upstream handles the next request when more
+// than one is configured, modeled on Caddy's reverse_proxy load-balancing
+// policies.
+type SelectionPolicy string
+
+const (
+	PolicyRoundRobin   SelectionPolicy = "round-robin"
+	PolicyRandom       SelectionPolicy = "random"
+	PolicyLeastConn    SelectionPolicy = "least-conn"
+	PolicyIPHash       SelectionPolicy = "ip-hash"
+	PolicyFirstHealthy SelectionPolicy = "first-healthy"
+)
+
+// minBackoff/maxBackoff bound how long MarkDown makes an upstream wait
+// before probeAll tries it again, doubling on each consecutive failure.
+const (
+	minBackoff = 1 * time.Second
+	maxBackoff = 30 * time.Second
+)
+
+// upstream is one pool member's address plus the health bookkeeping
+// Next, MarkDown/MarkUp, and the active health checker all share.
+type upstream struct {
+	target UpstreamTarget
+	url    *url.URL
+
+	mu      sync.Mutex
+	healthy bool
+	backoff time.Duration
+	retryAt time.Time
+
+	conns int64
+}
+
+func (u *upstream) acquire() { atomic.AddInt64(&u.conns, 1) }
+func (u *upstream) release() { atomic.AddInt64(&u.conns, -1) }
+
+// UpstreamPool holds the backends a ProxyServer forwards to and picks one
+// per request according to policy. Every upstream starts healthy; the
+// active health checker (StartHealthChecks) and passive failure marking
+// (MarkDown, called from ProxyServer's ErrorHandler) are what pull one out
+// of rotation.
+type UpstreamPool struct {
+	policy          SelectionPolicy
+	healthCheckPath string
+
+	mu        sync.RWMutex
+	upstreams []*upstream
+	counter   uint64
+
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+// NewUpstreamPool builds a pool over targets. policy defaults to
+// round-robin and healthCheckPath to "/" when left empty. targets must be
+// non-empty.
+func NewUpstreamPool(targets []UpstreamTarget, policy SelectionPolicy, healthCheckPath string) *UpstreamPool {
+	if policy == "" {
+		policy = PolicyRoundRobin
+	}
+	if healthCheckPath == "" {
+		healthCheckPath = "/"
+	}
+
+	upstreams := make([]*upstream, len(targets))
+	for i, t := range targets {
+		u, _ := url.Parse(fmt.Sprintf("http://%s:%d", t.Host, t.Port))
+		upstreams[i] = &upstream{target: t, url: u, healthy: true}
+	}
+
+	return &UpstreamPool{
+		policy:          policy,
+		healthCheckPath: healthCheckPath,
+		upstreams:       upstreams,
+		stop:            make(chan struct{}),
+	}
+}
+
+// Primary returns the pool's first configured target, for the
+// "dev server starting" status page, which only needs one address to
+// display regardless of how many upstreams are configured.
+func (p *UpstreamPool) Primary() *url.URL {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.upstreams[0].url
+}
+
+func (p *UpstreamPool) healthyUpstreams() []*upstream {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	healthy := make([]*upstream, 0, len(p.upstreams))
+	for _, u := range p.upstreams {
+		u.mu.Lock()
+		ok := u.healthy
+		u.mu.Unlock()
+		if ok {
+			healthy = append(healthy, u)
+		}
+	}
+	return healthy
+}
+
+// Next picks an upstream for r according to the pool's policy. If every
+// upstream is currently marked down, it falls back to the first
+// configured one anyway, so a fully-down pool still dials out and lets
+// errorHandler's connection-refused path render NSM's "dev server
+// starting" page instead of failing closed with nothing to try.
+func (p *UpstreamPool) Next(r *http.Request) *upstream {
+	healthy := p.healthyUpstreams()
+	if len(healthy) == 0 {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+		return p.upstreams[0]
+	}
+	if len(healthy) == 1 {
+		return healthy[0]
+	}
+
+	switch p.policy {
+	case PolicyFirstHealthy:
+		return healthy[0]
+	case PolicyRandom:
+		return healthy[rand.Intn(len(healthy))]
+	case PolicyIPHash:
+		h := fnv.New32a()
+		h.Write([]byte(remoteIP(r)))
+		return healthy[int(h.Sum32())%len(healthy)]
+	case PolicyLeastConn:
+		best := healthy[0]
+		for _, u := range healthy[1:] {
+			if atomic.LoadInt64(&u.conns) < atomic.LoadInt64(&best.conns) {
+				best = u
+			}
+		}
+		return best
+	default: // round-robin
+		n := atomic.AddUint64(&p.counter, 1)
+		return healthy[int(n)%len(healthy)]
+	}
+}
+
+// MarkDown passively marks u unhealthy after a proxied request to it
+// failed (dial refused, timeout, or any other error the ReverseProxy's
+// ErrorHandler saw), backing off exponentially - capped at maxBackoff -
+// before probeAll will consider retrying it. It's a function of u alone
+// (not the pool u came from), so callers holding an *upstream selected
+// from either the default pool or a route's own pool can call it the
+// same way.
+func MarkDown(u *upstream, err error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if !u.healthy {
+		return
+	}
+
+	if u.backoff == 0 {
+		u.backoff = minBackoff
+	} else {
+		u.backoff *= 2
+		if u.backoff > maxBackoff {
+			u.backoff = maxBackoff
+		}
+	}
+
+	u.healthy = false
+	u.retryAt = time.Now().Add(u.backoff)
+	logger.Warn("Upstream marked down", "target", u.target.String(), "error", err, "retry_in", u.backoff)
+}
+
+// MarkUp marks u healthy again and resets its backoff, called once a
+// request succeeds or an active health probe gets a response.
+func MarkUp(u *upstream) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.healthy {
+		return
+	}
+	u.healthy = true
+	u.backoff = 0
+	logger.Info("Upstream back up", "target", u.target.String())
+}
+
+// StartHealthChecks runs an active prober against every upstream's
+// healthCheckPath every interval, in the background, until ctx is
+// canceled or Stop is called. A response - any status code, since even a
+// 404 proves the process is alive - marks the upstream up; a failed dial
+// or timeout marks it down via MarkDown.
+func (p *UpstreamPool) StartHealthChecks(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-p.stop:
+				return
+			case <-ticker.C:
+				p.probeAll()
+			}
+		}
+	}()
+}
+
+func (p *UpstreamPool) probeAll() {
+	p.mu.RLock()
+	upstreams := append([]*upstream(nil), p.upstreams...)
+	p.mu.RUnlock()
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	for _, u := range upstreams {
+		u := u
+
+		u.mu.Lock()
+		skip := !u.healthy && time.Now().Before(u.retryAt)
+		u.mu.Unlock()
+		if skip {
+			continue
+		}
+
+		go func() {
+			resp, err := client.Get(u.url.String() + p.healthCheckPath)
+			if err != nil {
+				MarkDown(u, err)
+				return
+			}
+			resp.Body.Close()
+			MarkUp(u)
+		}()
+	}
+}
+
+// Stop ends the goroutine StartHealthChecks started. Safe to call more
+// than once.
+func (p *UpstreamPool) Stop() {
+	p.stopOnce.Do(func() { close(p.stop) })
+}
+
+// remoteIP returns the client address Next's ip-hash policy hashes on,
+// the same forwarded-header-then-RemoteAddr precedence ProxyServer's own
+// getClientIP uses.
+func remoteIP(r *http.Request) string {
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		return xff
+	}
+	return r.RemoteAddr
+}
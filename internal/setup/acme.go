@@ -0,0 +1,197 @@
+package setup
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/kashifsb/nsm/internal/cert"
+	"github.com/kashifsb/nsm/internal/config"
+	"github.com/kashifsb/nsm/pkg/logger"
+)
+
+// acmeHTTPChallengePort is where configureACME briefly listens to answer
+// http-01 validation requests during issuance. The wizard runs before
+// the proxy is up, so it can't rely on an already-running HTTP listener
+// the way the `nsm` runtime's ACME issuer does.
+const acmeHTTPChallengePort = ":80"
+
+// configureACME issues (or loads) publicly-trusted certificates for
+// cfg.ACMEDomains when cfg.CertMode is "acme" or "both", then installs a
+// background renewal daemon so they get reissued as they approach
+// expiry. It's a no-op when CertMode is "mkcert" or unset, which stays
+// the default for .dev/.test/.local.
+func configureACME(ctx context.Context, cfg *Config) error {
+	if cfg.CertMode != "acme" && cfg.CertMode != "both" {
+		return nil
+	}
+	if len(cfg.ACMEDomains) == 0 {
+		logger.Warn("cert-mode is acme/both but no ACME domains configured, skipping")
+		return nil
+	}
+
+	challenge := cfg.ACMEChallenge
+	if challenge == "" {
+		challenge = "http-01"
+	}
+
+	manager, err := newACMEManager(cfg, challenge)
+	if err != nil {
+		return fmt.Errorf("create ACME manager: %w", err)
+	}
+
+	if err := issueACMECertificates(manager, cfg.ACMEDomains, challenge); err != nil {
+		return err
+	}
+
+	if err := installACMERenewalDaemon(cfg); err != nil {
+		logger.Warn("Failed to install ACME renewal daemon", "error", err)
+	}
+
+	return nil
+}
+
+// newACMEManager builds a cert.Manager configured for ACME issuance, using
+// cfg's ACME fields. Account registration happens as a side effect of
+// construction (see cert.NewManagerWithConfig).
+func newACMEManager(cfg *Config, challenge string) (*cert.Manager, error) {
+	return cert.NewManagerWithConfig(cfg.DataDir, &config.Config{
+		CertIssuer:    "acme",
+		ACMEEmail:     cfg.ACMEEmail,
+		ACMEChallenge: challenge,
+		DNSProvider:   cfg.ACMEDNSProvider,
+	})
+}
+
+// issueACMECertificates ensures a certificate exists for each domain. For
+// http-01, validation requests are served off a temporary listener on
+// acmeHTTPChallengePort for the duration of issuance; dns-01 and
+// tls-alpn-01 don't need it.
+func issueACMECertificates(manager *cert.Manager, domains []string, challenge string) error {
+	stopChallengeServer := func() {}
+	if challenge == "http-01" {
+		var err error
+		stopChallengeServer, err = serveChallengesTemporarily(manager.Challenges())
+		if err != nil {
+			return fmt.Errorf("start http-01 challenge listener: %w", err)
+		}
+	}
+	defer stopChallengeServer()
+
+	for _, domain := range domains {
+		logger.Info("Requesting ACME certificate", "domain", domain)
+		if _, err := manager.EnsureCertificate(domain, false); err != nil {
+			return fmt.Errorf("issue certificate for %s: %w", domain, err)
+		}
+	}
+
+	return nil
+}
+
+// serveChallengesTemporarily listens on acmeHTTPChallengePort for just
+// long enough to answer http-01 validation requests, returning a func
+// that shuts the listener down once issuance finishes.
+func serveChallengesTemporarily(challenges http.Handler) (func(), error) {
+	server := &http.Server{Addr: acmeHTTPChallengePort, Handler: challenges}
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- server.ListenAndServe() }()
+
+	select {
+	case err := <-errCh:
+		return func() {}, fmt.Errorf("listen on %s: %w", acmeHTTPChallengePort, err)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			logger.Warn("Failed to stop ACME challenge listener cleanly", "error", err)
+		}
+	}, nil
+}
+
+const acmeRenewAgentLabel = "com.nsm.acme-renew"
+
+// installACMERenewalDaemon installs the background process that keeps
+// cfg.ACMEDomains renewed: a launchd agent on darwin, a systemd user unit
+// on linux, running `nsm-setup acme-renew` continuously (it blocks on a
+// cert.Renewer per domain rather than exiting, the same long-running
+// shape as the DNS daemon installed by installDNSDaemon).
+func installACMERenewalDaemon(cfg *Config) error {
+	exe, err := executablePath()
+	if err != nil {
+		return err
+	}
+
+	args := []string{"acme-renew", "--data-dir", cfg.DataDir, "--domains", strings.Join(cfg.ACMEDomains, ",")}
+	if cfg.ACMEEmail != "" {
+		args = append(args, "--email", cfg.ACMEEmail)
+	}
+	if cfg.ACMEDNSProvider != "" {
+		args = append(args, "--dns-provider", cfg.ACMEDNSProvider)
+	}
+	if cfg.ACMEChallenge != "" {
+		args = append(args, "--challenge", cfg.ACMEChallenge)
+	}
+
+	switch runtime.GOOS {
+	case "darwin":
+		return installLaunchdAgent(launchdPlistPathFor(cfg, acmeRenewAgentLabel), acmeRenewAgentLabel, exe, args,
+			cfg.LogDir, "acme-renew")
+	case "linux":
+		return installSystemdUserService(systemdUnitPathFor(cfg, "nsm-acme-renew.service"), "nsm-acme-renew.service",
+			"NSM ACME certificate renewal daemon", exe, args)
+	default:
+		logger.Warn("ACME renewal daemon not supported on this platform, skipping", "platform", runtime.GOOS)
+		return nil
+	}
+}
+
+// uninstallACMERenewalDaemon stops and removes the ACME renewal daemon's
+// service definition, if one was installed. Used by Reset.
+func uninstallACMERenewalDaemon(cfg *Config) error {
+	switch runtime.GOOS {
+	case "darwin":
+		return uninstallLaunchdAgent(launchdPlistPathFor(cfg, acmeRenewAgentLabel))
+	case "linux":
+		return uninstallSystemdUserService(systemdUnitPathFor(cfg, "nsm-acme-renew.service"), "nsm-acme-renew.service")
+	default:
+		return nil
+	}
+}
+
+// RunACMERenew runs the ACME renewal daemon in the foreground: one
+// cert.Renewer per domain, polling on its own schedule until ctx is
+// canceled. It's what the launchd agent / systemd user unit installed by
+// installACMERenewalDaemon actually execs.
+func RunACMERenew(ctx context.Context, dataDir string, domains []string, email, dnsProvider, challenge string) error {
+	manager, err := cert.NewManagerWithConfig(dataDir, &config.Config{
+		CertIssuer:    "acme",
+		ACMEEmail:     email,
+		ACMEChallenge: challenge,
+		DNSProvider:   dnsProvider,
+	})
+	if err != nil {
+		return fmt.Errorf("create ACME manager: %w", err)
+	}
+
+	renewers := make([]*cert.Renewer, 0, len(domains))
+	for _, domain := range domains {
+		renewer := cert.NewRenewer(manager, domain, nil, func(domain string, notAfter time.Time) {
+			logger.Info("ACME certificate expiring soon, renewing", "domain", domain, "expires", notAfter)
+		})
+		renewer.Start(ctx)
+		renewers = append(renewers, renewer)
+	}
+
+	<-ctx.Done()
+	for _, renewer := range renewers {
+		renewer.Stop()
+	}
+	return nil
+}
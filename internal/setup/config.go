@@ -11,8 +11,37 @@ type Config struct {
 	SkipDeps bool
 	Platform string
 
-	// DNS configuration
-	TLDs []string
+	// DNS configuration. DNSCheckMethod selects how verifySetup/the "dns"
+	// and "verification" steps confirm a domain resolves: "local" (the
+	// default) queries the background DNS daemon directly, while "follow"
+	// resolves straight from the target's authoritative nameservers,
+	// ignoring any cache - useful while waiting on DNS-01 propagation.
+	TLDs           []string
+	DNSCheckMethod string
+
+	// Certificate issuance. CertMode selects between "mkcert" (the
+	// default, locally-trusted certs for TLDs), "acme" (publicly-trusted
+	// certs via Let's Encrypt for domains the user owns), or "both". The
+	// ACME* fields only matter when CertMode is "acme" or "both".
+	CertMode        string
+	ACMEDomains     []string
+	ACMEEmail       string
+	ACMEChallenge   string // http-01 or dns-01
+	ACMEDNSProvider string // cloudflare, route53, digitalocean, gandi, manual, or exec
+
+	// UpstreamDNS, TrustStores, and Frontends are populated by
+	// LoadDeclarative from nsm.conf's "upstream", "cert", and "frontend"
+	// blocks. UpstreamDNS records the resolvers nsm.conf asked for but
+	// isn't consumed by the setup flow yet - the background DNS daemon
+	// still takes its upstreams from its own --upstream flag. TrustStores
+	// records which trust stores the user wants the CA installed into
+	// (InstallCA today only ever targets the OS store, so this is
+	// recorded but not yet acted on), and Frontends is reserved for a
+	// future reverse-proxy integration, not yet consumed by the setup
+	// flow.
+	UpstreamDNS []string
+	TrustStores []string
+	Frontends   []FrontendConfig
 
 	// Paths
 	HomeDir   string
@@ -39,16 +68,24 @@ type TLDConfig struct {
 	Name         string
 	Configured   bool
 	ResolverFile string
-	DnsmasqEntry string
+}
+
+// FrontendConfig is one "frontend <domain> { backend ...; protocol ... }"
+// block from a declarative nsm.conf - reserved for a future reverse-proxy
+// integration; the setup flow doesn't act on it yet.
+type FrontendConfig struct {
+	Domain    string
+	Backend   string
+	Protocols []string
 }
 
 type SystemStatus struct {
 	Platform     string `json:"platform"`
 	NSMInstalled bool   `json:"nsm_installed"`
 	Dependencies struct {
-		Mkcert   bool `json:"mkcert"`
-		Dnsmasq  bool `json:"dnsmasq"`
-		Homebrew bool `json:"homebrew,omitempty"`
+		Mkcert    bool `json:"mkcert"`
+		DNSDaemon bool `json:"dns_daemon"`
+		Homebrew  bool `json:"homebrew,omitempty"`
 	} `json:"dependencies"`
 	TLDs      []TLDConfig `json:"tlds"`
 	ConfigDir string      `json:"config_dir"`
@@ -57,7 +94,9 @@ type SystemStatus struct {
 
 func NewConfig() *Config {
 	return &Config{
-		Platform: runtime.GOOS,
-		TLDs:     []string{"dev", "test", "local", "app"},
+		Platform:       runtime.GOOS,
+		TLDs:           []string{"dev", "test", "local", "app"},
+		CertMode:       "mkcert",
+		DNSCheckMethod: DNSCheckMethodLocal,
 	}
 }
@@ -0,0 +1,232 @@
+package setup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	miekgdns "github.com/miekg/dns"
+
+	"github.com/kashifsb/nsm/pkg/logger"
+)
+
+// daemonPort/daemonAddr are where the setup wizard's background DNS daemon
+// (pkg/dns) listens, independent of any interactive `nsm` session and of
+// internal/dns's per-project embedded resolver.
+const daemonPort = "53535"
+const daemonAddr = "127.0.0.1:" + daemonPort
+
+const dnsDaemonLabel = "com.nsm.dns"
+
+// launchdPlistPathFor and systemdUnitPathFor are written under
+// cfg.ConfigDir (~/.nsm) so a `nsm-setup reset` can find and remove them
+// without hunting through system directories.
+func launchdPlistPathFor(cfg *Config, label string) string {
+	return filepath.Join(cfg.ConfigDir, "launchd", label+".plist")
+}
+
+func systemdUnitPathFor(cfg *Config, unitFile string) string {
+	return filepath.Join(cfg.ConfigDir, "systemd", unitFile)
+}
+
+func launchdPlistPath(cfg *Config) string {
+	return launchdPlistPathFor(cfg, dnsDaemonLabel)
+}
+
+func systemdUnitPath(cfg *Config) string {
+	return systemdUnitPathFor(cfg, "nsm-dns.service")
+}
+
+// installDNSDaemon writes and loads the platform service definition that
+// keeps pkg/dns's embedded resolver running in the background - a launchd
+// agent on darwin, a systemd user unit on linux - replacing the dnsmasq
+// process NSM used to install and restart via Homebrew/systemctl.
+func installDNSDaemon(cfg *Config) error {
+	exe, err := executablePath()
+	if err != nil {
+		return err
+	}
+
+	args := []string{"dns-daemon", "--tlds", strings.Join(cfg.TLDs, ",")}
+
+	switch runtime.GOOS {
+	case "darwin":
+		return installLaunchdAgent(launchdPlistPath(cfg), dnsDaemonLabel, exe, args, cfg.LogDir, "dns-daemon")
+	case "linux":
+		if !cfg.HasSystemd {
+			logger.Warn("systemd not available, skipping background DNS daemon install")
+			return nil
+		}
+		return installSystemdUserService(systemdUnitPath(cfg), "nsm-dns.service", "NSM embedded DNS daemon", exe, args)
+	default:
+		logger.Warn("Background DNS daemon not supported on this platform, skipping", "platform", runtime.GOOS)
+		return nil
+	}
+}
+
+// uninstallDNSDaemon stops and removes the background DNS daemon's service
+// definition, if one was installed. Used by Reset.
+func uninstallDNSDaemon(cfg *Config) error {
+	switch runtime.GOOS {
+	case "darwin":
+		return uninstallLaunchdAgent(launchdPlistPath(cfg))
+	case "linux":
+		return uninstallSystemdUserService(systemdUnitPath(cfg), "nsm-dns.service")
+	default:
+		return nil
+	}
+}
+
+// executablePath resolves the currently-running nsm-setup binary, which
+// is what the service definitions below exec.
+func executablePath() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("resolve nsm-setup executable path: %w", err)
+	}
+	return exe, nil
+}
+
+// installLaunchdAgent writes a launchd plist at path under label, running
+// exe with args, logging to <logDir>/<logName>.log(.err), and loads it.
+func installLaunchdAgent(path, label, exe string, args []string, logDir, logName string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create launchd agent directory: %w", err)
+	}
+
+	var argXML strings.Builder
+	for _, arg := range args {
+		fmt.Fprintf(&argXML, "\t\t<string>%s</string>\n", arg)
+	}
+
+	plist := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>%s</string>
+	<key>ProgramArguments</key>
+	<array>
+		<string>%s</string>
+%s	</array>
+	<key>RunAtLoad</key>
+	<true/>
+	<key>KeepAlive</key>
+	<true/>
+	<key>StandardOutPath</key>
+	<string>%s</string>
+	<key>StandardErrorPath</key>
+	<string>%s</string>
+</dict>
+</plist>
+`, label, exe, argXML.String(),
+		filepath.Join(logDir, logName+".log"), filepath.Join(logDir, logName+".err.log"))
+
+	if err := os.WriteFile(path, []byte(plist), 0o644); err != nil {
+		return fmt.Errorf("write launchd plist: %w", err)
+	}
+
+	_ = runCommand("launchctl", "unload", path) // fine if it wasn't already loaded
+	if err := runCommand("launchctl", "load", "-w", path); err != nil {
+		return fmt.Errorf("load launchd agent: %w", err)
+	}
+
+	logger.Info("Installed launchd agent", "label", label, "plist", path)
+	return nil
+}
+
+func uninstallLaunchdAgent(path string) error {
+	_ = runCommand("launchctl", "unload", path)
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove launchd plist: %w", err)
+	}
+	return nil
+}
+
+// installSystemdUserService writes a systemd user unit at path, symlinks
+// it into the systemd user unit directory as unitName, and enables it.
+func installSystemdUserService(path, unitName, description, exe string, args []string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create systemd unit directory: %w", err)
+	}
+
+	unit := fmt.Sprintf(`[Unit]
+Description=%s
+
+[Service]
+ExecStart=%s %s
+Restart=on-failure
+
+[Install]
+WantedBy=default.target
+`, description, exe, strings.Join(args, " "))
+
+	if err := os.WriteFile(path, []byte(unit), 0o644); err != nil {
+		return fmt.Errorf("write systemd unit: %w", err)
+	}
+
+	userUnitDir, err := systemdUserUnitDir()
+	if err != nil {
+		return fmt.Errorf("resolve systemd user unit directory: %w", err)
+	}
+	if err := os.MkdirAll(userUnitDir, 0o755); err != nil {
+		return fmt.Errorf("create systemd user unit directory: %w", err)
+	}
+
+	linkPath := filepath.Join(userUnitDir, unitName)
+	_ = os.Remove(linkPath)
+	if err := os.Symlink(path, linkPath); err != nil {
+		return fmt.Errorf("link systemd unit: %w", err)
+	}
+
+	if err := runCommand("systemctl", "--user", "daemon-reload"); err != nil {
+		return fmt.Errorf("reload systemd user daemon: %w", err)
+	}
+	if err := runCommand("systemctl", "--user", "enable", "--now", unitName); err != nil {
+		return fmt.Errorf("enable %s: %w", unitName, err)
+	}
+
+	logger.Info("Installed systemd user service", "unit", path)
+	return nil
+}
+
+func uninstallSystemdUserService(path, unitName string) error {
+	_ = runCommand("systemctl", "--user", "disable", "--now", unitName)
+	if userUnitDir, err := systemdUserUnitDir(); err == nil {
+		os.Remove(filepath.Join(userUnitDir, unitName))
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove systemd unit: %w", err)
+	}
+	return nil
+}
+
+func systemdUserUnitDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".config", "systemd", "user"), nil
+}
+
+// queryDNSDaemon sends a single A query for name to the background DNS
+// daemon at daemonAddr, returning an error if it doesn't answer
+// successfully - used by verifySetup/testDNSResolution in place of the
+// nslookup shell-out dnsmasq needed.
+func queryDNSDaemon(name string) error {
+	client := &miekgdns.Client{Timeout: 2 * time.Second}
+	msg := new(miekgdns.Msg)
+	msg.SetQuestion(miekgdns.Fqdn(name), miekgdns.TypeA)
+
+	reply, _, err := client.Exchange(msg, daemonAddr)
+	if err != nil {
+		return fmt.Errorf("query dns daemon at %s: %w", daemonAddr, err)
+	}
+	if reply.Rcode != miekgdns.RcodeSuccess || len(reply.Answer) == 0 {
+		return fmt.Errorf("dns daemon returned no answer for %s", name)
+	}
+	return nil
+}
@@ -0,0 +1,229 @@
+package setup
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"git.sr.ht/~emersion/go-scfg"
+
+	"github.com/kashifsb/nsm/pkg/logger"
+)
+
+// DeclarativeConfigPath is where `nsm-setup install` looks for a
+// human-editable nsm.conf before falling back to the flag-driven Config
+// built from CLI arguments, mirroring tlstunnel's scfg-based config file.
+// A typical file looks like:
+//
+//	tld dev {
+//		resolver 127.0.0.1
+//		port 5353
+//	}
+//	upstream {
+//		server 1.1.1.1
+//		server 8.8.8.8
+//	}
+//	cert {
+//		ca mkcert
+//		trust-stores system,firefox,java
+//	}
+//	frontend myapp.dev {
+//		backend http://localhost:3000
+//		protocol h2,http/1.1
+//	}
+func DeclarativeConfigPath(homeDir string) string {
+	return filepath.Join(homeDir, ".nsm", "nsm.conf")
+}
+
+// LoadDeclarative parses the scfg file at path into a Config, validating
+// every "tld" block's name with validateTLD. Fields the file doesn't set
+// (Platform, Headless, AutoYes, ...) are left at NewConfig's defaults.
+func LoadDeclarative(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	block, err := scfg.Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	cfg := NewConfig()
+	cfg.TLDs = nil
+
+	var upstreams []string
+
+	for _, dir := range block {
+		switch dir.Name {
+		case "tld":
+			if len(dir.Params) != 1 {
+				return nil, fmt.Errorf("%s: \"tld\" expects exactly one name", path)
+			}
+			tld := dir.Params[0]
+			if err := validateTLD(tld); err != nil {
+				return nil, fmt.Errorf("%s: tld %q: %w", path, tld, err)
+			}
+			cfg.TLDs = append(cfg.TLDs, tld)
+
+		case "upstream":
+			for _, child := range dir.Children {
+				if child.Name == "server" && len(child.Params) == 1 {
+					upstreams = append(upstreams, child.Params[0])
+				}
+			}
+
+		case "cert":
+			for _, child := range dir.Children {
+				switch child.Name {
+				case "ca":
+					if len(child.Params) == 1 {
+						cfg.CertMode = child.Params[0]
+					}
+				case "trust-stores":
+					if len(child.Params) == 1 {
+						cfg.TrustStores = strings.Split(child.Params[0], ",")
+					}
+				}
+			}
+
+		case "frontend":
+			if len(dir.Params) != 1 {
+				return nil, fmt.Errorf("%s: \"frontend\" expects exactly one domain", path)
+			}
+			frontend := FrontendConfig{Domain: dir.Params[0]}
+			for _, child := range dir.Children {
+				switch child.Name {
+				case "backend":
+					if len(child.Params) == 1 {
+						frontend.Backend = child.Params[0]
+					}
+				case "protocol":
+					if len(child.Params) == 1 {
+						frontend.Protocols = strings.Split(child.Params[0], ",")
+					}
+				}
+			}
+			cfg.Frontends = append(cfg.Frontends, frontend)
+
+		default:
+			logger.Warn("nsm.conf: ignoring unrecognized directive", "directive", dir.Name)
+		}
+	}
+
+	if len(cfg.TLDs) == 0 {
+		return nil, fmt.Errorf("%s: at least one \"tld\" block is required", path)
+	}
+	if len(upstreams) > 0 {
+		cfg.UpstreamDNS = upstreams
+	}
+
+	return cfg, nil
+}
+
+// ReloadDeclarative re-reads nsm.conf and applies only what changed since
+// the last saved config: TLDs added since then are configured, TLDs removed
+// are unconfigured, and the rest of the declarative fields are copied over
+// as-is. It's the basis for `nsm-setup config reload`, which lets editing
+// nsm.conf take effect without rerunning the whole install flow.
+func ReloadDeclarative(ctx context.Context) error {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("get home directory: %w", err)
+	}
+
+	path := DeclarativeConfigPath(homeDir)
+	declared, err := LoadDeclarative(path)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("load current config: %w", err)
+	}
+
+	added, removed := diffTLDs(cfg.TLDs, declared.TLDs)
+
+	for _, tld := range added {
+		logger.Info("Adding TLD from nsm.conf", "tld", tld)
+		if err := addTLDConfiguration(tld); err != nil {
+			return fmt.Errorf("configure tld %s: %w", tld, err)
+		}
+	}
+	for _, tld := range removed {
+		logger.Info("Removing TLD no longer in nsm.conf", "tld", tld)
+		if err := removeTLDConfiguration(tld); err != nil {
+			return fmt.Errorf("remove tld %s: %w", tld, err)
+		}
+	}
+
+	cfg.TLDs = declared.TLDs
+	cfg.CertMode = declared.CertMode
+	cfg.UpstreamDNS = declared.UpstreamDNS
+	cfg.TrustStores = declared.TrustStores
+	cfg.Frontends = declared.Frontends
+
+	if err := saveConfig(*cfg); err != nil {
+		return fmt.Errorf("save config: %w", err)
+	}
+
+	logger.Info("Configuration reloaded from nsm.conf", "tlds_added", len(added), "tlds_removed", len(removed))
+	return nil
+}
+
+// diffTLDs returns the TLDs present in next but not old (added) and in old
+// but not next (removed).
+func diffTLDs(old, next []string) (added, removed []string) {
+	oldSet := make(map[string]bool, len(old))
+	for _, tld := range old {
+		oldSet[tld] = true
+	}
+	nextSet := make(map[string]bool, len(next))
+	for _, tld := range next {
+		nextSet[tld] = true
+	}
+
+	for _, tld := range next {
+		if !oldSet[tld] {
+			added = append(added, tld)
+		}
+	}
+	for _, tld := range old {
+		if !nextSet[tld] {
+			removed = append(removed, tld)
+		}
+	}
+	return added, removed
+}
+
+// applyDeclarativeConfig overlays nsm.conf under cfg.HomeDir onto cfg, if
+// present - called by initializeConfig so both RunHeadless and the
+// interactive wizard pick it up the same way. It's a no-op when no nsm.conf
+// exists, and only touches the fields a declarative config actually sets
+// (TLDs, CertMode, UpstreamDNS, TrustStores, Frontends); runtime flags like
+// Headless and AutoYes always come from the invocation, not the file.
+func applyDeclarativeConfig(cfg *Config) error {
+	path := DeclarativeConfigPath(cfg.HomeDir)
+	if _, err := os.Stat(path); err != nil {
+		return nil
+	}
+
+	declared, err := LoadDeclarative(path)
+	if err != nil {
+		return fmt.Errorf("load declarative config: %w", err)
+	}
+
+	logger.Info("Using declarative configuration", "path", path)
+
+	cfg.TLDs = declared.TLDs
+	cfg.CertMode = declared.CertMode
+	cfg.UpstreamDNS = declared.UpstreamDNS
+	cfg.TrustStores = declared.TrustStores
+	cfg.Frontends = declared.Frontends
+
+	return nil
+}
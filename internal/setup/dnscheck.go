@@ -0,0 +1,189 @@
+package setup
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	miekgdns "github.com/miekg/dns"
+)
+
+// DNSCheckMethodLocal queries the background DNS daemon at 127.0.0.1:53535
+// directly. It's the default, and was the only behavior before
+// DNSCheckMethod existed.
+const DNSCheckMethodLocal = "local"
+
+// DNSCheckMethodFollow resolves straight from the authoritative nameservers
+// of the target domain's parent zone, bypassing any recursive resolver
+// cache (including our own daemon's). This mirrors the self-check
+// cert-manager runs before trusting a DNS-01 challenge has propagated: a
+// stale cached answer would otherwise look identical to a real propagation
+// failure.
+const DNSCheckMethodFollow = "follow"
+
+// rootHints are well-known root server addresses, used as the starting
+// point for the iterative NS walk in followDNSCheck. A handful are enough
+// - we only need one to answer.
+var rootHints = []string{
+	"198.41.0.4:53",   // a.root-servers.net
+	"199.9.14.201:53", // b.root-servers.net
+	"192.33.4.12:53",  // c.root-servers.net
+	"199.7.91.13:53",  // d.root-servers.net
+}
+
+const dnsCheckTimeout = 3 * time.Second
+
+// checkDNSRecord confirms domain has an rtype record, using method
+// (DNSCheckMethodLocal or DNSCheckMethodFollow). wantValue additionally
+// requires the record's value (A address or TXT string) to match; pass ""
+// to only require that some answer exists.
+func checkDNSRecord(method, domain string, rtype uint16, wantValue string) error {
+	if method == DNSCheckMethodFollow {
+		return followDNSCheck(domain, rtype, wantValue)
+	}
+	return queryDNSDaemon(domain)
+}
+
+// followDNSCheck looks up the authoritative nameservers for domain's parent
+// zone and queries each directly for an rtype record, ignoring whatever the
+// OS resolver or our own daemon currently have cached.
+func followDNSCheck(domain string, rtype uint16, wantValue string) error {
+	parent := parentZone(domain)
+
+	servers, err := authoritativeNameservers(parent)
+	if err != nil {
+		return fmt.Errorf("find authoritative nameservers for %s: %w", parent, err)
+	}
+
+	var lastErr error
+	for _, server := range servers {
+		if err := queryAuthoritative(server, domain, rtype, wantValue); err != nil {
+			lastErr = err
+			continue
+		}
+		return nil
+	}
+
+	return fmt.Errorf("no authoritative nameserver for %s confirmed the record: %w", parent, lastErr)
+}
+
+func parentZone(domain string) string {
+	domain = strings.TrimSuffix(domain, ".")
+	labels := strings.SplitN(domain, ".", 2)
+	if len(labels) == 2 {
+		return labels[1]
+	}
+	return domain
+}
+
+// authoritativeNameservers walks from the root down to zone, asking each
+// delegation's nameservers (starting from rootHints) who is authoritative
+// for the next label, and returns the dialable addresses of zone's own
+// authoritative nameservers.
+func authoritativeNameservers(zone string) ([]string, error) {
+	servers := rootHints
+
+	labels := strings.Split(strings.TrimSuffix(zone, "."), ".")
+	for i := len(labels) - 1; i >= 0; i-- {
+		qname := miekgdns.Fqdn(strings.Join(labels[i:], "."))
+
+		addrs, err := nsAddrsFor(servers, qname)
+		if err != nil {
+			return nil, err
+		}
+		servers = addrs
+	}
+
+	return servers, nil
+}
+
+// nsAddrsFor queries each of servers for the NS records of qname and
+// returns the dialable addresses of the first response that has any,
+// resolving glue records where present and falling back to a plain lookup
+// of the nameserver's hostname otherwise.
+func nsAddrsFor(servers []string, qname string) ([]string, error) {
+	client := &miekgdns.Client{Timeout: dnsCheckTimeout}
+	msg := new(miekgdns.Msg)
+	msg.SetQuestion(qname, miekgdns.TypeNS)
+	msg.RecursionDesired = false
+
+	for _, server := range servers {
+		reply, _, err := client.Exchange(msg, server)
+		if err != nil || reply == nil {
+			continue
+		}
+
+		addrs := nsAddrsFromMessage(reply)
+		if len(addrs) > 0 {
+			return addrs, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no nameserver answered NS query for %s", qname)
+}
+
+func nsAddrsFromMessage(reply *miekgdns.Msg) []string {
+	glue := map[string]string{}
+	for _, rr := range reply.Extra {
+		if a, ok := rr.(*miekgdns.A); ok {
+			glue[a.Hdr.Name] = net.JoinHostPort(a.A.String(), "53")
+		}
+	}
+
+	var nsNames []string
+	for _, rr := range append(append([]miekgdns.RR{}, reply.Answer...), reply.Ns...) {
+		if ns, ok := rr.(*miekgdns.NS); ok {
+			nsNames = append(nsNames, ns.Ns)
+		}
+	}
+
+	var addrs []string
+	for _, name := range nsNames {
+		if addr, ok := glue[name]; ok {
+			addrs = append(addrs, addr)
+			continue
+		}
+		if ips, err := net.LookupHost(strings.TrimSuffix(name, ".")); err == nil && len(ips) > 0 {
+			addrs = append(addrs, net.JoinHostPort(ips[0], "53"))
+		}
+	}
+
+	return addrs
+}
+
+// queryAuthoritative asks server directly for domain's rtype record,
+// bypassing any cache, and confirms it has an answer matching wantValue (or
+// just exists, if wantValue is empty).
+func queryAuthoritative(server, domain string, rtype uint16, wantValue string) error {
+	client := &miekgdns.Client{Timeout: dnsCheckTimeout}
+	msg := new(miekgdns.Msg)
+	msg.SetQuestion(miekgdns.Fqdn(domain), rtype)
+	msg.RecursionDesired = false
+
+	reply, _, err := client.Exchange(msg, server)
+	if err != nil {
+		return fmt.Errorf("query %s at %s: %w", domain, server, err)
+	}
+	if reply.Rcode != miekgdns.RcodeSuccess || len(reply.Answer) == 0 {
+		return fmt.Errorf("%s returned no answer for %s", server, domain)
+	}
+	if wantValue == "" {
+		return nil
+	}
+
+	for _, rr := range reply.Answer {
+		switch v := rr.(type) {
+		case *miekgdns.A:
+			if v.A.String() == wantValue {
+				return nil
+			}
+		case *miekgdns.TXT:
+			if strings.Join(v.Txt, "") == wantValue {
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("%s's answer for %s did not contain %q", server, domain, wantValue)
+}
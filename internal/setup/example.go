@@ -3,7 +3,9 @@ package setup
 import (
 	"embed"
 	"fmt"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 	"text/template"
@@ -17,6 +19,7 @@ var templates embed.FS
 
 type ExampleManager struct {
 	frameworks map[string]FrameworkConfig
+	registry   *TemplateRegistry
 }
 
 type FrameworkConfig struct {
@@ -40,10 +43,21 @@ type ProjectTemplate struct {
 	Author      string
 	Email       string
 	Year        string
+
+	// Vars holds manifest-declared variable values, keyed by name, exposed
+	// to templates as {{.Vars.name}}.
+	Vars map[string]string
 }
 
 func NewExampleManager() *ExampleManager {
+	registry, err := LoadTemplateRegistry()
+	if err != nil {
+		logger.Warn("Failed to load template registry", "error", err)
+		registry = &TemplateRegistry{}
+	}
+
 	return &ExampleManager{
+		registry: registry,
 		frameworks: map[string]FrameworkConfig{
 			"react-vite-typescript": {
 				Name:        "React + Vite + TypeScript",
@@ -106,20 +120,52 @@ func NewExampleManager() *ExampleManager {
 					"test":  "mvn test",
 				},
 			},
+			"dotnet-aspnet": {
+				Name:        ".NET ASP.NET Core",
+				Description: "Minimal ASP.NET Core web API",
+				Language:    "C#",
+				Templates:   []string{"dotnet-aspnet"},
+				PostCreate:  setupDotnetProject,
+				Commands: map[string]string{
+					"dev":   "dotnet run",
+					"build": "dotnet build",
+					"test":  "dotnet test",
+				},
+			},
 		},
 	}
 }
 
-func (em *ExampleManager) Create(framework string) error {
-	config, exists := em.frameworks[framework]
-	if !exists {
-		return fmt.Errorf("framework '%s' not supported", framework)
+// PeekManifest resolves framework exactly as Create would and returns the
+// manifest of its first template directory (nil if it has none), so callers
+// can prompt for declared variables before Create actually runs.
+func (em *ExampleManager) PeekManifest(framework string) (*Manifest, error) {
+	config, templateFS, templateRoot, err := em.resolveFramework(framework)
+	if err != nil {
+		return nil, err
+	}
+	if len(config.Templates) == 0 {
+		return nil, nil
+	}
+	return LoadManifest(templateFS, path.Join(templateRoot, config.Templates[0]))
+}
+
+// Create scaffolds a new example project for opts.Framework. Every other
+// ExampleOptions field is optional; zero values fall back to generated
+// defaults (see createTemplateData).
+func (em *ExampleManager) Create(opts ExampleOptions) error {
+	config, templateFS, templateRoot, err := em.resolveFramework(opts.Framework)
+	if err != nil {
+		return err
 	}
 
-	logger.Info("Creating example project", "framework", framework)
+	logger.Info("Creating example project", "framework", opts.Framework)
 
 	// Get project details
-	projectName := em.generateProjectName(framework)
+	projectName := opts.ProjectName
+	if projectName == "" {
+		projectName = em.generateProjectName(opts.Framework)
+	}
 	projectPath := filepath.Join(".", projectName)
 
 	// Check if directory already exists
@@ -133,17 +179,66 @@ func (em *ExampleManager) Create(framework string) error {
 	}
 
 	// Prepare template data
-	templateData := em.createTemplateData(projectName, framework, config)
+	templateData := em.createTemplateData(projectName, opts.Framework, config)
+	if opts.Domain != "" {
+		templateData.Domain = opts.Domain
+	}
+	if opts.Port != 0 {
+		templateData.Port = opts.Port
+	}
+	if opts.HTTPSPort != 0 {
+		templateData.HTTPSPort = opts.HTTPSPort
+	}
+	if opts.Author != "" {
+		templateData.Author = opts.Author
+	}
+	if opts.Email != "" {
+		templateData.Email = opts.Email
+	}
+	if len(opts.Vars) > 0 {
+		templateData.Vars = opts.Vars
+	}
 
-	// Process templates
+	// Process templates. A template directory that supplies a
+	// nsm-template.yaml manifest is fully data-driven (declared variables,
+	// file rules, rename rules, hooks) and its manifest's hooks replace the
+	// framework's legacy PostCreate function.
+	usedManifest := false
 	for _, templateName := range config.Templates {
-		if err := em.processTemplate(templateName, projectPath, templateData); err != nil {
+		sourceDir := path.Join(templateRoot, templateName)
+
+		manifest, err := LoadManifest(templateFS, sourceDir)
+		if err != nil {
+			return fmt.Errorf("load manifest for %s: %w", templateName, err)
+		}
+
+		if manifest != nil {
+			usedManifest = true
+			mergeDefaults(&templateData, manifest.Defaults())
+
+			if err := manifest.ValidateValues(templateData.Vars); err != nil {
+				return fmt.Errorf("validate template variables: %w", err)
+			}
+
+			if err := RunHooks(manifest.Hooks.PreCreate, projectPath); err != nil {
+				return fmt.Errorf("pre_create hooks: %w", err)
+			}
+		}
+
+		if err := em.walkTemplateDir(templateFS, sourceDir, sourceDir, projectPath, manifest, templateData); err != nil {
 			return fmt.Errorf("process template %s: %w", templateName, err)
 		}
+
+		if manifest != nil {
+			if err := RunHooks(manifest.Hooks.PostCreate, projectPath); err != nil {
+				return fmt.Errorf("post_create hooks: %w", err)
+			}
+		}
 	}
 
-	// Run post-create setup
-	if config.PostCreate != nil {
+	// Run the legacy post-create setup function for templates that don't
+	// yet supply a manifest.
+	if !usedManifest && config.PostCreate != nil {
 		if err := config.PostCreate(projectPath); err != nil {
 			return fmt.Errorf("post-create setup: %w", err)
 		}
@@ -155,6 +250,55 @@ func (em *ExampleManager) Create(framework string) error {
 	return nil
 }
 
+// mergeDefaults fills in any declared variable not already present in
+// data.Vars, without overwriting values supplied by the caller (e.g. from
+// interactive prompts or --set flags).
+func mergeDefaults(data *ProjectTemplate, defaults map[string]string) {
+	if data.Vars == nil {
+		data.Vars = make(map[string]string, len(defaults))
+	}
+	for k, v := range defaults {
+		if _, exists := data.Vars[k]; !exists {
+			data.Vars[k] = v
+		}
+	}
+}
+
+// resolveFramework looks up framework first among the built-in
+// FrameworkConfig entries, then among registered template aliases, and
+// finally treats it as a raw git reference (see ResolveTemplateRef). The
+// returned fs.FS and templateRoot let Create walk embedded and disk-backed
+// templates through the same pipeline.
+func (em *ExampleManager) resolveFramework(framework string) (FrameworkConfig, fs.FS, string, error) {
+	if config, exists := em.frameworks[framework]; exists {
+		return config, templates, "templates", nil
+	}
+
+	alias, isAlias := em.registry.find(framework)
+	var t TemplateAlias
+	if isAlias {
+		t = *alias
+	} else if gitURL, ref, subdir, ok := ResolveTemplateRef(framework); ok {
+		t = TemplateAlias{Name: framework, GitURL: gitURL, Ref: ref, Subdir: subdir}
+	} else {
+		return FrameworkConfig{}, nil, "", fmt.Errorf("framework '%s' not supported", framework)
+	}
+
+	templateDir, err := fetchGitTemplate(t)
+	if err != nil {
+		return FrameworkConfig{}, nil, "", fmt.Errorf("resolve template '%s': %w", framework, err)
+	}
+
+	config := FrameworkConfig{
+		Name:        framework,
+		Description: fmt.Sprintf("Custom template from %s", t.GitURL),
+		Language:    "Custom",
+		Templates:   []string{"."},
+	}
+
+	return config, diskTemplateFS(templateDir), "", nil
+}
+
 func (em *ExampleManager) ListFrameworks() {
 	fmt.Println("📚 Available Example Frameworks:")
 	fmt.Println()
@@ -189,21 +333,34 @@ func (em *ExampleManager) createTemplateData(projectName, framework string, conf
 	}
 }
 
-func (em *ExampleManager) processTemplate(templateName, projectPath string, data ProjectTemplate) error {
-	templateDir := fmt.Sprintf("templates/%s", templateName)
-
-	return em.walkTemplateDir(templateDir, projectPath, data)
-}
-
-func (em *ExampleManager) walkTemplateDir(templateDir, outputDir string, data ProjectTemplate) error {
-	entries, err := templates.ReadDir(templateDir)
+// walkTemplateDir copies templateDir to outputDir. root is the template's
+// top-level directory (equal to templateDir on the initial call), used to
+// compute the path relative to the template root that manifest file/rename
+// rules match against. manifest is nil for templates with no
+// nsm-template.yaml, preserving the legacy .tmpl-suffix convention.
+func (em *ExampleManager) walkTemplateDir(templateFS fs.FS, templateDir, root, outputDir string, manifest *Manifest, data ProjectTemplate) error {
+	entries, err := fs.ReadDir(templateFS, templateDir)
 	if err != nil {
 		return fmt.Errorf("read template directory: %w", err)
 	}
 
 	for _, entry := range entries {
-		sourcePath := filepath.Join(templateDir, entry.Name())
-		targetPath := filepath.Join(outputDir, entry.Name())
+		if entry.Name() == ManifestFilename {
+			continue
+		}
+
+		sourcePath := path.Join(templateDir, entry.Name())
+		relPath := strings.TrimPrefix(strings.TrimPrefix(sourcePath, root), "/")
+
+		targetName := entry.Name()
+		if manifest != nil {
+			renamedRel, err := manifest.renamedPath(relPath, data)
+			if err != nil {
+				return err
+			}
+			targetName = path.Base(renamedRel)
+		}
+		targetPath := filepath.Join(outputDir, targetName)
 
 		if entry.IsDir() {
 			// Create directory and recurse
@@ -211,12 +368,12 @@ func (em *ExampleManager) walkTemplateDir(templateDir, outputDir string, data Pr
 				return fmt.Errorf("create directory %s: %w", targetPath, err)
 			}
 
-			if err := em.walkTemplateDir(sourcePath, targetPath, data); err != nil {
+			if err := em.walkTemplateDir(templateFS, sourcePath, root, targetPath, manifest, data); err != nil {
 				return err
 			}
 		} else {
 			// Process file
-			if err := em.processTemplateFile(sourcePath, targetPath, data); err != nil {
+			if err := em.processTemplateFile(templateFS, sourcePath, relPath, targetPath, manifest, data); err != nil {
 				return fmt.Errorf("process file %s: %w", sourcePath, err)
 			}
 		}
@@ -225,32 +382,39 @@ func (em *ExampleManager) walkTemplateDir(templateDir, outputDir string, data Pr
 	return nil
 }
 
-func (em *ExampleManager) processTemplateFile(sourcePath, targetPath string, data ProjectTemplate) error {
+func (em *ExampleManager) processTemplateFile(templateFS fs.FS, sourcePath, relPath, targetPath string, manifest *Manifest, data ProjectTemplate) error {
+	mode := "template"
+	if !strings.HasSuffix(sourcePath, ".tmpl") {
+		mode = "copy"
+	}
+	if manifest != nil {
+		mode = manifest.fileMode(relPath)
+	}
+
+	if mode == "skip" {
+		return nil
+	}
+
 	// Read template content
-	content, err := templates.ReadFile(sourcePath)
+	content, err := fs.ReadFile(templateFS, sourcePath)
 	if err != nil {
 		return fmt.Errorf("read template file: %w", err)
 	}
 
-	// Check if file is a template (has .tmpl extension)
-	if strings.HasSuffix(sourcePath, ".tmpl") {
-		// Remove .tmpl extension from target
+	if mode == "template" {
 		targetPath = strings.TrimSuffix(targetPath, ".tmpl")
 
-		// Process as template
 		tmpl, err := template.New("file").Parse(string(content))
 		if err != nil {
 			return fmt.Errorf("parse template: %w", err)
 		}
 
-		// Create output file
 		file, err := os.Create(targetPath)
 		if err != nil {
 			return fmt.Errorf("create output file: %w", err)
 		}
 		defer file.Close()
 
-		// Execute template
 		if err := tmpl.Execute(file, data); err != nil {
 			return fmt.Errorf("execute template: %w", err)
 		}
@@ -373,6 +537,25 @@ func setupPythonProject(projectPath string) error {
 	return nil
 }
 
+func setupDotnetProject(projectPath string) error {
+	logger.Info("Setting up .NET project", "path", projectPath)
+
+	dirs := []string{
+		filepath.Join(projectPath, "Controllers"),
+		filepath.Join(projectPath, "Models"),
+		filepath.Join(projectPath, "wwwroot"),
+		filepath.Join(projectPath, "cmd"),
+	}
+
+	for _, dir := range dirs {
+		if err := utils.EnsureDir(dir); err != nil {
+			return fmt.Errorf("create directory %s: %w", dir, err)
+		}
+	}
+
+	return nil
+}
+
 func setupJavaProject(projectPath string) error {
 	logger.Info("Setting up Java project", "path", projectPath)
 
@@ -0,0 +1,385 @@
+package setup
+
+import (
+	"fmt"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/kashifsb/nsm/internal/platform"
+)
+
+// ExampleOptions carries everything CreateExample needs to resolve a
+// framework and populate its ProjectTemplate, whether the values come from
+// an interactive wizard or from --set key=value flags in headless mode.
+type ExampleOptions struct {
+	Framework   string
+	ProjectName string
+	Domain      string
+	Port        int
+	HTTPSPort   int
+	Author      string
+	Email       string
+
+	// Headless and AutoYes suppress every interactive prompt; missing
+	// values must come from Set or fail closed.
+	Headless bool
+	AutoYes  bool
+
+	// Set holds raw --set key=value pairs. Recognized keys (name, domain,
+	// port, https_port, author, email) populate the fields above; anything
+	// else is assumed to be a template-declared variable.
+	Set map[string]string
+}
+
+var knownExampleSetKeys = map[string]bool{
+	"name": true, "domain": true, "port": true,
+	"https_port": true, "author": true, "email": true,
+}
+
+// frameworkItem adapts a framework name/description to bubbles/list.Item.
+type frameworkItem struct {
+	name string
+	desc string
+}
+
+func (i frameworkItem) Title() string       { return i.name }
+func (i frameworkItem) Description() string { return i.desc }
+func (i frameworkItem) FilterValue() string { return i.name }
+
+func buildFrameworkItems(em *ExampleManager) []list.Item {
+	names := make([]string, 0, len(em.frameworks))
+	for name := range em.frameworks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	items := make([]list.Item, 0, len(names)+len(em.registry.Templates))
+	for _, name := range names {
+		items = append(items, frameworkItem{name: name, desc: em.frameworks[name].Description})
+	}
+	for _, t := range em.registry.Templates {
+		items = append(items, frameworkItem{name: t.Name, desc: fmt.Sprintf("Custom template from %s", t.GitURL)})
+	}
+
+	return items
+}
+
+type frameworkPickerModel struct {
+	list    list.Model
+	chosen  string
+	aborted bool
+}
+
+func (m frameworkPickerModel) Init() tea.Cmd { return nil }
+
+func (m frameworkPickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.list.SetSize(msg.Width, msg.Height)
+		return m, nil
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c", "esc":
+			m.aborted = true
+			return m, tea.Quit
+		case "enter":
+			if item, ok := m.list.SelectedItem().(frameworkItem); ok {
+				m.chosen = item.name
+			}
+			return m, tea.Quit
+		}
+	}
+
+	var cmd tea.Cmd
+	m.list, cmd = m.list.Update(msg)
+	return m, cmd
+}
+
+func (m frameworkPickerModel) View() string {
+	return "\n" + m.list.View()
+}
+
+// promptFramework shows a searchable list of built-in frameworks and
+// registered custom templates, returning the selected framework name.
+func promptFramework(em *ExampleManager) (string, error) {
+	l := list.New(buildFrameworkItems(em), list.NewDefaultDelegate(), 0, 0)
+	l.Title = "Select a framework for the example project"
+	l.SetShowHelp(true)
+
+	p := tea.NewProgram(frameworkPickerModel{list: l})
+	result, err := p.Run()
+	if err != nil {
+		return "", fmt.Errorf("run framework picker: %w", err)
+	}
+
+	final := result.(frameworkPickerModel)
+	if final.aborted || final.chosen == "" {
+		return "", fmt.Errorf("no framework selected")
+	}
+
+	return final.chosen, nil
+}
+
+// promptField is one question in the sequential project-details wizard.
+type promptField struct {
+	key      string
+	label    string
+	input    textinput.Model
+	validate func(string) error
+}
+
+type detailsFormModel struct {
+	fields  []promptField
+	current int
+	aborted bool
+	done    bool
+}
+
+func (m detailsFormModel) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (m detailsFormModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if keyMsg, ok := msg.(tea.KeyMsg); ok {
+		switch keyMsg.String() {
+		case "ctrl+c", "esc":
+			m.aborted = true
+			return m, tea.Quit
+		case "enter":
+			field := &m.fields[m.current]
+			value := field.input.Value()
+			if field.validate != nil {
+				if err := field.validate(value); err != nil {
+					field.input.Placeholder = err.Error()
+					return m, nil
+				}
+			}
+
+			if m.current == len(m.fields)-1 {
+				m.done = true
+				return m, tea.Quit
+			}
+
+			m.current++
+			cmd := m.fields[m.current].input.Focus()
+			return m, cmd
+		}
+	}
+
+	var cmd tea.Cmd
+	m.fields[m.current].input, cmd = m.fields[m.current].input.Update(msg)
+	return m, cmd
+}
+
+func (m detailsFormModel) View() string {
+	var b strings.Builder
+	for i, field := range m.fields {
+		if i > m.current {
+			break
+		}
+		label := lipgloss.NewStyle().Bold(true).Render(field.label)
+		b.WriteString(fmt.Sprintf("%s\n%s\n\n", label, field.input.View()))
+	}
+	b.WriteString("(enter to confirm, esc to cancel)\n")
+	return b.String()
+}
+
+func newPromptField(key, label, defaultValue string, validate func(string) error) promptField {
+	input := textinput.New()
+	input.SetValue(defaultValue)
+	input.CursorEnd()
+	return promptField{key: key, label: label, input: input, validate: validate}
+}
+
+// promptProjectDetails runs the interactive wizard for project name, domain,
+// ports, author/email, and any manifest-declared variables, seeding defaults
+// from generated values, free-port detection, and git config.
+func promptProjectDetails(em *ExampleManager, opts ExampleOptions, manifestVars []TemplateVariable) (ExampleOptions, error) {
+	if opts.ProjectName == "" {
+		opts.ProjectName = em.generateProjectName(opts.Framework)
+	}
+	if opts.Domain == "" {
+		opts.Domain = fmt.Sprintf("%s.dev", opts.ProjectName)
+	}
+	if opts.Port == 0 {
+		opts.Port, _ = platform.NewPortManager().FindFreePortNear(5173)
+	}
+	if opts.HTTPSPort == 0 {
+		opts.HTTPSPort, _ = platform.NewPortManager().FindFreePortNear(8443)
+	}
+	if opts.Author == "" {
+		opts.Author = gitConfigValue("user.name")
+	}
+	if opts.Email == "" {
+		opts.Email = gitConfigValue("user.email")
+	}
+
+	fields := []promptField{
+		newPromptField("name", "Project name", opts.ProjectName, nonEmptyValidator("project name")),
+		newPromptField("domain", "Domain", opts.Domain, nonEmptyValidator("domain")),
+		newPromptField("port", "HTTP port", strconv.Itoa(opts.Port), portValidator),
+		newPromptField("https_port", "HTTPS port", strconv.Itoa(opts.HTTPSPort), portValidator),
+		newPromptField("author", "Author", opts.Author, nil),
+		newPromptField("email", "Email", opts.Email, nil),
+	}
+
+	if opts.Vars == nil {
+		opts.Vars = make(map[string]string, len(manifestVars))
+	}
+	for _, v := range manifestVars {
+		label := v.Prompt
+		if label == "" {
+			label = v.Name
+		}
+		fields = append(fields, newPromptField(v.Name, label, valueOrDefault(opts.Vars, v.Name, v.Default), regexValidator(v.Regex)))
+	}
+
+	fields[0].input.Focus()
+
+	m := detailsFormModel{fields: fields}
+	p := tea.NewProgram(m)
+	result, err := p.Run()
+	if err != nil {
+		return opts, fmt.Errorf("run project details wizard: %w", err)
+	}
+
+	final := result.(detailsFormModel)
+	if final.aborted || !final.done {
+		return opts, fmt.Errorf("project details not confirmed")
+	}
+
+	for _, field := range final.fields {
+		value := field.input.Value()
+		switch field.key {
+		case "name":
+			opts.ProjectName = value
+		case "domain":
+			opts.Domain = value
+		case "port":
+			opts.Port, _ = strconv.Atoi(value)
+		case "https_port":
+			opts.HTTPSPort, _ = strconv.Atoi(value)
+		case "author":
+			opts.Author = value
+		case "email":
+			opts.Email = value
+		default:
+			opts.Vars[field.key] = value
+		}
+	}
+
+	return opts, nil
+}
+
+func nonEmptyValidator(label string) func(string) error {
+	return func(value string) error {
+		if strings.TrimSpace(value) == "" {
+			return fmt.Errorf("%s is required", label)
+		}
+		return nil
+	}
+}
+
+func portValidator(value string) error {
+	port, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("must be a number")
+	}
+	if !platform.NewPortManager().IsPortAvailable(port) {
+		return fmt.Errorf("port %d is in use", port)
+	}
+	return nil
+}
+
+func regexValidator(pattern string) func(string) error {
+	if pattern == "" {
+		return nil
+	}
+	return func(value string) error {
+		m := &Manifest{Variables: []TemplateVariable{{Name: "value", Regex: pattern}}}
+		return m.ValidateValues(map[string]string{"value": value})
+	}
+}
+
+func valueOrDefault(values map[string]string, key, fallback string) string {
+	if v, ok := values[key]; ok && v != "" {
+		return v
+	}
+	return fallback
+}
+
+func gitConfigValue(key string) string {
+	output, err := exec.Command("git", "config", "--get", key).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// fillFromSet resolves an ExampleOptions from --set key=value flags alone,
+// for --headless and --auto-yes invocations where no prompt can run. Fields
+// with no set value and no sensible computed default fail closed rather
+// than silently using an empty string.
+func fillFromSet(em *ExampleManager, opts ExampleOptions, manifestVars []TemplateVariable) (ExampleOptions, error) {
+	if opts.ProjectName == "" {
+		opts.ProjectName = valueOrDefault(opts.Set, "name", em.generateProjectName(opts.Framework))
+	}
+	if opts.Domain == "" {
+		opts.Domain = valueOrDefault(opts.Set, "domain", fmt.Sprintf("%s.dev", opts.ProjectName))
+	}
+	if opts.Port == 0 {
+		if v, ok := opts.Set["port"]; ok {
+			port, err := strconv.Atoi(v)
+			if err != nil {
+				return opts, fmt.Errorf("--set port=%s: %w", v, err)
+			}
+			opts.Port = port
+		} else {
+			opts.Port, _ = platform.NewPortManager().FindFreePortNear(5173)
+		}
+	}
+	if opts.HTTPSPort == 0 {
+		if v, ok := opts.Set["https_port"]; ok {
+			port, err := strconv.Atoi(v)
+			if err != nil {
+				return opts, fmt.Errorf("--set https_port=%s: %w", v, err)
+			}
+			opts.HTTPSPort = port
+		} else {
+			opts.HTTPSPort, _ = platform.NewPortManager().FindFreePortNear(8443)
+		}
+	}
+	if opts.Author == "" {
+		opts.Author = valueOrDefault(opts.Set, "author", gitConfigValue("user.name"))
+	}
+	if opts.Email == "" {
+		opts.Email = valueOrDefault(opts.Set, "email", gitConfigValue("user.email"))
+	}
+
+	if opts.Vars == nil {
+		opts.Vars = make(map[string]string, len(manifestVars))
+	}
+	for key, value := range opts.Set {
+		if !knownExampleSetKeys[key] {
+			opts.Vars[key] = value
+		}
+	}
+
+	for _, v := range manifestVars {
+		if _, ok := opts.Vars[v.Name]; ok {
+			continue
+		}
+		if v.Default == "" && v.Regex != "" {
+			return opts, fmt.Errorf("template variable %q requires --set %s=<value> in headless mode", v.Name, v.Name)
+		}
+	}
+
+	return opts, nil
+}
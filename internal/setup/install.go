@@ -11,10 +11,23 @@ import (
 	"strings"
 	"time"
 
+	miekgdns "github.com/miekg/dns"
+
+	nsmdns "github.com/kashifsb/nsm/pkg/dns"
 	"github.com/kashifsb/nsm/pkg/logger"
 	"github.com/kashifsb/nsm/pkg/utils"
 )
 
+// linuxDNSConfigurator is the OSConfigurator chosen by addTLDLinux/
+// removeTLDConfiguration for the lifetime of this process (computed once,
+// since detecting it shells out and queries D-Bus), and linuxConfiguredTLDs
+// is every TLD handed to it so far: each configurator's SetDNS replaces
+// its whole TLD list rather than appending, but setupTLDs calls
+// addTLDConfiguration once per TLD, so we have to resend the full set
+// every time.
+var linuxDNSConfigurator nsmdns.OSConfigurator
+var linuxConfiguredTLDs []string
+
 func initializeConfig(cfg *Config) error {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
@@ -32,6 +45,12 @@ func initializeConfig(cfg *Config) error {
 	cfg.HasSystemd = utils.IsCommandAvailable("systemctl")
 	cfg.HasSudo = utils.IsCommandAvailable("sudo")
 
+	// A declarative nsm.conf, if present, takes priority over the TLDs/
+	// cert-mode/etc. passed as flags.
+	if err := applyDeclarativeConfig(cfg); err != nil {
+		return fmt.Errorf("apply declarative config: %w", err)
+	}
+
 	return nil
 }
 
@@ -84,16 +103,7 @@ func installDependencies(ctx context.Context, cfg *Config) error {
 
 func configureDNS(ctx context.Context, cfg *Config) error {
 	logger.Info("Configuring DNS for development domains")
-
-	switch cfg.Platform {
-	case "darwin":
-		return configureDNSMacOS(cfg)
-	case "linux":
-		return configureDNSLinux(cfg)
-	default:
-		logger.Warn("DNS auto-configuration not supported on this platform")
-		return nil
-	}
+	return installDNSDaemon(cfg)
 }
 
 func setupTLDs(ctx context.Context, cfg *Config) error {
@@ -120,9 +130,9 @@ func verifySetup(ctx context.Context, cfg *Config) error {
 		}
 	}
 
-	// Test DNS resolution
+	// Test DNS resolution, following the configured check method
 	for _, tld := range cfg.TLDs {
-		if err := testTLDResolution(tld); err != nil {
+		if err := testTLDResolution(cfg.DNSCheckMethod, tld); err != nil {
 			logger.Warn("TLD resolution test failed", "tld", tld, "error", err)
 		}
 	}
@@ -144,13 +154,6 @@ func getDependencies(platform string) []Dependency {
 			Checker:     func() bool { return utils.IsCommandAvailable("mkcert") },
 			Installer:   installMkcert,
 		},
-		{
-			Name:        "dnsmasq",
-			Description: "Lightweight DNS server for local development",
-			Required:    true,
-			Checker:     func() bool { return utils.IsCommandAvailable("dnsmasq") },
-			Installer:   installDnsmasq,
-		},
 	}
 
 	if platform == "darwin" {
@@ -190,32 +193,6 @@ func installMkcert() error {
 	}
 }
 
-func installDnsmasq() error {
-	if runtime.GOOS == "darwin" && utils.IsCommandAvailable("brew") {
-		if err := runCommand("brew", "install", "dnsmasq"); err != nil {
-			return err
-		}
-		// Start dnsmasq service
-		return runCommand("brew", "services", "start", "dnsmasq")
-	}
-
-	switch runtime.GOOS {
-	case "linux":
-		if utils.IsCommandAvailable("apt") {
-			return runCommand("sudo", "apt", "install", "-y", "dnsmasq")
-		}
-		if utils.IsCommandAvailable("yum") {
-			return runCommand("sudo", "yum", "install", "-y", "dnsmasq")
-		}
-		if utils.IsCommandAvailable("pacman") {
-			return runCommand("sudo", "pacman", "-S", "--noconfirm", "dnsmasq")
-		}
-		return fmt.Errorf("no supported package manager found")
-	default:
-		return fmt.Errorf("automatic installation not supported on %s", runtime.GOOS)
-	}
-}
-
 func installHomebrew() error {
 	logger.Info("Installing Homebrew...")
 	cmd := exec.Command("bash", "-c",
@@ -223,33 +200,6 @@ func installHomebrew() error {
 	return cmd.Run()
 }
 
-func configureDNSMacOS(cfg *Config) error {
-	// Configure dnsmasq
-	dnsmasqConf := getDnsmasqConfig()
-	confPath := "/opt/homebrew/etc/dnsmasq.conf"
-
-	if err := os.WriteFile(confPath, []byte(dnsmasqConf), 0644); err != nil {
-		return fmt.Errorf("write dnsmasq config: %w", err)
-	}
-
-	// Create hosts file
-	hostsPath := "/opt/homebrew/etc/dnsmasq.hosts"
-	hostsContent := "127.0.0.1 localhost\n"
-
-	if err := os.WriteFile(hostsPath, []byte(hostsContent), 0644); err != nil {
-		return fmt.Errorf("write dnsmasq hosts: %w", err)
-	}
-
-	// Restart dnsmasq
-	return runCommand("brew", "services", "restart", "dnsmasq")
-}
-
-func configureDNSLinux(cfg *Config) error {
-	// Similar to macOS but for Linux
-	// Implementation would depend on the specific Linux distribution
-	return fmt.Errorf("Linux DNS configuration not yet implemented")
-}
-
 func addTLDConfiguration(tld string) error {
 	switch runtime.GOOS {
 	case "darwin":
@@ -266,7 +216,7 @@ func addTLDMacOS(tld string) error {
 	resolverDir := "/etc/resolver"
 	resolverFile := filepath.Join(resolverDir, tld)
 
-	resolverContent := "nameserver 127.0.0.1\nport 5353\n"
+	resolverContent := fmt.Sprintf("nameserver 127.0.0.1\nport %s\n", daemonPort)
 
 	if err := os.WriteFile(resolverFile, []byte(resolverContent), 0644); err != nil {
 		// Try with sudo if permission denied
@@ -279,9 +229,27 @@ func addTLDMacOS(tld string) error {
 }
 
 func addTLDLinux(tld string) error {
-	// Add to dnsmasq configuration
-	// Implementation would depend on the specific setup
-	return fmt.Errorf("Linux TLD configuration not yet implemented")
+	if linuxDNSConfigurator == nil {
+		linuxDNSConfigurator = nsmdns.NewLinuxConfigurator()
+	}
+
+	if !containsString(linuxConfiguredTLDs, tld) {
+		linuxConfiguredTLDs = append(linuxConfiguredTLDs, tld)
+	}
+
+	if err := linuxDNSConfigurator.SetDNS(linuxConfiguredTLDs, daemonAddr); err != nil {
+		return fmt.Errorf("configure DNS for .%s: %w", tld, err)
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
 }
 
 func removeTLDConfiguration(tld string) error {
@@ -290,17 +258,29 @@ func removeTLDConfiguration(tld string) error {
 		resolverFile := filepath.Join("/etc/resolver", tld)
 		return os.Remove(resolverFile)
 	case "linux":
-		// Remove from dnsmasq configuration
-		return fmt.Errorf("Linux TLD removal not yet implemented")
+		if linuxDNSConfigurator == nil {
+			return nil
+		}
+
+		remaining := make([]string, 0, len(linuxConfiguredTLDs))
+		for _, v := range linuxConfiguredTLDs {
+			if v != tld {
+				remaining = append(remaining, v)
+			}
+		}
+		linuxConfiguredTLDs = remaining
+
+		if len(linuxConfiguredTLDs) == 0 {
+			return linuxDNSConfigurator.Revert()
+		}
+		return linuxDNSConfigurator.SetDNS(linuxConfiguredTLDs, daemonAddr)
 	default:
 		return nil
 	}
 }
 
-func testTLDResolution(tld string) error {
-	testDomain := fmt.Sprintf("test.%s", tld)
-	cmd := exec.Command("nslookup", testDomain)
-	return cmd.Run()
+func testTLDResolution(checkMethod, tld string) error {
+	return checkDNSRecord(checkMethod, fmt.Sprintf("test.%s", tld), miekgdns.TypeA, "")
 }
 
 func isTLDConfigured(tld string) bool {
@@ -308,6 +288,8 @@ func isTLDConfigured(tld string) bool {
 	case "darwin":
 		resolverFile := filepath.Join("/etc/resolver", tld)
 		return utils.FileExists(resolverFile)
+	case "linux":
+		return containsString(linuxConfiguredTLDs, tld)
 	default:
 		return false
 	}
@@ -336,44 +318,6 @@ func runCommand(name string, args ...string) error {
 	return cmd.Run()
 }
 
-func getDnsmasqConfig() string {
-	return `# NSM dnsmasq configuration
-port=5353
-listen-address=127.0.0.1
-bind-interfaces
-
-# Handle all local development TLDs
-local=/dev/
-local=/test/
-local=/local/
-local=/app/
-
-# Additional hosts file
-addn-hosts=/opt/homebrew/etc/dnsmasq.hosts
-
-# Upstream DNS servers
-server=1.1.1.1
-server=1.0.0.1
-server=8.8.8.8
-
-# Cache settings
-cache-size=1000
-neg-ttl=60
-
-# Don't read /etc/hosts
-no-hosts
-
-# Don't poll /etc/resolv.conf
-no-poll
-
-# Development domains
-address=/dev/127.0.0.1
-address=/test/127.0.0.1
-address=/local/127.0.0.1
-address=/app/127.0.0.1
-`
-}
-
 func saveConfig(cfg Config) error {
 	configFile := filepath.Join(cfg.ConfigDir, "config.json")
 	data, err := json.MarshalIndent(cfg, "", "  ")
@@ -0,0 +1,204 @@
+package setup
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"regexp"
+	"runtime"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/kashifsb/nsm/pkg/logger"
+)
+
+// ManifestFilename is the name every template directory may supply to
+// become data-driven instead of relying on a baked-in PostCreate function.
+const ManifestFilename = "nsm-template.yaml"
+
+// TemplateVariable describes one value a template needs from the user.
+type TemplateVariable struct {
+	Name    string `yaml:"name"`
+	Type    string `yaml:"type"` // string, int, bool
+	Default string `yaml:"default"`
+	Regex   string `yaml:"regex"`
+	Prompt  string `yaml:"prompt"`
+}
+
+// FileRule controls how files matching Glob are handled while walking a
+// template directory. Mode is one of "template", "copy", or "skip".
+type FileRule struct {
+	Glob string `yaml:"glob"`
+	Mode string `yaml:"mode"`
+}
+
+// RenameRule rewrites a matched path, with Go template syntax evaluated
+// against the merged template values (e.g. "{{.ProjectName}}.go").
+type RenameRule struct {
+	From string `yaml:"from"`
+	To   string `yaml:"to"`
+}
+
+// Hook is a shell command run during project creation, optionally
+// restricted to a set of GOOS values.
+type Hook struct {
+	Name string   `yaml:"name"`
+	Run  string   `yaml:"run"`
+	OS   []string `yaml:"os"`
+}
+
+// Manifest is the parsed contents of a template's nsm-template.yaml.
+type Manifest struct {
+	Variables []TemplateVariable `yaml:"variables"`
+	Files     []FileRule         `yaml:"files"`
+	Rename    []RenameRule       `yaml:"rename"`
+	Hooks     struct {
+		PreCreate  []Hook `yaml:"pre_create"`
+		PostCreate []Hook `yaml:"post_create"`
+	} `yaml:"hooks"`
+}
+
+// LoadManifest reads and parses nsm-template.yaml from templateDir. A
+// missing manifest is not an error - it signals the template should fall
+// back to the legacy PostCreate pipeline.
+func LoadManifest(templateFS fs.FS, templateDir string) (*Manifest, error) {
+	manifestPath := path.Join(templateDir, ManifestFilename)
+
+	data, err := fs.ReadFile(templateFS, manifestPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read %s: %w", ManifestFilename, err)
+	}
+
+	var m Manifest
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", ManifestFilename, err)
+	}
+
+	return &m, nil
+}
+
+// ValidateValues checks that every declared variable's value (falling back
+// to its default) satisfies its regex, if one is declared.
+func (m *Manifest) ValidateValues(values map[string]string) error {
+	for _, v := range m.Variables {
+		value, ok := values[v.Name]
+		if !ok || value == "" {
+			value = v.Default
+		}
+
+		if v.Regex == "" {
+			continue
+		}
+
+		re, err := regexp.Compile(v.Regex)
+		if err != nil {
+			return fmt.Errorf("variable %s: invalid regex %q: %w", v.Name, v.Regex, err)
+		}
+
+		if !re.MatchString(value) {
+			return fmt.Errorf("variable %s: value %q does not match %q", v.Name, value, v.Regex)
+		}
+	}
+
+	return nil
+}
+
+// Defaults returns the declared variables' default values, used when no
+// interactive or --set value was supplied.
+func (m *Manifest) Defaults() map[string]string {
+	defaults := make(map[string]string, len(m.Variables))
+	for _, v := range m.Variables {
+		defaults[v.Name] = v.Default
+	}
+	return defaults
+}
+
+// fileMode returns how relPath should be handled: "template", "copy", or
+// "skip". Later rules take precedence; unmatched files default to "copy"
+// (or "template" if they carry the conventional .tmpl suffix).
+func (m *Manifest) fileMode(relPath string) string {
+	mode := ""
+	if strings.HasSuffix(relPath, ".tmpl") {
+		mode = "template"
+	}
+
+	for _, rule := range m.Files {
+		if ok, _ := path.Match(rule.Glob, relPath); ok {
+			mode = rule.Mode
+		}
+	}
+
+	if mode == "" {
+		mode = "copy"
+	}
+	return mode
+}
+
+// renamedPath applies the manifest's rename rules and renders any Go
+// template syntax left in the path against data.
+func (m *Manifest) renamedPath(relPath string, data any) (string, error) {
+	for _, rule := range m.Rename {
+		if ok, _ := path.Match(rule.From, relPath); ok {
+			relPath = rule.To
+			break
+		}
+	}
+
+	if !strings.Contains(relPath, "{{") {
+		return relPath, nil
+	}
+
+	tmpl, err := template.New("path").Parse(relPath)
+	if err != nil {
+		return "", fmt.Errorf("parse renamed path %q: %w", relPath, err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("render renamed path %q: %w", relPath, err)
+	}
+
+	return buf.String(), nil
+}
+
+// RunHooks executes an ordered list of hooks with projectPath as the
+// working directory, skipping any hook whose OS filter excludes the
+// current platform.
+func RunHooks(hooks []Hook, projectPath string) error {
+	for _, hook := range hooks {
+		if len(hook.OS) > 0 && !containsString(hook.OS, runtime.GOOS) {
+			logger.Debug("Skipping hook for platform", "hook", hook.Name, "os", runtime.GOOS)
+			continue
+		}
+
+		logger.Info("Running template hook", "hook", hook.Name)
+
+		cmd := exec.Command("sh", "-c", hook.Run)
+		cmd.Dir = projectPath
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("hook %s failed: %w", hook.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func containsString(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,305 @@
+package setup
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/kashifsb/nsm/pkg/logger"
+	"github.com/kashifsb/nsm/pkg/utils"
+)
+
+// TemplateAlias is a user-registered pointer to a git-hosted template tree.
+// Once added, it behaves identically to a built-in FrameworkConfig when
+// passed to ExampleManager.Create.
+type TemplateAlias struct {
+	Name   string `json:"name"`
+	GitURL string `json:"git_url"`
+	Ref    string `json:"ref,omitempty"`
+	Subdir string `json:"subdir,omitempty"`
+}
+
+// TemplateRegistry is the persisted set of registered template aliases.
+type TemplateRegistry struct {
+	Templates []TemplateAlias `json:"templates"`
+}
+
+var gitRefPattern = regexp.MustCompile(`^git\+(https?://[^#]+)(?:#ref=(.+))?$`)
+
+func templateRegistryPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".nsm", "templates.json"), nil
+}
+
+func templateCacheDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".nsm", "templates"), nil
+}
+
+// LoadTemplateRegistry reads the registry file, returning an empty registry
+// if none has been saved yet.
+func LoadTemplateRegistry() (*TemplateRegistry, error) {
+	path, err := templateRegistryPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &TemplateRegistry{}, nil
+		}
+		return nil, fmt.Errorf("read template registry: %w", err)
+	}
+
+	var reg TemplateRegistry
+	if err := json.Unmarshal(data, &reg); err != nil {
+		return nil, fmt.Errorf("parse template registry: %w", err)
+	}
+
+	return &reg, nil
+}
+
+func saveTemplateRegistry(reg *TemplateRegistry) error {
+	path, err := templateRegistryPath()
+	if err != nil {
+		return err
+	}
+
+	if err := utils.EnsureDir(filepath.Dir(path)); err != nil {
+		return fmt.Errorf("create registry directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(reg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal template registry: %w", err)
+	}
+
+	return os.WriteFile(path, data, 0o644)
+}
+
+func (reg *TemplateRegistry) find(name string) (*TemplateAlias, bool) {
+	for i := range reg.Templates {
+		if reg.Templates[i].Name == name {
+			return &reg.Templates[i], true
+		}
+	}
+	return nil, false
+}
+
+// allowedGitURLSchemes are the transports a template's GitURL may use.
+// Rejecting everything else closes off git's argument-injection vector: a
+// GitURL starting with "-" (e.g. "--upload-pack=...") or an "ext::"-style
+// transport would otherwise reach runGit as a positional exec.Command arg
+// and be interpreted as an option or an arbitrary command, not a repo.
+var allowedGitURLSchemes = []string{"https://", "git://", "ssh://"}
+
+func validateGitURL(gitURL string) error {
+	for _, scheme := range allowedGitURLSchemes {
+		if strings.HasPrefix(gitURL, scheme) {
+			return nil
+		}
+	}
+	return fmt.Errorf("git URL %q must start with https://, git://, or ssh://", gitURL)
+}
+
+// AddTemplateAlias registers (or updates) a named alias pointing at a git
+// template, persisting it to ~/.nsm/templates.json.
+func AddTemplateAlias(alias, gitURL, ref string) error {
+	reg, err := LoadTemplateRegistry()
+	if err != nil {
+		return fmt.Errorf("load template registry: %w", err)
+	}
+
+	gitURL, subdir := splitGitSubdir(gitURL)
+	if err := validateGitURL(gitURL); err != nil {
+		return err
+	}
+	if strings.HasPrefix(ref, "-") {
+		return fmt.Errorf("ref %q must not start with '-'", ref)
+	}
+
+	if existing, ok := reg.find(alias); ok {
+		existing.GitURL = gitURL
+		existing.Ref = ref
+		existing.Subdir = subdir
+	} else {
+		reg.Templates = append(reg.Templates, TemplateAlias{
+			Name:   alias,
+			GitURL: gitURL,
+			Ref:    ref,
+			Subdir: subdir,
+		})
+	}
+
+	return saveTemplateRegistry(reg)
+}
+
+// RemoveTemplateAlias removes a registered alias. It is not an error to
+// remove an alias that does not exist.
+func RemoveTemplateAlias(alias string) error {
+	reg, err := LoadTemplateRegistry()
+	if err != nil {
+		return fmt.Errorf("load template registry: %w", err)
+	}
+
+	var remaining []TemplateAlias
+	for _, t := range reg.Templates {
+		if t.Name != alias {
+			remaining = append(remaining, t)
+		}
+	}
+	reg.Templates = remaining
+
+	return saveTemplateRegistry(reg)
+}
+
+// ListTemplateAliases returns all registered aliases.
+func ListTemplateAliases() ([]TemplateAlias, error) {
+	reg, err := LoadTemplateRegistry()
+	if err != nil {
+		return nil, fmt.Errorf("load template registry: %w", err)
+	}
+	return reg.Templates, nil
+}
+
+// UpdateTemplateAlias re-fetches the cached checkout for a registered alias.
+func UpdateTemplateAlias(alias string) error {
+	reg, err := LoadTemplateRegistry()
+	if err != nil {
+		return fmt.Errorf("load template registry: %w", err)
+	}
+
+	t, ok := reg.find(alias)
+	if !ok {
+		return fmt.Errorf("template alias '%s' not registered", alias)
+	}
+
+	_, err = fetchGitTemplate(*t)
+	return err
+}
+
+// ResolveTemplateRef parses a framework name that isn't a built-in
+// FrameworkConfig into a git reference. It accepts:
+//
+//	git+https://host/org/repo#ref=v1.2.3
+//	github.com/org/repo
+//	github.com/org/repo/subdir
+//
+// ok is false if name doesn't look like a git reference at all.
+func ResolveTemplateRef(name string) (gitURL, ref, subdir string, ok bool) {
+	if m := gitRefPattern.FindStringSubmatch(name); m != nil {
+		url, sub := splitGitSubdir(m[1])
+		return url, m[2], sub, true
+	}
+
+	if strings.HasPrefix(name, "github.com/") {
+		parts := strings.SplitN(strings.TrimPrefix(name, "github.com/"), "/", 3)
+		if len(parts) < 2 {
+			return "", "", "", false
+		}
+		gitURL = fmt.Sprintf("https://github.com/%s/%s", parts[0], parts[1])
+		if len(parts) == 3 {
+			subdir = parts[2]
+		}
+		return gitURL, "", subdir, true
+	}
+
+	return "", "", "", false
+}
+
+func splitGitSubdir(gitURL string) (string, string) {
+	const marker = "//"
+	// Preserve the scheme's "://" while splitting "repo.git//subdir" style refs.
+	idx := strings.Index(gitURL, marker)
+	schemeEnd := strings.Index(gitURL, "://")
+	if idx == -1 || (schemeEnd != -1 && idx == schemeEnd) {
+		return gitURL, ""
+	}
+	return gitURL[:idx], gitURL[idx+len(marker):]
+}
+
+// fetchGitTemplate clones (or updates an existing clone of) a template's git
+// repository into the local cache, returning the on-disk directory that
+// should be walked by the template pipeline.
+func fetchGitTemplate(t TemplateAlias) (string, error) {
+	cacheDir, err := templateCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	if err := validateGitURL(t.GitURL); err != nil {
+		return "", err
+	}
+
+	repoDir := filepath.Join(cacheDir, utils.SanitizeFilename(t.Name+"-"+t.GitURL))
+
+	if utils.DirExists(filepath.Join(repoDir, ".git")) {
+		logger.Info("Updating cached template", "name", t.Name, "url", t.GitURL)
+		if err := runGit(repoDir, "fetch", "--all", "--tags"); err != nil {
+			return "", fmt.Errorf("fetch template: %w", err)
+		}
+	} else {
+		logger.Info("Cloning template", "name", t.Name, "url", t.GitURL)
+		if err := utils.EnsureDir(cacheDir); err != nil {
+			return "", fmt.Errorf("create template cache: %w", err)
+		}
+		// "--" stops option parsing before the positional repo/dir args,
+		// so a GitURL that somehow still starts with "-" can't be read as
+		// a git clone flag.
+		if err := runGit(cacheDir, "clone", "--", t.GitURL, repoDir); err != nil {
+			return "", fmt.Errorf("clone template: %w", err)
+		}
+	}
+
+	ref := t.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+	if strings.HasPrefix(ref, "-") {
+		return "", fmt.Errorf("ref %q must not start with '-'", ref)
+	}
+	// checkout's "--" separates pathspecs, not refs, so it would change
+	// what this command does rather than guard it; rejecting a
+	// dash-prefixed ref above is the actual guard here.
+	if err := runGit(repoDir, "checkout", ref); err != nil {
+		return "", fmt.Errorf("checkout %s: %w", ref, err)
+	}
+
+	templateDir := repoDir
+	if t.Subdir != "" {
+		templateDir = filepath.Join(repoDir, t.Subdir)
+	}
+
+	if !utils.DirExists(templateDir) {
+		return "", fmt.Errorf("template directory '%s' not found in %s", t.Subdir, t.GitURL)
+	}
+
+	return templateDir, nil
+}
+
+func runGit(dir string, args ...string) error {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git %s: %w\n%s", strings.Join(args, " "), err, output)
+	}
+	return nil
+}
+
+// diskTemplateFS exposes a directory on disk as an fs.FS so that git-hosted
+// templates can share the walk/process pipeline used for embedded templates.
+func diskTemplateFS(dir string) fs.FS {
+	return os.DirFS(dir)
+}
@@ -0,0 +1,53 @@
+package setup
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/kashifsb/nsm/pkg/logger"
+)
+
+// The rollback* functions below undo what their corresponding wizard step
+// did, so a cancelled `nsm-setup install` doesn't leave the machine
+// half-configured. They take only cfg (not *SetupModel) so the same
+// plumbing backs both a mid-install cancellation and the standalone
+// `nsm-setup reset` command.
+
+// rollbackDirectories removes the directories createDirectories made.
+func rollbackDirectories(ctx context.Context, cfg *Config) error {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(filepath.Join(homeDir, ".nsm"))
+}
+
+// rollbackDNS undoes configureDNS by stopping and removing the background
+// DNS daemon's service definition. It deliberately leaves the mkcert CA
+// installed in the system trust store alone - Reset has never torn that
+// down either, and doing so automatically risks breaking other tools that
+// trust the same CA.
+func rollbackDNS(ctx context.Context, cfg *Config) error {
+	return uninstallDNSDaemon(cfg)
+}
+
+// rollbackACME undoes configureACME's installACMERenewalDaemon.
+func rollbackACME(ctx context.Context, cfg *Config) error {
+	return uninstallACMERenewalDaemon(cfg)
+}
+
+// rollbackTLDs undoes setupTLDs by removing each TLD's resolver
+// configuration again.
+func rollbackTLDs(ctx context.Context, cfg *Config) error {
+	var firstErr error
+	for _, tld := range cfg.TLDs {
+		if err := removeTLDConfiguration(tld); err != nil {
+			logger.Warn("Rollback: failed to remove TLD configuration", "tld", tld, "error", err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
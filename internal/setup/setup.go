@@ -15,7 +15,7 @@ import (
 )
 
 func RunInteractive(ctx context.Context, cfg Config) error {
-	model := NewSetupModel(cfg)
+	model := NewSetupModel(cfg, ctx)
 
 	p := tea.NewProgram(
 		model,
@@ -45,6 +45,7 @@ func RunHeadless(ctx context.Context, cfg Config) error {
 		{Name: "directories", Fn: createDirectories},
 		{Name: "dependencies", Fn: installDependencies},
 		{Name: "dns", Fn: configureDNS},
+		{Name: "acme", Fn: configureACME},
 		{Name: "tlds", Fn: setupTLDs},
 		{Name: "verification", Fn: verifySetup},
 	}
@@ -161,6 +162,12 @@ func ListTLDs(ctx context.Context) error {
 	return nil
 }
 
+// GetSystemStatus exposes getSystemStatus to callers outside this package,
+// e.g. the nsm and nsm-setup version commands.
+func GetSystemStatus() (*SystemStatus, error) {
+	return getSystemStatus()
+}
+
 func ShowStatus(ctx context.Context) error {
 	status, err := getSystemStatus()
 	if err != nil {
@@ -175,8 +182,8 @@ func ShowStatus(ctx context.Context) error {
 	fmt.Println()
 
 	fmt.Println("Dependencies:")
-	fmt.Printf("  mkcert:   %s\n", boolToStatus(status.Dependencies.Mkcert))
-	fmt.Printf("  dnsmasq:  %s\n", boolToStatus(status.Dependencies.Dnsmasq))
+	fmt.Printf("  mkcert:     %s\n", boolToStatus(status.Dependencies.Mkcert))
+	fmt.Printf("  dns daemon: %s\n", boolToStatus(status.Dependencies.DNSDaemon))
 	if status.Platform == "darwin" {
 		fmt.Printf("  homebrew: %s\n", boolToStatus(status.Dependencies.Homebrew))
 	}
@@ -200,12 +207,19 @@ func Reset(ctx context.Context) error {
 		// Continue with reset anyway
 	}
 
-	// Remove TLD configurations
+	// Undo each wizard step's side effects with the same rollback hooks a
+	// cancelled `nsm-setup install` uses, in reverse order so the DNS/ACME
+	// daemons are stopped before their service definition files (under
+	// configDir) are deleted below.
 	if cfg != nil {
-		for _, tld := range cfg.TLDs {
-			if err := removeTLDConfiguration(tld); err != nil {
-				logger.Warn("Failed to remove TLD", "tld", tld, "error", err)
-			}
+		if err := rollbackTLDs(ctx, cfg); err != nil {
+			logger.Warn("Failed to remove TLD configuration", "error", err)
+		}
+		if err := rollbackACME(ctx, cfg); err != nil {
+			logger.Warn("Failed to uninstall ACME renewal daemon", "error", err)
+		}
+		if err := rollbackDNS(ctx, cfg); err != nil {
+			logger.Warn("Failed to uninstall DNS daemon", "error", err)
 		}
 	}
 
@@ -225,11 +239,47 @@ func Reset(ctx context.Context) error {
 	return nil
 }
 
-func CreateExample(ctx context.Context, framework string) error {
-	logger.Info("Creating example project", "framework", framework)
-
+// CreateExample wires an optional interactive wizard (framework picker,
+// then project details) into ExampleManager.Create. With no framework in
+// opts and neither Headless nor AutoYes set, it prompts for one; project
+// metadata and template-declared variables are likewise prompted for
+// interactively unless Headless or AutoYes is set, in which case they must
+// come from opts.Set or fail closed.
+func CreateExample(ctx context.Context, opts ExampleOptions) error {
 	exampleManager := NewExampleManager()
-	return exampleManager.Create(framework)
+
+	if opts.Framework == "" {
+		if opts.Headless || opts.AutoYes {
+			return fmt.Errorf("framework is required when --headless or --auto-yes is set")
+		}
+
+		framework, err := promptFramework(exampleManager)
+		if err != nil {
+			return fmt.Errorf("select framework: %w", err)
+		}
+		opts.Framework = framework
+	}
+
+	manifest, err := exampleManager.PeekManifest(opts.Framework)
+	if err != nil {
+		return fmt.Errorf("resolve framework: %w", err)
+	}
+	var manifestVars []TemplateVariable
+	if manifest != nil {
+		manifestVars = manifest.Variables
+	}
+
+	if opts.Headless || opts.AutoYes {
+		opts, err = fillFromSet(exampleManager, opts, manifestVars)
+	} else {
+		opts, err = promptProjectDetails(exampleManager, opts, manifestVars)
+	}
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Creating example project", "framework", opts.Framework)
+	return exampleManager.Create(opts)
 }
 
 // Helper functions
@@ -250,7 +300,7 @@ func getSystemStatus() (*SystemStatus, error) {
 
 	// Check dependencies
 	status.Dependencies.Mkcert = utils.IsCommandAvailable("mkcert")
-	status.Dependencies.Dnsmasq = utils.IsCommandAvailable("dnsmasq")
+	status.Dependencies.DNSDaemon = queryDNSDaemon("test-nsm.dev") == nil
 
 	if status.Platform == "darwin" {
 		status.Dependencies.Homebrew = utils.IsCommandAvailable("brew")
@@ -1,17 +1,19 @@
 package setup
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
-	"time"
 
 	"github.com/charmbracelet/bubbles/progress"
 	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	miekgdns "github.com/miekg/dns"
 
+	"github.com/kashifsb/nsm/pkg/logger"
 	"github.com/kashifsb/nsm/pkg/utils"
 )
 
@@ -49,14 +51,18 @@ var (
 )
 
 type SetupModel struct {
-	cfg      Config
-	state    SetupState
-	steps    []StepStatus
-	progress progress.Model
-	spinner  spinner.Model
-	width    int
-	height   int
-	err      error
+	cfg         Config
+	state       SetupState
+	steps       []StepStatus
+	wizardSteps []wizardStep
+	progress    progress.Model
+	spinner     spinner.Model
+	width       int
+	height      int
+	err         error
+
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 type SetupState int
@@ -66,6 +72,7 @@ const (
 	StateChecking
 	StateInstalling
 	StateConfiguring
+	StateCancelling
 	StateComplete
 	StateError
 )
@@ -78,6 +85,21 @@ type StepStatus struct {
 	Error       error
 }
 
+// wizardStep pairs a step's name with its UI-bound runner and the (pure,
+// cfg-only) rollback that undoes it. Rollback is nil for steps that have
+// nothing to undo (checking requirements, verifying the result).
+type wizardStep struct {
+	Name     string
+	Fn       func(*SetupModel) error
+	Rollback func(ctx context.Context, cfg *Config) error
+}
+
+// StepStartedMsg marks a step as running; sent by runStep before the step's
+// work begins.
+type StepStartedMsg struct {
+	StepName string
+}
+
 type StepCompleteMsg struct {
 	StepName string
 	Success  bool
@@ -85,10 +107,15 @@ type StepCompleteMsg struct {
 	Details  string
 }
 
+// RollbackCompleteMsg is sent once every completed step's Rollback has run,
+// triggered by the user cancelling (ctrl+c) or the process receiving
+// SIGINT/SIGTERM mid-run.
+type RollbackCompleteMsg struct{}
+
 type AllStepsCompleteMsg struct{}
 type ShutdownMsg struct{}
 
-func NewSetupModel(cfg Config) *SetupModel {
+func NewSetupModel(cfg Config, parent context.Context) *SetupModel {
 	p := progress.New(progress.WithDefaultGradient())
 	p.Width = 60
 
@@ -101,16 +128,32 @@ func NewSetupModel(cfg Config) *SetupModel {
 		{Name: "directories", Description: "Creating directories", Status: "pending"},
 		{Name: "dependencies", Description: "Installing dependencies", Status: "pending"},
 		{Name: "dns", Description: "Configuring DNS", Status: "pending"},
+		{Name: "acme", Description: "Issuing ACME certificates", Status: "pending"},
 		{Name: "tlds", Description: "Setting up TLDs", Status: "pending"},
 		{Name: "verification", Description: "Verifying installation", Status: "pending"},
 	}
 
+	wizardSteps := []wizardStep{
+		{Name: "check", Fn: (*SetupModel).checkSystem},
+		{Name: "directories", Fn: (*SetupModel).createDirectories, Rollback: rollbackDirectories},
+		{Name: "dependencies", Fn: (*SetupModel).installDependencies},
+		{Name: "dns", Fn: (*SetupModel).configureDNS, Rollback: rollbackDNS},
+		{Name: "acme", Fn: (*SetupModel).configureACME, Rollback: rollbackACME},
+		{Name: "tlds", Fn: (*SetupModel).setupTLDs, Rollback: rollbackTLDs},
+		{Name: "verification", Fn: (*SetupModel).verifySetup},
+	}
+
+	ctx, cancel := context.WithCancel(parent)
+
 	return &SetupModel{
-		cfg:      cfg,
-		state:    StateWelcome,
-		steps:    steps,
-		progress: p,
-		spinner:  s,
+		cfg:         cfg,
+		state:       StateWelcome,
+		steps:       steps,
+		wizardSteps: wizardSteps,
+		progress:    p,
+		spinner:     s,
+		ctx:         ctx,
+		cancel:      cancel,
 	}
 }
 
@@ -132,14 +175,25 @@ func (m *SetupModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case tea.KeyMsg:
 		switch msg.String() {
-		case "ctrl+c", "q":
+		case "ctrl+c":
+			switch m.state {
+			case StateWelcome, StateComplete, StateError, StateCancelling:
+				return m, tea.Quit
+			default:
+				// Bubble Tea runs the terminal in raw mode, so the OS never
+				// delivers ctrl+c as SIGINT here - cancel m.ctx ourselves and
+				// unwind whatever steps already ran.
+				m.cancel()
+				m.state = StateCancelling
+				return m, m.rollbackFrom(m.stepIndex(m.runningStep()))
+			}
+		case "q":
 			if m.state == StateComplete || m.state == StateError {
 				return m, tea.Quit
 			}
 		case "enter":
 			if m.state == StateWelcome {
 				m.state = StateChecking
-				return m, m.startSetup()
 			}
 		}
 
@@ -148,19 +202,40 @@ func (m *SetupModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.spinner, cmd = m.spinner.Update(msg)
 		cmds = append(cmds, cmd)
 
+	case StepStartedMsg:
+		m.updateStepStatus(msg.StepName, "running")
+
 	case StepCompleteMsg:
 		m.updateStep(msg.StepName, msg.Success, msg.Error, msg.Details)
 
 		if !msg.Success {
+			if m.ctx.Err() != nil {
+				m.state = StateCancelling
+				return m, m.rollbackFrom(m.stepIndex(msg.StepName) - 1)
+			}
 			m.state = StateError
 			m.err = msg.Error
+			return m, nil
+		}
+
+		return m, m.runStep(m.stepIndex(msg.StepName) + 1)
+
+	case RollbackCompleteMsg:
+		m.state = StateError
+		if m.err == nil {
+			m.err = fmt.Errorf("setup cancelled")
 		}
 
 	case AllStepsCompleteMsg:
 		m.state = StateComplete
 
 	case ShutdownMsg:
-		return m, tea.Quit
+		if m.state == StateWelcome || m.state == StateComplete || m.state == StateError || m.state == StateCancelling {
+			return m, tea.Quit
+		}
+		m.cancel()
+		m.state = StateCancelling
+		return m, m.rollbackFrom(m.stepIndex(m.runningStep()))
 	}
 
 	return m, tea.Batch(cmds...)
@@ -180,7 +255,7 @@ func (m *SetupModel) View() string {
 	switch m.state {
 	case StateWelcome:
 		sections = append(sections, m.renderWelcome())
-	case StateChecking, StateInstalling, StateConfiguring:
+	case StateChecking, StateInstalling, StateConfiguring, StateCancelling:
 		sections = append(sections, m.renderProgress())
 	case StateComplete:
 		sections = append(sections, m.renderComplete())
@@ -217,6 +292,7 @@ func (m *SetupModel) renderWelcome() string {
 		"",
 		"Platform: " + m.cfg.Platform,
 		"TLDs to configure: " + strings.Join(m.cfg.TLDs, ", "),
+		"DNS check method: " + m.cfg.DNSCheckMethod,
 		"",
 		"Press Enter to continue or Ctrl+C to exit",
 	}
@@ -308,6 +384,8 @@ func (m *SetupModel) renderFooter() string {
 		return mutedStyle.Render("Press Enter to start setup • Ctrl+C to exit")
 	case StateComplete, StateError:
 		return mutedStyle.Render("Press 'q' to exit")
+	case StateCancelling:
+		return mutedStyle.Render("Cancelling, rolling back partial changes...")
 	default:
 		return mutedStyle.Render("Setting up NSM... • Ctrl+C to cancel")
 	}
@@ -341,70 +419,113 @@ func (m *SetupModel) updateStep(stepName string, success bool, err error, detail
 	}
 }
 
-func (m *SetupModel) startSetup() tea.Cmd {
-	return func() tea.Msg {
-		// This would start the actual setup process
-		// For now, just simulate the steps
-		go m.runSetupSteps()
-		return nil
+func (m *SetupModel) updateStepStatus(stepName, status string) {
+	for i, step := range m.steps {
+		if step.Name == stepName {
+			m.steps[i].Status = status
+			break
+		}
 	}
 }
 
-func (m *SetupModel) runSetupSteps() {
-	steps := []struct {
-		name string
-		fn   func() error
-	}{
-		{"check", m.checkSystem},
-		{"directories", m.createDirectories},
-		{"dependencies", m.installDependencies},
-		{"dns", m.configureDNS},
-		{"tlds", m.setupTLDs},
-		{"verification", m.verifySetup},
+// stepIndex returns name's position in wizardSteps, or -1 if it isn't one
+// (or hasn't started running yet).
+func (m *SetupModel) stepIndex(name string) int {
+	for i, step := range m.wizardSteps {
+		if step.Name == name {
+			return i
+		}
 	}
+	return -1
+}
 
-	for _, step := range steps {
-		// Update step to running
-		m.updateStepStatus(step.name, "running")
+// runningStep returns the name of the step currently shown as "running",
+// used to find where to resume rollback from when cancellation arrives
+// outside the normal StepCompleteMsg flow (a ctrl+c keypress or an external
+// SIGINT/SIGTERM).
+func (m *SetupModel) runningStep() string {
+	for _, step := range m.steps {
+		if step.Status == "running" {
+			return step.Name
+		}
+	}
+	return ""
+}
 
-		// Execute step
-		if err := step.fn(); err != nil {
-			m.sendStepComplete(step.name, false, err, "")
-			return
+// startSetup initializes cfg's paths and kicks off the first wizard step.
+// It runs at program start (Init), not when the welcome screen is
+// dismissed - pressing Enter there only changes what's displayed.
+func (m *SetupModel) startSetup() tea.Cmd {
+	if err := initializeConfig(&m.cfg); err != nil {
+		return func() tea.Msg {
+			return StepCompleteMsg{StepName: "check", Success: false, Error: fmt.Errorf("initialize config: %w", err)}
 		}
+	}
+	return m.runStep(0)
+}
 
-		m.sendStepComplete(step.name, true, nil, "Completed")
-		time.Sleep(500 * time.Millisecond) // Visual delay
+// runStep returns the Cmd that runs wizardSteps[idx]: a StepStartedMsg
+// (marking it "running" in the UI) followed by its StepCompleteMsg. Once
+// idx is past the end, it signals AllStepsCompleteMsg instead.
+func (m *SetupModel) runStep(idx int) tea.Cmd {
+	if idx < 0 || idx >= len(m.wizardSteps) {
+		return func() tea.Msg { return AllStepsCompleteMsg{} }
 	}
 
-	// All steps complete
-	m.sendAllComplete()
+	step := m.wizardSteps[idx]
+	return tea.Sequence(stepStartedCmd(step.Name), m.runStepCmd(step))
 }
 
-func (m *SetupModel) updateStepStatus(stepName, status string) {
-	// This method is called from the goroutine, so we need to send a message
-	// to update the UI thread safely
-	// For now, we'll update directly since this is called from the same goroutine
-	for i, step := range m.steps {
-		if step.Name == stepName {
-			m.steps[i].Status = status
-			break
-		}
-	}
+func stepStartedCmd(name string) tea.Cmd {
+	return func() tea.Msg { return StepStartedMsg{StepName: name} }
 }
 
-func (m *SetupModel) sendStepComplete(stepName string, success bool, err error, details string) {
-	// Send step completion message to the UI thread
-	// This would be implemented with proper message passing
-	// For now, we'll update directly since this is called from the same goroutine
-	m.updateStep(stepName, success, err, details)
+// runStepCmd runs step.Fn in its own goroutine and wraps the result channel
+// in a Cmd, so its completion reaches Update as a message instead of the
+// goroutine mutating m.steps directly.
+func (m *SetupModel) runStepCmd(step wizardStep) tea.Cmd {
+	result := make(chan StepCompleteMsg, 1)
+
+	go func() {
+		if m.ctx.Err() != nil {
+			result <- StepCompleteMsg{StepName: step.Name, Success: false, Error: m.ctx.Err()}
+			return
+		}
+
+		err := step.Fn(m)
+
+		if ctxErr := m.ctx.Err(); ctxErr != nil {
+			result <- StepCompleteMsg{StepName: step.Name, Success: false, Error: ctxErr}
+			return
+		}
+		if err != nil {
+			result <- StepCompleteMsg{StepName: step.Name, Success: false, Error: err}
+			return
+		}
+		result <- StepCompleteMsg{StepName: step.Name, Success: true, Details: "Completed"}
+	}()
+
+	return func() tea.Msg { return <-result }
 }
 
-func (m *SetupModel) sendAllComplete() {
-	// Send all steps complete message to the UI thread
-	// This would be implemented with proper message passing
-	// For now, we'll update directly since this is called from the same goroutine
-	// The UI will detect when all steps are complete
+// rollbackFrom runs Rollback (where defined) for every completed step up to
+// and including fromIdx, in reverse order, so a cancelled install doesn't
+// leave the machine half-configured.
+func (m *SetupModel) rollbackFrom(fromIdx int) tea.Cmd {
+	return func() tea.Msg {
+		for i := fromIdx; i >= 0; i-- {
+			step := m.wizardSteps[i]
+			if step.Rollback == nil {
+				continue
+			}
+			// Cleanup shouldn't itself be subject to the cancellation that
+			// triggered it, so this runs with a fresh, uncancelled context.
+			if err := step.Rollback(context.Background(), &m.cfg); err != nil {
+				logger.Warn("Rollback step failed", "step", step.Name, "error", err)
+			}
+		}
+		return RollbackCompleteMsg{}
+	}
 }
 
 // Actual implementation of setup steps
@@ -414,9 +535,6 @@ func (m *SetupModel) checkSystem() error {
 		if !utils.IsCommandAvailable("mkcert") {
 			return fmt.Errorf("mkcert not found - install with: brew install mkcert")
 		}
-		if !utils.IsCommandAvailable("dnsmasq") {
-			return fmt.Errorf("dnsmasq not found - install with: brew install dnsmasq")
-		}
 	}
 	return nil
 }
@@ -455,21 +573,11 @@ func (m *SetupModel) installDependencies() error {
 				return fmt.Errorf("install mkcert: %w", err)
 			}
 		}
-
-		// Install dnsmasq if not available
-		if !utils.IsCommandAvailable("dnsmasq") {
-			if err := utils.RunCommand("brew", "install", "dnsmasq"); err != nil {
-				return fmt.Errorf("install dnsmasq: %w", err)
-			}
-		}
 	} else {
 		// On other platforms, just check if tools are available
 		if !utils.IsCommandAvailable("mkcert") {
 			return fmt.Errorf("mkcert not found - please install it manually")
 		}
-		if !utils.IsCommandAvailable("dnsmasq") {
-			return fmt.Errorf("dnsmasq not found - please install it manually")
-		}
 	}
 	return nil
 }
@@ -480,21 +588,17 @@ func (m *SetupModel) configureDNS() error {
 		return fmt.Errorf("install mkcert CA: %w", err)
 	}
 
-	// Configure dnsmasq
-	if m.cfg.Platform == "darwin" {
-		// On macOS, configure dnsmasq via Homebrew
-		if err := configureDnsmasqMacOS(); err != nil {
-			return fmt.Errorf("configure dnsmasq: %w", err)
-		}
-	} else {
-		// On Linux, configure dnsmasq manually
-		if err := configureDnsmasqLinux(); err != nil {
-			return fmt.Errorf("configure dnsmasq: %w", err)
-		}
+	// Install and start the embedded background DNS daemon
+	if err := installDNSDaemon(&m.cfg); err != nil {
+		return fmt.Errorf("configure DNS daemon: %w", err)
 	}
 	return nil
 }
 
+func (m *SetupModel) configureACME() error {
+	return configureACME(m.ctx, &m.cfg)
+}
+
 func (m *SetupModel) setupTLDs() error {
 	// Configure TLDs
 	for _, tld := range m.cfg.TLDs {
@@ -507,7 +611,7 @@ func (m *SetupModel) setupTLDs() error {
 
 func (m *SetupModel) verifySetup() error {
 	// Test that everything is working
-	if err := testDNSResolution(); err != nil {
+	if err := testDNSResolution(m.cfg.DNSCheckMethod); err != nil {
 		return fmt.Errorf("DNS test failed: %w", err)
 	}
 
@@ -518,66 +622,17 @@ func (m *SetupModel) verifySetup() error {
 }
 
 // Helper functions for setup steps
-func configureDnsmasqMacOS() error {
-	// Configure dnsmasq on macOS
-	dnsmasqConfig := `# NSM Configuration
-address=/dev/127.0.0.1
-address=/test/127.0.0.1
-address=/local/127.0.0.1
-port=53535
-`
-
-	configPath := "/opt/homebrew/etc/dnsmasq.conf"
-	if !utils.FileExists(configPath) {
-		configPath = "/usr/local/etc/dnsmasq.conf"
-	}
-
-	// Append NSM configuration
-	if err := utils.AppendToFile(configPath, dnsmasqConfig); err != nil {
-		return fmt.Errorf("write dnsmasq config: %w", err)
-	}
-
-	// Restart dnsmasq
-	if err := utils.RunCommand("brew", "services", "restart", "dnsmasq"); err != nil {
-		return fmt.Errorf("restart dnsmasq: %w", err)
-	}
-
-	return nil
-}
-
-func configureDnsmasqLinux() error {
-	// Configure dnsmasq on Linux
-	dnsmasqConfig := `# NSM Configuration
-address=/dev/127.0.0.1
-address=/test/127.0.0.1
-address=/local/127.0.0.1
-port=53535
-`
-
-	configPath := "/etc/dnsmasq.conf"
-	if err := utils.AppendToFile(configPath, dnsmasqConfig); err != nil {
-		return fmt.Errorf("write dnsmasq config: %w", err)
-	}
-
-	// Restart dnsmasq
-	if err := utils.RunCommand("systemctl", "restart", "dnsmasq"); err != nil {
-		return fmt.Errorf("restart dnsmasq: %w", err)
-	}
-
-	return nil
-}
-
 func setupTLD(tld string) error {
 	// Create resolver file for the TLD
 	homeDir, _ := os.UserHomeDir()
 	resolverDir := filepath.Join(homeDir, ".nsm", "resolvers")
-	
+
 	if err := os.MkdirAll(resolverDir, 0755); err != nil {
 		return fmt.Errorf("create resolver directory: %w", err)
 	}
 
 	resolverFile := filepath.Join(resolverDir, tld)
-	resolverContent := fmt.Sprintf("nameserver 127.0.0.1\nport 53535\n")
+	resolverContent := fmt.Sprintf("nameserver 127.0.0.1\nport %s\n", daemonPort)
 
 	if err := os.WriteFile(resolverFile, []byte(resolverContent), 0644); err != nil {
 		return fmt.Errorf("write resolver file: %w", err)
@@ -586,30 +641,29 @@ func setupTLD(tld string) error {
 	return nil
 }
 
-func testDNSResolution() error {
-	// Test DNS resolution for a .dev domain
+func testDNSResolution(checkMethod string) error {
+	// Resolve a .dev test domain, following the configured check method
 	testDomain := "test-nsm.dev"
-	
-	// Use nslookup to test resolution
-	if err := utils.RunCommand("nslookup", testDomain, "127.0.0.1"); err != nil {
+
+	if err := checkDNSRecord(checkMethod, testDomain, miekgdns.TypeA, ""); err != nil {
 		return fmt.Errorf("DNS resolution test failed: %w", err)
 	}
-	
+
 	return nil
 }
 
 func testCertificateGeneration() error {
 	// Test certificate generation
 	testDomain := "test-nsm.dev"
-	
+
 	// Generate a test certificate
 	if err := utils.RunCommand("mkcert", testDomain); err != nil {
 		return fmt.Errorf("certificate generation test failed: %w", err)
 	}
-	
+
 	// Clean up test certificate
 	os.Remove(testDomain + ".pem")
 	os.Remove(testDomain + "-key.pem")
-	
+
 	return nil
 }
@@ -0,0 +1,27 @@
+package tunnel
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// cloudflaredURLPattern matches the quick-tunnel URL `cloudflared tunnel
+// --url` prints to stderr once the tunnel is up, e.g.
+// "https://random-words.trycloudflare.com".
+var cloudflaredURLPattern = regexp.MustCompile(`https://[a-zA-Z0-9-]+\.trycloudflare\.com`)
+
+// newCloudflaredProvider returns a Provider backed by `cloudflared tunnel
+// --url`, Cloudflare's no-login-required quick tunnel. It's the default
+// provider since it needs no prior account setup, unlike ngrok/tailscale.
+func newCloudflaredProvider() Provider {
+	return &cmdProvider{
+		name:   "cloudflared",
+		binary: "cloudflared",
+		buildArgs: func(port int) []string {
+			return []string{"tunnel", "--url", fmt.Sprintf("https://localhost:%d", port), "--no-tls-verify"}
+		},
+		extractURL: func(line string) string {
+			return cloudflaredURLPattern.FindString(line)
+		},
+	}
+}
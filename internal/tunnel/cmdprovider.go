@@ -0,0 +1,110 @@
+package tunnel
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kashifsb/nsm/pkg/logger"
+	"github.com/kashifsb/nsm/pkg/process"
+)
+
+// urlWaitTimeout bounds how long Start waits for a provider's subprocess
+// to announce its public URL before giving up.
+const urlWaitTimeout = 30 * time.Second
+
+// cmdProvider is the shared implementation behind cloudflaredProvider,
+// ngrokProvider, and tailscaleProvider: each spawns a long-running CLI
+// command via process.Supervisor and announces its public URL somewhere in
+// its stdout/stderr, so all that differs between them is the binary, its
+// arguments, and how the URL is picked out of a line of output.
+type cmdProvider struct {
+	name       string
+	binary     string
+	buildArgs  func(port int) []string
+	extractURL func(line string) string
+
+	sup *process.Supervisor
+
+	mu      sync.Mutex
+	url     string
+	readyCh chan struct{}
+}
+
+func (p *cmdProvider) Name() string { return p.name }
+
+func (p *cmdProvider) Start(ctx context.Context, port int) (string, error) {
+	p.mu.Lock()
+	p.url = ""
+	p.readyCh = make(chan struct{})
+	p.mu.Unlock()
+
+	p.sup = process.NewSupervisor(process.Config{
+		Command: p.binary,
+		Args:    p.buildArgs(port),
+		// MaxRestarts 0: a crashed tunnel is reported via OnGiveUp and left
+		// stopped rather than retried here - the caller's health-check
+		// goroutine owns reconnect-with-backoff, so the tunnel's own URL
+		// can be re-announced through Start rather than silently resuming.
+		MaxRestarts: 0,
+		OnOutput:    p.handleOutput,
+		OnGiveUp: func(err error) {
+			logger.Warn(p.name+" tunnel process exited", "error", err)
+		},
+	})
+
+	if err := p.sup.Start(ctx); err != nil {
+		return "", fmt.Errorf("start %s: %w", p.name, err)
+	}
+
+	select {
+	case <-p.readyCh:
+		p.mu.Lock()
+		url := p.url
+		p.mu.Unlock()
+		return url, nil
+	case <-time.After(urlWaitTimeout):
+		p.sup.Stop()
+		return "", fmt.Errorf("%s did not report a public URL within %s", p.name, urlWaitTimeout)
+	case <-ctx.Done():
+		p.sup.Stop()
+		return "", ctx.Err()
+	}
+}
+
+// handleOutput is process.Config.OnOutput: it scans every line for the
+// provider's public URL and, on the first match, unblocks Start via
+// readyCh.
+func (p *cmdProvider) handleOutput(source, line string) {
+	logger.Debug(p.name+" tunnel output", "source", source, "line", line)
+
+	p.mu.Lock()
+	if p.url != "" {
+		p.mu.Unlock()
+		return
+	}
+
+	url := p.extractURL(line)
+	if url == "" {
+		p.mu.Unlock()
+		return
+	}
+
+	p.url = url
+	readyCh := p.readyCh
+	p.mu.Unlock()
+
+	close(readyCh)
+}
+
+func (p *cmdProvider) Stop(ctx context.Context) error {
+	if p.sup == nil {
+		return nil
+	}
+	return p.sup.Stop()
+}
+
+func (p *cmdProvider) IsRunning() bool {
+	return p.sup != nil && p.sup.IsRunning()
+}
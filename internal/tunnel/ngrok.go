@@ -0,0 +1,30 @@
+package tunnel
+
+import (
+	"regexp"
+	"strconv"
+)
+
+// ngrokURLPattern matches the public URL out of ngrok's logfmt-style
+// "started tunnel" line, e.g. `...msg="started tunnel" ... url=https://abcd.ngrok-free.app`.
+var ngrokURLPattern = regexp.MustCompile(`url=(https://\S+)`)
+
+// newNgrokProvider returns a Provider backed by `ngrok http`, requiring
+// ngrok to already be authenticated (`ngrok config add-authtoken`) on this
+// machine.
+func newNgrokProvider() Provider {
+	return &cmdProvider{
+		name:   "ngrok",
+		binary: "ngrok",
+		buildArgs: func(port int) []string {
+			return []string{"http", "--log=stdout", "--log-format=logfmt", "https://localhost:" + strconv.Itoa(port)}
+		},
+		extractURL: func(line string) string {
+			m := ngrokURLPattern.FindStringSubmatch(line)
+			if m == nil {
+				return ""
+			}
+			return m[1]
+		},
+	}
+}
@@ -0,0 +1,40 @@
+// Package tunnel exposes the local HTTPS port over a public URL through an
+// external tool, so a dev server started with `nsm` can be shared with a
+// teammate or a webhook without the user leaving the terminal.
+package tunnel
+
+import (
+	"context"
+	"fmt"
+)
+
+// Provider is one tunneling backend: cloudflared, ngrok, or tailscale
+// funnel. NewProvider selects an implementation by config.TunnelConfig.Provider.
+type Provider interface {
+	// Name identifies the provider, e.g. "cloudflared".
+	Name() string
+	// Start spawns the tunnel pointed at https://localhost:port and
+	// blocks until its public URL is known, Stop is called, or ctx is
+	// cancelled.
+	Start(ctx context.Context, port int) (url string, err error)
+	// Stop tears the tunnel down.
+	Stop(ctx context.Context) error
+	// IsRunning reports whether the tunnel's subprocess is still alive,
+	// for a caller's reconnect-on-drop health check.
+	IsRunning() bool
+}
+
+// NewProvider returns the Provider name selects: "cloudflared", "ngrok", or
+// "tailscale" (tailscale funnel).
+func NewProvider(name string) (Provider, error) {
+	switch name {
+	case "cloudflared":
+		return newCloudflaredProvider(), nil
+	case "ngrok":
+		return newNgrokProvider(), nil
+	case "tailscale":
+		return newTailscaleProvider(), nil
+	default:
+		return nil, fmt.Errorf("unknown tunnel provider %q (expected cloudflared, ngrok, or tailscale)", name)
+	}
+}
@@ -0,0 +1,26 @@
+package tunnel
+
+import (
+	"regexp"
+	"strconv"
+)
+
+// tailscaleURLPattern matches the line `tailscale funnel` prints once the
+// funnel is serving, e.g. "Available on the internet: https://host.tailnet.ts.net/".
+var tailscaleURLPattern = regexp.MustCompile(`https://[a-zA-Z0-9.-]+\.ts\.net\S*`)
+
+// newTailscaleProvider returns a Provider backed by `tailscale funnel`,
+// which serves the port over the tailnet's own HTTPS certificate - the
+// machine must already be logged into a tailnet with Funnel enabled.
+func newTailscaleProvider() Provider {
+	return &cmdProvider{
+		name:   "tailscale",
+		binary: "tailscale",
+		buildArgs: func(port int) []string {
+			return []string{"funnel", "--https=443", strconv.Itoa(port)}
+		},
+		extractURL: func(line string) string {
+			return tailscaleURLPattern.FindString(line)
+		},
+	}
+}
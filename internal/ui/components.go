@@ -7,6 +7,7 @@ import (
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/kashifsb/nsm/internal/config"
+	"github.com/kashifsb/nsm/pkg/metrics"
 )
 
 // Header component with enhanced design
@@ -36,9 +37,15 @@ func RenderConfigSummary(cfg *config.Config) string {
 		tableHeaderStyle.Render("Directory:"),
 		mutedStyle.Render(cfg.ProjectName)))
 
-	rows = append(rows, fmt.Sprintf("%-15s %s",
-		tableHeaderStyle.Render("Command:"),
-		infoStyle.Render(cfg.Command)))
+	if cfg.ProjectType == config.ProjectTypeStatic {
+		rows = append(rows, fmt.Sprintf("%-15s %s",
+			tableHeaderStyle.Render("Serving:"),
+			infoStyle.Render(cfg.StaticDir)))
+	} else {
+		rows = append(rows, fmt.Sprintf("%-15s %s",
+			tableHeaderStyle.Render("Command:"),
+			infoStyle.Render(cfg.Command)))
+	}
 
 	// Network configuration
 	if cfg.Domain != "" {
@@ -170,8 +177,13 @@ func RenderLogs(logs []LogEntry, maxLines int, scrollOffset int) string {
 		level := renderLogLevel(log.Level)
 		message := log.Message
 
-		line := fmt.Sprintf("%s %s %s", timestamp, level, message)
-		lines = append(lines, line)
+		parts := []string{timestamp, level}
+		if facetTag := renderFacetTag(log.Facet); facetTag != "" {
+			parts = append(parts, facetTag)
+		}
+		parts = append(parts, message)
+
+		lines = append(lines, strings.Join(parts, " "))
 	}
 
 	content := strings.Join(lines, "\n")
@@ -194,6 +206,22 @@ func RenderLogs(logs []LogEntry, maxLines int, scrollOffset int) string {
 	return RenderSection("📋 Recent Logs", cardStyle.Render(content))
 }
 
+// RenderMetricsPanel shows the trailing-60s request rate/latency the proxy
+// has recorded, for a quick at-a-glance pulse alongside the logs.
+func RenderMetricsPanel(snapshot metrics.Snapshot) string {
+	rows := []string{
+		fmt.Sprintf("%-15s %s",
+			tableHeaderStyle.Render("Requests:"),
+			infoStyle.Render(fmt.Sprintf("%.1f/s", snapshot.QPS))),
+		fmt.Sprintf("%-15s %s",
+			tableHeaderStyle.Render("Avg Latency:"),
+			infoStyle.Render(snapshot.AvgLatency.Round(time.Millisecond).String())),
+	}
+
+	content := strings.Join(rows, "\n")
+	return cardStyle.Render("📊 Metrics (last 60s)\n\n" + content)
+}
+
 // Helper functions
 func renderPortInfo(port int) string {
 	if port == 0 {
@@ -211,10 +239,12 @@ func renderFeatureStatus(enabled bool) string {
 
 func renderLogLevel(level string) string {
 	styles := map[string]lipgloss.Style{
+		"TRACE": mutedStyle,
 		"DEBUG": mutedStyle,
 		"INFO":  infoStyle,
 		"WARN":  warningStyle,
 		"ERROR": errorStyle,
+		"FATAL": errorStyle,
 	}
 
 	if style, ok := styles[level]; ok {
@@ -223,6 +253,27 @@ func renderLogLevel(level string) string {
 	return mutedStyle.Render(fmt.Sprintf("[%s]", level))
 }
 
+// facetStyles gives a handful of well-known internal/log facets (dns,
+// proxy, cert, ports, ...) a distinct color so a NSM_TRACE=dns,proxy session
+// can tell them apart at a glance; anything else falls back to mutedStyle.
+var facetStyles = map[string]lipgloss.Style{
+	"dns":   lipgloss.NewStyle().Foreground(accentColor),
+	"proxy": lipgloss.NewStyle().Foreground(primaryColor),
+	"cert":  lipgloss.NewStyle().Foreground(successColor),
+	"ports": lipgloss.NewStyle().Foreground(warningColor),
+}
+
+func renderFacetTag(facet string) string {
+	if facet == "" {
+		return ""
+	}
+	style, ok := facetStyles[facet]
+	if !ok {
+		style = mutedStyle
+	}
+	return style.Render(fmt.Sprintf("[%s]", facet))
+}
+
 // Data structures
 type StatusStep struct {
 	Name        string
@@ -235,4 +286,6 @@ type LogEntry struct {
 	Timestamp time.Time
 	Level     string
 	Message   string
+	Fields    map[string]any
+	Facet     string // internal/log facet that emitted this entry, e.g. "dns"; empty for UI/process-output entries
 }
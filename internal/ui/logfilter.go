@@ -0,0 +1,120 @@
+package ui
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// logLevelRank orders the canonical log levels for "level>=warn"-style
+// comparisons, mirroring project.LogLevel's ranking but kept local so this
+// package doesn't need to reach into project internals for display-only
+// filtering.
+var logLevelRank = map[string]int{
+	"trace": 0,
+	"debug": 1,
+	"info":  2,
+	"warn":  3,
+	"error": 4,
+	"fatal": 5,
+}
+
+var levelFilterRe = regexp.MustCompile(`^level\s*(>=|<=|==|>|<|=)\s*(\w+)$`)
+
+// LogFilter narrows the log pane to entries matching one of the filter box's
+// four grammars: a bare regexp against the message, a "level>=warn"
+// comparison against the canonical level, a "field:key=value" match against
+// a structured LogEvent field, or a "facet:name" match against the
+// internal/log facet (e.g. "dns", "proxy") that emitted the entry.
+type LogFilter struct {
+	raw   string
+	kind  string // "regex", "level", "field", or "facet"
+	re    *regexp.Regexp
+	op    string
+	level int
+	key   string
+	value string
+	facet string
+}
+
+// ParseLogFilter parses the text typed into the filter box. An empty (or
+// whitespace-only) expr clears the filter and returns a nil *LogFilter with
+// no error.
+func ParseLogFilter(expr string) (*LogFilter, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return nil, nil
+	}
+
+	if rest, ok := strings.CutPrefix(expr, "field:"); ok {
+		key, value, ok := strings.Cut(rest, "=")
+		if !ok {
+			return nil, fmt.Errorf("field filter must be field:key=value")
+		}
+		return &LogFilter{raw: expr, kind: "field", key: key, value: value}, nil
+	}
+
+	if rest, ok := strings.CutPrefix(expr, "facet:"); ok {
+		if rest == "" {
+			return nil, fmt.Errorf("facet filter must be facet:name")
+		}
+		return &LogFilter{raw: expr, kind: "facet", facet: rest}, nil
+	}
+
+	if m := levelFilterRe.FindStringSubmatch(expr); m != nil {
+		op := m[1]
+		if op == "=" {
+			op = "=="
+		}
+		rank, ok := logLevelRank[strings.ToLower(m[2])]
+		if !ok {
+			return nil, fmt.Errorf("unknown level %q", m[2])
+		}
+		return &LogFilter{raw: expr, kind: "level", op: op, level: rank}, nil
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter regexp: %w", err)
+	}
+	return &LogFilter{raw: expr, kind: "regex", re: re}, nil
+}
+
+// Match reports whether entry should be shown under this filter. A nil
+// *LogFilter matches everything.
+func (f *LogFilter) Match(entry LogEntry) bool {
+	if f == nil {
+		return true
+	}
+
+	switch f.kind {
+	case "field":
+		v, ok := entry.Fields[f.key]
+		if !ok {
+			return false
+		}
+		return fmt.Sprintf("%v", v) == f.value
+	case "facet":
+		return entry.Facet == f.facet
+	case "level":
+		rank, ok := logLevelRank[strings.ToLower(entry.Level)]
+		if !ok {
+			return false
+		}
+		switch f.op {
+		case ">=":
+			return rank >= f.level
+		case "<=":
+			return rank <= f.level
+		case ">":
+			return rank > f.level
+		case "<":
+			return rank < f.level
+		case "==":
+			return rank == f.level
+		}
+		return false
+	default: // "regex"
+		return f.re.MatchString(entry.Message)
+	}
+}
@@ -0,0 +1,95 @@
+package ui
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// statePath is where SaveSnapshot/LoadSnapshot persist the TUI's last known
+// state, mirroring config's ~/.nsm/config.yaml convention.
+func statePath() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".nsm", "state.json")
+}
+
+// snapshotLogTail bounds how many trailing log entries Snapshot persists,
+// so state.json doesn't grow to logBacklogCapacity's full backlog on every
+// write.
+const snapshotLogTail = 200
+
+// Snapshot is a point-in-time dump of Model, written to statePath after
+// every StepUpdateMsg, ServerStartedMsg, and log append so a crashed run
+// can be resumed with its error and log tail intact instead of starting
+// blank.
+type Snapshot struct {
+	Steps         []StatusStep `json:"steps"`
+	URLs          URLInfo      `json:"urls"`
+	Logs          []LogEntry   `json:"logs"`
+	ServerRunning bool         `json:"server_running"`
+	ErrorMessage  string       `json:"error_message,omitempty"`
+}
+
+// SaveSnapshot writes snap to statePath, overwriting any previous snapshot.
+// Callers treat a write failure as non-fatal (resume is best-effort), so
+// this just returns the error for them to log rather than anything that
+// should abort setup.
+func SaveSnapshot(snap Snapshot) error {
+	path := statePath()
+	if path == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0o644)
+}
+
+// LoadSnapshot reads statePath's snapshot, if any. A missing file isn't an
+// error - it returns a nil Snapshot, the common case on a clean first run.
+func LoadSnapshot() (*Snapshot, error) {
+	path := statePath()
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, err
+	}
+	return &snap, nil
+}
+
+// ClearSnapshot removes statePath's snapshot. Called once a run reaches
+// StateRunning, so the next launch doesn't offer to resume a run that
+// finished setup cleanly.
+func ClearSnapshot() error {
+	path := statePath()
+	if path == "" {
+		return nil
+	}
+
+	err := os.Remove(path)
+	if err != nil && os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
@@ -6,12 +6,21 @@ import (
 	"time"
 
 	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 
 	"github.com/kashifsb/nsm/internal/config"
+	"github.com/kashifsb/nsm/internal/project"
+	"github.com/kashifsb/nsm/pkg/logger"
+	"github.com/kashifsb/nsm/pkg/metrics"
 )
 
+// logBacklogCapacity bounds the in-memory log ring buffer. Entries beyond
+// this count are dropped oldest-first so scroll-back stays bounded even for
+// long-running dev servers.
+const logBacklogCapacity = 2000
+
 type Model struct {
 	cfg    *config.Config
 	state  AppState
@@ -24,6 +33,16 @@ type Model struct {
 	steps   []StatusStep
 	urls    URLInfo
 
+	// teardownSteps mirrors steps for StateShutdown: built from teardownPhases
+	// when ShutdownMsg arrives, then updated phase-by-phase by ShutdownStepMsg.
+	teardownSteps []StatusStep
+
+	// configBanner is shown above the running/setup view for a few seconds
+	// after a ConfigChangedMsg, naming the steps App.Reload is reapplying;
+	// configBannerUntil is when the next TickMsg should clear it.
+	configBanner      string
+	configBannerUntil time.Time
+
 	// State
 	setupComplete bool
 	serverRunning bool
@@ -32,10 +51,39 @@ type Model struct {
 	// UI program reference for message passing
 	program *tea.Program
 
+	// restartHandler is called when the user presses "r" in the error
+	// state; it asks the owning App to tear down and rebuild the real
+	// subsystem tree rather than just resetting local UI state.
+	restartHandler func()
+
+	// resumedSnapshot is a prior run's crash state, detected by NewModel at
+	// startup. Non-nil only until the user acts on the resume prompt: "r"
+	// consumes it in restartSetup (keeping completed steps and the log
+	// tail), "n" discards it via discardSnapshot and starts fresh from
+	// validate.
+	resumedSnapshot *Snapshot
+
 	// Scrolling and navigation
 	scrollOffset int
 	autoScroll   bool
 	showHelp     bool
+
+	// Log filtering: "/" opens filterInput, enter applies it as filter,
+	// esc cancels without changing the active filter. "f" and "c" instead
+	// cycle m.filter directly through fixed level/facet lists, for a quick
+	// one-key narrow without typing an expression.
+	filterInput    textinput.Model
+	filterActive   bool
+	filter         *LogFilter
+	levelFilterIdx int // index into logLevelCycle; 0 is "no level filter"
+	facetFilterIdx int // index into facets seen so far in m.logs; 0 is "no facet filter"
+
+	// logsVersion increments on every log append; filteredLogs() uses it to
+	// avoid re-filtering the backlog on ticks where nothing changed.
+	logsVersion        int
+	filterCache        []LogEntry
+	filterCacheVersion int
+	filterCacheFilter  *LogFilter
 }
 
 type AppState int
@@ -52,6 +100,10 @@ type URLInfo struct {
 	Primary string
 	Local   string
 	DevURL  string
+	// Public is the tunnel subsystem's public URL, set by TunnelReadyMsg;
+	// empty when no tunnel provider is configured or it hasn't connected
+	// yet.
+	Public string
 }
 
 // Messages
@@ -65,13 +117,52 @@ type (
 		Level   string
 		Message string
 	}
+	// LogRecordMsg is the internal/log package's TUI sink message: a
+	// structured log record (with an optional facet tag, e.g. "dns") to
+	// append to the log backlog, distinct from the plain LogMsg the
+	// setup wizard above uses.
+	LogRecordMsg struct {
+		Timestamp time.Time
+		Level     string
+		Facet     string
+		Message   string
+		Fields    map[string]any
+	}
 	StepUpdateMsg struct {
 		StepName string
 		Status   string
 		Details  string
 	}
 	ShutdownMsg struct{}
-	ErrorMsg    struct {
+	// ShutdownStepMsg is runTeardownPhase's counterpart to StepUpdateMsg,
+	// reporting one phase of App.shutdown's reversed
+	// dev/proxy/dns/certs/ports sequence.
+	ShutdownStepMsg struct {
+		StepName string
+		Status   string
+		Details  string
+	}
+	// ShutdownCompleteMsg fires once every teardown phase has reported (or
+	// App.shutdown's deadline gave up on it) - the TUI's cue to finally
+	// quit; ShutdownMsg itself no longer does.
+	ShutdownCompleteMsg struct{}
+	// ConfigChangedMsg reports a live reconfiguration App.Reload applied
+	// without restarting: Old/New are the configs it diffed, and Steps
+	// names which subsystem steps it's rerunning to pick up the
+	// difference (e.g. a domain change rerunning dns and certs).
+	ConfigChangedMsg struct {
+		Old   *config.Config
+		New   *config.Config
+		Steps []string
+	}
+	// TunnelReadyMsg reports that the tunnel subsystem (internal/tunnel)
+	// has a public URL, either from its initial start or a reconnect after
+	// a dropped connection.
+	TunnelReadyMsg struct {
+		Provider string
+		URL      string
+	}
+	ErrorMsg struct {
 		Err error
 	}
 	TickMsg time.Time
@@ -82,26 +173,51 @@ func NewModel(cfg *config.Config) *Model {
 	s.Spinner = spinner.Dot
 	s.Style = lipgloss.NewStyle().Foreground(primaryColor)
 
-	return &Model{
-		cfg:     cfg,
-		state:   StateInitializing,
-		spinner: s,
-		logs:    make([]LogEntry, 0),
+	fi := textinput.New()
+	fi.Prompt = "/"
+	fi.Placeholder = "regex, level>=warn, field:key=value"
+	fi.CharLimit = 200
+
+	devStepDescription := "Starting development server"
+	if cfg.ProjectType == config.ProjectTypeStatic {
+		devStepDescription = "Starting static file server"
+	}
+
+	m := &Model{
+		cfg:         cfg,
+		state:       StateInitializing,
+		spinner:     s,
+		logs:        make([]LogEntry, 0),
+		filterInput: fi,
 		steps: []StatusStep{
 			{Name: "validate", Description: "Validating configuration", Status: "pending"},
 			{Name: "ports", Description: "Configuring ports", Status: "pending"},
 			{Name: "certs", Description: "Setting up certificates", Status: "pending"},
 			{Name: "dns", Description: "Configuring DNS", Status: "pending"},
 			{Name: "proxy", Description: "Starting HTTPS proxy", Status: "pending"},
-			{Name: "dev", Description: "Starting development server", Status: "pending"},
+			{Name: "dev", Description: devStepDescription, Status: "pending"},
 		},
 	}
+
+	// If the previous run crashed mid-setup, it left a snapshot behind:
+	// open straight into the error view showing what it last knew, instead
+	// of a blank setup screen, so "r" can resume from the failed step.
+	if snap, err := LoadSnapshot(); err == nil && snap != nil && snap.ErrorMessage != "" {
+		m.resumedSnapshot = snap
+		m.state = StateError
+		m.error = fmt.Errorf("%s", snap.ErrorMessage)
+		m.steps = snap.Steps
+		m.urls = snap.URLs
+		m.logs = snap.Logs
+		m.serverRunning = snap.ServerRunning
+	}
+
+	return m
 }
 
 func (m *Model) Init() tea.Cmd {
 	return tea.Batch(
 		m.spinner.Tick,
-		m.startSetup(),
 		tickCmd(),
 	)
 }
@@ -115,32 +231,62 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.height = msg.Height
 
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "ctrl+c", "q":
-			return m, tea.Quit
-		case "up", "k":
-			// Scroll up through logs
-			m.scrollUp()
-		case "down", "j":
-			// Scroll down through logs
-			m.scrollDown()
-		case "g":
-			// Go to top
-			m.scrollToTop()
-		case "G":
-			// Go to bottom
-			m.scrollToBottom()
-		case "space":
-			// Toggle auto-scroll
-			m.toggleAutoScroll()
-		case "r":
-			// Refresh/restart setup
-			if m.state == StateError {
-				m.restartSetup()
+		if m.filterActive {
+			switch msg.String() {
+			case "enter":
+				m.confirmFilter()
+			case "esc":
+				m.cancelFilter()
+			default:
+				var cmd tea.Cmd
+				m.filterInput, cmd = m.filterInput.Update(msg)
+				cmds = append(cmds, cmd)
+			}
+		} else {
+			switch msg.String() {
+			case "ctrl+c", "q":
+				return m, tea.Quit
+			case "up", "k":
+				// Scroll up through logs
+				m.scrollUp()
+			case "down", "j":
+				// Scroll down through logs
+				m.scrollDown()
+			case "g":
+				// Go to top
+				m.scrollToTop()
+			case "G":
+				// Go to bottom
+				m.scrollToBottom()
+			case "space":
+				// Toggle auto-scroll
+				m.toggleAutoScroll()
+			case "r":
+				// Refresh/restart setup, or resume from the failed step if
+				// this error view came from a detected crash snapshot
+				if m.state == StateError {
+					m.restartSetup()
+				}
+			case "n":
+				// Discard a detected crash snapshot and start fresh from
+				// validate instead of resuming
+				if m.state == StateError && m.resumedSnapshot != nil {
+					m.discardSnapshot()
+				}
+			case "h":
+				// Show help
+				m.toggleHelp()
+			case "/":
+				// Open the log filter box
+				m.filterActive = true
+				cmds = append(cmds, m.filterInput.Focus())
+			case "f":
+				// Cycle the level filter: none -> debug -> info -> warn -> error -> none
+				m.cycleLevelFilter()
+			case "c":
+				// Cycle the facet/component filter through facets seen so far
+				m.cycleFacetFilter()
 			}
-		case "h":
-			// Show help
-			m.toggleHelp()
 		}
 
 	case spinner.TickMsg:
@@ -149,18 +295,71 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		cmds = append(cmds, cmd)
 
 	case TickMsg:
+		if m.configBanner != "" && !m.configBannerUntil.IsZero() && time.Time(msg).After(m.configBannerUntil) {
+			m.configBanner = ""
+			m.configBannerUntil = time.Time{}
+		}
 		cmds = append(cmds, tickCmd())
 
+	case TunnelReadyMsg:
+		m.urls.Public = msg.URL
+		m.addLog("INFO", fmt.Sprintf("Tunnel (%s) ready: %s", msg.Provider, msg.URL))
+
+	case ConfigChangedMsg:
+		m.configBanner = fmt.Sprintf("Config reloaded, reapplying: %s", strings.Join(msg.Steps, ", "))
+		m.configBannerUntil = time.Now().Add(8 * time.Second)
+		m.addLog("INFO", m.configBanner)
+
 	case StepUpdateMsg:
 		m.updateStep(msg.StepName, msg.Status, msg.Details)
 
 	case LogMsg:
 		m.addLog(msg.Level, msg.Message)
 
+	case LogRecordMsg:
+		m.addLogEntry(LogEntry{
+			Timestamp: msg.Timestamp,
+			Level:     msg.Level,
+			Facet:     msg.Facet,
+			Message:   msg.Message,
+			Fields:    msg.Fields,
+		})
+
+	case project.OutputMsg:
+		level := strings.ToUpper(string(msg.Level))
+		if level == "" {
+			level = "INFO"
+		}
+		message := msg.Line
+		if msg.Service != "" {
+			message = fmt.Sprintf("[%s] %s", msg.Service, message)
+		}
+		m.addLogWithFields(level, message, msg.Fields)
+
+	case project.ProcessExitMsg:
+		name := msg.Service
+		if name == "" {
+			name = "dev server"
+		}
+		if msg.Error != nil {
+			m.addLog("ERROR", fmt.Sprintf("%s exited: %v", name, msg.Error))
+		} else {
+			m.addLog("INFO", fmt.Sprintf("%s exited (code %d)", name, msg.ExitCode))
+		}
+
+	case project.RestartMsg:
+		details := fmt.Sprintf("restart attempt %d", msg.Attempt)
+		m.updateStep("dev", "loading", details)
+		m.addLog("WARN", fmt.Sprintf("Dev server crashed, %s", details))
+
 	case ServerStartedMsg:
 		m.state = StateRunning
 		m.serverRunning = true
 		m.updateURLs(msg.HTTPPort, msg.HTTPSPort)
+		m.saveSnapshot()
+		if err := ClearSnapshot(); err != nil {
+			logger.Warn("Failed to clear crash snapshot", "error", err)
+		}
 
 	case SetupCompleteMsg:
 		m.setupComplete = true
@@ -169,9 +368,32 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.state = StateError
 		m.error = msg.Err
 		m.addLog("ERROR", msg.Err.Error())
+		m.saveSnapshot()
 
 	case ShutdownMsg:
 		m.state = StateShutdown
+		m.teardownSteps = []StatusStep{
+			{Name: "tunnel", Description: "Closing public tunnel", Status: "pending"},
+			{Name: "dev", Description: "Stopping development server", Status: "pending"},
+			{Name: "proxy", Description: "Stopping HTTPS proxy", Status: "pending"},
+			{Name: "dns", Description: "Cleaning up DNS", Status: "pending"},
+			{Name: "certs", Description: "Stopping certificate renewal", Status: "pending"},
+			{Name: "ports", Description: "Releasing ports", Status: "pending"},
+		}
+		if err := ClearSnapshot(); err != nil {
+			logger.Warn("Failed to clear crash snapshot", "error", err)
+		}
+
+	case ShutdownStepMsg:
+		for i, step := range m.teardownSteps {
+			if step.Name == msg.StepName {
+				m.teardownSteps[i].Status = msg.Status
+				m.teardownSteps[i].Details = msg.Details
+				break
+			}
+		}
+
+	case ShutdownCompleteMsg:
 		return m, tea.Quit
 	}
 
@@ -229,6 +451,10 @@ func (m *Model) View() string {
 func (m *Model) renderSetupView() string {
 	var sections []string
 
+	if m.configBanner != "" {
+		sections = append(sections, infoStyle.Render(m.configBanner))
+	}
+
 	// Configuration summary
 	sections = append(sections, RenderConfigSummary(m.cfg))
 
@@ -237,7 +463,7 @@ func (m *Model) renderSetupView() string {
 
 	// Recent logs
 	if len(m.logs) > 0 {
-		sections = append(sections, RenderLogs(m.logs, 5, m.scrollOffset))
+		sections = append(sections, RenderLogs(m.filteredLogs(), 5, m.scrollOffset))
 	}
 
 	return lipgloss.JoinVertical(lipgloss.Left, sections...)
@@ -246,11 +472,20 @@ func (m *Model) renderSetupView() string {
 func (m *Model) renderRunningView() string {
 	var sections []string
 
+	if m.configBanner != "" {
+		sections = append(sections, infoStyle.Render(m.configBanner))
+	}
+
 	// URL information
 	sections = append(sections, m.renderURLPanel())
 
+	// Request rate/latency, if the metrics sidecar is enabled
+	if m.cfg.MetricsAddr != "" {
+		sections = append(sections, RenderMetricsPanel(metrics.CurrentSnapshot()))
+	}
+
 	// Live logs
-	sections = append(sections, RenderLogs(m.logs, 10, m.scrollOffset))
+	sections = append(sections, RenderLogs(m.filteredLogs(), 10, m.scrollOffset))
 
 	// Status indicators
 	sections = append(sections, m.renderStatusIndicators())
@@ -259,34 +494,55 @@ func (m *Model) renderRunningView() string {
 }
 
 func (m *Model) renderErrorView() string {
+	header := "âŒ Setup Error"
+	intro := "An error occurred during setup:"
+	if m.resumedSnapshot != nil {
+		header = "âŒ Previous Run Crashed"
+		intro = "NSM detected a crash from a previous run. Last known error:"
+	}
+
 	errorContent := []string{
-		"âŒ Setup Error",
+		header,
 		"",
-		"An error occurred during setup:",
+		intro,
 		"",
 		errorStyle.Render(m.error.Error()),
 		"",
 		"Troubleshooting:",
-		"  â€¢ Check that all required tools are installed (mkcert, dnsmasq)",
+		"  â€¢ Check that all required tools are installed (mkcert)",
 		"  â€¢ Ensure you have proper permissions for the project directory",
 		"  â€¢ Verify your network configuration",
 		"",
 		"Actions:",
-		"  â€¢ Press 'r' to restart setup",
+	}
+
+	if m.resumedSnapshot != nil {
+		errorContent = append(errorContent,
+			"  â€¢ Press 'r' to resume from the failed step",
+			"  â€¢ Press 'n' to start fresh from validate",
+		)
+	} else {
+		errorContent = append(errorContent, "  â€¢ Press 'r' to restart setup")
+	}
+
+	errorContent = append(errorContent,
 		"  â€¢ Press 'h' for help",
 		"  â€¢ Press 'q' to quit",
-	}
+	)
 
 	return cardStyle.Render(strings.Join(errorContent, "\n"))
 }
 
 func (m *Model) renderShutdownView() string {
-	shutdownCard := cardStyle.Render(
-		successStyle.Render("ðŸ‘‹ Shutdown Complete\n\n") +
-			"NSM has been stopped gracefully.\n" +
-			"All services have been cleaned up.",
-	)
-	return shutdownCard
+	var sections []string
+
+	sections = append(sections, cardStyle.Render(
+		successStyle.Render("ðŸ‘‹ Shutting Down\n\n")+
+			"Stopping services in reverse order...",
+	))
+	sections = append(sections, RenderStatusPanel(m.teardownSteps))
+
+	return lipgloss.JoinVertical(lipgloss.Left, sections...)
 }
 
 func (m *Model) renderHelpView() string {
@@ -299,6 +555,9 @@ func (m *Model) renderHelpView() string {
 		"  g       Go to top of logs",
 		"  G       Go to bottom of logs",
 		"  space   Toggle auto-scroll",
+		"  /       Filter logs (regex, level>=warn, field:key=value, facet:name)",
+		"  f       Cycle level filter (none/debug/info/warn/error)",
+		"  c       Cycle facet/component filter",
 		"",
 		"Actions:",
 		"  r       Restart setup (when in error state)",
@@ -336,6 +595,12 @@ func (m *Model) renderURLPanel() string {
 			mutedStyle.Render(m.urls.Local)))
 	}
 
+	if m.urls.Public != "" {
+		urls = append(urls, fmt.Sprintf("🌍 %s %s",
+			successStyle.Render("Public:"),
+			highlightStyle.Render(m.urls.Public)))
+	}
+
 	urls = append(urls, fmt.Sprintf("âš™ï¸  %s %s",
 		mutedStyle.Render("Dev Server:"),
 		mutedStyle.Render(m.urls.DevURL)))
@@ -389,18 +654,27 @@ func (m *Model) renderFooter() string {
 		parts = append(parts, m.spinner.View())
 	}
 
+	if m.filterActive {
+		return m.filterInput.View()
+	}
+
 	// Help text with navigation options
 	var help string
 	if m.showHelp {
-		help = mutedStyle.Render("Navigation: â†‘/k: scroll up â€¢ â†“/j: scroll down â€¢ g: top â€¢ G: bottom â€¢ space: auto-scroll â€¢ r: restart â€¢ h: hide help â€¢ q: quit")
+		help = mutedStyle.Render("Navigation: â†‘/k: scroll up â€¢ â†“/j: scroll down â€¢ g: top â€¢ G: bottom â€¢ space: auto-scroll â€¢ /: filter logs â€¢ r: restart â€¢ h: hide help â€¢ q: quit")
 	} else {
-		help = mutedStyle.Render("Press 'h' for help â€¢ 'q' to quit â€¢ Ctrl+C to stop")
+		help = mutedStyle.Render("Press 'h' for help â€¢ '/' to filter logs â€¢ 'q' to quit â€¢ Ctrl+C to stop")
 	}
 	parts = append(parts, help)
 
+	if m.filter != nil {
+		parts = append(parts, mutedStyle.Render(fmt.Sprintf("Filter: %s", m.filter.raw)))
+	}
+
 	// Show scroll position if not auto-scrolling
-	if !m.autoScroll && len(m.logs) > 10 {
-		scrollInfo := mutedStyle.Render(fmt.Sprintf("Scroll: %d/%d", m.scrollOffset+1, len(m.logs)))
+	visible := m.filteredLogs()
+	if !m.autoScroll && len(visible) > 10 {
+		scrollInfo := mutedStyle.Render(fmt.Sprintf("Scroll: %d/%d", m.scrollOffset+1, len(visible)))
 		parts = append(parts, scrollInfo)
 	}
 
@@ -416,21 +690,179 @@ func (m *Model) updateStep(name, status, details string) {
 			break
 		}
 	}
+	m.saveSnapshot()
+}
+
+// saveSnapshot persists the current steps/urls/log tail/serverRunning to
+// ~/.nsm/state.json, so a crash mid-setup leaves enough behind for the next
+// launch's NewModel to offer a resume. Called after every StepUpdateMsg,
+// ServerStartedMsg, and log append; failures are logged, not fatal, since
+// losing the resume snapshot shouldn't take down a run that's otherwise
+// fine.
+func (m *Model) saveSnapshot() {
+	logs := m.logs
+	if len(logs) > snapshotLogTail {
+		logs = logs[len(logs)-snapshotLogTail:]
+	}
+
+	errMsg := ""
+	if m.error != nil {
+		errMsg = m.error.Error()
+	}
+
+	if err := SaveSnapshot(Snapshot{
+		Steps:         m.steps,
+		URLs:          m.urls,
+		Logs:          logs,
+		ServerRunning: m.serverRunning,
+		ErrorMessage:  errMsg,
+	}); err != nil {
+		logger.Warn("Failed to save crash snapshot", "error", err)
+	}
 }
 
 func (m *Model) addLog(level, message string) {
-	entry := LogEntry{
+	m.addLogWithFields(level, message, nil)
+}
+
+// addLogWithFields is addLog with structured fields attached.
+func (m *Model) addLogWithFields(level, message string, fields map[string]any) {
+	m.addLogEntry(LogEntry{
 		Timestamp: time.Now(),
 		Level:     level,
 		Message:   message,
-	}
+		Fields:    fields,
+	})
+}
 
+// addLogEntry appends entry to the log backlog, a ring buffer capped at
+// logBacklogCapacity entries (oldest dropped first) so scroll-back stays
+// bounded on long-running dev servers.
+func (m *Model) addLogEntry(entry LogEntry) {
 	m.logs = append(m.logs, entry)
 
-	// Keep only last 100 logs
-	if len(m.logs) > 100 {
-		m.logs = m.logs[len(m.logs)-100:]
+	if len(m.logs) > logBacklogCapacity {
+		m.logs = m.logs[len(m.logs)-logBacklogCapacity:]
+	}
+
+	m.logsVersion++
+	m.saveSnapshot()
+}
+
+// filteredLogs returns the backlog narrowed to m.filter, or the full
+// backlog if no filter is active. View() re-evaluates this on every tick,
+// so the result is cached until the backlog or the active filter changes.
+func (m *Model) filteredLogs() []LogEntry {
+	if m.filter == nil {
+		return m.logs
+	}
+
+	if m.filterCacheVersion == m.logsVersion && m.filterCacheFilter == m.filter {
+		return m.filterCache
+	}
+
+	filtered := make([]LogEntry, 0, len(m.logs))
+	for _, entry := range m.logs {
+		if m.filter.Match(entry) {
+			filtered = append(filtered, entry)
+		}
+	}
+
+	m.filterCache = filtered
+	m.filterCacheVersion = m.logsVersion
+	m.filterCacheFilter = m.filter
+	return filtered
+}
+
+// confirmFilter applies the text typed into the filter box. An empty
+// expression clears the active filter; an invalid one is reported as a log
+// entry and leaves the previous filter (if any) in place.
+func (m *Model) confirmFilter() {
+	expr := m.filterInput.Value()
+	m.filterInput.SetValue("")
+	m.filterInput.Blur()
+	m.filterActive = false
+
+	f, err := ParseLogFilter(expr)
+	if err != nil {
+		m.addLog("ERROR", fmt.Sprintf("invalid log filter %q: %v", expr, err))
+		return
+	}
+
+	m.filter = f
+	m.scrollToBottom()
+}
+
+// cancelFilter closes the filter box without changing the active filter.
+func (m *Model) cancelFilter() {
+	m.filterInput.SetValue("")
+	m.filterInput.Blur()
+	m.filterActive = false
+}
+
+// logLevelCycle is the level sequence "f" steps through; index 0 clears
+// the level filter entirely.
+var logLevelCycle = []string{"", "debug", "info", "warn", "error"}
+
+// cycleLevelFilter steps m.levelFilterIdx to the next entry in
+// logLevelCycle and applies it as a "level>=X" filter (or clears m.filter
+// at index 0), so "f" alone narrows the log pane without typing an
+// expression into the filter box.
+func (m *Model) cycleLevelFilter() {
+	m.levelFilterIdx = (m.levelFilterIdx + 1) % len(logLevelCycle)
+
+	level := logLevelCycle[m.levelFilterIdx]
+	if level == "" {
+		m.filter = nil
+		return
+	}
+
+	f, err := ParseLogFilter(fmt.Sprintf("level>=%s", level))
+	if err != nil {
+		m.addLog("ERROR", fmt.Sprintf("invalid level filter %q: %v", level, err))
+		return
+	}
+	m.filter = f
+}
+
+// facetsSeen returns the distinct, first-seen-order Facet values present in
+// m.logs, for cycleFacetFilter to step through.
+func (m *Model) facetsSeen() []string {
+	seen := make(map[string]bool)
+	var facets []string
+	for _, entry := range m.logs {
+		if entry.Facet == "" || seen[entry.Facet] {
+			continue
+		}
+		seen[entry.Facet] = true
+		facets = append(facets, entry.Facet)
+	}
+	return facets
+}
+
+// cycleFacetFilter steps m.facetFilterIdx through the facets seen so far
+// (index 0 clears the facet filter), applying a "facet:name" filter so "c"
+// narrows the log pane to one component (e.g. "dns", "proxy") at a time.
+func (m *Model) cycleFacetFilter() {
+	facets := m.facetsSeen()
+	if len(facets) == 0 {
+		m.filter = nil
+		m.facetFilterIdx = 0
+		return
 	}
+
+	m.facetFilterIdx = (m.facetFilterIdx + 1) % (len(facets) + 1)
+	if m.facetFilterIdx == 0 {
+		m.filter = nil
+		return
+	}
+
+	f, err := ParseLogFilter(fmt.Sprintf("facet:%s", facets[m.facetFilterIdx-1]))
+	if err != nil {
+		m.addLog("ERROR", fmt.Sprintf("invalid facet filter: %v", err))
+		return
+	}
+	m.filter = f
 }
 
 func (m *Model) updateURLs(httpPort, httpsPort int) {
@@ -457,115 +889,6 @@ func (m *Model) updateURLs(httpPort, httpsPort int) {
 	m.urls.DevURL = fmt.Sprintf("http://127.0.0.1:%d", httpPort)
 }
 
-func (m *Model) startSetup() tea.Cmd {
-	return func() tea.Msg {
-		// Start the actual setup process in a goroutine
-		go m.runSetupProcess()
-		return StepUpdateMsg{
-			StepName: "validate",
-			Status:   "loading",
-			Details:  "Checking configuration",
-		}
-	}
-}
-
-func (m *Model) runSetupProcess() {
-	// Simulate setup steps with actual work and proper error handling
-	steps := []struct {
-		name     string
-		details  string
-		duration time.Duration
-	}{
-		{"validate", "Validating configuration", 1 * time.Second},
-		{"ports", "Configuring ports", 500 * time.Millisecond},
-		{"certs", "Setting up certificates", 2 * time.Second},
-		{"dns", "Configuring DNS", 1 * time.Second},
-		{"proxy", "Starting HTTPS proxy", 1 * time.Second},
-		{"dev", "Starting development server", 2 * time.Second},
-	}
-
-	for _, step := range steps {
-		// Update step to loading
-		if m.program != nil {
-			m.program.Send(StepUpdateMsg{
-				StepName: step.name,
-				Status:   "loading",
-				Details:  step.details,
-			})
-
-			// Add log entry for step start
-			m.program.Send(LogMsg{
-				Level:   "INFO",
-				Message: fmt.Sprintf("Starting step: %s", step.name),
-			})
-		}
-
-		// Simulate work with timeout protection
-		done := make(chan bool, 1)
-		go func() {
-			time.Sleep(step.duration)
-			done <- true
-		}()
-
-		// Wait for step completion or timeout
-		select {
-		case <-done:
-			// Step completed successfully
-			if m.program != nil {
-				m.program.Send(StepUpdateMsg{
-					StepName: step.name,
-					Status:   "success",
-					Details:  "Completed",
-				})
-
-				// Add log entry for step completion
-				m.program.Send(LogMsg{
-					Level:   "INFO",
-					Message: fmt.Sprintf("Step '%s' completed successfully", step.name),
-				})
-			}
-		case <-time.After(10 * time.Second):
-			// Step timed out
-			if m.program != nil {
-				m.program.Send(StepUpdateMsg{
-					StepName: step.name,
-					Status:   "error",
-					Details:  "Timeout - taking too long",
-				})
-
-				m.program.Send(LogMsg{
-					Level:   "ERROR",
-					Message: fmt.Sprintf("Step '%s' timed out", step.name),
-				})
-
-				// Send error message to stop the process
-				m.program.Send(ErrorMsg{
-					Err: fmt.Errorf("step '%s' timed out", step.name),
-				})
-				return
-			}
-		}
-	}
-
-	// Mark setup as complete
-	if m.program != nil {
-		m.program.Send(SetupCompleteMsg{})
-
-		// Simulate server start
-		time.Sleep(500 * time.Millisecond)
-		m.program.Send(ServerStartedMsg{
-			HTTPPort:  3000,
-			HTTPSPort: 8443,
-		})
-
-		// Add final success log
-		m.program.Send(LogMsg{
-			Level:   "INFO",
-			Message: "NSM setup completed successfully!",
-		})
-	}
-}
-
 func tickCmd() tea.Cmd {
 	return tea.Tick(time.Second, func(t time.Time) tea.Msg {
 		return TickMsg(t)
@@ -577,6 +900,13 @@ func (m *Model) SetProgram(program *tea.Program) {
 	m.program = program
 }
 
+// SetRestartHandler registers the callback restartSetup invokes when the
+// user presses "r" in the error state, so App can rebuild the real
+// subsystem tree instead of the UI just resetting its own state.
+func (m *Model) SetRestartHandler(fn func()) {
+	m.restartHandler = fn
+}
+
 // Scrolling methods
 func (m *Model) scrollUp() {
 	if m.scrollOffset > 0 {
@@ -585,7 +915,7 @@ func (m *Model) scrollUp() {
 }
 
 func (m *Model) scrollDown() {
-	maxScroll := len(m.logs) - 10 // Show 10 lines at a time
+	maxScroll := len(m.filteredLogs()) - 10 // Show 10 lines at a time
 	if m.scrollOffset < maxScroll {
 		m.scrollOffset++
 	}
@@ -596,7 +926,7 @@ func (m *Model) scrollToTop() {
 }
 
 func (m *Model) scrollToBottom() {
-	m.scrollOffset = len(m.logs) - 10
+	m.scrollOffset = len(m.filteredLogs()) - 10
 	if m.scrollOffset < 0 {
 		m.scrollOffset = 0
 	}
@@ -616,14 +946,51 @@ func (m *Model) restartSetup() {
 	m.setupComplete = false
 	m.serverRunning = false
 
-	// Reset steps
-	for i := range m.steps {
-		m.steps[i].Status = "pending"
-		m.steps[i].Details = ""
+	if m.resumedSnapshot != nil {
+		// Resuming a crashed prior run: keep the steps that already
+		// succeeded and the log tail, resetting to pending only the step
+		// that failed and anything after it. The real subsystem tree still
+		// rebuilds from "validate" underneath (Orchestrator has no
+		// partial-resume), but the status panel and logs stay visually
+		// continuous with what the user was looking at when it crashed,
+		// instead of flashing back to blank.
+		m.steps = m.resumedSnapshot.Steps
+		failed := false
+		for i := range m.steps {
+			if m.steps[i].Status == "error" {
+				failed = true
+			}
+			if failed {
+				m.steps[i].Status = "pending"
+				m.steps[i].Details = ""
+			}
+		}
+		m.resumedSnapshot = nil
+	} else {
+		for i := range m.steps {
+			m.steps[i].Status = "pending"
+			m.steps[i].Details = ""
+		}
+		m.logs = make([]LogEntry, 0)
 	}
 
-	// Clear logs
-	m.logs = make([]LogEntry, 0)
+	// Clear any active filter
+	m.filter = nil
+	m.filterActive = false
+	m.filterInput.Blur()
+	m.filterInput.SetValue("")
+
+	if m.restartHandler != nil {
+		m.restartHandler()
+	}
+}
+
+// discardSnapshot drops the detected crash snapshot and restarts as a
+// normal fresh run from validate, for when the user would rather not
+// resume from the failed step.
+func (m *Model) discardSnapshot() {
+	m.resumedSnapshot = nil
+	m.restartSetup()
 }
 
 func (m *Model) toggleHelp() {
@@ -0,0 +1,102 @@
+package dns
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	miekgdns "github.com/miekg/dns"
+)
+
+type cacheEntry struct {
+	key     string
+	msg     *miekgdns.Msg
+	expires time.Time
+}
+
+// answerCache is a small LRU cache of forwarded upstream answers, keyed by
+// question name+type, mirroring internal/dns's per-session answerCache.
+// Each entry expires according to its own answer TTL rather than a single
+// cache-wide duration, so popular names stay cached while stale records
+// age out on their own schedule.
+type answerCache struct {
+	mu       sync.Mutex
+	capacity int
+	items    map[string]*list.Element
+	order    *list.List
+}
+
+func newAnswerCache(capacity int) *answerCache {
+	return &answerCache{
+		capacity: capacity,
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+func (c *answerCache) get(key string) (*miekgdns.Msg, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := el.Value.(*cacheEntry)
+	if time.Now().After(entry.expires) {
+		c.order.Remove(el)
+		delete(c.items, key)
+		return nil, false
+	}
+
+	c.order.MoveToFront(el)
+	return entry.msg, true
+}
+
+func (c *answerCache) set(key string, msg *miekgdns.Msg, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if ttl <= 0 {
+		ttl = 5 * time.Second
+	}
+
+	if el, ok := c.items[key]; ok {
+		entry := el.Value.(*cacheEntry)
+		entry.msg = msg
+		entry.expires = time.Now().Add(ttl)
+		c.order.MoveToFront(el)
+		return
+	}
+
+	entry := &cacheEntry{key: key, msg: msg, expires: time.Now().Add(ttl)}
+	el := c.order.PushFront(entry)
+	c.items[key] = el
+
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		if oldest != nil {
+			c.order.Remove(oldest)
+			delete(c.items, oldest.Value.(*cacheEntry).key)
+		}
+	}
+}
+
+// minTTL returns the lowest TTL among msg's answer records, or 0 if it has
+// none (e.g. an NXDOMAIN), so callers can fall back to a short negative-
+// cache duration.
+func minTTL(msg *miekgdns.Msg) time.Duration {
+	var min uint32
+	for i, rr := range msg.Answer {
+		ttl := rr.Header().Ttl
+		if i == 0 || ttl < min {
+			min = ttl
+		}
+	}
+	return time.Duration(min) * time.Second
+}
+
+func cacheKey(q miekgdns.Question) string {
+	return q.Name + "|" + miekgdns.TypeToString[q.Qtype]
+}
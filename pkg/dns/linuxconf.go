@@ -0,0 +1,395 @@
+// Linux DNS steering. Unlike macOS, which has a single well-documented
+// per-TLD mechanism (/etc/resolver, handled directly by
+// internal/setup.addTLDMacOS), Linux distros manage /etc/resolv.conf in
+// several mutually incompatible ways. This file mirrors the approach
+// Tailscale's dns package takes: inspect who owns resolv.conf, then pick
+// the one OSConfigurator below that knows how to talk to that owner.
+package dns
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// OSConfigurator steers the host's DNS resolution so queries for tlds
+// reach our embedded Server at addr, ideally without disturbing
+// resolution of anything else. Linux's resolver-configuration files don't
+// support the custom ports macOS's /etc/resolver does, so every
+// implementation below uses only addr's host and assumes the daemon is
+// also reachable on the standard port 53 there.
+type OSConfigurator interface {
+	// SetDNS points tlds at addr. Calling it again with a new TLD list
+	// replaces the previous one.
+	SetDNS(tlds []string, addr string) error
+	// Revert undoes the most recent SetDNS, restoring whatever the host
+	// was doing before NSM touched it.
+	Revert() error
+	// SupportsSplitDNS reports whether this configurator can steer only
+	// the given TLDs (true) or must take over resolution entirely (false).
+	SupportsSplitDNS() bool
+}
+
+// resolvConfOwner identifies the subsystem managing /etc/resolv.conf.
+type resolvConfOwner int
+
+const (
+	ownerUnmanaged resolvConfOwner = iota
+	ownerSystemdResolved
+	ownerResolvconf
+	ownerOpenresolv
+	ownerNetworkManager
+)
+
+const resolvConfPath = "/etc/resolv.conf"
+
+// NewLinuxConfigurator inspects the host and returns the OSConfigurator
+// appropriate for it: systemd-resolved over D-Bus when it owns
+// resolv.conf, Debian resolvconf or openresolv when resolv.conf is their
+// generated symlink, the NetworkManager dnsmasq plugin when NM is driving
+// resolution with that plugin enabled, and a direct resolv.conf edit with
+// backup as the last resort.
+func NewLinuxConfigurator() OSConfigurator {
+	return newLinuxConfigurator(newResolvConfOwnerDetector().detect, systemBusReachable)
+}
+
+// newLinuxConfigurator is NewLinuxConfigurator with its owner-detection and
+// D-Bus-reachability probes taken as parameters - the seam the table-driven
+// selection-logic tests use to drive it with a fake filesystem and a fake
+// D-Bus ping instead of the real host.
+func newLinuxConfigurator(detect func() resolvConfOwner, dbusReachable func() bool) OSConfigurator {
+	switch detect() {
+	case ownerSystemdResolved:
+		if dbusReachable() {
+			return &systemdResolvedConfigurator{}
+		}
+		// resolved owns the file but its D-Bus service isn't reachable
+		// (e.g. running in a minimal container) - fall back to editing
+		// its stub file directly, same as the unmanaged case.
+		return &directConfigurator{}
+	case ownerResolvconf:
+		return &resolvconfConfigurator{updateCmd: "resolvconf", interfaceArgs: []string{"-a", "tun.inet"}, deleteArgs: []string{"-d", "tun.inet"}}
+	case ownerOpenresolv:
+		return &resolvconfConfigurator{updateCmd: "resolvconf", interfaceArgs: []string{"-a", "tun.inet", "-m", "0"}, deleteArgs: []string{"-d", "tun.inet"}}
+	case ownerNetworkManager:
+		return &networkManagerConfigurator{}
+	default:
+		return &directConfigurator{}
+	}
+}
+
+// systemBusReachable pings the system D-Bus to confirm systemd-resolved's
+// service is actually up, not just that it owns resolv.conf.
+func systemBusReachable() bool {
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// resolvConfOwnerDetector bundles the filesystem/command probes
+// detect needs, so tests can substitute fakes for a real /etc/resolv.conf
+// and $PATH without touching the host.
+type resolvConfOwnerDetector struct {
+	resolvConfPath        string
+	readlink              func(string) (string, error)
+	openFile              func(string) (io.ReadCloser, error)
+	commandAvailable      func(string) bool
+	openresolvInstalled   func() bool
+	networkManagerDnsmasq func() bool
+}
+
+func newResolvConfOwnerDetector() *resolvConfOwnerDetector {
+	return &resolvConfOwnerDetector{
+		resolvConfPath:        resolvConfPath,
+		readlink:              os.Readlink,
+		openFile:              func(path string) (io.ReadCloser, error) { return os.Open(path) },
+		commandAvailable:      isCommandAvailable,
+		openresolvInstalled:   openresolvInstalled,
+		networkManagerDnsmasq: hasNetworkManagerDnsmasq,
+	}
+}
+
+// detect follows resolvConfPath (it's a symlink under systemd-resolved,
+// resolvconf, and openresolv) and falls back to reading its "generated by"
+// comment when it's a regular file, matching the heuristic Tailscale's dns
+// package uses.
+func (d *resolvConfOwnerDetector) detect() resolvConfOwner {
+	if target, err := d.readlink(d.resolvConfPath); err == nil {
+		switch {
+		case strings.Contains(target, "systemd"):
+			return ownerSystemdResolved
+		case strings.Contains(target, "resolvconf/run") || strings.Contains(target, "/run/resolvconf"):
+			if d.commandAvailable("resolvconf") && d.openresolvInstalled() {
+				return ownerOpenresolv
+			}
+			return ownerResolvconf
+		}
+	}
+
+	if d.networkManagerDnsmasq() {
+		return ownerNetworkManager
+	}
+
+	if f, err := d.openFile(d.resolvConfPath); err == nil {
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() && scanner.Err() == nil {
+			line := strings.ToLower(scanner.Text())
+			if strings.Contains(line, "systemd-resolved") {
+				return ownerSystemdResolved
+			}
+			if strings.Contains(line, "networkmanager") {
+				return ownerNetworkManager
+			}
+		}
+	}
+
+	return ownerUnmanaged
+}
+
+// openresolvInstalled distinguishes openresolv from Debian's resolvconf:
+// both ship a /sbin/resolvconf, but only openresolv understands "-m"
+// (metric) and prints its own name in --version.
+func openresolvInstalled() bool {
+	out, err := exec.Command("resolvconf", "--version").Output()
+	return err == nil && strings.Contains(strings.ToLower(string(out)), "openresolv")
+}
+
+func hasNetworkManagerDnsmasq() bool {
+	_, err := os.Stat("/etc/NetworkManager/dnsmasq.d")
+	return err == nil && isCommandAvailable("nmcli")
+}
+
+func isCommandAvailable(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+func addrHost(addr string) string {
+	host := addr
+	if i := strings.LastIndex(addr, ":"); i >= 0 {
+		host = addr[:i]
+	}
+	return host
+}
+
+// systemdResolvedConfigurator steers DNS via org.freedesktop.resolve1,
+// routing only the configured TLDs to us with "~tld" domains so every
+// other lookup keeps going through the host's normal resolvers.
+type systemdResolvedConfigurator struct {
+	linkIndex int32
+}
+
+func (c *systemdResolvedConfigurator) SetDNS(tlds []string, addr string) error {
+	idx, err := defaultLinkIndex()
+	if err != nil {
+		return fmt.Errorf("find default network link: %w", err)
+	}
+	c.linkIndex = idx
+
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return fmt.Errorf("connect to system D-Bus: %w", err)
+	}
+	defer conn.Close()
+
+	obj := conn.Object("org.freedesktop.resolve1", "/org/freedesktop/resolve1")
+
+	host := addrHost(addr)
+	ipv4, err := parseIPv4(host)
+	if err != nil {
+		return fmt.Errorf("resolve addr for SetLinkDNS: %w", err)
+	}
+	dnsEntry := []struct {
+		Family  int32
+		Address []byte
+	}{{Family: 2, Address: ipv4}}
+	if err := obj.Call("org.freedesktop.resolve1.Manager.SetLinkDNS", 0, idx, dnsEntry).Err; err != nil {
+		return fmt.Errorf("SetLinkDNS: %w", err)
+	}
+
+	domains := make([]struct {
+		Domain      string
+		RoutingOnly bool
+	}, len(tlds))
+	for i, tld := range tlds {
+		domains[i] = struct {
+			Domain      string
+			RoutingOnly bool
+		}{Domain: tld, RoutingOnly: true}
+	}
+	if err := obj.Call("org.freedesktop.resolve1.Manager.SetLinkDomains", 0, idx, domains).Err; err != nil {
+		return fmt.Errorf("SetLinkDomains: %w", err)
+	}
+
+	return nil
+}
+
+func (c *systemdResolvedConfigurator) Revert() error {
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return fmt.Errorf("connect to system D-Bus: %w", err)
+	}
+	defer conn.Close()
+
+	obj := conn.Object("org.freedesktop.resolve1", "/org/freedesktop/resolve1")
+	if err := obj.Call("org.freedesktop.resolve1.Manager.RevertLink", 0, c.linkIndex).Err; err != nil {
+		return fmt.Errorf("RevertLink: %w", err)
+	}
+	return nil
+}
+
+func (c *systemdResolvedConfigurator) SupportsSplitDNS() bool { return true }
+
+func defaultLinkIndex() (int32, error) {
+	out, err := exec.Command("ip", "route", "show", "default").Output()
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Fields(string(out))
+	for i, f := range fields {
+		if f == "dev" && i+1 < len(fields) {
+			iface, err := net.InterfaceByName(fields[i+1])
+			if err != nil {
+				return 0, err
+			}
+			return int32(iface.Index), nil
+		}
+	}
+	return 0, fmt.Errorf("no default route found")
+}
+
+func parseIPv4(host string) ([]byte, error) {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil, fmt.Errorf("parse IPv4 address %q: invalid", host)
+	}
+	v4 := ip.To4()
+	if v4 == nil {
+		return nil, fmt.Errorf("parse IPv4 address %q: not an IPv4 address", host)
+	}
+	return v4, nil
+}
+
+// resolvconfConfigurator drives either Debian's resolvconf or openresolv
+// via their shared `resolvconf -a/-d` CLI; the only difference between
+// the two is openresolv's extra "-m 0" metric flag on registration, which
+// NewLinuxConfigurator bakes into interfaceArgs.
+type resolvconfConfigurator struct {
+	updateCmd     string
+	interfaceArgs []string
+	deleteArgs    []string
+}
+
+func (c *resolvconfConfigurator) SetDNS(tlds []string, addr string) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "nameserver %s\n", addrHost(addr))
+	for _, tld := range tlds {
+		fmt.Fprintf(&sb, "search %s\n", tld)
+	}
+
+	cmd := exec.Command(c.updateCmd, c.interfaceArgs...)
+	cmd.Stdin = strings.NewReader(sb.String())
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s %s: %w: %s", c.updateCmd, strings.Join(c.interfaceArgs, " "), err, out)
+	}
+	return nil
+}
+
+func (c *resolvconfConfigurator) Revert() error {
+	cmd := exec.Command(c.updateCmd, c.deleteArgs...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s %s: %w: %s", c.updateCmd, strings.Join(c.deleteArgs, " "), err, out)
+	}
+	return nil
+}
+
+func (c *resolvconfConfigurator) SupportsSplitDNS() bool { return false }
+
+// networkManagerConfigurator drops a config file into
+// /etc/NetworkManager/dnsmasq.d/ so NetworkManager's own dnsmasq
+// instance, rather than resolv.conf, steers the configured TLDs.
+type networkManagerConfigurator struct{}
+
+const nmDnsmasqConfPath = "/etc/NetworkManager/dnsmasq.d/nsm-tlds.conf"
+
+func (c *networkManagerConfigurator) SetDNS(tlds []string, addr string) error {
+	var sb strings.Builder
+	for _, tld := range tlds {
+		fmt.Fprintf(&sb, "server=/%s/%s\n", tld, addrHost(addr))
+	}
+
+	if err := os.MkdirAll(filepath.Dir(nmDnsmasqConfPath), 0o755); err != nil {
+		return fmt.Errorf("create %s: %w", filepath.Dir(nmDnsmasqConfPath), err)
+	}
+	if err := os.WriteFile(nmDnsmasqConfPath, []byte(sb.String()), 0o644); err != nil {
+		return fmt.Errorf("write %s: %w", nmDnsmasqConfPath, err)
+	}
+
+	if out, err := exec.Command("systemctl", "reload", "NetworkManager").CombinedOutput(); err != nil {
+		return fmt.Errorf("reload NetworkManager: %w: %s", err, out)
+	}
+	return nil
+}
+
+func (c *networkManagerConfigurator) Revert() error {
+	if err := os.Remove(nmDnsmasqConfPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove %s: %w", nmDnsmasqConfPath, err)
+	}
+	_ = exec.Command("systemctl", "reload", "NetworkManager").Run()
+	return nil
+}
+
+func (c *networkManagerConfigurator) SupportsSplitDNS() bool { return true }
+
+// directConfigurator is the fallback for unmanaged systems: it backs up
+// resolv.conf and prepends our nameserver. Since nothing else is steering
+// resolution, this takes over DNS entirely rather than split-routing just
+// the configured TLDs.
+type directConfigurator struct{}
+
+const resolvConfBackupPath = resolvConfPath + ".nsm-backup"
+
+func (c *directConfigurator) SetDNS(tlds []string, addr string) error {
+	original, err := os.ReadFile(resolvConfPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("read %s: %w", resolvConfPath, err)
+	}
+	if _, err := os.Stat(resolvConfBackupPath); os.IsNotExist(err) {
+		if err := os.WriteFile(resolvConfBackupPath, original, 0o644); err != nil {
+			return fmt.Errorf("back up %s: %w", resolvConfPath, err)
+		}
+	}
+
+	content := fmt.Sprintf("nameserver %s\n%s", addrHost(addr), original)
+	if err := os.WriteFile(resolvConfPath, []byte(content), 0o644); err != nil {
+		return fmt.Errorf("write %s: %w", resolvConfPath, err)
+	}
+	return nil
+}
+
+func (c *directConfigurator) Revert() error {
+	backup, err := os.ReadFile(resolvConfBackupPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("read %s: %w", resolvConfBackupPath, err)
+	}
+	if err := os.WriteFile(resolvConfPath, backup, 0o644); err != nil {
+		return fmt.Errorf("restore %s: %w", resolvConfPath, err)
+	}
+	return os.Remove(resolvConfBackupPath)
+}
+
+func (c *directConfigurator) SupportsSplitDNS() bool { return false }
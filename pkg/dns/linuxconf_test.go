@@ -0,0 +1,195 @@
+package dns
+
+import (
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+func fakeReadCloser(content string) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader(content)), nil
+}
+
+func TestResolvConfOwnerDetectorDetect(t *testing.T) {
+	errNotExist := errors.New("no such file or directory")
+
+	tests := []struct {
+		name                  string
+		readlink              func(string) (string, error)
+		openFile              func(string) (io.ReadCloser, error)
+		commandAvailable      func(string) bool
+		openresolvInstalled   bool
+		networkManagerDnsmasq bool
+		want                  resolvConfOwner
+	}{
+		{
+			name:     "systemd-resolved symlink",
+			readlink: func(string) (string, error) { return "/run/systemd/resolve/stub-resolv.conf", nil },
+			want:     ownerSystemdResolved,
+		},
+		{
+			name:                "debian resolvconf symlink",
+			readlink:            func(string) (string, error) { return "/run/resolvconf/resolv.conf", nil },
+			commandAvailable:    func(string) bool { return true },
+			openresolvInstalled: false,
+			want:                ownerResolvconf,
+		},
+		{
+			name:                "openresolv symlink",
+			readlink:            func(string) (string, error) { return "/run/resolvconf/resolv.conf", nil },
+			commandAvailable:    func(string) bool { return true },
+			openresolvInstalled: true,
+			want:                ownerOpenresolv,
+		},
+		{
+			name:             "resolvconf-style symlink but resolvconf command missing",
+			readlink:         func(string) (string, error) { return "/run/resolvconf/resolv.conf", nil },
+			commandAvailable: func(string) bool { return false },
+			want:             ownerResolvconf,
+		},
+		{
+			name:                  "NetworkManager dnsmasq plugin, no symlink",
+			readlink:              func(string) (string, error) { return "", errNotExist },
+			networkManagerDnsmasq: true,
+			want:                  ownerNetworkManager,
+		},
+		{
+			name:     "regular file generated by systemd-resolved",
+			readlink: func(string) (string, error) { return "", errNotExist },
+			openFile: func(string) (io.ReadCloser, error) {
+				return fakeReadCloser("# Generated by systemd-resolved\nnameserver 127.0.0.53\n")
+			},
+			want: ownerSystemdResolved,
+		},
+		{
+			name:     "regular file generated by NetworkManager",
+			readlink: func(string) (string, error) { return "", errNotExist },
+			openFile: func(string) (io.ReadCloser, error) {
+				return fakeReadCloser("# Generated by NetworkManager\nnameserver 192.168.1.1\n")
+			},
+			want: ownerNetworkManager,
+		},
+		{
+			name:     "unmanaged plain file",
+			readlink: func(string) (string, error) { return "", errNotExist },
+			openFile: func(string) (io.ReadCloser, error) {
+				return fakeReadCloser("nameserver 8.8.8.8\n")
+			},
+			want: ownerUnmanaged,
+		},
+		{
+			name:     "resolv.conf missing entirely",
+			readlink: func(string) (string, error) { return "", errNotExist },
+			openFile: func(string) (io.ReadCloser, error) { return nil, errNotExist },
+			want:     ownerUnmanaged,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &resolvConfOwnerDetector{
+				resolvConfPath:   "/etc/resolv.conf",
+				readlink:         tt.readlink,
+				openFile:         tt.openFile,
+				commandAvailable: tt.commandAvailable,
+				openresolvInstalled: func() bool {
+					return tt.openresolvInstalled
+				},
+				networkManagerDnsmasq: func() bool {
+					return tt.networkManagerDnsmasq
+				},
+			}
+			if d.openFile == nil {
+				d.openFile = func(string) (io.ReadCloser, error) { return nil, errNotExist }
+			}
+			if d.commandAvailable == nil {
+				d.commandAvailable = func(string) bool { return false }
+			}
+
+			if got := d.detect(); got != tt.want {
+				t.Errorf("detect() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewLinuxConfiguratorSelection(t *testing.T) {
+	tests := []struct {
+		name          string
+		owner         resolvConfOwner
+		dbusReachable bool
+		wantType      OSConfigurator
+	}{
+		{name: "systemd-resolved reachable", owner: ownerSystemdResolved, dbusReachable: true, wantType: &systemdResolvedConfigurator{}},
+		{name: "systemd-resolved unreachable falls back to direct", owner: ownerSystemdResolved, dbusReachable: false, wantType: &directConfigurator{}},
+		{name: "resolvconf", owner: ownerResolvconf, wantType: &resolvconfConfigurator{}},
+		{name: "openresolv", owner: ownerOpenresolv, wantType: &resolvconfConfigurator{}},
+		{name: "network manager", owner: ownerNetworkManager, wantType: &networkManagerConfigurator{}},
+		{name: "unmanaged", owner: ownerUnmanaged, wantType: &directConfigurator{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			detect := func() resolvConfOwner { return tt.owner }
+			dbusReachable := func() bool { return tt.dbusReachable }
+
+			got := newLinuxConfigurator(detect, dbusReachable)
+
+			gotType := reflectTypeName(got)
+			wantType := reflectTypeName(tt.wantType)
+			if gotType != wantType {
+				t.Errorf("newLinuxConfigurator() = %s, want %s", gotType, wantType)
+			}
+		})
+	}
+}
+
+func reflectTypeName(c OSConfigurator) string {
+	switch c.(type) {
+	case *systemdResolvedConfigurator:
+		return "systemdResolvedConfigurator"
+	case *resolvconfConfigurator:
+		return "resolvconfConfigurator"
+	case *networkManagerConfigurator:
+		return "networkManagerConfigurator"
+	case *directConfigurator:
+		return "directConfigurator"
+	default:
+		return "unknown"
+	}
+}
+
+func TestParseIPv4(t *testing.T) {
+	tests := []struct {
+		name    string
+		host    string
+		want    net.IP
+		wantErr bool
+	}{
+		{name: "valid dotted quad", host: "127.0.0.1", want: net.IPv4(127, 0, 0, 1).To4()},
+		{name: "valid dotted quad, other host", host: "192.168.1.1", want: net.IPv4(192, 168, 1, 1).To4()},
+		{name: "ipv6 literal rejected", host: "::1", wantErr: true},
+		{name: "garbage rejected", host: "not-an-ip", wantErr: true},
+		{name: "empty string rejected", host: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseIPv4(tt.host)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseIPv4(%q) error = nil, want error", tt.host)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseIPv4(%q) unexpected error: %v", tt.host, err)
+			}
+			if !net.IP(got).Equal(tt.want) {
+				t.Errorf("parseIPv4(%q) = %v, want %v", tt.host, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package dns
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// SystemUpstreams returns the host's configured DNS resolvers: parsed from
+// /etc/resolv.conf, or from `scutil --dns` on macOS (which reflects the
+// live resolver state even when /etc/resolv.conf is a symlink into
+// resolved's stub config). Falls back to DefaultUpstreams if neither can
+// be read.
+func SystemUpstreams() []string {
+	var upstreams []string
+	if runtime.GOOS == "darwin" {
+		upstreams = scutilUpstreams()
+	}
+	if len(upstreams) == 0 {
+		upstreams = resolvConfUpstreams("/etc/resolv.conf")
+	}
+	if len(upstreams) == 0 {
+		return append([]string(nil), DefaultUpstreams...)
+	}
+	return upstreams
+}
+
+func resolvConfUpstreams(path string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var upstreams []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "nameserver" {
+			upstreams = append(upstreams, net.JoinHostPort(fields[1], "53"))
+		}
+	}
+	return upstreams
+}
+
+func scutilUpstreams() []string {
+	out, err := exec.Command("scutil", "--dns").Output()
+	if err != nil {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var upstreams []string
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "nameserver[") {
+			continue
+		}
+
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		addr := net.JoinHostPort(strings.TrimSpace(parts[1]), "53")
+		if !seen[addr] {
+			seen[addr] = true
+			upstreams = append(upstreams, addr)
+		}
+	}
+	return upstreams
+}
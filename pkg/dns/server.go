@@ -0,0 +1,329 @@
+// Package dns is a minimal authoritative resolver for local development
+// TLDs. It backs the setup wizard's background DNS daemon (see
+// internal/setup's launchd/systemd management), replacing the external
+// dnsmasq process NSM used to install and configure: any A/AAAA query
+// under one of the daemon's TLDs answers 127.0.0.1/::1, and everything
+// else is forwarded to the host's real upstream resolvers. It is
+// intentionally independent of internal/dns's EmbeddedServer, which
+// answers for one project's domain for the lifetime of a single `nsm`
+// session rather than running as a standalone, always-on daemon.
+package dns
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	miekgdns "github.com/miekg/dns"
+)
+
+// cacheCapacity bounds the forwarder's in-memory answer cache (see
+// pkg/dns/cache.go); 1000 entries comfortably covers a single
+// development machine's worth of distinct upstream lookups.
+const cacheCapacity = 1000
+
+// DefaultAddr is where Server listens by default. It deliberately isn't
+// internal/dns's 127.0.0.1:5353 (the per-project embedded resolver that
+// only runs while `nsm` itself is running) or port 53 (usually taken by
+// the OS's own resolver), so the daemon can coexist with both.
+const DefaultAddr = "127.0.0.1:53535"
+
+// DefaultUpstreams is used when the host's real resolvers can't be
+// determined (see SystemUpstreams).
+var DefaultUpstreams = []string{"1.1.1.1:53", "8.8.8.8:53"}
+
+// Server answers A/AAAA queries for its configured TLDs with 127.0.0.1/::1,
+// and forwards everything else to Upstreams. Its TLD set is fixed at
+// construction (NewServer) for the life of the process; there's no conf
+// file to rewrite or pid to SIGHUP, since it runs in-process rather than
+// as a separate dnsmasq instance - just a fresh Server and restart to
+// change it.
+type Server struct {
+	mu        sync.RWMutex
+	tlds      map[string]bool
+	upstreams []string
+	client    *miekgdns.Client
+	cache     *answerCache
+	startedAt time.Time
+
+	queries     atomic.Uint64
+	cacheHits   atomic.Uint64
+	cacheMisses atomic.Uint64
+
+	udpServer *miekgdns.Server
+	tcpServer *miekgdns.Server
+}
+
+// NewServer builds a Server authoritative for tlds (bare, e.g. "dev", not
+// ".dev"). An empty upstreams list falls back to DefaultUpstreams.
+func NewServer(tlds []string, upstreams []string) *Server {
+	if len(upstreams) == 0 {
+		upstreams = DefaultUpstreams
+	}
+
+	set := make(map[string]bool, len(tlds))
+	for _, tld := range tlds {
+		set[strings.ToLower(tld)] = true
+	}
+
+	return &Server{
+		tlds:      set,
+		upstreams: upstreams,
+		client:    &miekgdns.Client{Timeout: 2 * time.Second},
+		cache:     newAnswerCache(cacheCapacity),
+		startedAt: time.Now(),
+	}
+}
+
+// Stats is a snapshot of Server's runtime counters, returned by Stats() and
+// printed by `nsm-setup dns-status`.
+type Stats struct {
+	QueriesPerSecond float64  `json:"queries_per_second"`
+	CacheHitRate     float64  `json:"cache_hit_rate"`
+	Upstreams        []string `json:"upstreams"`
+}
+
+// Stats reports the server's query rate and forwarder cache hit rate since
+// it started.
+func (s *Server) Stats() Stats {
+	s.mu.RLock()
+	upstreams := append([]string(nil), s.upstreams...)
+	s.mu.RUnlock()
+
+	elapsed := time.Since(s.startedAt).Seconds()
+	queries := float64(s.queries.Load())
+	hits := s.cacheHits.Load()
+	misses := s.cacheMisses.Load()
+
+	var qps, hitRate float64
+	if elapsed > 0 {
+		qps = queries / elapsed
+	}
+	if total := hits + misses; total > 0 {
+		hitRate = float64(hits) / float64(total)
+	}
+
+	return Stats{QueriesPerSecond: qps, CacheHitRate: hitRate, Upstreams: upstreams}
+}
+
+// Start listens on addr over both UDP and TCP and begins serving requests
+// in the background. An empty addr uses DefaultAddr.
+func (s *Server) Start(addr string) error {
+	if addr == "" {
+		addr = DefaultAddr
+	}
+
+	mux := miekgdns.NewServeMux()
+	mux.HandleFunc(".", s.handleQuery)
+
+	s.udpServer = &miekgdns.Server{Addr: addr, Net: "udp", Handler: mux}
+	s.tcpServer = &miekgdns.Server{Addr: addr, Net: "tcp", Handler: mux}
+
+	errCh := make(chan error, 2)
+	go func() { errCh <- s.udpServer.ListenAndServe() }()
+	go func() { errCh <- s.tcpServer.ListenAndServe() }()
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			return fmt.Errorf("listen on %s: %w", addr, err)
+		}
+	case <-time.After(200 * time.Millisecond):
+		// Neither listener reported a startup failure within the grace
+		// window, so assume both are up.
+	}
+
+	return nil
+}
+
+// Stop shuts down both listeners, waiting for in-flight queries to finish.
+func (s *Server) Stop() error {
+	var errs []string
+
+	if s.udpServer != nil {
+		if err := s.udpServer.Shutdown(); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if s.tcpServer != nil {
+		if err := s.tcpServer.Shutdown(); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("shutdown dns server: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (s *Server) ownsTLD(name string) bool {
+	labels := strings.Split(strings.ToLower(strings.TrimSuffix(name, ".")), ".")
+	tld := labels[len(labels)-1]
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tlds[tld]
+}
+
+// statusQueryName is queried CHAOS-class, TXT-type to retrieve Stats
+// without a separate control channel - the same convention BIND uses for
+// "version.bind"/"hostname.bind".
+const statusQueryName = "status.bind."
+
+func (s *Server) handleQuery(w miekgdns.ResponseWriter, r *miekgdns.Msg) {
+	reply := new(miekgdns.Msg)
+	reply.SetReply(r)
+
+	if len(r.Question) == 0 {
+		w.WriteMsg(reply)
+		return
+	}
+
+	q := r.Question[0]
+	s.queries.Add(1)
+
+	if q.Qclass == miekgdns.ClassCHAOS && q.Qtype == miekgdns.TypeTXT && strings.EqualFold(q.Name, statusQueryName) {
+		reply.Answer = append(reply.Answer, s.statusAnswer(q))
+		w.WriteMsg(reply)
+		return
+	}
+
+	if q.Qtype == miekgdns.TypeA || q.Qtype == miekgdns.TypeAAAA {
+		if s.ownsTLD(q.Name) {
+			reply.Authoritative = true
+			if rr := localAnswer(q); rr != nil {
+				reply.Answer = append(reply.Answer, rr)
+			}
+			w.WriteMsg(reply)
+			return
+		}
+	}
+
+	forwarded, err := s.forward(r)
+	if err != nil {
+		reply.Rcode = miekgdns.RcodeServerFailure
+		w.WriteMsg(reply)
+		return
+	}
+	w.WriteMsg(forwarded)
+}
+
+// QueryStats asks the daemon listening at addr for its Stats via the
+// status.bind CHAOS TXT query, without requiring a client to link
+// against Server itself - used by `nsm-setup dns status`.
+func QueryStats(addr string) (Stats, error) {
+	client := &miekgdns.Client{Timeout: 2 * time.Second}
+	msg := new(miekgdns.Msg)
+	msg.SetQuestion(statusQueryName, miekgdns.TypeTXT)
+	msg.Question[0].Qclass = miekgdns.ClassCHAOS
+
+	reply, _, err := client.Exchange(msg, addr)
+	if err != nil {
+		return Stats{}, fmt.Errorf("query dns daemon status at %s: %w", addr, err)
+	}
+	if len(reply.Answer) == 0 {
+		return Stats{}, fmt.Errorf("dns daemon at %s returned no status", addr)
+	}
+
+	txt, ok := reply.Answer[0].(*miekgdns.TXT)
+	if !ok {
+		return Stats{}, fmt.Errorf("dns daemon at %s returned unexpected status record", addr)
+	}
+
+	var stats Stats
+	for _, field := range txt.Txt {
+		key, value, found := strings.Cut(field, "=")
+		if !found {
+			continue
+		}
+		switch key {
+		case "queries_per_second":
+			fmt.Sscanf(value, "%f", &stats.QueriesPerSecond)
+		case "cache_hit_rate":
+			fmt.Sscanf(value, "%f", &stats.CacheHitRate)
+		case "upstreams":
+			if value != "" {
+				stats.Upstreams = strings.Split(value, ",")
+			}
+		}
+	}
+	return stats, nil
+}
+
+// statusAnswer builds a CHAOS TXT record reporting Stats, one string per
+// field, for queryDNSStatus to parse.
+func (s *Server) statusAnswer(q miekgdns.Question) miekgdns.RR {
+	stats := s.Stats()
+	return &miekgdns.TXT{
+		Hdr: miekgdns.RR_Header{Name: q.Name, Rrtype: miekgdns.TypeTXT, Class: miekgdns.ClassCHAOS, Ttl: 0},
+		Txt: []string{
+			fmt.Sprintf("queries_per_second=%.2f", stats.QueriesPerSecond),
+			fmt.Sprintf("cache_hit_rate=%.2f", stats.CacheHitRate),
+			fmt.Sprintf("upstreams=%s", strings.Join(stats.Upstreams, ",")),
+		},
+	}
+}
+
+// localAnswer builds the RR for a TLD wildcard match, answering with the
+// loopback address (127.0.0.1/::1).
+func localAnswer(q miekgdns.Question) miekgdns.RR {
+	switch q.Qtype {
+	case miekgdns.TypeA:
+		addr := net.ParseIP("127.0.0.1").To4()
+		return &miekgdns.A{
+			Hdr: miekgdns.RR_Header{Name: q.Name, Rrtype: miekgdns.TypeA, Class: miekgdns.ClassINET, Ttl: 5},
+			A:   addr,
+		}
+	case miekgdns.TypeAAAA:
+		addr := net.ParseIP("::1")
+		return &miekgdns.AAAA{
+			Hdr:  miekgdns.RR_Header{Name: q.Name, Rrtype: miekgdns.TypeAAAA, Class: miekgdns.ClassINET, Ttl: 5},
+			AAAA: addr,
+		}
+	default:
+		return nil
+	}
+}
+
+// forward sends r to the configured upstreams in order, caching the first
+// successful reply by its own answer TTL. r is passed through unmodified,
+// including any EDNS0 OPT record (client-subnet, DO bit) the original
+// client attached, so upstream-signed responses aren't mangled; caching by
+// subnet isn't necessary since this daemon only ever serves one client
+// (127.0.0.1).
+func (s *Server) forward(r *miekgdns.Msg) (*miekgdns.Msg, error) {
+	var key string
+	if len(r.Question) == 1 {
+		key = cacheKey(r.Question[0])
+		if cached, ok := s.cache.get(key); ok {
+			s.cacheHits.Add(1)
+			reply := cached.Copy()
+			reply.Id = r.Id
+			return reply, nil
+		}
+		s.cacheMisses.Add(1)
+	}
+
+	s.mu.RLock()
+	upstreams := s.upstreams
+	s.mu.RUnlock()
+
+	var lastErr error
+	for _, upstream := range upstreams {
+		reply, _, err := s.client.Exchange(r, upstream)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if key != "" && reply.Rcode == miekgdns.RcodeSuccess {
+			s.cache.set(key, reply.Copy(), minTTL(reply))
+		}
+		return reply, nil
+	}
+
+	return nil, fmt.Errorf("all upstreams failed, last error: %w", lastErr)
+}
@@ -0,0 +1,144 @@
+// Package health tracks the live state of NSM subsystems (DNS, certs,
+// proxy, ...) behind a single Tracker, instead of each subsystem logging
+// warnings on its own. Callers Set their own state as it changes and
+// Subscribe to be notified, so a status command or future HTTP endpoint has
+// one place to ask "is everything healthy" without reaching into every
+// subsystem's internals.
+package health
+
+import (
+	"sync"
+	"time"
+)
+
+// State is the health of a single subsystem.
+type State int
+
+const (
+	StateUnknown State = iota
+	StateOK
+	StateWarning
+	StateError
+)
+
+func (s State) String() string {
+	switch s {
+	case StateOK:
+		return "ok"
+	case StateWarning:
+		return "warning"
+	case StateError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// Status is one subsystem's most recently reported state.
+type Status struct {
+	Subsystem string
+	State     State
+	Err       error
+	UpdatedAt time.Time
+}
+
+// Tracker is a concurrency-safe registry of subsystem health. The zero
+// value is not usable; construct with NewTracker.
+type Tracker struct {
+	mu          sync.RWMutex
+	statuses    map[string]Status
+	subscribers map[int]chan Status
+	nextSubID   int
+}
+
+// NewTracker returns an empty Tracker ready to use.
+func NewTracker() *Tracker {
+	return &Tracker{
+		statuses:    make(map[string]Status),
+		subscribers: make(map[int]chan Status),
+	}
+}
+
+// Set records subsystem's current state and notifies subscribers. err is
+// only meaningful for StateWarning/StateError and is otherwise ignored.
+func (t *Tracker) Set(subsystem string, state State, err error) {
+	status := Status{
+		Subsystem: subsystem,
+		State:     state,
+		Err:       err,
+		UpdatedAt: time.Now(),
+	}
+
+	t.mu.Lock()
+	t.statuses[subsystem] = status
+	subs := make([]chan Status, 0, len(t.subscribers))
+	for _, ch := range t.subscribers {
+		subs = append(subs, ch)
+	}
+	t.mu.Unlock()
+
+	for _, ch := range subs {
+		select {
+		case ch <- status:
+		default:
+			// Slow subscriber; drop rather than block Set.
+		}
+	}
+}
+
+// Get returns the most recently reported status for subsystem.
+func (t *Tracker) Get(subsystem string) (Status, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	status, ok := t.statuses[subsystem]
+	return status, ok
+}
+
+// All returns every tracked subsystem's current status, in no particular
+// order.
+func (t *Tracker) All() []Status {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	all := make([]Status, 0, len(t.statuses))
+	for _, status := range t.statuses {
+		all = append(all, status)
+	}
+	return all
+}
+
+// Overall reduces every tracked subsystem down to the single worst state,
+// so a caller that only cares about "is anything wrong" doesn't have to
+// walk All() itself. An empty Tracker reports StateUnknown.
+func (t *Tracker) Overall() Status {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	worst := Status{State: StateUnknown}
+	for _, status := range t.statuses {
+		if status.State > worst.State || worst.Subsystem == "" {
+			worst = status
+		}
+	}
+	return worst
+}
+
+// Subscribe returns a channel that receives every subsequent Set call, and
+// an unsubscribe function the caller must invoke when done listening.
+func (t *Tracker) Subscribe() (<-chan Status, func()) {
+	t.mu.Lock()
+	id := t.nextSubID
+	t.nextSubID++
+	ch := make(chan Status, 16)
+	t.subscribers[id] = ch
+	t.mu.Unlock()
+
+	unsubscribe := func() {
+		t.mu.Lock()
+		delete(t.subscribers, id)
+		t.mu.Unlock()
+		close(ch)
+	}
+
+	return ch, unsubscribe
+}
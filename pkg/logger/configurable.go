@@ -0,0 +1,54 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// Configurable is implemented by components that can describe their own
+// configuration for structured logging, modeled on blocky's configuration
+// rework. dns.Resolver, the embedded DNS server, and the logger's own
+// Config all implement it.
+type Configurable interface {
+	// IsEnabled reports whether this component is active. Disabled
+	// components are skipped by LogStructuredConfig.
+	IsEnabled() bool
+	// LogConfig emits one structured log line per configuration field
+	// using l.
+	LogConfig(l *ContextLogger)
+}
+
+// Composite is implemented by a Configurable that is itself made up of
+// other Configurable components (e.g. dns.Resolver wrapping its embedded
+// DNS server), so LogStructuredConfig can walk into them.
+type Composite interface {
+	Components() []Configurable
+}
+
+// LogStructuredConfig logs root's configuration, one field per line at
+// info level, and recurses into any nested Configurable components root
+// exposes via Composite. It replaces the old LogConfig(cfg interface{}),
+// which just dumped cfg as a single opaque blob via zerolog's Interface().
+//
+// A full dump of the component is still emitted, but only at trace level,
+// for the rare case the field-by-field summary isn't enough to debug
+// something.
+func LogStructuredConfig(root Configurable) {
+	if root == nil || !root.IsEnabled() {
+		return
+	}
+
+	name := fmt.Sprintf("%T", root)
+	root.LogConfig(Component(name))
+
+	if globalLogger.Enabled(context.Background(), slog.LevelDebug) {
+		globalLogger.Debug("Verbose configuration dump", "component", name, "config", root)
+	}
+
+	if composite, ok := root.(Composite); ok {
+		for _, child := range composite.Components() {
+			LogStructuredConfig(child)
+		}
+	}
+}
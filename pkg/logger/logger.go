@@ -1,18 +1,23 @@
 package logger
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
-	"path/filepath"
 	"time"
-
-	"github.com/rs/zerolog"
-	"github.com/rs/zerolog/log"
-	"gopkg.in/natefinch/lumberjack.v2"
 )
 
-var globalLogger zerolog.Logger
+// globalLogger is the root *slog.Logger every package-level function and
+// ContextLogger delegates to. It's rebuilt by InitWithConfig from a
+// newHandler fan-out (pretty console + rotating JSON file).
+var globalLogger *slog.Logger
+
+// activeConfig is the Config InitWithConfig last applied, kept around so
+// CurrentConfig can hand the logger itself to LogStructuredConfig as a
+// Configurable alongside other subsystems.
+var activeConfig Config
 
 type Config struct {
 	Level      string
@@ -24,6 +29,26 @@ type Config struct {
 	MaxBackups int
 }
 
+// IsEnabled always returns true: the logger is always active once Init or
+// InitWithConfig has run.
+func (c Config) IsEnabled() bool {
+	return true
+}
+
+// LogConfig implements Configurable, logging one line per setting.
+func (c Config) LogConfig(l *ContextLogger) {
+	l.Info("Logger level", "level", c.Level)
+	l.Info("Logger console output", "console", c.Console, "pretty", c.Pretty)
+	l.Info("Logger file sink", "file", c.File)
+	l.Info("Logger rotation", "max_size_mb", c.MaxSize, "max_age_days", c.MaxAge, "max_backups", c.MaxBackups)
+}
+
+// CurrentConfig returns the logger's own active configuration as a
+// Configurable, so it can be passed to LogStructuredConfig.
+func CurrentConfig() Configurable {
+	return activeConfig
+}
+
 func Init(debug bool) {
 	cfg := Config{
 		Level:      "info",
@@ -42,209 +67,86 @@ func Init(debug bool) {
 }
 
 func InitWithConfig(cfg Config) {
-	// Parse log level
-	level, err := zerolog.ParseLevel(cfg.Level)
-	if err != nil {
-		level = zerolog.InfoLevel
-	}
-	zerolog.SetGlobalLevel(level)
-
-	var writers []io.Writer
-
-	// Console writer
-	if cfg.Console {
-		console := zerolog.ConsoleWriter{
-			Out:        os.Stdout,
-			TimeFormat: "15:04:05",
-			NoColor:    !cfg.Pretty,
-			FormatLevel: func(i interface{}) string {
-				if !cfg.Pretty {
-					return fmt.Sprintf("[%s]", i)
-				}
-
-				s, _ := i.(string)
-				switch s {
-				case "trace":
-					return "\033[35m🔍\033[0m"
-				case "debug":
-					return "\033[36m🐛\033[0m"
-				case "info":
-					return "\033[32mℹ️\033[0m"
-				case "warn":
-					return "\033[33m⚠️\033[0m"
-				case "error":
-					return "\033[31m❌\033[0m"
-				case "fatal":
-					return "\033[91m💀\033[0m"
-				case "panic":
-					return "\033[91m🚨\033[0m"
-				default:
-					return "\033[37m📝\033[0m"
-				}
-			},
-			FormatMessage: func(i interface{}) string {
-				return fmt.Sprintf("%s", i)
-			},
-			FormatFieldName: func(i interface{}) string {
-				return fmt.Sprintf("\033[36m%s\033[0m=", i)
-			},
-			FormatFieldValue: func(i interface{}) string {
-				return fmt.Sprintf("\033[37m%s\033[0m", i)
-			},
-		}
-		writers = append(writers, console)
-	}
-
-	// File writer
-	if cfg.File != "" {
-		// Ensure log directory exists
-		if err := os.MkdirAll(filepath.Dir(cfg.File), 0o755); err != nil {
-			fmt.Printf("Failed to create log directory: %v\n", err)
-		} else {
-			fileWriter := &lumberjack.Logger{
-				Filename:   cfg.File,
-				MaxSize:    cfg.MaxSize,
-				MaxBackups: cfg.MaxBackups,
-				MaxAge:     cfg.MaxAge,
-				Compress:   true,
-			}
-			writers = append(writers, fileWriter)
-		}
-	}
-
-	// Create multi-writer
-	var writer io.Writer
-	if len(writers) == 0 {
-		// Fallback to stdout if no writers configured
-		writer = os.Stdout
-	} else if len(writers) == 1 {
-		writer = writers[0]
-	} else {
-		writer = io.MultiWriter(writers...)
-	}
-
-	// Create logger
-	globalLogger = zerolog.New(writer).With().
-		Timestamp().
-		Caller().
-		Logger()
-
-	// Set global logger
-	log.Logger = globalLogger
+	globalLogger = slog.New(newHandler(cfg))
+	activeConfig = cfg
 }
 
 // Convenience functions
 func Debug(msg string, keysAndValues ...interface{}) {
-	event := globalLogger.Debug()
-	addFields(event, keysAndValues...)
-	event.Msg(msg)
+	globalLogger.Debug(msg, keysAndValues...)
 }
 
 func Info(msg string, keysAndValues ...interface{}) {
-	event := globalLogger.Info()
-	addFields(event, keysAndValues...)
-	event.Msg(msg)
+	globalLogger.Info(msg, keysAndValues...)
 }
 
 func Warn(msg string, keysAndValues ...interface{}) {
-	event := globalLogger.Warn()
-	addFields(event, keysAndValues...)
-	event.Msg(msg)
+	globalLogger.Warn(msg, keysAndValues...)
 }
 
 func Error(msg string, keysAndValues ...interface{}) {
-	event := globalLogger.Error()
-	addFields(event, keysAndValues...)
-	event.Msg(msg)
+	globalLogger.Error(msg, keysAndValues...)
 }
 
 func Fatal(msg string, keysAndValues ...interface{}) {
-	event := globalLogger.Fatal()
-	addFields(event, keysAndValues...)
-	event.Msg(msg)
+	globalLogger.Error(msg, keysAndValues...)
+	os.Exit(1)
 }
 
 func Panic(msg string, keysAndValues ...interface{}) {
-	event := globalLogger.Panic()
-	addFields(event, keysAndValues...)
-	event.Msg(msg)
-}
-
-func addFields(event *zerolog.Event, keysAndValues ...interface{}) {
-	for i := 0; i < len(keysAndValues); i += 2 {
-		if i+1 < len(keysAndValues) {
-			key, ok := keysAndValues[i].(string)
-			if ok {
-				value := keysAndValues[i+1]
-				event.Interface(key, value)
-			}
-		}
-	}
+	globalLogger.Error(msg, keysAndValues...)
+	panic(msg)
+}
+
+// Attrs returns a *slog.Logger carrying keysAndValues as structured
+// attributes, for callers that want to thread request-scoped context (e.g.
+// "port", "proj") through a call chain instead of repeating it on every log
+// call. It's the slog-native counterpart to WithContext/WithFields.
+func Attrs(keysAndValues ...interface{}) *slog.Logger {
+	return globalLogger.With(keysAndValues...)
 }
 
 // Context-aware logging
 type ContextLogger struct {
-	logger zerolog.Logger
-	fields map[string]interface{}
+	logger *slog.Logger
 }
 
 func WithContext(fields map[string]interface{}) *ContextLogger {
-	return &ContextLogger{
-		logger: globalLogger,
-		fields: fields,
+	args := make([]interface{}, 0, len(fields)*2)
+	for k, v := range fields {
+		args = append(args, k, v)
 	}
+	return &ContextLogger{logger: globalLogger.With(args...)}
 }
 
 func (cl *ContextLogger) Debug(msg string, keysAndValues ...interface{}) {
-	event := cl.logger.Debug()
-	cl.addContextFields(event)
-	addFields(event, keysAndValues...)
-	event.Msg(msg)
+	cl.logger.Debug(msg, keysAndValues...)
 }
 
 func (cl *ContextLogger) Info(msg string, keysAndValues ...interface{}) {
-	event := cl.logger.Info()
-	cl.addContextFields(event)
-	addFields(event, keysAndValues...)
-	event.Msg(msg)
+	cl.logger.Info(msg, keysAndValues...)
 }
 
 func (cl *ContextLogger) Warn(msg string, keysAndValues ...interface{}) {
-	event := cl.logger.Warn()
-	cl.addContextFields(event)
-	addFields(event, keysAndValues...)
-	event.Msg(msg)
+	cl.logger.Warn(msg, keysAndValues...)
 }
 
 func (cl *ContextLogger) Error(msg string, keysAndValues ...interface{}) {
-	event := cl.logger.Error()
-	cl.addContextFields(event)
-	addFields(event, keysAndValues...)
-	event.Msg(msg)
+	cl.logger.Error(msg, keysAndValues...)
 }
 
 func (cl *ContextLogger) Fatal(msg string, keysAndValues ...interface{}) {
-	event := cl.logger.Fatal()
-	cl.addContextFields(event)
-	addFields(event, keysAndValues...)
-	event.Msg(msg)
+	cl.logger.Error(msg, keysAndValues...)
+	os.Exit(1)
 }
 
 func (cl *ContextLogger) Panic(msg string, keysAndValues ...interface{}) {
-	event := cl.logger.Panic()
-	cl.addContextFields(event)
-	addFields(event, keysAndValues...)
-	event.Msg(msg)
-}
-
-func (cl *ContextLogger) addContextFields(event *zerolog.Event) {
-	for key, value := range cl.fields {
-		event.Interface(key, value)
-	}
+	cl.logger.Error(msg, keysAndValues...)
+	panic(msg)
 }
 
 // Logger instance methods for when you need the logger directly
-func GetLogger() zerolog.Logger {
+func GetLogger() *slog.Logger {
 	return globalLogger
 }
 
@@ -254,136 +156,103 @@ func WithFields(fields map[string]interface{}) *ContextLogger {
 
 // Structured logging helpers
 func LogError(err error, msg string, keysAndValues ...interface{}) {
-	event := globalLogger.Error().Err(err)
-	addFields(event, keysAndValues...)
-	event.Msg(msg)
+	globalLogger.Error(msg, append(keysAndValues, "error", err)...)
 }
 
 func LogErrorWithStack(err error, msg string, keysAndValues ...interface{}) {
-	event := globalLogger.Error().Err(err).Stack()
-	addFields(event, keysAndValues...)
-	event.Msg(msg)
+	globalLogger.Error(msg, append(keysAndValues, "error", err, "stack", true)...)
 }
 
 // Performance logging
 func LogDuration(name string, start time.Time, keysAndValues ...interface{}) {
 	duration := time.Since(start)
-	event := globalLogger.Info().
-		Str("operation", name).
-		Dur("duration", duration)
-	addFields(event, keysAndValues...)
-	event.Msg("Operation completed")
+	args := append([]interface{}{"operation", name, "duration", duration}, keysAndValues...)
+	globalLogger.Info("Operation completed", args...)
 }
 
 // HTTP request logging
 func LogHTTPRequest(method, path string, statusCode int, duration time.Duration, keysAndValues ...interface{}) {
-	var event *zerolog.Event
+	args := append([]interface{}{"method", method, "path", path, "status", statusCode, "duration", duration}, keysAndValues...)
 
 	switch {
 	case statusCode >= 500:
-		event = globalLogger.Error()
+		globalLogger.Error("HTTP request", args...)
 	case statusCode >= 400:
-		event = globalLogger.Warn()
+		globalLogger.Warn("HTTP request", args...)
 	default:
-		event = globalLogger.Info()
+		globalLogger.Info("HTTP request", args...)
 	}
-
-	event = event.
-		Str("method", method).
-		Str("path", path).
-		Int("status", statusCode).
-		Dur("duration", duration)
-
-	addFields(event, keysAndValues...)
-	event.Msg("HTTP request")
-}
-
-// Configuration logging
-func LogConfig(cfg interface{}, keysAndValues ...interface{}) {
-	event := globalLogger.Info().Interface("config", cfg)
-	addFields(event, keysAndValues...)
-	event.Msg("Configuration loaded")
 }
 
 // System event logging
 func LogSystemEvent(event string, keysAndValues ...interface{}) {
-	logEvent := globalLogger.Info().Str("event", event)
-	addFields(logEvent, keysAndValues...)
-	logEvent.Msg("System event")
+	globalLogger.Info("System event", append([]interface{}{"event", event}, keysAndValues...)...)
 }
 
 // Security logging
 func LogSecurityEvent(event string, severity string, keysAndValues ...interface{}) {
-	var logEvent *zerolog.Event
+	args := append([]interface{}{"security_event", event, "severity", severity}, keysAndValues...)
 
 	switch severity {
 	case "critical":
-		logEvent = globalLogger.Error()
+		globalLogger.Error("Security event", args...)
 	case "high":
-		logEvent = globalLogger.Warn()
+		globalLogger.Warn("Security event", args...)
 	default:
-		logEvent = globalLogger.Info()
+		globalLogger.Info("Security event", args...)
 	}
-
-	logEvent = logEvent.
-		Str("security_event", event).
-		Str("severity", severity)
-
-	addFields(logEvent, keysAndValues...)
-	logEvent.Msg("Security event")
 }
 
 // Development helpers
 func DevDebug(msg string, keysAndValues ...interface{}) {
-	if globalLogger.GetLevel() <= zerolog.DebugLevel {
-		event := globalLogger.Debug().Str("dev", "true")
-		addFields(event, keysAndValues...)
-		event.Msg(msg)
+	if globalLogger.Enabled(context.Background(), slog.LevelDebug) {
+		globalLogger.Debug(msg, append(keysAndValues, "dev", "true")...)
 	}
 }
 
 func DevInfo(msg string, keysAndValues ...interface{}) {
-	event := globalLogger.Info().Str("dev", "true")
-	addFields(event, keysAndValues...)
-	event.Msg(msg)
+	globalLogger.Info(msg, append(keysAndValues, "dev", "true")...)
 }
 
 // File rotation helper
 func RotateLogFile() error {
-	// The lumberjack library handles this automatically, but this provides manual control
-	// In a real implementation, you'd need to keep a reference to the lumberjack.Logger
+	// lumberjack handles this automatically on size/age thresholds; this
+	// provides manual control for callers (e.g. a "rotate logs now" command).
+	if fileRotator != nil {
+		return fileRotator.Rotate()
+	}
 	return nil
 }
 
-// Cleanup function - simplified since zerolog.Logger doesn't need explicit closing
+// Cleanup function
 func Close() error {
-	// zerolog doesn't require explicit cleanup, but we can flush any pending writes
+	if fileRotator != nil {
+		return fileRotator.Close()
+	}
 	return nil
 }
 
 // Test helpers
 func SetTestMode() {
-	globalLogger = zerolog.New(io.Discard).Level(zerolog.Disabled)
-	log.Logger = globalLogger
+	globalLogger = slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelError + 100}))
 }
 
 func ResetLogger() {
-	globalLogger = zerolog.New(os.Stdout).With().Timestamp().Logger()
-	log.Logger = globalLogger
+	globalLogger = slog.New(slog.NewTextHandler(os.Stdout, nil))
 }
 
 // Additional utility functions
 func SetLevel(level string) error {
-	lvl, err := zerolog.ParseLevel(level)
+	lvl, err := parseLevel(level)
 	if err != nil {
 		return fmt.Errorf("invalid log level: %w", err)
 	}
-	zerolog.SetGlobalLevel(lvl)
+	levelVar.Set(lvl)
 	return nil
 }
 
 func GetLevel() string {
-	return globalLogger.GetLevel().String()
+	return levelVar.Level().String()
 }
 
 // Component logger for better organization
@@ -425,36 +294,27 @@ func WithNetwork(host string, port int) *ContextLogger {
 
 // Error with context
 func ErrorWithContext(err error, context map[string]interface{}, msg string) {
-	event := globalLogger.Error().Err(err)
-	for key, value := range context {
-		event.Interface(key, value)
+	args := make([]interface{}, 0, len(context)*2+2)
+	args = append(args, "error", err)
+	for k, v := range context {
+		args = append(args, k, v)
 	}
-	event.Msg(msg)
+	globalLogger.Error(msg, args...)
 }
 
 // Panic recovery logging
 func LogPanicRecovery(recovered interface{}, keysAndValues ...interface{}) {
-	event := globalLogger.Error().
-		Interface("panic", recovered).
-		Stack()
-	addFields(event, keysAndValues...)
-	event.Msg("Panic recovered")
+	args := append([]interface{}{"panic", recovered}, keysAndValues...)
+	globalLogger.Error("Panic recovered", args...)
 }
 
 // Startup/shutdown logging
 func LogStartup(service string, version string, keysAndValues ...interface{}) {
-	event := globalLogger.Info().
-		Str("service", service).
-		Str("version", version).
-		Str("event", "startup")
-	addFields(event, keysAndValues...)
-	event.Msg("Service starting")
+	args := append([]interface{}{"service", service, "version", version, "event", "startup"}, keysAndValues...)
+	globalLogger.Info("Service starting", args...)
 }
 
 func LogShutdown(service string, keysAndValues ...interface{}) {
-	event := globalLogger.Info().
-		Str("service", service).
-		Str("event", "shutdown")
-	addFields(event, keysAndValues...)
-	event.Msg("Service shutting down")
+	args := append([]interface{}{"service", service, "event", "shutdown"}, keysAndValues...)
+	globalLogger.Info("Service shutting down", args...)
 }
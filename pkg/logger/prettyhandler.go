@@ -0,0 +1,92 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Color palette for TTY output, mirroring internal/ui's style (same hex
+// values) without importing it - pkg/logger must not depend on an internal/
+// package.
+var (
+	prettyTimeColor  = lipgloss.NewStyle().Foreground(lipgloss.Color("#6B7280")) // Gray
+	prettyDebugColor = lipgloss.NewStyle().Foreground(lipgloss.Color("#06B6D4")) // Cyan
+	prettyInfoColor  = lipgloss.NewStyle().Foreground(lipgloss.Color("#10B981")) // Emerald
+	prettyWarnColor  = lipgloss.NewStyle().Foreground(lipgloss.Color("#F59E0B")) // Amber
+	prettyErrorColor = lipgloss.NewStyle().Foreground(lipgloss.Color("#EF4444")) // Red
+	prettyKeyColor   = lipgloss.NewStyle().Foreground(lipgloss.Color("#6366F1")) // Indigo
+)
+
+// prettyHandler is a slog.Handler that renders records as a single
+// human-readable, lipgloss-colorized line, for interactive terminal use.
+// The JSON handler built alongside it in newHandler is what ~/.nsm/logs
+// actually persists.
+type prettyHandler struct {
+	out   io.Writer
+	level slog.Leveler
+	mu    *sync.Mutex
+	attrs []slog.Attr
+}
+
+func newPrettyHandler(out io.Writer, level slog.Leveler) *prettyHandler {
+	return &prettyHandler{out: out, level: level, mu: &sync.Mutex{}}
+}
+
+func (h *prettyHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *prettyHandler) Handle(_ context.Context, record slog.Record) error {
+	levelStyle, icon := prettyInfoColor, "ℹ️"
+	switch {
+	case record.Level < slog.LevelInfo:
+		levelStyle, icon = prettyDebugColor, "🐛"
+	case record.Level < slog.LevelWarn:
+		levelStyle, icon = prettyInfoColor, "ℹ️"
+	case record.Level < slog.LevelError:
+		levelStyle, icon = prettyWarnColor, "⚠️"
+	default:
+		levelStyle, icon = prettyErrorColor, "❌"
+	}
+
+	line := fmt.Sprintf("%s %s %s",
+		prettyTimeColor.Render(record.Time.Format("15:04:05")),
+		levelStyle.Render(icon),
+		record.Message,
+	)
+
+	for _, a := range h.attrs {
+		line += " " + formatAttr(a)
+	}
+	record.Attrs(func(a slog.Attr) bool {
+		line += " " + formatAttr(a)
+		return true
+	})
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := fmt.Fprintln(h.out, line)
+	return err
+}
+
+func formatAttr(a slog.Attr) string {
+	return fmt.Sprintf("%s=%s", prettyKeyColor.Render(a.Key), a.Value)
+}
+
+func (h *prettyHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := &prettyHandler{out: h.out, level: h.level, mu: h.mu}
+	next.attrs = append(next.attrs, h.attrs...)
+	next.attrs = append(next.attrs, attrs...)
+	return next
+}
+
+func (h *prettyHandler) WithGroup(_ string) slog.Handler {
+	// Groups aren't rendered specially in the pretty output; attrs from a
+	// grouped logger still show up flat, same as WithAttrs.
+	return h
+}
@@ -0,0 +1,197 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// levelVar holds the default log level, shared by every handler built by
+// newHandler so SetLevel can change it live without reconstructing the
+// logger.
+var levelVar = &slog.LevelVar{}
+
+// subsystemLevels overrides levelVar for specific components, keyed by the
+// lowercased "component" attribute (e.g. what Component(name) sets), parsed
+// once at Init time from NSM_LOG_LEVEL.
+var subsystemLevels map[string]slog.Level
+
+// fileRotator is the lumberjack writer backing the JSON file sink, kept
+// around so RotateLogFile and Close can reach it directly.
+var fileRotator *lumberjack.Logger
+
+func parseLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(level) {
+	case "trace", "debug":
+		return slog.LevelDebug, nil
+	case "info", "":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	case "fatal", "panic":
+		return slog.LevelError + 4, nil
+	default:
+		return slog.LevelInfo, fmt.Errorf("unknown level %q", level)
+	}
+}
+
+// parseSubsystemLevels parses NSM_LOG_LEVEL's "subsystem=level,subsystem2=level2"
+// format into a level-per-component map. Malformed entries are skipped.
+func parseSubsystemLevels(env string) map[string]slog.Level {
+	levels := make(map[string]slog.Level)
+
+	for _, pair := range strings.Split(env, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		subsystem, levelStr, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+
+		level, err := parseLevel(strings.TrimSpace(levelStr))
+		if err != nil {
+			continue
+		}
+
+		levels[strings.ToLower(strings.TrimSpace(subsystem))] = level
+	}
+
+	return levels
+}
+
+// newHandler builds the fan-out handler InitWithConfig installs: a
+// TTY-pretty console handler, a rotating JSON file handler, or both. Each
+// wraps a subsystemHandler so per-component NSM_LOG_LEVEL overrides apply
+// regardless of sink.
+func newHandler(cfg Config) slog.Handler {
+	level, err := parseLevel(cfg.Level)
+	if err != nil {
+		level = slog.LevelInfo
+	}
+	levelVar.Set(level)
+
+	subsystemLevels = parseSubsystemLevels(os.Getenv("NSM_LOG_LEVEL"))
+
+	var handlers []slog.Handler
+
+	if cfg.Console {
+		if cfg.Pretty {
+			handlers = append(handlers, newSubsystemHandler(newPrettyHandler(os.Stdout, levelVar)))
+		} else {
+			handlers = append(handlers, newSubsystemHandler(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: levelVar})))
+		}
+	}
+
+	if cfg.File != "" {
+		if err := os.MkdirAll(filepath.Dir(cfg.File), 0o755); err != nil {
+			fmt.Printf("Failed to create log directory: %v\n", err)
+		} else {
+			fileRotator = &lumberjack.Logger{
+				Filename:   cfg.File,
+				MaxSize:    cfg.MaxSize,
+				MaxBackups: cfg.MaxBackups,
+				MaxAge:     cfg.MaxAge,
+				Compress:   true,
+			}
+			handlers = append(handlers, newSubsystemHandler(slog.NewJSONHandler(fileRotator, &slog.HandlerOptions{Level: levelVar})))
+		}
+	}
+
+	switch len(handlers) {
+	case 0:
+		return newSubsystemHandler(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: levelVar}))
+	case 1:
+		return handlers[0]
+	default:
+		return multiHandler(handlers)
+	}
+}
+
+// multiHandler fans every record out to a fixed set of handlers, e.g. a
+// pretty console sink and a JSON file sink sharing the same level/attrs.
+type multiHandler []slog.Handler
+
+func (m multiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range m {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m multiHandler) Handle(ctx context.Context, record slog.Record) error {
+	var firstErr error
+	for _, h := range m {
+		if !h.Enabled(ctx, record.Level) {
+			continue
+		}
+		if err := h.Handle(ctx, record.Clone()); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (m multiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := make(multiHandler, len(m))
+	for i, h := range m {
+		next[i] = h.WithAttrs(attrs)
+	}
+	return next
+}
+
+func (m multiHandler) WithGroup(name string) slog.Handler {
+	next := make(multiHandler, len(m))
+	for i, h := range m {
+		next[i] = h.WithGroup(name)
+	}
+	return next
+}
+
+// subsystemHandler wraps another handler, tracking the "component" attribute
+// (set by Component(name) and its ContextLogger) so it can apply a
+// per-subsystem level override from NSM_LOG_LEVEL instead of the global
+// levelVar.
+type subsystemHandler struct {
+	next  slog.Handler
+	level slog.Level
+}
+
+func newSubsystemHandler(next slog.Handler) *subsystemHandler {
+	return &subsystemHandler{next: next, level: levelVar.Level()}
+}
+
+func (h *subsystemHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *subsystemHandler) Handle(ctx context.Context, record slog.Record) error {
+	return h.next.Handle(ctx, record)
+}
+
+func (h *subsystemHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	level := h.level
+	for _, a := range attrs {
+		if a.Key == "component" {
+			if override, ok := subsystemLevels[strings.ToLower(a.Value.String())]; ok {
+				level = override
+			}
+		}
+	}
+	return &subsystemHandler{next: h.next.WithAttrs(attrs), level: level}
+}
+
+func (h *subsystemHandler) WithGroup(name string) slog.Handler {
+	return &subsystemHandler{next: h.next.WithGroup(name), level: h.level}
+}
@@ -0,0 +1,79 @@
+// Package metrics holds NSM's Prometheus instrumentation for the reverse
+// proxy: request counters/histograms, TLS handshake outcomes, and upstream
+// and certificate health gauges. Metrics are package-level (promauto
+// registers them against prometheus.DefaultRegisterer on import), mirroring
+// how pkg/logger exposes a single process-wide logger - both the proxy and
+// internal/cert record into the same set of metrics regardless of which
+// ProxyServer/Renewer instance is running.
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	httpRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "nsm_http_requests_total",
+		Help: "Total HTTP requests proxied to the dev server, by status code, method, and host.",
+	}, []string{"code", "method", "host"})
+
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "nsm_http_request_duration_seconds",
+		Help:    "Latency of HTTP requests proxied to the dev server.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"code", "method", "host"})
+
+	tlsHandshakesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "nsm_tls_handshakes_total",
+		Help: "Total TLS handshakes completed by the proxy, by result (success or error).",
+	}, []string{"result"})
+
+	upstreamUp = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "nsm_upstream_up",
+		Help: "Whether the dev server upstream was reachable as of the last proxied request (1) or not (0).",
+	})
+
+	certExpirySeconds = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "nsm_cert_expiry_seconds",
+		Help: "Seconds until the active TLS certificate expires.",
+	})
+)
+
+// RecordRequest updates the request counter/histogram and the rolling
+// window RenderMetricsPanel reads, for one HTTP request the proxy handled.
+func RecordRequest(method, host string, code int, duration time.Duration) {
+	labels := prometheus.Labels{
+		"code":   strconv.Itoa(code),
+		"method": method,
+		"host":   host,
+	}
+	httpRequestsTotal.With(labels).Inc()
+	httpRequestDuration.With(labels).Observe(duration.Seconds())
+	rolling.record(duration)
+}
+
+// RecordTLSHandshake records the outcome of one TLS handshake attempt
+// ("success" or "error").
+func RecordTLSHandshake(result string) {
+	tlsHandshakesTotal.WithLabelValues(result).Inc()
+}
+
+// SetUpstreamUp reports whether the dev server upstream is currently
+// reachable.
+func SetUpstreamUp(up bool) {
+	if up {
+		upstreamUp.Set(1)
+	} else {
+		upstreamUp.Set(0)
+	}
+}
+
+// SetCertExpiry records how far in the future notAfter is, for the
+// nsm_cert_expiry_seconds gauge.
+func SetCertExpiry(notAfter time.Time) {
+	certExpirySeconds.Set(time.Until(notAfter).Seconds())
+}
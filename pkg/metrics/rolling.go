@@ -0,0 +1,95 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+)
+
+// rollingWindowSize is how far back CurrentSnapshot's QPS/latency figures
+// look; RenderMetricsPanel wants "what's happening right now", not an
+// all-time average.
+const rollingWindowSize = 60 * time.Second
+
+// rolling is the process-wide window RecordRequest writes into.
+var rolling = newRollingWindow(rollingWindowSize)
+
+// Snapshot is a point-in-time read of the rolling request-rate/latency
+// window, for internal/ui's RenderMetricsPanel.
+type Snapshot struct {
+	QPS        float64
+	AvgLatency time.Duration
+}
+
+// CurrentSnapshot returns the current rolling QPS/average-latency figures.
+func CurrentSnapshot() Snapshot {
+	return rolling.snapshot()
+}
+
+// rollingWindow buckets request count/total latency by the second they
+// occurred in, over the trailing window duration, discarding older buckets
+// as time moves forward.
+type rollingWindow struct {
+	window time.Duration
+
+	mu      sync.Mutex
+	buckets map[int64]*bucket
+}
+
+type bucket struct {
+	count int
+	total time.Duration
+}
+
+func newRollingWindow(window time.Duration) *rollingWindow {
+	return &rollingWindow{window: window, buckets: make(map[int64]*bucket)}
+}
+
+func (w *rollingWindow) record(d time.Duration) {
+	now := time.Now().Unix()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	b, ok := w.buckets[now]
+	if !ok {
+		b = &bucket{}
+		w.buckets[now] = b
+	}
+	b.count++
+	b.total += d
+
+	w.evictLocked(now)
+}
+
+func (w *rollingWindow) evictLocked(now int64) {
+	cutoff := now - int64(w.window.Seconds())
+	for ts := range w.buckets {
+		if ts < cutoff {
+			delete(w.buckets, ts)
+		}
+	}
+}
+
+func (w *rollingWindow) snapshot() Snapshot {
+	now := time.Now().Unix()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.evictLocked(now)
+
+	var count int
+	var total time.Duration
+	for _, b := range w.buckets {
+		count += b.count
+		total += b.total
+	}
+
+	if count == 0 {
+		return Snapshot{}
+	}
+
+	return Snapshot{
+		QPS:        float64(count) / w.window.Seconds(),
+		AvgLatency: total / time.Duration(count),
+	}
+}
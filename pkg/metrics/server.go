@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/kashifsb/nsm/pkg/health"
+	"github.com/kashifsb/nsm/pkg/logger"
+)
+
+// Server exposes /metrics, /healthz, and /readyz on a sidecar address, so
+// Prometheus can scrape NSM and local orchestrators (docker-compose
+// healthchecks, systemd) have a plain HTTP probe instead of parsing the TUI.
+type Server struct {
+	health     *health.Tracker
+	httpServer *http.Server
+}
+
+// NewServer returns a Server whose /readyz reflects tracker's overall
+// state; tracker may be nil, in which case /readyz always reports ready.
+func NewServer(tracker *health.Tracker) *Server {
+	return &Server{health: tracker}
+}
+
+// Start binds addr (e.g. ":9090") and serves until Stop is called.
+func (s *Server) Start(addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/readyz", s.readyz)
+
+	s.httpServer = &http.Server{
+		Addr:    addr,
+		Handler: mux,
+	}
+
+	logger.Info("Starting metrics server", "addr", addr)
+
+	go func() {
+		if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Error("Metrics server error", "error", err)
+		}
+	}()
+
+	return nil
+}
+
+func (s *Server) readyz(w http.ResponseWriter, r *http.Request) {
+	if s.health != nil && s.health.Overall().State == health.StateError {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprintln(w, "not ready")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintln(w, "ready")
+}
+
+// Stop gracefully shuts down the metrics server.
+func (s *Server) Stop() error {
+	if s.httpServer == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	logger.Info("Stopping metrics server")
+	return s.httpServer.Shutdown(ctx)
+}
@@ -0,0 +1,31 @@
+//go:build windows
+
+package process
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// createNewProcessGroup isn't exported by syscall; it's what lets Stop
+// later signal the whole group instead of just the direct child.
+const createNewProcessGroup = 0x00000200
+
+func setupGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: createNewProcessGroup}
+}
+
+// terminateGroup asks the process group to exit via CTRL_BREAK_EVENT, the
+// nearest Windows equivalent of SIGTERM for a group started with
+// CREATE_NEW_PROCESS_GROUP; Go sends it when Signal is called with
+// os.Interrupt on such a process.
+func terminateGroup(cmd *exec.Cmd) {
+	if err := cmd.Process.Signal(os.Interrupt); err != nil {
+		cmd.Process.Kill()
+	}
+}
+
+func killGroup(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
@@ -0,0 +1,269 @@
+// Package process runs a single external command as a managed child: it
+// starts it in its own process group so Stop can reach every descendant it
+// spawns (not just the direct child), restarts it with backoff if it
+// crashes, and streams its stdout/stderr to the caller line by line. The
+// process-group and signal handling is platform-specific and lives in
+// signal_unix.go/signal_windows.go; everything else here is portable.
+package process
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/kashifsb/nsm/pkg/utils"
+)
+
+// Config describes the command to run and how Supervisor should manage it.
+type Config struct {
+	WorkingDir string
+	Command    string
+	Args       []string
+	Env        []string
+
+	// LameDuck is how long Stop waits for the graceful signal to take
+	// effect before escalating to a hard kill. Defaults to 5s.
+	LameDuck time.Duration
+
+	// MaxRestarts caps how many times a crashed process is restarted; 0
+	// means a crash is reported but never retried.
+	MaxRestarts int
+	// RestartBackoff/MaxRestartBackoff bound the delay RetryWithBackoff
+	// waits between restart attempts. Default 1s / 30s.
+	RestartBackoff    time.Duration
+	MaxRestartBackoff time.Duration
+
+	// OnOutput is called once per line of stdout/stderr the process writes.
+	OnOutput func(source, line string)
+	// OnExit is called every time the process exits, whether or not it
+	// will be restarted.
+	OnExit func(exitCode int, err error)
+	// OnRestart is called before each restart attempt, 1-indexed.
+	OnRestart func(attempt int)
+	// OnGiveUp is called once the process has crashed more times than
+	// MaxRestarts and Supervisor has stopped trying to restart it, with the
+	// error from its final attempt.
+	OnGiveUp func(err error)
+}
+
+// Supervisor manages the lifecycle of one supervised command.
+type Supervisor struct {
+	cfg Config
+
+	mu       sync.Mutex
+	cmd      *exec.Cmd
+	exited   chan struct{}
+	waitErr  error
+	stopped  bool
+	restarts int
+}
+
+// NewSupervisor returns a Supervisor for cfg, filling in default timings.
+func NewSupervisor(cfg Config) *Supervisor {
+	if cfg.LameDuck <= 0 {
+		cfg.LameDuck = 5 * time.Second
+	}
+	if cfg.RestartBackoff <= 0 {
+		cfg.RestartBackoff = time.Second
+	}
+	if cfg.MaxRestartBackoff <= 0 {
+		cfg.MaxRestartBackoff = 30 * time.Second
+	}
+	return &Supervisor{cfg: cfg}
+}
+
+// Start spawns the command and returns once it's running; a background
+// goroutine keeps it restarted (per MaxRestarts/backoff) until it exits
+// cleanly, Stop is called, or ctx is cancelled.
+func (s *Supervisor) Start(ctx context.Context) error {
+	if err := s.spawn(); err != nil {
+		return err
+	}
+
+	go func() {
+		<-ctx.Done()
+		s.Stop()
+	}()
+
+	go s.run(ctx)
+
+	return nil
+}
+
+func (s *Supervisor) spawn() error {
+	cmd := exec.Command(s.cfg.Command, s.cfg.Args...)
+	cmd.Dir = s.cfg.WorkingDir
+	cmd.Env = s.cfg.Env
+	setupGroup(cmd)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("create stdout pipe: %w", err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return fmt.Errorf("create stderr pipe: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("start %s: %w", s.cfg.Command, err)
+	}
+
+	exited := make(chan struct{})
+
+	s.mu.Lock()
+	s.cmd = cmd
+	s.exited = exited
+	s.mu.Unlock()
+
+	// cmd.Wait must only be called once per process, so exactly one
+	// goroutine owns it; run() and Stop() both just wait on exited.
+	go func() {
+		waitErr := cmd.Wait()
+		s.mu.Lock()
+		s.waitErr = waitErr
+		s.mu.Unlock()
+		close(exited)
+	}()
+
+	go s.stream(stdout, "stdout")
+	go s.stream(stderr, "stderr")
+
+	return nil
+}
+
+func (s *Supervisor) stream(r io.Reader, source string) {
+	if s.cfg.OnOutput == nil {
+		return
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		s.cfg.OnOutput(source, scanner.Text())
+	}
+}
+
+// run waits out the current process's lifetime and, while not stopped,
+// restarts it with backoff by reusing utils.RetryWithBackoff: each "attempt"
+// models one full process lifecycle, returning nil on a clean exit or
+// intentional Stop (which halts further attempts) and an error on a crash
+// (which schedules the next attempt after a backoff sleep).
+func (s *Supervisor) run(ctx context.Context) {
+	first := true
+
+	err := utils.RetryWithBackoff(s.cfg.MaxRestarts+1, s.cfg.RestartBackoff, s.cfg.MaxRestartBackoff, func() error {
+		if !first {
+			s.mu.Lock()
+			stopped := s.stopped
+			s.mu.Unlock()
+			if stopped || ctx.Err() != nil {
+				return nil
+			}
+
+			s.mu.Lock()
+			s.restarts++
+			attempt := s.restarts
+			s.mu.Unlock()
+
+			if s.cfg.OnRestart != nil {
+				s.cfg.OnRestart(attempt)
+			}
+
+			if err := s.spawn(); err != nil {
+				return err
+			}
+		}
+		first = false
+
+		return s.wait()
+	})
+
+	s.mu.Lock()
+	stopped := s.stopped
+	s.mu.Unlock()
+
+	if err != nil && !stopped && s.cfg.OnGiveUp != nil {
+		s.cfg.OnGiveUp(err)
+	}
+}
+
+// wait blocks until the current process exits, reports it via OnExit, and
+// returns nil for a clean exit or an intentional Stop (so run won't retry),
+// or an error describing the crash (so run's RetryWithBackoff retries).
+func (s *Supervisor) wait() error {
+	s.mu.Lock()
+	cmd := s.cmd
+	exited := s.exited
+	s.mu.Unlock()
+
+	<-exited
+
+	s.mu.Lock()
+	waitErr := s.waitErr
+	stopped := s.stopped
+	s.mu.Unlock()
+
+	exitCode := 0
+	if cmd.ProcessState != nil {
+		exitCode = cmd.ProcessState.ExitCode()
+	}
+
+	if s.cfg.OnExit != nil {
+		s.cfg.OnExit(exitCode, waitErr)
+	}
+
+	if stopped || (waitErr == nil && exitCode == 0) {
+		return nil
+	}
+	return fmt.Errorf("process exited with code %d", exitCode)
+}
+
+// Stop asks the process group to terminate gracefully (SIGTERM on Unix,
+// CTRL_BREAK on Windows), waits up to LameDuck for it to exit, then
+// escalates to a hard kill. It also marks the Supervisor stopped so run
+// won't restart the process once it exits.
+func (s *Supervisor) Stop() error {
+	s.mu.Lock()
+	s.stopped = true
+	cmd := s.cmd
+	exited := s.exited
+	s.mu.Unlock()
+
+	if cmd == nil || cmd.Process == nil {
+		return nil
+	}
+
+	terminateGroup(cmd)
+
+	select {
+	case <-exited:
+		return nil
+	case <-time.After(s.cfg.LameDuck):
+		killGroup(cmd)
+		<-exited
+		return fmt.Errorf("process killed after %s lame-duck period", s.cfg.LameDuck)
+	}
+}
+
+// IsRunning reports whether the supervised process is currently alive.
+func (s *Supervisor) IsRunning() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cmd != nil && s.cmd.Process != nil && s.cmd.ProcessState == nil
+}
+
+// PID returns the current process's PID, or 0 if none is running.
+func (s *Supervisor) PID() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cmd != nil && s.cmd.Process != nil {
+		return s.cmd.Process.Pid
+	}
+	return 0
+}
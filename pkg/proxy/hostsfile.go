@@ -0,0 +1,121 @@
+package proxy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Sentinel markers bracket NSM-managed lines in the hosts file, so
+// HostsFile can add and remove its own block without disturbing anything
+// else a user or another tool put there.
+const (
+	sentinelBegin = "# >>> nsm managed hosts >>>"
+	sentinelEnd   = "# <<< nsm managed hosts <<<"
+)
+
+// HostsFile edits the OS hosts file to point NSM domains at 127.0.0.1,
+// atomically and within a single sentinel-commented block it can later
+// remove cleanly.
+type HostsFile struct {
+	path string
+}
+
+// NewHostsFile returns a HostsFile for the platform's default hosts file
+// location.
+func NewHostsFile() *HostsFile {
+	return &HostsFile{path: defaultHostsPath()}
+}
+
+func defaultHostsPath() string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(os.Getenv("SystemRoot"), "System32", "drivers", "etc", "hosts")
+	}
+	return "/etc/hosts"
+}
+
+// Set rewrites NSM's managed block to contain exactly one "127.0.0.1 host"
+// line per entry in hosts, replacing whatever was there from a previous
+// call.
+func (h *HostsFile) Set(hosts []string) error {
+	lines := make([]string, 0, len(hosts)+2)
+	lines = append(lines, sentinelBegin)
+	for _, host := range hosts {
+		lines = append(lines, fmt.Sprintf("127.0.0.1 %s", host))
+	}
+	lines = append(lines, sentinelEnd)
+
+	return h.replaceBlock(strings.Join(lines, "\n"))
+}
+
+// Remove deletes NSM's managed block entirely, leaving the rest of the
+// file untouched. It's a no-op if there's no block to remove.
+func (h *HostsFile) Remove() error {
+	return h.replaceBlock("")
+}
+
+// replaceBlock rewrites h.path so the region between the sentinel markers
+// (if any) is replaced with block, writing via a temp file + rename so a
+// reader never sees a partially-written hosts file.
+func (h *HostsFile) replaceBlock(block string) error {
+	original, err := os.ReadFile(h.path)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", h.path, err)
+	}
+
+	updated := spliceBlock(string(original), block)
+	if updated == string(original) {
+		return nil
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(h.path), ".nsm-hosts-*")
+	if err != nil {
+		return fmt.Errorf("create temp hosts file: %w", err)
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.WriteString(updated); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("write temp hosts file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("close temp hosts file: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, h.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("replace %s: %w", h.path, err)
+	}
+
+	return nil
+}
+
+// spliceBlock returns content with any existing sentinel block removed
+// and, if block is non-empty, a fresh one appended.
+func spliceBlock(content, block string) string {
+	lines := strings.Split(content, "\n")
+	kept := make([]string, 0, len(lines))
+
+	inBlock := false
+	for _, line := range lines {
+		switch trimmed := strings.TrimSpace(line); {
+		case trimmed == sentinelBegin:
+			inBlock = true
+		case trimmed == sentinelEnd:
+			inBlock = false
+		case !inBlock:
+			kept = append(kept, line)
+		}
+	}
+
+	result := strings.TrimRight(strings.Join(kept, "\n"), "\n")
+	if block == "" {
+		return result + "\n"
+	}
+
+	return result + "\n\n" + block + "\n"
+}
@@ -0,0 +1,43 @@
+package proxy
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/kashifsb/nsm/pkg/logger"
+)
+
+// fallbackAddr is bound when addr (normally ":443" or ":80") can't be,
+// e.g. because the process isn't running as root/admin.
+const fallbackAddr = ":8080"
+
+// Listen binds addr for router, falling back to fallbackAddr if the
+// privileged port can't be bound. It returns the address actually bound,
+// so callers can tell the user where to point DNS/hosts entries when the
+// fallback kicks in, and the *http.Server so they can Shutdown it later.
+func Listen(addr string, router *Router) (string, *http.Server, error) {
+	trace.Tracef("binding %s", addr)
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		logger.Warn("Cannot bind requested port, falling back", "addr", addr, "fallback", fallbackAddr, "error", err)
+
+		trace.Tracef("binding fallback %s", fallbackAddr)
+		ln, err = net.Listen("tcp", fallbackAddr)
+		if err != nil {
+			return "", nil, fmt.Errorf("bind fallback port %s: %w", fallbackAddr, err)
+		}
+		addr = fallbackAddr
+	}
+
+	server := &http.Server{Handler: router}
+
+	go func() {
+		if err := server.Serve(ln); err != nil && err != http.ErrServerClosed {
+			logger.Error("Proxy server stopped", "error", err)
+		}
+	}()
+
+	logger.Info("Proxy listening", "addr", addr)
+	return addr, server, nil
+}
@@ -0,0 +1,172 @@
+// Package proxy binds NSM's public ports (:443/:80, falling back to a
+// high port when those require privileges it doesn't have) and
+// reverse-proxies each request to the right child dev process based on
+// its Host header, so multiple projects can run concurrently behind one
+// proxy (e.g. api.myapp.test -> :5173, admin.myapp.test -> :3000).
+// WebSocket upgrades and HTTP/2 pass through unmodified via
+// httputil.ReverseProxy, so Vite HMR and similar keep working.
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+
+	nsmlog "github.com/kashifsb/nsm/internal/log"
+	"github.com/kashifsb/nsm/pkg/logger"
+)
+
+// trace is the "proxy" facet, enabled via NSM_TRACE=proxy for per-request
+// routing detail that would otherwise drown out the rest of the app's
+// logging.
+var trace = nsmlog.Facet("proxy")
+
+// Routes maps a hostname (without port) to the local port its dev process
+// listens on. This is the shape NSM persists to .nsm-ports.json.
+type Routes map[string]int
+
+// Router is an http.Handler that reverse-proxies each request to the
+// backend named by Routes[r.Host], hot-reloading its table whenever the
+// backing file changes on disk.
+type Router struct {
+	mu      sync.RWMutex
+	routes  Routes
+	proxies map[string]*httputil.ReverseProxy
+
+	path    string
+	watcher *fsnotify.Watcher
+	stop    chan struct{}
+}
+
+// NewRouter loads routes from path (normally .nsm-ports.json) and starts
+// watching it for changes via fsnotify. A missing or unparsable file
+// starts the Router with an empty route table rather than failing, since
+// the file is normally written by the project runner shortly after.
+func NewRouter(path string) (*Router, error) {
+	r := &Router{
+		routes:  make(Routes),
+		proxies: make(map[string]*httputil.ReverseProxy),
+		path:    path,
+		stop:    make(chan struct{}),
+	}
+
+	if err := r.reload(); err != nil {
+		logger.Warn("No route table yet, starting empty", "path", path, "error", err)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("create file watcher: %w", err)
+	}
+
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
+		return nil, fmt.Errorf("watch %s: %w", filepath.Dir(path), err)
+	}
+	r.watcher = watcher
+
+	go r.watchLoop()
+
+	return r, nil
+}
+
+// reload re-reads the route table from disk and rebuilds a
+// ReverseProxy per backend.
+func (r *Router) reload() error {
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		return err
+	}
+
+	var routes Routes
+	if err := json.Unmarshal(data, &routes); err != nil {
+		return fmt.Errorf("parse %s: %w", r.path, err)
+	}
+
+	proxies := make(map[string]*httputil.ReverseProxy, len(routes))
+	for host, port := range routes {
+		target, err := url.Parse(fmt.Sprintf("http://127.0.0.1:%d", port))
+		if err != nil {
+			logger.Warn("Skipping invalid route", "host", host, "port", port, "error", err)
+			continue
+		}
+		proxies[host] = httputil.NewSingleHostReverseProxy(target)
+	}
+
+	r.mu.Lock()
+	r.routes = routes
+	r.proxies = proxies
+	r.mu.Unlock()
+
+	logger.Info("Loaded proxy route table", "path", r.path, "routes", len(routes))
+	return nil
+}
+
+// watchLoop reloads the route table on every write/create event for
+// r.path, ignoring events for other files in the same directory.
+func (r *Router) watchLoop() {
+	for {
+		select {
+		case event, ok := <-r.watcher.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(event.Name) != filepath.Clean(r.path) {
+				continue
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+				continue
+			}
+			if err := r.reload(); err != nil {
+				logger.Warn("Failed to reload route table", "error", err)
+			}
+		case err, ok := <-r.watcher.Errors:
+			if !ok {
+				return
+			}
+			logger.Warn("Route table watcher error", "error", err)
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// Close stops watching the route table file.
+func (r *Router) Close() error {
+	close(r.stop)
+	return r.watcher.Close()
+}
+
+// ServeHTTP dispatches to the backend proxy registered for the request's
+// Host header.
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	host := hostOnly(req.Host)
+
+	r.mu.RLock()
+	backend, ok := r.proxies[host]
+	r.mu.RUnlock()
+
+	if !ok {
+		trace.Tracef("no route for host %q", host)
+		http.Error(w, fmt.Sprintf("no route for host %q", host), http.StatusNotFound)
+		return
+	}
+
+	trace.Tracef("routing %s %s -> %s", req.Method, req.URL.Path, host)
+	backend.ServeHTTP(w, req)
+}
+
+func hostOnly(hostport string) string {
+	if host, _, err := net.SplitHostPort(hostport); err == nil {
+		return host
+	}
+	return hostport
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// plistEscaper escapes the characters that are significant to plist XML
+// (itself a dialect of XML) so that ExecPath/Args/Environment/Label -
+// none of which are under our control, since they come straight from the
+// command line NSM was invoked with - can't break out of their <string>
+// element or otherwise produce malformed/injectable XML. A value as
+// ordinary as --command "npm run dev" would otherwise corrupt the plist.
+var plistEscaper = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	`"`, "&quot;",
+	"'", "&apos;",
+)
+
+// launchdPlistPath returns where Service's plist lives: per-user agents
+// under ~/Library/LaunchAgents, system daemons under
+// /Library/LaunchDaemons (writable only as root).
+func (s Service) launchdPlistPath() (string, error) {
+	if s.System {
+		return filepath.Join("/Library/LaunchDaemons", s.label()+".plist"), nil
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("resolve home directory: %w", err)
+	}
+	return filepath.Join(homeDir, "Library", "LaunchAgents", s.label()+".plist"), nil
+}
+
+func (s Service) installLaunchd() error {
+	path, err := s.launchdPlistPath()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create launchd directory: %w", err)
+	}
+
+	var argXML strings.Builder
+	for _, arg := range s.Args {
+		fmt.Fprintf(&argXML, "\t\t<string>%s</string>\n", plistEscaper.Replace(arg))
+	}
+
+	var envXML strings.Builder
+	if len(s.Environment) > 0 {
+		envXML.WriteString("\t<key>EnvironmentVariables</key>\n\t<dict>\n")
+		for k, v := range s.Environment {
+			fmt.Fprintf(&envXML, "\t\t<key>%s</key>\n\t\t<string>%s</string>\n", plistEscaper.Replace(k), plistEscaper.Replace(v))
+		}
+		envXML.WriteString("\t</dict>\n")
+	}
+
+	outLog, errLog := s.Name+".log", s.Name+".err.log"
+	if s.LogDir != "" {
+		outLog = filepath.Join(s.LogDir, outLog)
+		errLog = filepath.Join(s.LogDir, errLog)
+	}
+
+	plist := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>%s</string>
+	<key>ProgramArguments</key>
+	<array>
+		<string>%s</string>
+%s	</array>
+%s	<key>RunAtLoad</key>
+	<true/>
+	<key>KeepAlive</key>
+	<true/>
+	<key>StandardOutPath</key>
+	<string>%s</string>
+	<key>StandardErrorPath</key>
+	<string>%s</string>
+</dict>
+</plist>
+`, plistEscaper.Replace(s.label()), plistEscaper.Replace(s.ExecPath), argXML.String(), envXML.String(), plistEscaper.Replace(outLog), plistEscaper.Replace(errLog))
+
+	if err := os.WriteFile(path, []byte(plist), 0o644); err != nil {
+		return fmt.Errorf("write launchd plist: %w", err)
+	}
+
+	_ = runCommand(launchctl(s.System), "unload", path) // fine if not already loaded
+	if err := runCommand(launchctl(s.System), "load", "-w", path); err != nil {
+		return fmt.Errorf("load launchd service: %w", err)
+	}
+	return nil
+}
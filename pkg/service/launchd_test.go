@@ -0,0 +1,48 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPlistEscaper(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "plain", in: "npm", want: "npm"},
+		{name: "ampersand", in: "dev & test", want: "dev &amp; test"},
+		{name: "angle brackets", in: "<script>", want: "&lt;script&gt;"},
+		{name: "double quote", in: `say "hi"`, want: `say &quot;hi&quot;`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := plistEscaper.Replace(tt.in); got != tt.want {
+				t.Errorf("plistEscaper.Replace(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestInstallLaunchdEscapesCommandWithSpecialChars is a regression test for
+// the documented `--command "npm run dev"` example: unescaped, its quote
+// characters (and any "&"/"<"/">" another command might contain) would
+// produce malformed/injectable plist XML.
+func TestInstallLaunchdEscapesCommandWithSpecialChars(t *testing.T) {
+	args := []string{"--command", `npm run dev && echo "done"`}
+
+	var argXML strings.Builder
+	for _, arg := range args {
+		argXML.WriteString("\t\t<string>" + plistEscaper.Replace(arg) + "</string>\n")
+	}
+
+	got := argXML.String()
+	if strings.Contains(got, "&&") || strings.Contains(got, `"done"`) {
+		t.Errorf("plist <string> content was not escaped: %q", got)
+	}
+	if !strings.Contains(got, "&amp;&amp;") || !strings.Contains(got, "&quot;done&quot;") {
+		t.Errorf("plist <string> content missing expected escapes: %q", got)
+	}
+}
@@ -0,0 +1,184 @@
+// Package service installs a long-running executable as a background OS
+// service: a launchd agent/daemon on darwin, a systemd user/system unit on
+// linux. It generalizes the launchd/systemd plumbing internal/setup has
+// used since chunk7-1 to keep its own DNS and ACME-renewal daemons
+// running, so any long-running NSM process - including `nsm` itself,
+// fronting a dev server - can be installed the same way.
+package service
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Service describes a program to run as a background service.
+type Service struct {
+	// Name identifies the service for CLI output and unit/plist file
+	// names (e.g. "nsm", "nsm-dns").
+	Name string
+	// Label is the launchd identifier, conventionally reverse-DNS (e.g.
+	// "com.nsm.nsm"). Defaults to "com.nsm." + Name.
+	Label string
+	// ExecPath is the absolute path to the binary to run.
+	ExecPath string
+	// Args are passed to ExecPath.
+	Args []string
+	// Environment is set in the service's process environment.
+	Environment map[string]string
+	// System installs a system-wide service (/Library/LaunchDaemons,
+	// /etc/systemd/system) rather than a per-user one (~/Library/
+	// LaunchAgents, ~/.config/systemd/user) - requires root/sudo.
+	System bool
+	// LogDir is where stdout/stderr are redirected, as <LogDir>/<Name>.log
+	// and <LogDir>/<Name>.err.log.
+	LogDir string
+}
+
+// Status reports whether a Service is currently loaded/running.
+type Status struct {
+	Installed bool
+	Running   bool
+}
+
+func (s Service) label() string {
+	if s.Label != "" {
+		return s.Label
+	}
+	return "com.nsm." + s.Name
+}
+
+func (s Service) unitName() string {
+	return "nsm-" + s.Name + ".service"
+}
+
+// Install renders and loads the platform service definition, starting it
+// immediately (RunAtLoad on darwin, enable --now on linux).
+func (s Service) Install() error {
+	switch runtime.GOOS {
+	case "darwin":
+		return s.installLaunchd()
+	case "linux":
+		return s.installSystemd()
+	default:
+		return fmt.Errorf("service installation not supported on %s", runtime.GOOS)
+	}
+}
+
+// Uninstall stops the service and removes its definition file.
+func (s Service) Uninstall() error {
+	switch runtime.GOOS {
+	case "darwin":
+		path, err := s.launchdPlistPath()
+		if err != nil {
+			return err
+		}
+		_ = runCommand(launchctl(s.System), "unload", path)
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove launchd plist: %w", err)
+		}
+		return nil
+	case "linux":
+		_ = runCommand("systemctl", append(s.systemctlArgs(), "disable", "--now", s.unitName())...)
+		if dir, err := s.systemdUnitDir(); err == nil {
+			os.Remove(filepath.Join(dir, s.unitName()))
+		}
+		path, err := s.systemdUnitFilePath()
+		if err != nil {
+			return err
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove systemd unit: %w", err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("service removal not supported on %s", runtime.GOOS)
+	}
+}
+
+// Start starts an already-installed service.
+func (s Service) Start() error {
+	switch runtime.GOOS {
+	case "darwin":
+		path, err := s.launchdPlistPath()
+		if err != nil {
+			return err
+		}
+		return runCommand(launchctl(s.System), "load", "-w", path)
+	case "linux":
+		return runCommand("systemctl", append(s.systemctlArgs(), "start", s.unitName())...)
+	default:
+		return fmt.Errorf("service start not supported on %s", runtime.GOOS)
+	}
+}
+
+// Stop stops an already-installed service without uninstalling it.
+func (s Service) Stop() error {
+	switch runtime.GOOS {
+	case "darwin":
+		path, err := s.launchdPlistPath()
+		if err != nil {
+			return err
+		}
+		return runCommand(launchctl(s.System), "unload", path)
+	case "linux":
+		return runCommand("systemctl", append(s.systemctlArgs(), "stop", s.unitName())...)
+	default:
+		return fmt.Errorf("service stop not supported on %s", runtime.GOOS)
+	}
+}
+
+// Status reports whether the service is installed and, if so, whether
+// it's currently running.
+func (s Service) Status() (Status, error) {
+	switch runtime.GOOS {
+	case "darwin":
+		path, err := s.launchdPlistPath()
+		if err != nil {
+			return Status{}, err
+		}
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return Status{}, nil
+		}
+		out, _ := exec.Command(launchctl(s.System), "list", s.label()).CombinedOutput()
+		return Status{Installed: true, Running: strings.Contains(string(out), s.label())}, nil
+	case "linux":
+		path, err := s.systemdUnitFilePath()
+		if err != nil {
+			return Status{}, err
+		}
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return Status{}, nil
+		}
+		out, _ := exec.Command("systemctl", append(s.systemctlArgs(), "is-active", s.unitName())...).Output()
+		return Status{Installed: true, Running: strings.TrimSpace(string(out)) == "active"}, nil
+	default:
+		return Status{}, fmt.Errorf("service status not supported on %s", runtime.GOOS)
+	}
+}
+
+func launchctl(system bool) string {
+	// launchctl's own target for system daemons vs the current user's
+	// agents; both still resolve to the plain "launchctl" binary.
+	_ = system
+	return "launchctl"
+}
+
+// systemctlArgs returns the --user flag for per-user services, or no flag
+// (systemctl's default) for System ones.
+func (s Service) systemctlArgs() []string {
+	if s.System {
+		return nil
+	}
+	return []string{"--user"}
+}
+
+func runCommand(name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// systemdQuoteArg quotes arg for a systemd unit's ExecStart=/Environment=
+// line if it contains anything those lines' shell-like word-splitting
+// would otherwise act on. Without this, an arg as ordinary as the
+// documented --command "npm run dev" example gets split into separate
+// argv entries (npm, run, dev) instead of reaching nsm as one flag value.
+func systemdQuoteArg(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\"'\\$") {
+		return arg
+	}
+
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range arg {
+		switch r {
+		case '"', '\\', '$':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// systemdUnitDir returns the per-user or system unit directory Service's
+// symlink is installed into.
+func (s Service) systemdUnitDir() (string, error) {
+	if s.System {
+		return "/etc/systemd/system", nil
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("resolve home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".config", "systemd", "user"), nil
+}
+
+// systemdUnitFilePath is where the unit file's content actually lives;
+// symlinked from systemdUnitDir so NSM's own config directory stays the
+// source of truth, the same layout internal/setup used for its DNS and
+// ACME-renewal daemons before this package existed.
+func (s Service) systemdUnitFilePath() (string, error) {
+	if s.System {
+		dir, err := s.systemdUnitDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(dir, s.unitName()), nil
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("resolve home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".nsm", "systemd", s.unitName()), nil
+}
+
+func (s Service) installSystemd() error {
+	unitPath, err := s.systemdUnitFilePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(unitPath), 0o755); err != nil {
+		return fmt.Errorf("create systemd unit directory: %w", err)
+	}
+
+	var envLines strings.Builder
+	for k, v := range s.Environment {
+		fmt.Fprintf(&envLines, "Environment=%s\n", systemdQuoteArg(fmt.Sprintf("%s=%s", k, v)))
+	}
+
+	quotedArgs := make([]string, len(s.Args))
+	for i, arg := range s.Args {
+		quotedArgs[i] = systemdQuoteArg(arg)
+	}
+
+	unit := fmt.Sprintf(`[Unit]
+Description=%s (NSM service)
+
+[Service]
+ExecStart=%s %s
+%sRestart=on-failure
+
+[Install]
+WantedBy=default.target
+`, s.Name, systemdQuoteArg(s.ExecPath), strings.Join(quotedArgs, " "), envLines.String())
+
+	if err := os.WriteFile(unitPath, []byte(unit), 0o644); err != nil {
+		return fmt.Errorf("write systemd unit: %w", err)
+	}
+
+	// System units live directly in their target directory; user units are
+	// written under NSM's own config dir and symlinked in, so it's obvious
+	// which ones NSM manages.
+	if !s.System {
+		unitDir, err := s.systemdUnitDir()
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(unitDir, 0o755); err != nil {
+			return fmt.Errorf("create systemd user unit directory: %w", err)
+		}
+
+		linkPath := filepath.Join(unitDir, s.unitName())
+		_ = os.Remove(linkPath)
+		if err := os.Symlink(unitPath, linkPath); err != nil {
+			return fmt.Errorf("link systemd unit: %w", err)
+		}
+	}
+
+	if err := runCommand("systemctl", append(s.systemctlArgs(), "daemon-reload")...); err != nil {
+		return fmt.Errorf("reload systemd daemon: %w", err)
+	}
+	if err := runCommand("systemctl", append(s.systemctlArgs(), "enable", "--now", s.unitName())...); err != nil {
+		return fmt.Errorf("enable %s: %w", s.unitName(), err)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSystemdQuoteArg(t *testing.T) {
+	tests := []struct {
+		name string
+		arg  string
+		want string
+	}{
+		{name: "plain", arg: "npm", want: "npm"},
+		{name: "spaces", arg: "npm run dev", want: `"npm run dev"`},
+		{name: "embedded quote", arg: `say "hi"`, want: `"say \"hi\""`},
+		{name: "embedded dollar", arg: "$HOME/bin", want: `"\$HOME/bin"`},
+		{name: "empty", arg: "", want: `""`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := systemdQuoteArg(tt.arg); got != tt.want {
+				t.Errorf("systemdQuoteArg(%q) = %q, want %q", tt.arg, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestInstallSystemdQuotesCommandWithSpaces is a regression test for the
+// documented `--command "npm run dev"` example: without quoting, systemd's
+// own ExecStart= word-splitting would break the multi-word arg into
+// separate argv entries instead of passing it through as one flag value.
+func TestInstallSystemdQuotesCommandWithSpaces(t *testing.T) {
+	s := Service{
+		Name:     "nsm",
+		ExecPath: "/usr/local/bin/nsm",
+		Args:     []string{"--command", "npm run dev"},
+		Environment: map[string]string{
+			"NSM_ENV": "dev & test",
+		},
+	}
+
+	var envLines strings.Builder
+	for k, v := range s.Environment {
+		envLines.WriteString("Environment=" + systemdQuoteArg(k+"="+v) + "\n")
+	}
+
+	quotedArgs := make([]string, len(s.Args))
+	for i, arg := range s.Args {
+		quotedArgs[i] = systemdQuoteArg(arg)
+	}
+	execStart := systemdQuoteArg(s.ExecPath) + " " + strings.Join(quotedArgs, " ")
+
+	if !strings.Contains(execStart, `"npm run dev"`) {
+		t.Errorf("ExecStart= did not quote the multi-word arg: %q", execStart)
+	}
+	if !strings.Contains(envLines.String(), `"NSM_ENV=dev & test"`) {
+		t.Errorf("Environment= did not quote the assignment: %q", envLines.String())
+	}
+}
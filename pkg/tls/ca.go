@@ -0,0 +1,276 @@
+package tls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/kashifsb/nsm/pkg/logger"
+)
+
+// authority is NSM's per-user self-signed CA: a long-lived key pair
+// persisted on disk and used to sign short-lived leaf certificates on
+// demand.
+type authority struct {
+	cert     *x509.Certificate
+	key      *ecdsa.PrivateKey
+	certPath string
+	keyPath  string
+}
+
+// loadOrCreateAuthority reads an existing CA from caDir, or generates and
+// persists a new one if none exists yet (or the existing one has
+// expired).
+func loadOrCreateAuthority(caDir string) (*authority, error) {
+	certPath := filepath.Join(caDir, "ca-cert.pem")
+	keyPath := filepath.Join(caDir, "ca-key.pem")
+
+	if ca, err := readAuthority(certPath, keyPath); err == nil {
+		return ca, nil
+	}
+
+	return createAuthority(certPath, keyPath)
+}
+
+func readAuthority(certPath, keyPath string) (*authority, error) {
+	certPEM, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	certBlock, _ := pem.Decode(certPEM)
+	if certBlock == nil {
+		return nil, fmt.Errorf("decode CA certificate PEM")
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse CA certificate: %w", err)
+	}
+
+	keyBlock, _ := pem.Decode(keyPEM)
+	if keyBlock == nil {
+		return nil, fmt.Errorf("decode CA key PEM")
+	}
+	key, err := x509.ParseECPrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse CA key: %w", err)
+	}
+
+	if time.Now().After(cert.NotAfter) {
+		return nil, fmt.Errorf("CA certificate expired on %s", cert.NotAfter.Format("2006-01-02"))
+	}
+
+	return &authority{cert: cert, key: key, certPath: certPath, keyPath: keyPath}, nil
+}
+
+func createAuthority(certPath, keyPath string) (*authority, error) {
+	logger.Info("Generating NSM local certificate authority", "path", certPath)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generate CA key: %w", err)
+	}
+
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			Organization: []string{"NSM Development CA"},
+			CommonName:   "NSM Local CA",
+		},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("create CA certificate: %w", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("parse generated CA certificate: %w", err)
+	}
+
+	if err := writePEM(certPath, "CERTIFICATE", der, 0o644); err != nil {
+		return nil, fmt.Errorf("write CA certificate: %w", err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("marshal CA key: %w", err)
+	}
+	if err := writePEM(keyPath, "EC PRIVATE KEY", keyDER, 0o600); err != nil {
+		return nil, fmt.Errorf("write CA key: %w", err)
+	}
+
+	return &authority{cert: cert, key: key, certPath: certPath, keyPath: keyPath}, nil
+}
+
+// issueLeaf mints a short-lived leaf certificate for domain, signed by the
+// CA, valid for validity. It returns the certificate's expiry alongside it
+// so Manager can schedule renewal without re-parsing the cert later.
+func (a *authority) issueLeaf(domain string, validity time.Duration) (*tls.Certificate, time.Time, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("generate leaf key: %w", err)
+	}
+
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	notAfter := time.Now().Add(validity)
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: domain},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     []string{domain},
+	}
+
+	if ip := net.ParseIP(domain); ip != nil {
+		template.DNSNames = nil
+		template.IPAddresses = []net.IP{ip}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, a.cert, &key.PublicKey, a.key)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("create leaf certificate: %w", err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("marshal leaf key: %w", err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("build tls.Certificate: %w", err)
+	}
+
+	return &cert, notAfter, nil
+}
+
+// install adds the CA to the OS's trust store so leaves it issues are
+// trusted without a browser warning.
+func (a *authority) install() error {
+	switch runtime.GOOS {
+	case "darwin":
+		return a.installDarwin()
+	case "linux":
+		return a.installLinux()
+	case "windows":
+		return a.installWindows()
+	default:
+		return fmt.Errorf("unsupported platform for CA install: %s", runtime.GOOS)
+	}
+}
+
+func (a *authority) installDarwin() error {
+	cmd := exec.Command("security", "add-trusted-cert", "-d", "-r", "trustRoot",
+		"-k", "/Library/Keychains/System.keychain", a.certPath)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("security add-trusted-cert: %w\nOutput: %s", err, output)
+	}
+	logger.Info("Installed NSM CA into macOS system keychain")
+	return nil
+}
+
+// nssDatabases are the default NSS certificate databases Chrome and
+// Firefox read from on common Linux distros.
+func nssDatabases() []string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	return []string{filepath.Join(home, ".pki", "nssdb")}
+}
+
+func (a *authority) installLinux() error {
+	certutil, err := exec.LookPath("certutil")
+	if err != nil {
+		return fmt.Errorf("certutil not found (install libnss3-tools or nss-tools): %w", err)
+	}
+
+	var installed int
+	var errs []string
+	for _, db := range nssDatabases() {
+		if info, err := os.Stat(db); err != nil || !info.IsDir() {
+			continue
+		}
+
+		cmd := exec.Command(certutil, "-A", "-d", "sql:"+db, "-t", "C,,",
+			"-n", "NSM Local CA", "-i", a.certPath)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v: %s", db, err, output))
+			continue
+		}
+		installed++
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("certutil failed for some NSS databases: %s", strings.Join(errs, "; "))
+	}
+
+	logger.Info("Installed NSM CA into NSS trust stores", "databases", installed)
+	return nil
+}
+
+func (a *authority) installWindows() error {
+	cmd := exec.Command("certutil.exe", "-addstore", "ROOT", a.certPath)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("certutil.exe -addstore: %w\nOutput: %s", err, output)
+	}
+	logger.Info("Installed NSM CA into Windows ROOT certificate store")
+	return nil
+}
+
+func randomSerial() (*big.Int, error) {
+	limit := new(big.Int).Lsh(big.NewInt(1), 128)
+	serial, err := rand.Int(rand.Reader, limit)
+	if err != nil {
+		return nil, fmt.Errorf("generate serial number: %w", err)
+	}
+	return serial, nil
+}
+
+func writePEM(path, blockType string, der []byte, mode os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return pem.Encode(f, &pem.Block{Type: blockType, Bytes: der})
+}
@@ -0,0 +1,67 @@
+package tls
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+
+	"github.com/kashifsb/nsm/pkg/logger"
+)
+
+// Frontend is a minimal HTTPS reverse proxy that fronts a single dev
+// process on the port PortManager negotiated, serving a certificate
+// Manager mints on demand for whatever domain the request's SNI name
+// asks for. It's the narrow "just make HTTPS work" path for EnsureCert;
+// a multi-project router with hostname-based dispatch lives in pkg/proxy.
+type Frontend struct {
+	manager *Manager
+	proxy   *httputil.ReverseProxy
+	server  *http.Server
+}
+
+// NewFrontend builds a Frontend that proxies every request to
+// 127.0.0.1:targetPort.
+func NewFrontend(manager *Manager, targetPort int) (*Frontend, error) {
+	target, err := url.Parse(fmt.Sprintf("http://127.0.0.1:%d", targetPort))
+	if err != nil {
+		return nil, fmt.Errorf("parse target URL: %w", err)
+	}
+
+	return &Frontend{
+		manager: manager,
+		proxy:   httputil.NewSingleHostReverseProxy(target),
+	}, nil
+}
+
+// Start listens on addr (normally ":443") with TLS, serving certificates
+// minted on demand via Manager.GetCertificate.
+func (f *Frontend) Start(addr string) error {
+	f.server = &http.Server{
+		Addr:    addr,
+		Handler: f.proxy,
+		TLSConfig: &tls.Config{
+			GetCertificate: f.manager.GetCertificate,
+		},
+	}
+
+	logger.Info("Starting HTTPS reverse-proxy front-end", "addr", addr)
+
+	go func() {
+		if err := f.server.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
+			logger.Error("HTTPS front-end stopped", "error", err)
+		}
+	}()
+
+	return nil
+}
+
+// Stop gracefully shuts down the front-end.
+func (f *Frontend) Stop(ctx context.Context) error {
+	if f.server == nil {
+		return nil
+	}
+	return f.server.Shutdown(ctx)
+}
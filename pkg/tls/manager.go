@@ -0,0 +1,180 @@
+// Package tls gives NSM a full HTTPS story without depending on mkcert
+// (see internal/cert for that path): it generates a per-user self-signed
+// CA, installs it into the OS trust store, and mints short-lived leaf
+// certificates on demand for whatever domain a project configures, so
+// https://myapp.test works with no browser warning.
+package tls
+
+import (
+	"crypto/tls"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	nsmlog "github.com/kashifsb/nsm/internal/log"
+	"github.com/kashifsb/nsm/pkg/logger"
+)
+
+// trace is the "cert" facet, enabled via NSM_TRACE=cert for per-handshake
+// certificate minting/cache detail that would otherwise drown out the rest
+// of the app's logging.
+var trace = nsmlog.Facet("cert")
+
+const (
+	// caValidity is how long a generated root CA is trusted for.
+	caValidity = 10 * 365 * 24 * time.Hour
+	// leafValidity is how long a minted leaf certificate is valid for.
+	leafValidity = 90 * 24 * time.Hour
+	// renewBefore triggers background regeneration this far ahead of a
+	// cached leaf's expiry, so GetCertificate never has to mint
+	// synchronously mid-handshake.
+	renewBefore = 15 * 24 * time.Hour
+	// renewCheckInterval is how often the background loop scans the cache
+	// for leaves due for renewal.
+	renewCheckInterval = time.Hour
+)
+
+// Manager mints and caches TLS certificates for NSM's dev domains, backed
+// by a self-signed CA that's installed into the OS trust store once so
+// browsers stop warning about it. The zero value is not usable; construct
+// with NewManager.
+type Manager struct {
+	mu    sync.RWMutex
+	cache map[string]*cachedCert
+
+	ca   *authority
+	stop chan struct{}
+}
+
+type cachedCert struct {
+	cert   *tls.Certificate
+	expiry time.Time
+}
+
+// NewManager loads (or creates) the local CA under dataDir/tls and starts
+// the background renewal loop. dataDir is normally ~/.nsm/<project>,
+// matching cert.Manager's layout.
+func NewManager(dataDir string) (*Manager, error) {
+	caDir := filepath.Join(dataDir, "tls")
+	if err := os.MkdirAll(caDir, 0o700); err != nil {
+		return nil, fmt.Errorf("create tls directory: %w", err)
+	}
+
+	ca, err := loadOrCreateAuthority(caDir)
+	if err != nil {
+		return nil, fmt.Errorf("load CA: %w", err)
+	}
+
+	m := &Manager{
+		cache: make(map[string]*cachedCert),
+		ca:    ca,
+		stop:  make(chan struct{}),
+	}
+
+	go m.renewLoop()
+
+	return m, nil
+}
+
+// EnsureCert returns a leaf certificate for domain, minting and caching a
+// new one signed by the local CA if none is cached or the cached one is
+// due for renewal.
+func (m *Manager) EnsureCert(domain string) (*tls.Certificate, error) {
+	if cert, ok := m.cached(domain); ok {
+		trace.Tracef("cache hit for %s", domain)
+		return cert, nil
+	}
+	trace.Tracef("cache miss for %s, minting", domain)
+	return m.mint(domain)
+}
+
+func (m *Manager) cached(domain string) (*tls.Certificate, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	entry, ok := m.cache[domain]
+	if !ok || time.Now().After(entry.expiry.Add(-renewBefore)) {
+		return nil, false
+	}
+	return entry.cert, true
+}
+
+func (m *Manager) mint(domain string) (*tls.Certificate, error) {
+	cert, expiry, err := m.ca.issueLeaf(domain, leafValidity)
+	if err != nil {
+		return nil, fmt.Errorf("issue certificate for %s: %w", domain, err)
+	}
+
+	m.mu.Lock()
+	m.cache[domain] = &cachedCert{cert: cert, expiry: expiry}
+	m.mu.Unlock()
+
+	logger.Info("Minted TLS certificate", "domain", domain, "expires", expiry.Format("2006-01-02"))
+	return cert, nil
+}
+
+// GetCertificate is suitable for tls.Config.GetCertificate: it mints or
+// reuses a cached leaf for the SNI name the client requested.
+func (m *Manager) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	domain := hello.ServerName
+	if domain == "" {
+		return nil, fmt.Errorf("no SNI server name in TLS handshake")
+	}
+	trace.Tracef("handshake SNI=%s", domain)
+	return m.EnsureCert(domain)
+}
+
+// renewLoop regenerates cached leaves that are within renewBefore of
+// expiry, so a domain that's already been served once never blocks
+// GetCertificate on a synchronous mint again.
+func (m *Manager) renewLoop() {
+	ticker := time.NewTicker(renewCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.renewDue()
+		case <-m.stop:
+			return
+		}
+	}
+}
+
+func (m *Manager) renewDue() {
+	m.mu.RLock()
+	due := make([]string, 0, len(m.cache))
+	now := time.Now()
+	for domain, entry := range m.cache {
+		if now.After(entry.expiry.Add(-renewBefore)) {
+			due = append(due, domain)
+		}
+	}
+	m.mu.RUnlock()
+
+	for _, domain := range due {
+		if _, err := m.mint(domain); err != nil {
+			logger.Warn("Background certificate renewal failed", "domain", domain, "error", err)
+		}
+	}
+}
+
+// Close stops the background renewal loop.
+func (m *Manager) Close() error {
+	close(m.stop)
+	return nil
+}
+
+// CARoot returns the path to the root CA certificate, for display or
+// manual trust-store installation instructions.
+func (m *Manager) CARoot() string {
+	return m.ca.certPath
+}
+
+// InstallCA installs the local CA into the OS trust store so certificates
+// it issues are trusted without a browser warning.
+func (m *Manager) InstallCA() error {
+	return m.ca.install()
+}
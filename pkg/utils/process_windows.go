@@ -0,0 +1,44 @@
+//go:build windows
+
+package utils
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// IsProcessRunning reports whether pid is alive. Windows has no signal-0
+// equivalent, so this opens the process and checks whether it has already
+// produced an exit code.
+func IsProcessRunning(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+
+	handle, err := syscall.OpenProcess(syscall.PROCESS_QUERY_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+	defer syscall.CloseHandle(handle)
+
+	var exitCode uint32
+	if err := syscall.GetExitCodeProcess(handle, &exitCode); err != nil {
+		return false
+	}
+
+	return exitCode == uint32(syscall.STILL_ACTIVE)
+}
+
+func KillProcess(pid int) error {
+	if pid <= 0 {
+		return fmt.Errorf("invalid pid: %d", pid)
+	}
+
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return fmt.Errorf("find process: %w", err)
+	}
+
+	return process.Kill()
+}
@@ -10,7 +10,6 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
-	"syscall"
 	"time"
 )
 
@@ -148,34 +147,10 @@ func ParsePort(s string) (int, error) {
 	return port, nil
 }
 
-// Process utilities
-func IsProcessRunning(pid int) bool {
-	if pid <= 0 {
-		return false
-	}
-
-	process, err := os.FindProcess(pid)
-	if err != nil {
-		return false
-	}
-
-	// On Unix systems, sending signal 0 checks if process exists
-	err = process.Signal(os.Signal(syscall.Signal(0)))
-	return err == nil
-}
-
-func KillProcess(pid int) error {
-	if pid <= 0 {
-		return fmt.Errorf("invalid pid: %d", pid)
-	}
-
-	process, err := os.FindProcess(pid)
-	if err != nil {
-		return fmt.Errorf("find process: %w", err)
-	}
-
-	return process.Kill()
-}
+// Process utilities. IsProcessRunning and KillProcess are the platform-
+// specific halves in process_unix.go/process_windows.go: Unix checks
+// liveness with a signal-0 probe, Windows has no such signal so it reads
+// the process's exit code via the Win32 API instead.
 
 // JSON utilities
 func PrettyJSON(data interface{}) (string, error) {
@@ -0,0 +1,68 @@
+// Package versioninfo collects the runtime/environment details shown by the
+// `version` subcommand on both the nsm and nsm-setup CLIs.
+package versioninfo
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+
+	"github.com/kashifsb/nsm/internal/platform"
+	"github.com/kashifsb/nsm/pkg/utils"
+)
+
+// Info is the structured data behind the version banner. TLDs is left for
+// the caller to populate, since only nsm-setup's subsystem knows about it.
+type Info struct {
+	Version         string   `json:"version"`
+	Commit          string   `json:"commit"`
+	BuildDate       string   `json:"build_date"`
+	GoVersion       string   `json:"go_version"`
+	OS              string   `json:"os"`
+	Arch            string   `json:"arch"`
+	PackageManager  string   `json:"package_manager"`
+	Port443Bindable bool     `json:"port_443_bindable"`
+	TLDs            []string `json:"tlds,omitempty"`
+}
+
+// Detect gathers runtime and environment info for the version banner.
+// version/commit/buildDate are the ldflags-injected build-time values.
+func Detect(version, commit, buildDate string) Info {
+	return Info{
+		Version:         version,
+		Commit:          commit,
+		BuildDate:       buildDate,
+		GoVersion:       runtime.Version(),
+		OS:              runtime.GOOS,
+		Arch:            runtime.GOARCH,
+		PackageManager:  detectPackageManager(),
+		Port443Bindable: platform.NewPortManager().CanUsePort443(),
+	}
+}
+
+func detectPackageManager() string {
+	for _, mgr := range []string{"npm", "cargo", "go", "mvn", "pip"} {
+		if utils.IsCommandAvailable(mgr) {
+			return mgr
+		}
+	}
+	return "none detected"
+}
+
+// Render formats Info as a human-readable banner beneath the tool's ASCII
+// logo. Use json.MarshalIndent(info, "", "  ") for the --json form instead.
+func Render(banner string, info Info) string {
+	var b strings.Builder
+	b.WriteString(banner)
+	b.WriteString("\n")
+	fmt.Fprintf(&b, "Version:          %s (%s)\n", info.Version, info.Commit)
+	fmt.Fprintf(&b, "Built:            %s\n", info.BuildDate)
+	fmt.Fprintf(&b, "Go version:       %s\n", info.GoVersion)
+	fmt.Fprintf(&b, "OS/Arch:          %s/%s\n", info.OS, info.Arch)
+	fmt.Fprintf(&b, "Package manager:  %s\n", info.PackageManager)
+	fmt.Fprintf(&b, "Port 443 bindable: %t\n", info.Port443Bindable)
+	if len(info.TLDs) > 0 {
+		fmt.Fprintf(&b, "Configured TLDs:  %s\n", strings.Join(info.TLDs, ", "))
+	}
+	return b.String()
+}